@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +11,7 @@ import (
 	"github.com/pilshchikov/homebrew-go/internal/cmd"
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
 )
 
 var (
@@ -20,11 +23,18 @@ var (
 func main() {
 	if err := run(); err != nil {
 		logger.Error("brew failed: %v", err)
+		var usageErr *cmd.UsageError
+		if errors.As(err, &usageErr) {
+			os.Exit(usageErr.ExitCode())
+		}
 		os.Exit(1)
 	}
 }
 
 func run() error {
+	// Config errors are reported through the logger before flags are known
+	logger.Init(false, false, false)
+
 	// Initialize configuration
 	cfg, err := config.New()
 	if err != nil {
@@ -48,10 +58,18 @@ func run() error {
 		cfg.HomebrewLibrary = filepath.Join(cfg.HomebrewRepository, "Library")
 	}
 
+	cfg.AppVersion = Version
+
 	// Initialize logger with config
 	logger.Init(cfg.Debug, cfg.Verbose, cfg.Quiet)
 
+	// Ctrl-C cancels the command and removes half-written kegs and downloads
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := utils.HandleInterrupts(cancel)
+	defer stop()
+
 	// Create and execute root command
 	rootCmd := cmd.NewRootCmd(cfg, Version, GitCommit, BuildDate)
-	return rootCmd.Execute()
+	return rootCmd.ExecuteContext(ctx)
 }
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// Analytics categories published under formulae.brew.sh/api/analytics
+const (
+	AnalyticsInstall          = "install"
+	AnalyticsInstallOnRequest = "install-on-request"
+	AnalyticsBuildError       = "build-error"
+)
+
+// AnalyticsPeriods are the day ranges analytics are published for
+var AnalyticsPeriods = []int{30, 90, 365}
+
+// FormulaAnalytics holds a formula's counts keyed by category and period,
+// in the same shape as the "analytics" field of the formula API
+type FormulaAnalytics map[string]map[string]map[string]int
+
+// AnalyticsClient reads the aggregate analytics Homebrew publishes, caching
+// each dataset for an hour
+type AnalyticsClient struct {
+	client *Client
+
+	mu       sync.Mutex
+	datasets map[string]map[string]int
+}
+
+// analyticsResponse is a published analytics dataset
+type analyticsResponse struct {
+	Category string `json:"category"`
+	Items    []struct {
+		Formula string          `json:"formula"`
+		Count   json.RawMessage `json:"count"`
+	} `json:"items"`
+}
+
+// NewAnalyticsClient creates an analytics client for the configured API
+func NewAnalyticsClient(cfg *config.Config) *AnalyticsClient {
+	return &AnalyticsClient{
+		client:   NewClient(cfg),
+		datasets: make(map[string]map[string]int),
+	}
+}
+
+// InstallCount returns how often a formula was installed in the last days
+func (a *AnalyticsClient) InstallCount(formula string, days int) (int, error) {
+	return a.Count(AnalyticsInstall, formula, days)
+}
+
+// InstallOnRequestCount returns how often a formula was installed directly
+// rather than as a dependency in the last days
+func (a *AnalyticsClient) InstallOnRequestCount(formula string, days int) (int, error) {
+	return a.Count(AnalyticsInstallOnRequest, formula, days)
+}
+
+// BuildErrorCount returns how often a formula failed to build in the last days
+func (a *AnalyticsClient) BuildErrorCount(formula string, days int) (int, error) {
+	return a.Count(AnalyticsBuildError, formula, days)
+}
+
+// Count returns a formula's count in a category; formulae absent from the
+// dataset have a count of zero
+func (a *AnalyticsClient) Count(category, formula string, days int) (int, error) {
+	counts, err := a.dataset(category, days)
+	if err != nil {
+		return 0, err
+	}
+	return counts[formula], nil
+}
+
+// FormulaAnalytics collects every category and period for a formula
+func (a *AnalyticsClient) FormulaAnalytics(formula string) (FormulaAnalytics, error) {
+	result := make(FormulaAnalytics)
+	for _, category := range []string{AnalyticsInstall, AnalyticsInstallOnRequest, AnalyticsBuildError} {
+		result[category] = make(map[string]map[string]int)
+		for _, days := range AnalyticsPeriods {
+			count, err := a.Count(category, formula, days)
+			if err != nil {
+				return nil, err
+			}
+			result[category][fmt.Sprintf("%dd", days)] = map[string]int{formula: count}
+		}
+	}
+	return result, nil
+}
+
+// dataset returns the parsed counts for a category and period
+func (a *AnalyticsClient) dataset(category string, days int) (map[string]int, error) {
+	if err := ValidateAnalyticsPeriod(days); err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s/%dd", category, days)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if counts, ok := a.datasets[key]; ok {
+		return counts, nil
+	}
+
+	data, err := a.fetch(key)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := parseAnalytics(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s analytics: %w", key, err)
+	}
+
+	a.datasets[key] = counts
+	return counts, nil
+}
+
+// fetch reads a dataset from the cache, downloading it when missing or
+// older than an hour
+func (a *AnalyticsClient) fetch(key string) ([]byte, error) {
+	var cacheFile string
+	if a.client.config.HomebrewCache != "" {
+		cacheFile = filepath.Join(a.client.config.HomebrewCache, "api", "analytics", key+".json")
+		if a.client.isCacheValid(cacheFile) {
+			if data, err := os.ReadFile(cacheFile); err == nil {
+				logger.Debug("Using cached %s analytics", key)
+				return data, nil
+			}
+		}
+	}
+
+	url := fmt.Sprintf("%s/analytics/%s.json", a.client.apiDomain, key)
+	req, err := a.client.newRequest(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.client.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s analytics: %w", key, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("analytics request failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if cacheFile != "" {
+		if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+			logger.Warn("Failed to create cache directory: %v", err)
+		} else if err := writeCacheFile(cacheFile, data); err != nil {
+			logger.Warn("Failed to cache %s analytics: %v", key, err)
+		}
+	}
+
+	return data, nil
+}
+
+// parseAnalytics maps formula names to counts. Counts are published as
+// comma-grouped strings such as "12,345".
+func parseAnalytics(data []byte) (map[string]int, error) {
+	var response analyticsResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("malformed JSON: %w", err)
+	}
+
+	counts := make(map[string]int, len(response.Items))
+	for _, item := range response.Items {
+		if item.Formula == "" {
+			continue
+		}
+		count, err := parseAnalyticsCount(item.Count)
+		if err != nil {
+			return nil, fmt.Errorf("bad count for %s: %w", item.Formula, err)
+		}
+		counts[item.Formula] += count
+	}
+	return counts, nil
+}
+
+func parseAnalyticsCount(raw json.RawMessage) (int, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return 0, nil
+	}
+
+	var s string
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0, err
+		}
+	} else {
+		s = string(raw)
+	}
+	return strconv.Atoi(strings.ReplaceAll(s, ",", ""))
+}
+
+// ValidateAnalyticsPeriod returns an error unless analytics are published
+// for a window of days
+func ValidateAnalyticsPeriod(days int) error {
+	for _, period := range AnalyticsPeriods {
+		if days == period {
+			return nil
+		}
+	}
+	return fmt.Errorf("analytics are only published for %v days, not %d", AnalyticsPeriods, days)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// newAnalyticsServer serves install analytics where wget's count is scaled
+// by the period so each dataset is distinguishable
+func newAnalyticsServer(t *testing.T, requests *int) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		path, ok := strings.CutPrefix(r.URL.Path, "/analytics/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		category, period, _ := strings.Cut(strings.TrimSuffix(path, ".json"), "/")
+		var days int
+		if _, err := fmt.Sscanf(period, "%dd", &days); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch category {
+		case AnalyticsInstall, AnalyticsInstallOnRequest:
+			_, _ = fmt.Fprintf(w, `{"category": %q, "total_items": 2, "items": [
+				{"number": 1, "formula": "wget", "count": "%s", "percent": "1.5"},
+				{"number": 2, "formula": "curl", "count": "42", "percent": "0.1"}
+			]}`, category, formatThousands(days*1000+7))
+		case AnalyticsBuildError:
+			_, _ = fmt.Fprintf(w, `{"category": %q, "items": [{"number": 1, "formula": "gcc", "count": 3}]}`, category)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func formatThousands(n int) string {
+	return fmt.Sprintf("%d,%03d", n/1000, n%1000)
+}
+
+func TestAnalyticsClientPeriods(t *testing.T) {
+	logger.Init(false, false, true)
+
+	var requests int
+	server := newAnalyticsServer(t, &requests)
+	defer server.Close()
+
+	client := NewAnalyticsClient(&config.Config{HomebrewCache: t.TempDir()})
+	client.client.apiDomain = server.URL
+
+	for _, days := range AnalyticsPeriods {
+		count, err := client.InstallCount("wget", days)
+		if err != nil {
+			t.Fatalf("InstallCount(wget, %d) error = %v", days, err)
+		}
+		if want := days*1000 + 7; count != want {
+			t.Errorf("InstallCount(wget, %d) = %d, want %d", days, count, want)
+		}
+	}
+
+	if count, err := client.InstallOnRequestCount("curl", 90); err != nil || count != 42 {
+		t.Errorf("InstallOnRequestCount(curl, 90) = %d, %v; want 42", count, err)
+	}
+	if count, err := client.BuildErrorCount("gcc", 30); err != nil || count != 3 {
+		t.Errorf("BuildErrorCount(gcc, 30) = %d, %v; want 3", count, err)
+	}
+
+	// Formulae missing from a dataset have no analytics rather than an error
+	if count, err := client.InstallCount("no-such-formula", 365); err != nil || count != 0 {
+		t.Errorf("InstallCount(no-such-formula) = %d, %v; want 0, nil", count, err)
+	}
+
+	if _, err := client.InstallCount("wget", 7); err == nil {
+		t.Error("Expected error for unpublished period")
+	}
+}
+
+func TestAnalyticsClientCache(t *testing.T) {
+	logger.Init(false, false, true)
+
+	var requests int
+	server := newAnalyticsServer(t, &requests)
+	defer server.Close()
+
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
+
+	first := NewAnalyticsClient(cfg)
+	first.client.apiDomain = server.URL
+	if _, err := first.InstallCount("wget", 30); err != nil {
+		t.Fatalf("InstallCount() error = %v", err)
+	}
+	if _, err := first.InstallCount("curl", 30); err != nil {
+		t.Fatalf("InstallCount() error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected one request for a dataset, got %d", requests)
+	}
+
+	// A new client reads the on-disk cache
+	second := NewAnalyticsClient(cfg)
+	second.client.apiDomain = server.URL
+	if count, err := second.InstallCount("wget", 30); err != nil || count != 30007 {
+		t.Errorf("Cached InstallCount() = %d, %v", count, err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected cached dataset to be reused, got %d requests", requests)
+	}
+}
+
+func TestFormulaAnalytics(t *testing.T) {
+	logger.Init(false, false, true)
+
+	var requests int
+	server := newAnalyticsServer(t, &requests)
+	defer server.Close()
+
+	client := NewAnalyticsClient(&config.Config{})
+	client.client.apiDomain = server.URL
+
+	data, err := client.FormulaAnalytics("wget")
+	if err != nil {
+		t.Fatalf("FormulaAnalytics() error = %v", err)
+	}
+	if got := data[AnalyticsInstall]["365d"]["wget"]; got != 365007 {
+		t.Errorf("install 365d = %d, want 365007", got)
+	}
+	if got := data[AnalyticsBuildError]["30d"]["wget"]; got != 0 {
+		t.Errorf("build-error 30d = %d, want 0", got)
+	}
+}
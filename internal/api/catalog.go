@@ -0,0 +1,238 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// catalogDigestHeader carries the SHA256 of a catalog response when the
+// server provides one
+const catalogDigestHeader = "X-Checksum-Sha256"
+
+// fetchCatalog downloads a full catalog such as formula.json, checks that it
+// is a JSON array of objects carrying key, and verifies its digest if one is
+// published
+func (c *Client) fetchCatalog(name, key string) ([]byte, error) {
+	url := fmt.Sprintf("%s/%s", c.apiDomain, name)
+
+	req, err := c.newRequest(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if err := validateCatalog(body, key); err != nil {
+		return nil, fmt.Errorf("invalid %s response: %w", name, err)
+	}
+
+	if expected := c.catalogDigest(url, resp); expected != "" {
+		if err := verifyCatalogDigest(sha256.Sum256(body), expected); err != nil {
+			return nil, fmt.Errorf("invalid %s response: %w", name, err)
+		}
+	}
+
+	return body, nil
+}
+
+// validateCatalog rejects truncated, HTML or otherwise malformed catalogs
+func validateCatalog(body []byte, key string) error {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return fmt.Errorf("expected a JSON array")
+	}
+
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(trimmed, &entries); err != nil {
+		return fmt.Errorf("malformed JSON: %w", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("catalog is empty")
+	}
+
+	for i, entry := range entries {
+		if value, ok := entry[key].(string); !ok || value == "" {
+			return fmt.Errorf("entry %d has no %q", i, key)
+		}
+	}
+
+	return nil
+}
+
+// catalogDigest returns the published SHA256 for a catalog, taken from the
+// response header or a companion .sha256 file, or "" if none is available
+func (c *Client) catalogDigest(url string, resp *http.Response) string {
+	if digest := resp.Header.Get(catalogDigestHeader); formula.IsSHA256(digest) {
+		return strings.ToLower(digest)
+	}
+
+	req, err := c.newRequest(url + ".sha256")
+	if err != nil {
+		return ""
+	}
+
+	digestResp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Debug("No digest available for %s: %v", url, err)
+		return ""
+	}
+	defer func() { _ = digestResp.Body.Close() }()
+
+	if digestResp.StatusCode != 200 {
+		return ""
+	}
+
+	data, err := io.ReadAll(io.LimitReader(digestResp.Body, 1024))
+	if err != nil {
+		return ""
+	}
+
+	// Accept both a bare digest and sha256sum's "<digest>  <file>" format
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || !formula.IsSHA256(fields[0]) {
+		logger.Debug("Ignoring malformed digest for %s", url)
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+func verifyCatalogDigest(sum [sha256.Size]byte, expected string) error {
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("SHA256 mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
+
+// catalogCacheFile returns where the validated formula.json is cached
+func (c *Client) catalogCacheFile() string {
+	return filepath.Join(c.config.HomebrewCache, "api", "formula.json")
+}
+
+// cacheCatalog stores a validated formula.json for offline lookups
+func (c *Client) cacheCatalog(body []byte) {
+	if c.config.HomebrewCache == "" {
+		return
+	}
+
+	cacheFile := c.catalogCacheFile()
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+		logger.Warn("Failed to create cache directory: %v", err)
+		return
+	}
+	if err := writeCacheFile(cacheFile, body); err != nil {
+		logger.Warn("Failed to cache formulae catalog: %v", err)
+		return
+	}
+
+	c.catalogMu.Lock()
+	c.catalog, c.catalogLoaded = nil, false
+	c.catalogMu.Unlock()
+}
+
+// catalogFormula looks a formula up in the cached catalog. It reports false
+// when the cache is missing or stale, or the entry lacks install metadata.
+func (c *Client) catalogFormula(name string) (*formula.Formula, bool) {
+	c.catalogMu.Lock()
+	defer c.catalogMu.Unlock()
+
+	if !c.catalogLoaded {
+		c.catalog = c.loadCatalog()
+		c.catalogLoaded = true
+	}
+
+	entry, ok := c.catalog[name]
+	if !ok {
+		return nil, false
+	}
+
+	f := formulaFromAPIResponse(entry)
+	if f.Version == "" || f.URL == "" {
+		return nil, false
+	}
+	return f, true
+}
+
+// catalogNames lists the formulae in the cached catalog, reporting false
+// when the cache is missing or stale
+func (c *Client) catalogNames() ([]string, bool) {
+	c.catalogMu.Lock()
+	defer c.catalogMu.Unlock()
+
+	if !c.catalogLoaded {
+		c.catalog = c.loadCatalog()
+		c.catalogLoaded = true
+	}
+	if len(c.catalog) == 0 {
+		return nil, false
+	}
+
+	var names []string
+	for key, entry := range c.catalog {
+		if key == entry.Name {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+// loadCatalog parses the cached catalog, indexing entries by name, full
+// name and aliases
+func (c *Client) loadCatalog() map[string]*FormulaAPIResponse {
+	if c.config.HomebrewCache == "" {
+		return nil
+	}
+
+	cacheFile := c.catalogCacheFile()
+	if !c.isCacheValid(cacheFile) {
+		return nil
+	}
+
+	data, err := os.ReadFile(cacheFile)
+	if err != nil {
+		return nil
+	}
+
+	var entries []*FormulaAPIResponse
+	if err := json.Unmarshal(data, &entries); err != nil {
+		logger.Debug("Ignoring unreadable formulae catalog: %v", err)
+		return nil
+	}
+
+	catalog := make(map[string]*FormulaAPIResponse, len(entries))
+	for _, entry := range entries {
+		for _, key := range append([]string{entry.Name, entry.FullName}, entry.Aliases...) {
+			if key != "" {
+				catalog[key] = entry
+			}
+		}
+	}
+	return catalog
+}
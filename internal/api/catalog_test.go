@@ -0,0 +1,249 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestValidateCatalog(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "valid", body: `[{"name": "wget"}, {"name": "curl"}]`},
+		{name: "truncated", body: `[{"name": "wget"}, {"na`, wantErr: true},
+		{name: "html error page", body: `<html><body>Bad Gateway</body></html>`, wantErr: true},
+		{name: "empty array", body: `[]`, wantErr: true},
+		{name: "object instead of array", body: `{"name": "wget"}`, wantErr: true},
+		{name: "entry missing key", body: `[{"name": "wget"}, {"desc": "no name"}]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCatalog([]byte(tt.body), "name")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCatalog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRefreshCacheKeepsCacheOnTruncatedResponse(t *testing.T) {
+	logger.Init(false, false, true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/formula.json" {
+			_, _ = w.Write([]byte(`[{"name": "wget"}, {"name": "cu`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{HomebrewCache: t.TempDir()})
+	client.apiDomain = server.URL
+
+	cacheFile := filepath.Join(client.config.HomebrewCache, "api", "formula_names.txt")
+	client.cacheNames(cacheFile, []string{"curl", "git", "wget"})
+
+	if err := client.RefreshCache(); err == nil {
+		t.Fatal("RefreshCache() expected error for truncated response")
+	}
+
+	names, err := client.readCachedNames(cacheFile)
+	if err != nil || len(names) != 3 {
+		t.Fatalf("Cached formulae list should be retained, got %v (%v)", names, err)
+	}
+
+	// An expired cache is still preferred over a broken response
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(cacheFile, old, old); err != nil {
+		t.Fatal(err)
+	}
+	names, err = client.listAllFormulae()
+	if err != nil || len(names) != 3 {
+		t.Errorf("listAllFormulae() = %v, %v; want cached names", names, err)
+	}
+}
+
+func TestListAllFormulaeUsesFreshCatalog(t *testing.T) {
+	logger.Init(false, false, true)
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if r.URL.Path == "/formula.json" {
+			_, _ = w.Write([]byte(`[{"name": "wget"}, {"name": "curl"}]`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{HomebrewCache: t.TempDir()})
+	client.apiDomain = server.URL
+
+	// Without any cache the catalog is downloaded and its digest looked up
+	names, err := client.listAllFormulae()
+	if err != nil || strings.Join(names, ",") != "wget,curl" {
+		t.Fatalf("listAllFormulae() = %v, %v", names, err)
+	}
+	if strings.Join(requests, ",") != "/formula.json,/formula.json.sha256" {
+		t.Errorf("Expected the catalog and its digest to be requested, got %v", requests)
+	}
+
+	// A fresh catalog answers without a download, even if the names list is gone
+	requests = nil
+	if err := os.Remove(filepath.Join(client.config.HomebrewCache, "api", "formula_names.txt")); err != nil {
+		t.Fatal(err)
+	}
+	client = NewClient(client.config)
+	client.apiDomain = server.URL
+	names, err = client.listAllFormulae()
+	if err != nil || strings.Join(names, ",") != "curl,wget" {
+		t.Fatalf("listAllFormulae() = %v, %v", names, err)
+	}
+	if len(requests) != 0 {
+		t.Errorf("Expected no requests with a fresh catalog, got %v", requests)
+	}
+}
+
+func TestFetchCatalogDigest(t *testing.T) {
+	body := []byte(`[{"name": "wget"}]`)
+	sum := sha256.Sum256(body)
+	good := hex.EncodeToString(sum[:])
+	bad := hex.EncodeToString(make([]byte, sha256.Size))
+
+	tests := []struct {
+		name      string
+		header    string
+		companion string
+		wantErr   bool
+	}{
+		{name: "no digest published"},
+		{name: "matching header", header: good},
+		{name: "mismatched header", header: bad, wantErr: true},
+		{name: "matching companion file", companion: good + "  formula.json\n"},
+		{name: "mismatched companion file", companion: bad, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/formula.json":
+					if tt.header != "" {
+						w.Header().Set(catalogDigestHeader, tt.header)
+					}
+					_, _ = w.Write(body)
+				case "/formula.json.sha256":
+					if tt.companion == "" {
+						http.NotFound(w, r)
+						return
+					}
+					_, _ = w.Write([]byte(tt.companion))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			client := NewClient(&config.Config{HomebrewCache: t.TempDir()})
+			client.apiDomain = server.URL
+
+			_, err := client.fetchCatalog("formula.json", "name")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("fetchCatalog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSearchCasksDigestMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cask.json":
+			_, _ = w.Write([]byte(`[{"token": "firefox", "name": "Firefox"}]`))
+		case "/cask.json.sha256":
+			_, _ = w.Write([]byte(hex.EncodeToString(make([]byte, sha256.Size))))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{})
+	client.apiDomain = server.URL
+
+	if _, err := client.SearchCasks("fire", 0); err == nil {
+		t.Error("SearchCasks() expected digest mismatch error")
+	}
+}
+
+func TestGetFormulaFromCachedCatalog(t *testing.T) {
+	logger.Init(false, false, true)
+
+	catalog := `[
+		{"name": "wget", "full_name": "wget", "aliases": ["gnu-wget"], "desc": "Internet file retriever",
+		 "versions": {"stable": "1.21.4"},
+		 "urls": {"stable": {"url": "https://ftp.gnu.org/gnu/wget/wget-1.21.4.tar.gz", "checksum": "abc123"}},
+		 "dependencies": ["openssl@3"]},
+		{"name": "curl", "full_name": "curl"}
+	]`
+
+	var formulaRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/formula.json":
+			_, _ = w.Write([]byte(catalog))
+		case "/formula/curl.json":
+			formulaRequests++
+			_, _ = w.Write([]byte(`{"name": "curl", "versions": {"stable": "8.5.0"}}`))
+		default:
+			if strings.HasPrefix(r.URL.Path, "/formula/") {
+				formulaRequests++
+			}
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{HomebrewCache: t.TempDir()})
+	client.apiDomain = server.URL
+
+	if err := client.RefreshCache(); err != nil {
+		t.Fatalf("RefreshCache() error = %v", err)
+	}
+
+	for _, name := range []string{"wget", "gnu-wget"} {
+		f, err := client.GetFormula(name)
+		if err != nil {
+			t.Fatalf("GetFormula(%s) error = %v", name, err)
+		}
+		if f.Name != "wget" || f.Version != "1.21.4" || f.SHA256 != "abc123" || len(f.Dependencies) != 1 {
+			t.Errorf("GetFormula(%s) = %+v", name, f)
+		}
+	}
+	if formulaRequests != 0 {
+		t.Errorf("Catalog hits made %d per-formula requests, want 0", formulaRequests)
+	}
+
+	// Entries without install metadata still go to the per-formula endpoint
+	if f, err := client.GetFormula("curl"); err != nil || f.Version != "8.5.0" {
+		t.Errorf("GetFormula(curl) = %+v, %v", f, err)
+	}
+	if formulaRequests != 1 {
+		t.Errorf("Expected one per-formula request for curl, got %d", formulaRequests)
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// NameKind says whether a name refers to a formula or a cask
+type NameKind int
+
+const (
+	// KindUnknown is a name neither the name lists nor the API know
+	KindUnknown NameKind = iota
+	// KindFormula is a formula name
+	KindFormula
+	// KindCask is a cask token
+	KindCask
+)
+
+// Classifier decides whether names refer to formulae or casks. It consults
+// the cached formula and cask name lists first and only asks the API about
+// names they don't settle. Results are remembered for the classifier's life.
+type Classifier struct {
+	client *Client
+
+	mu       sync.Mutex
+	loaded   bool
+	formulae map[string]bool
+	casks    map[string]bool
+	results  map[string]NameKind
+}
+
+// NewClassifier creates a classifier backed by client
+func NewClassifier(client *Client) *Classifier {
+	return &Classifier{client: client, results: make(map[string]NameKind)}
+}
+
+// Classify returns what name refers to. Names that are both a formula and a
+// cask are treated as formulae, as Homebrew does.
+func (cl *Classifier) Classify(name string) NameKind {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if kind, ok := cl.results[name]; ok {
+		return kind
+	}
+	cl.loadNames()
+
+	kind := KindUnknown
+	switch {
+	case cl.formulae[name]:
+		kind = KindFormula
+	case cl.casks[name]:
+		kind = KindCask
+	default:
+		// The lists may be missing or older than the API
+		kind = cl.askAPI(name)
+	}
+
+	logger.Debug("Classified %s as %s", name, kind)
+	cl.results[name] = kind
+	return kind
+}
+
+// IsCask reports whether name is known to be a cask
+func (cl *Classifier) IsCask(name string) bool {
+	return cl.Classify(name) == KindCask
+}
+
+// loadNames reads the cached name lists once, however old they are
+func (cl *Classifier) loadNames() {
+	if cl.loaded {
+		return
+	}
+	cl.loaded = true
+
+	apiCache := filepath.Join(cl.client.config.HomebrewCache, "api")
+	cl.formulae = cl.readNameSet(filepath.Join(apiCache, "formula_names.txt"))
+	cl.casks = cl.readNameSet(filepath.Join(apiCache, "cask_names.txt"))
+}
+
+func (cl *Classifier) readNameSet(filename string) map[string]bool {
+	names, err := cl.client.readCachedNames(filename)
+	if err != nil {
+		return nil
+	}
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name != "" {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+// askAPI looks the name up as a formula, then as a cask
+func (cl *Classifier) askAPI(name string) NameKind {
+	if _, err := cl.client.GetFormula(name); err == nil {
+		return KindFormula
+	}
+	if _, err := cl.client.GetCask(name); err == nil {
+		return KindCask
+	}
+	return KindUnknown
+}
+
+// String returns the kind's name
+func (k NameKind) String() string {
+	switch k {
+	case KindFormula:
+		return "formula"
+	case KindCask:
+		return "cask"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestClassifier(t *testing.T) {
+	logger.Init(false, false, true)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/formula/newformula.json":
+			_, _ = w.Write([]byte(`{"name": "newformula", "versions": {"stable": "1.0"}}`))
+		case "/cask/newcask.json":
+			_, _ = w.Write([]byte(`{"token": "newcask", "version": "1.0"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
+	apiCache := filepath.Join(cfg.HomebrewCache, "api")
+	_ = os.MkdirAll(apiCache, 0755)
+	_ = os.WriteFile(filepath.Join(apiCache, "formula_names.txt"), []byte("wget\ndocker\nfirefox-app"), 0644)
+	_ = os.WriteFile(filepath.Join(apiCache, "cask_names.txt"), []byte("firefox\ndocker\nvisual-studio-code"), 0644)
+
+	client := NewClient(cfg)
+	client.apiDomain = server.URL
+	classifier := NewClassifier(client)
+
+	tests := []struct {
+		name string
+		want NameKind
+	}{
+		{"wget", KindFormula},
+		{"firefox-app", KindFormula},
+		{"firefox", KindCask},
+		{"visual-studio-code", KindCask},
+		{"docker", KindFormula},
+		{"newformula", KindFormula},
+		{"newcask", KindCask},
+		{"nothing", KindUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifier.Classify(tt.name); got != tt.want {
+				t.Errorf("Classify(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	// Listed names never reach the API and answers are remembered
+	before := requests.Load()
+	for _, tt := range tests {
+		classifier.Classify(tt.name)
+	}
+	if got := requests.Load(); got != before {
+		t.Errorf("Expected repeated lookups to be cached, got %d more requests", got-before)
+	}
+	if before != 5 {
+		t.Errorf("Expected 5 API requests for unlisted names, got %d", before)
+	}
+}
@@ -1,6 +1,10 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,11 +12,14 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pilshchikov/homebrew-go/internal/cask"
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/errors"
 	"github.com/pilshchikov/homebrew-go/internal/formula"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 	"github.com/pilshchikov/homebrew-go/internal/utils"
@@ -24,10 +31,28 @@ type Client struct {
 	httpClient *http.Client
 	apiDomain  string
 	userAgent  string
+
+	// ctx cancels in-flight requests when the running command is interrupted
+	ctx context.Context
+
+	// strictVerification fails bottle downloads whose size differs from the
+	// Content-Length instead of warning
+	strictVerification bool
+
+	// catalog indexes the cached formula.json by name, loaded on first use
+	catalogMu     sync.Mutex
+	catalog       map[string]*FormulaAPIResponse
+	catalogLoaded bool
+
+	// formulae memoizes GetFormula so shared dependencies are resolved once
+	// per run
+	formulaeMu sync.Mutex
+	formulae   map[string]*formula.Formula
 }
 
 const (
-	archARM64 = "arm64"
+	archARM64  = "arm64"
+	archX86_64 = "x86_64"
 )
 
 // FormulaAPIResponse represents the API response for a formula
@@ -95,31 +120,199 @@ func NewClient(cfg *config.Config) *Client {
 		apiDomain = "https://formulae.brew.sh/api"
 	}
 
-	userAgent := fmt.Sprintf("Homebrew-Go/3.0.0 (%s; %s) Go/%s",
-		runtime.GOOS, runtime.GOARCH, "1.20")
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent(cfg.AppVersion)
+	}
 
 	return &Client{
 		config: cfg,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:       30 * time.Second,
+			Transport:     utils.Transport,
+			CheckRedirect: utils.CheckRedirect,
 		},
 		apiDomain: apiDomain,
 		userAgent: userAgent,
+		ctx:       context.Background(),
+		formulae:  make(map[string]*formula.Formula),
+	}
+}
+
+// defaultUserAgent identifies this build, its platform and Go runtime
+func defaultUserAgent(version string) string {
+	if version == "" {
+		version = "dev"
+	}
+	return fmt.Sprintf("Homebrew-Go/%s (%s; %s) Go/%s",
+		version, runtime.GOOS, runtime.GOARCH, strings.TrimPrefix(runtime.Version(), "go"))
+}
+
+// newRequest creates a GET request carrying the client's User-Agent and, if
+// enabled, a fresh X-Request-ID
+func (c *Client) newRequest(url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(c.ctx, "GET", url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.config.RequestID {
+		id, err := newRequestID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate request ID: %w", err)
+		}
+		req.Header.Set("X-Request-ID", id)
+		logger.Debug("GET %s (request ID %s)", url, id)
+	}
+	return req, nil
+}
+
+// newRequestID returns a random version 4 UUID
+func newRequestID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// kegOnlyReason describes why a formula is keg-only, preferring the API's
+// explanation over its reason symbol such as ":provided_by_macos"
+func kegOnlyReason(reason map[string]string) string {
+	if explanation := strings.TrimSpace(reason["explanation"]); explanation != "" {
+		return explanation
+	}
+	return strings.ReplaceAll(strings.TrimPrefix(reason["reason"], ":"), "_", " ")
+}
+
+// serviceFromAPI converts a formula's `service` block. The run command may
+// be a string, a list, or a list per OS keyed by "macos" and "linux".
+func serviceFromAPI(data map[string]interface{}) *formula.Service {
+	if len(data) == 0 {
+		return nil
+	}
+
+	svc := &formula.Service{}
+	run := data["run"]
+	if byOS, ok := run.(map[string]interface{}); ok {
+		osKey := "linux"
+		if runtime.GOOS == "darwin" {
+			osKey = "macos"
+		}
+		run = byOS[osKey]
+	}
+	switch run := run.(type) {
+	case string:
+		svc.Run = []string{run}
+	case []interface{}:
+		for _, arg := range run {
+			if arg, ok := arg.(string); ok {
+				svc.Run = append(svc.Run, arg)
+			}
+		}
+	}
+	if len(svc.Run) == 0 {
+		return nil
+	}
+
+	svc.RunType, _ = data["run_type"].(string)
+	svc.WorkingDir, _ = data["working_dir"].(string)
+	svc.LogPath, _ = data["log_path"].(string)
+	svc.ErrorLogPath, _ = data["error_log_path"].(string)
+	if keepAlive, ok := data["keep_alive"].(map[string]interface{}); ok {
+		svc.KeepAlive, _ = keepAlive["always"].(bool)
 	}
+	if env, ok := data["environment_variables"].(map[string]interface{}); ok {
+		svc.Environment = make(map[string]string, len(env))
+		for key, value := range env {
+			if value, ok := value.(string); ok {
+				svc.Environment[key] = value
+			}
+		}
+	}
+
+	return svc
 }
 
-// GetFormula fetches formula data from the API
+// SetTransport replaces the transport used for this client's requests
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = transport
+}
+
+// SetContext makes requests made by this client stop when ctx is cancelled
+func (c *Client) SetContext(ctx context.Context) {
+	if ctx != nil {
+		c.ctx = ctx
+	}
+}
+
+// SetStrictVerification makes bottle downloads fail on a size mismatch
+func (c *Client) SetStrictVerification(strict bool) {
+	c.strictVerification = strict
+}
+
+// GetFormula fetches formula data from the API. Results are reused for the
+// rest of the run unless --no-cache is given.
 func (c *Client) GetFormula(name string) (*formula.Formula, error) {
+	if c.config.NoCache {
+		return c.fetchFormula(name)
+	}
+
+	c.formulaeMu.Lock()
+	cached, ok := c.formulae[name]
+	c.formulaeMu.Unlock()
+	if ok {
+		logger.Debug("Reusing formula %s resolved earlier in this run", name)
+		return copyFormula(cached), nil
+	}
+
+	// The cached full catalog answers most lookups without a request
+	f, ok := c.catalogFormula(name)
+	if ok {
+		logger.Debug("Resolved formula %s from cached catalog", name)
+	} else {
+		var err error
+		if f, err = c.fetchFormula(name); err != nil {
+			return nil, err
+		}
+	}
+
+	c.formulaeMu.Lock()
+	c.formulae[name] = f
+	c.formulaeMu.Unlock()
+
+	return copyFormula(f), nil
+}
+
+// copyFormula returns a shallow copy so callers can't alter memoized formulae
+func copyFormula(f *formula.Formula) *formula.Formula {
+	clone := *f
+	return &clone
+}
+
+// FormulaNotFoundError is returned when the API has no formula of that name
+type FormulaNotFoundError struct {
+	Name string
+}
+
+func (e *FormulaNotFoundError) Error() string {
+	return fmt.Sprintf("formula %s not found", e.Name)
+}
+
+// fetchFormula requests a single formula from the API
+func (c *Client) fetchFormula(name string) (*formula.Formula, error) {
 	logger.Debug("Fetching formula %s from API", name)
 
 	url := fmt.Sprintf("%s/formula/%s.json", c.apiDomain, name)
 
-	req, err := http.NewRequest("GET", url, http.NoBody)
+	req, err := c.newRequest(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("User-Agent", c.userAgent)
 	req.Header.Set("Accept", "application/json")
 
 	resp, err := c.httpClient.Do(req)
@@ -129,7 +322,7 @@ func (c *Client) GetFormula(name string) (*formula.Formula, error) {
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode == 404 {
-		return nil, fmt.Errorf("formula %s not found", name)
+		return nil, &FormulaNotFoundError{Name: name}
 	}
 
 	if resp.StatusCode != 200 {
@@ -146,6 +339,14 @@ func (c *Client) GetFormula(name string) (*formula.Formula, error) {
 		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
 	}
 
+	f := formulaFromAPIResponse(&apiResponse)
+
+	logger.Debug("Successfully fetched formula %s", name)
+	return f, nil
+}
+
+// formulaFromAPIResponse converts an API formula description to a Formula
+func formulaFromAPIResponse(apiResponse *FormulaAPIResponse) *formula.Formula {
 	// Convert API response to our Formula struct
 	f := &formula.Formula{
 		Name:              apiResponse.Name,
@@ -158,8 +359,14 @@ func (c *Client) GetFormula(name string) (*formula.Formula, error) {
 		TestDependencies:  apiResponse.TestDependencies,
 		Caveats:           apiResponse.Caveats,
 		KegOnly:           apiResponse.KegOnly,
+		KegOnlyReason:     kegOnlyReason(apiResponse.KegOnlyReason),
 		Deprecated:        apiResponse.Deprecated,
+		DeprecationDate:   apiResponse.DeprecationDate,
+		DeprecationReason: apiResponse.DeprecationReason,
 		Disabled:          apiResponse.Disabled,
+		DisableDate:       apiResponse.DisableDate,
+		DisableReason:     apiResponse.DisableReason,
+		Service:           serviceFromAPI(apiResponse.Service),
 	}
 
 	// Extract version information
@@ -176,16 +383,26 @@ func (c *Client) GetFormula(name string) (*formula.Formula, error) {
 			f.SHA256 = sha256
 		}
 	}
+	if urls, ok := apiResponse.Urls["head"].(map[string]interface{}); ok {
+		if url, ok := urls["url"].(string); ok {
+			f.Head = &formula.Head{URL: url}
+			if branch, ok := urls["branch"].(string); ok {
+				f.Head.Branch = branch
+			}
+		}
+	}
 
 	// Extract bottle information
 	if bottle, ok := apiResponse.Bottle["stable"].(map[string]interface{}); ok {
 		if files, ok := bottle["files"].(map[string]interface{}); ok {
 			f.Bottle = &formula.Bottle{
 				Stable: &formula.BottleSpec{
-					Rebuild: 0,
-					Files:   make(map[string]formula.BottleFile),
+					Files: make(map[string]formula.BottleFile),
 				},
 			}
+			if rebuild, ok := bottle["rebuild"].(float64); ok {
+				f.Bottle.Stable.Rebuild = int(rebuild)
+			}
 
 			for platform, fileInfo := range files {
 				if fileData, ok := fileInfo.(map[string]interface{}); ok {
@@ -202,12 +419,39 @@ func (c *Client) GetFormula(name string) (*formula.Formula, error) {
 		}
 	}
 
-	logger.Debug("Successfully fetched formula %s", name)
-	return f, nil
+	return f
+}
+
+// DefaultSearchLimit caps search results when no explicit limit is given
+const DefaultSearchLimit = 20
+
+// Search match ranks, best first
+const (
+	matchExact = iota
+	matchPrefix
+	matchSubstring
+	matchNone
+)
+
+// matchRank classifies how name matches a lowercase query
+func matchRank(name, query string) int {
+	name = strings.ToLower(name)
+	switch {
+	case name == query:
+		return matchExact
+	case strings.HasPrefix(name, query):
+		return matchPrefix
+	case strings.Contains(name, query):
+		return matchSubstring
+	default:
+		return matchNone
+	}
 }
 
-// SearchFormulae searches for formulae by name or description
-func (c *Client) SearchFormulae(query string) ([]SearchResult, error) {
+// SearchFormulae searches for formulae by name, returning at most limit
+// results (0 means unlimited). Exact matches come first, then prefix and
+// other substring matches, each sorted by name.
+func (c *Client) SearchFormulae(query string, limit int) ([]SearchResult, error) {
 	logger.Debug("Searching formulae for: %s", query)
 
 	// For now, we'll fetch all formulae and filter locally
@@ -217,25 +461,40 @@ func (c *Client) SearchFormulae(query string) ([]SearchResult, error) {
 		return nil, fmt.Errorf("failed to get formulae list: %w", err)
 	}
 
-	var results []SearchResult
 	query = strings.ToLower(query)
 
+	var matches []string
 	for _, formulaName := range formulaeList {
-		if strings.Contains(strings.ToLower(formulaName), query) {
-			// Fetch detailed info for matching formulae
-			if len(results) < 20 { // Limit results
-				if formula, err := c.GetFormula(formulaName); err == nil {
-					result := SearchResult{
-						Name:       formula.Name,
-						FullName:   formula.FullName,
-						Desc:       formula.Description,
-						Homepage:   formula.Homepage,
-						Deprecated: formula.Deprecated,
-						Disabled:   formula.Disabled,
-					}
-					results = append(results, result)
-				}
+		if matchRank(formulaName, query) != matchNone {
+			matches = append(matches, formulaName)
+		}
+	}
+
+	// Sort before limiting so each page is stable
+	sort.Slice(matches, func(i, j int) bool {
+		ri, rj := matchRank(matches[i], query), matchRank(matches[j], query)
+		if ri != rj {
+			return ri < rj
+		}
+		return matches[i] < matches[j]
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	var results []SearchResult
+	for _, formulaName := range matches {
+		// Fetch detailed info for matching formulae
+		if formula, err := c.GetFormula(formulaName); err == nil {
+			result := SearchResult{
+				Name:       formula.Name,
+				FullName:   formula.FullName,
+				Desc:       formula.Description,
+				Homepage:   formula.Homepage,
+				Deprecated: formula.Deprecated,
+				Disabled:   formula.Disabled,
 			}
+			results = append(results, result)
 		}
 	}
 
@@ -253,31 +512,36 @@ func (c *Client) listAllFormulae() ([]string, error) {
 		}
 	}
 
-	// Fetch from API
-	url := fmt.Sprintf("%s/formula.json", c.apiDomain)
-
-	req, err := http.NewRequest("GET", url, http.NoBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	// A fresh catalog already lists every formula, so there is nothing to
+	// download or verify
+	if names, ok := c.catalogNames(); ok {
+		c.cacheNames(cacheFile, names)
+		return names, nil
 	}
 
-	req.Header.Set("User-Agent", c.userAgent)
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := c.httpClient.Do(req)
+	names, err := c.fetchFormulaNames()
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch formulae list: %w", err)
+		// A stale list is better than none when the API misbehaves
+		if cached, cacheErr := c.readCachedNames(cacheFile); cacheErr == nil {
+			logger.Warn("Using cached formulae list: %v", err)
+			return cached, nil
+		}
+		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
-	}
+	// Cache the results
+	c.cacheNames(cacheFile, names)
 
-	body, err := io.ReadAll(resp.Body)
+	return names, nil
+}
+
+// fetchFormulaNames downloads and validates formula.json
+func (c *Client) fetchFormulaNames() ([]string, error) {
+	body, err := c.fetchCatalog("formula.json", "name")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to fetch formulae list: %w", err)
 	}
+	c.cacheCatalog(body)
 
 	var formulae []map[string]interface{}
 	if err := json.Unmarshal(body, &formulae); err != nil {
@@ -291,12 +555,21 @@ func (c *Client) listAllFormulae() ([]string, error) {
 		}
 	}
 
-	// Cache the results
-	c.cacheNames(cacheFile, names)
-
 	return names, nil
 }
 
+// RefreshCache fetches API metadata again, keeping the cached copy if the
+// new download fails validation
+func (c *Client) RefreshCache() error {
+	names, err := c.fetchFormulaNames()
+	if err != nil {
+		return err
+	}
+
+	c.cacheNames(filepath.Join(c.config.HomebrewCache, "api", "formula_names.txt"), names)
+	return nil
+}
+
 // isCacheValid checks if the cache file is recent enough
 func (c *Client) isCacheValid(filename string) bool {
 	info, err := os.Stat(filename)
@@ -328,11 +601,25 @@ func (c *Client) cacheNames(filename string, names []string) {
 	}
 
 	data := strings.Join(names, "\n")
-	if err := os.WriteFile(filename, []byte(data), 0600); err != nil {
+	if err := writeCacheFile(filename, []byte(data)); err != nil {
 		logger.Warn("Failed to cache formulae names: %v", err)
 	}
 }
 
+// writeCacheFile writes to a temporary file first so readers never see a
+// partial cache entry
+func writeCacheFile(filename string, data []byte) error {
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
 // DownloadBottle downloads a bottle file
 func (c *Client) DownloadBottle(formula *formula.Formula, platform string) (string, error) {
 	if formula.Bottle == nil || formula.Bottle.Stable == nil {
@@ -364,13 +651,11 @@ func (c *Client) DownloadBottle(formula *formula.Formula, platform string) (stri
 	}
 
 	// Download the bottle
-	req, err := http.NewRequest("GET", bottleFile.URL, http.NoBody)
+	req, err := c.newRequest(bottleFile.URL)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("User-Agent", c.userAgent)
-
 	// Add authentication for GitHub Container Registry if needed
 	if strings.Contains(bottleFile.URL, "ghcr.io") {
 		if authErr := c.addGHCRAuth(req); authErr != nil {
@@ -397,21 +682,73 @@ func (c *Client) DownloadBottle(formula *formula.Formula, platform string) (stri
 	}
 	defer func() { _ = file.Close() }()
 
-	_, err = io.Copy(file, resp.Body)
+	// A partial bottle is useless, so drop it if brew is interrupted
+	defer utils.RegisterCleanup(func() { _ = os.Remove(filepath) })()
+
+	// Hash while writing so the bottle isn't read back for verification
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hasher), resp.Body)
 	if err != nil {
 		return "", fmt.Errorf("failed to save bottle: %w", err)
 	}
 
-	// Verify checksum
-	if !c.isFileValid(filepath, bottleFile.SHA256) {
-		_ = os.Remove(filepath)
-		return "", fmt.Errorf("bottle checksum verification failed")
+	if resp.ContentLength > 0 && size != resp.ContentLength {
+		if c.strictVerification {
+			_ = file.Close()
+			_ = os.Remove(filepath)
+			return "", fmt.Errorf("bottle size verification failed: downloaded %d bytes, expected %d", size, resp.ContentLength)
+		}
+		logger.Warn("Downloaded size (%d bytes) differs from expected size (%d bytes)", size, resp.ContentLength)
+	}
+
+	if bottleFile.SHA256 != "" {
+		if actual := hex.EncodeToString(hasher.Sum(nil)); !strings.EqualFold(actual, bottleFile.SHA256) {
+			_ = file.Close()
+			_ = os.Remove(filepath)
+			return "", fmt.Errorf("bottle checksum verification failed: %w", errors.NewChecksumMismatchError(formula.Name, formula.Version, errors.ChecksumMismatch{
+				Expected: bottleFile.SHA256,
+				Actual:   actual,
+				Size:     size,
+			}))
+		}
 	}
 
 	logger.Success("Downloaded bottle: %s", filename)
 	return filepath, nil
 }
 
+// DownloadSize asks the server how large a download is with a HEAD request,
+// authenticating to GHCR as bottle downloads do. It returns 0 when the
+// server doesn't say or doesn't answer within timeout.
+func (c *Client) DownloadSize(url string, timeout time.Duration) int64 {
+	req, err := c.newRequest(url)
+	if err != nil {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+	req.Method = http.MethodHead
+
+	if strings.Contains(url, "ghcr.io") {
+		if err := c.addGHCRAuth(req); err != nil {
+			logger.Debug("GHCR authentication failed: %v", err)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Debug("Could not determine the size of %s: %v", url, err)
+		return 0
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		logger.Debug("Could not determine the size of %s: HTTP %d", url, resp.StatusCode)
+		return 0
+	}
+	return resp.ContentLength
+}
+
 // isFileValid checks if a file exists and has the correct checksum
 func (c *Client) isFileValid(filepath, expectedSHA256 string) bool {
 	if _, err := os.Stat(filepath); os.IsNotExist(err) {
@@ -422,8 +759,22 @@ func (c *Client) isFileValid(filepath, expectedSHA256 string) bool {
 		return true // No checksum to verify
 	}
 
-	// Verify SHA256 checksum
-	return utils.VerifySHA256(filepath, expectedSHA256) == nil
+	actual, err := utils.ComputeSHA256(filepath)
+	if err != nil {
+		logger.Debug("Cannot verify cached %s: %v", filepath, err)
+		return false
+	}
+	if strings.EqualFold(actual, expectedSHA256) {
+		return true
+	}
+
+	size := int64(-1)
+	if info, err := os.Stat(filepath); err == nil {
+		size = info.Size()
+	}
+	mismatch := errors.ChecksumMismatch{Expected: expectedSHA256, Actual: actual, Size: size}
+	logger.Warn("Cached %s is corrupt (%s); downloading it again", filepath, mismatch.String())
+	return false
 }
 
 // addGHCRAuth adds authentication for GitHub Container Registry
@@ -454,13 +805,11 @@ func (c *Client) addGHCRAuth(req *http.Request) error {
 
 	logger.Debug("Requesting GHCR token for scope: %s", scope)
 
-	tokenReq, err := http.NewRequest("GET", tokenURL, http.NoBody)
+	tokenReq, err := c.newRequest(tokenURL)
 	if err != nil {
 		return fmt.Errorf("failed to create token request: %w", err)
 	}
 
-	// Set appropriate headers for token request
-	tokenReq.Header.Set("User-Agent", c.userAgent)
 	tokenReq.Header.Set("Accept", "application/json")
 
 	tokenResp, err := c.httpClient.Do(tokenReq)
@@ -549,19 +898,20 @@ func (c *Client) downloadWithRetry(req *http.Request, url string) (*http.Respons
 // GetPlatformTag returns the platform tag for bottle selection
 func (c *Client) GetPlatformTag() string {
 	// This should match Homebrew's platform detection logic
+	arch := EffectiveArch(c.config)
 	switch runtime.GOOS {
 	case "darwin":
-		if runtime.GOARCH == archARM64 {
+		if arch == archARM64 {
 			return "arm64_sequoia" // Latest macOS version
 		}
 		return "x86_64_sequoia"
 	case "linux":
-		if runtime.GOARCH == archARM64 {
+		if arch == archARM64 {
 			return "arm64_linux"
 		}
 		return "x86_64_linux"
 	default:
-		return runtime.GOOS + "_" + runtime.GOARCH
+		return runtime.GOOS + "_" + arch
 	}
 }
 
@@ -569,7 +919,13 @@ func (c *Client) GetPlatformTag() string {
 func (c *Client) GetCask(name string) (*cask.Cask, error) {
 	url := fmt.Sprintf("%s/cask/%s.json", c.apiDomain, name)
 
-	resp, err := http.Get(url)
+	req, err := c.newRequest(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch cask: %w", err)
 	}
@@ -591,12 +947,109 @@ func (c *Client) GetCask(name string) (*cask.Cask, error) {
 	return c.parseCaskFromAPI(apiResponse)
 }
 
-// SearchCasks searches for casks matching the given query
-func (c *Client) SearchCasks(query string) ([]*cask.Cask, error) {
+// parseCaskVariations converts the API's per-platform overrides, keyed by
+// tags such as "arm64_sonoma" or "sonoma", into arch-specific URLs. Only the
+// variations for the given OS are used, so overrides for older releases
+// don't replace the base URL; Intel Macs use the bare OS name as the tag.
+func parseCaskVariations(value interface{}, osTag string) []cask.CaskURL {
+	variations, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var urls []cask.CaskURL
+	for _, arch := range []string{archARM64, archX86_64} {
+		tags := []string{arch + "_" + osTag}
+		if arch == archX86_64 {
+			tags = append(tags, osTag)
+		}
+		for _, tag := range tags {
+			variation, ok := variations[tag].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			url, _ := variation["url"].(string)
+			if url == "" {
+				continue
+			}
+
+			caskURL := cask.CaskURL{URL: url, Arch: arch}
+			if sha256, ok := variation["sha256"].(string); ok {
+				caskURL.Sha256 = sha256
+			}
+			urls = append(urls, caskURL)
+			break
+		}
+	}
+	return urls
+}
+
+// platformOS returns the OS part of the platform tag, such as "sequoia"
+func (c *Client) platformOS() string {
+	tag := c.GetPlatformTag()
+	for _, arch := range []string{archARM64, archX86_64} {
+		if name, ok := strings.CutPrefix(tag, arch+"_"); ok {
+			return name
+		}
+	}
+	return tag
+}
+
+// parseCaskInstaller converts an API installer stanza; a script may be given
+// either as a bare executable or as a map with executable, args and sudo
+func parseCaskInstaller(data map[string]interface{}) cask.CaskInstaller {
+	installer := cask.CaskInstaller{}
+	if manual, ok := data["manual"].(string); ok {
+		installer.Manual = manual
+	}
+
+	switch script := data["script"].(type) {
+	case string:
+		installer.Script = &cask.CaskScript{Executable: script}
+	case map[string]interface{}:
+		s := &cask.CaskScript{Args: parseStringList(script["args"])}
+		if executable, ok := script["executable"].(string); ok {
+			s.Executable = executable
+		}
+		if sudo, ok := script["sudo"].(bool); ok {
+			s.Sudo = sudo
+		}
+		installer.Script = s
+	}
+
+	return installer
+}
+
+// parseStringList accepts either a single string or a list of strings
+func parseStringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				items = append(items, str)
+			}
+		}
+		return items
+	}
+	return nil
+}
+
+// SearchCasks searches for casks matching the given query, returning at most
+// limit results ranked like SearchFormulae by token (0 means unlimited)
+func (c *Client) SearchCasks(query string, limit int) ([]*cask.Cask, error) {
 	// For now, use a simple approach - in practice this would use dedicated search endpoints
 	url := fmt.Sprintf("%s/cask.json", c.apiDomain)
 
-	resp, err := http.Get(url)
+	req, err := c.newRequest(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search casks: %w", err)
 	}
@@ -606,36 +1059,71 @@ func (c *Client) SearchCasks(query string) ([]*cask.Cask, error) {
 		return nil, fmt.Errorf("API request failed: %s", resp.Status)
 	}
 
-	var caskList []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&caskList); err != nil {
-		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	// Decode the list one entry at a time so only matches are kept in memory,
+	// hashing the stream so it can be checked against a published digest
+	hasher := sha256.New()
+	decoder := json.NewDecoder(io.TeeReader(resp.Body, hasher))
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("failed to decode search response: expected a JSON array")
 	}
 
 	var results []*cask.Cask
+	var tokens []string
 	queryLower := strings.ToLower(query)
 
-	for _, caskData := range caskList {
+	for decoder.More() {
+		var caskData map[string]interface{}
+		if err := decoder.Decode(&caskData); err != nil {
+			return nil, fmt.Errorf("failed to decode search response: %w", err)
+		}
+
 		// Basic search - check if query matches token or name
-		if token, ok := caskData["token"].(string); ok {
-			if strings.Contains(strings.ToLower(token), queryLower) {
-				if c, err := c.parseCaskFromAPI(caskData); err == nil {
-					results = append(results, c)
-				}
-			}
+		token, _ := caskData["token"].(string)
+		name, _ := caskData["name"].(string)
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+		if !strings.Contains(strings.ToLower(token), queryLower) &&
+			!strings.Contains(strings.ToLower(name), queryLower) {
+			continue
 		}
 
-		if name, ok := caskData["name"].(string); ok {
-			if strings.Contains(strings.ToLower(name), queryLower) {
-				if c, err := c.parseCaskFromAPI(caskData); err == nil {
-					results = append(results, c)
-				}
-			}
+		if c, err := c.parseCaskFromAPI(caskData); err == nil {
+			results = append(results, c)
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+
+	if expected := c.catalogDigest(url, resp); expected != "" {
+		// The tee has hashed everything decoded so far; hash the remainder
+		_, _ = io.Copy(hasher, resp.Body)
+		var sum [sha256.Size]byte
+		copy(sum[:], hasher.Sum(nil))
+		if err := verifyCatalogDigest(sum, expected); err != nil {
+			return nil, fmt.Errorf("invalid cask.json response: %w", err)
 		}
+	}
 
-		// Limit results to avoid too many matches
-		if len(results) >= 50 {
-			break
+	// Keep the full token list so names can be classified without the API
+	if c.config.HomebrewCache != "" {
+		c.cacheNames(filepath.Join(c.config.HomebrewCache, "api", "cask_names.txt"), tokens)
+	}
+
+	// Sort before limiting so each page is stable
+	rank := func(c *cask.Cask) int {
+		return min(matchRank(c.Token, queryLower), matchRank(c.Name, queryLower))
+	}
+	sort.Slice(results, func(i, j int) bool {
+		ri, rj := rank(results[i]), rank(results[j])
+		if ri != rj {
+			return ri < rj
 		}
+		return results[i].Token < results[j].Token
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
 	}
 
 	return results, nil
@@ -678,6 +1166,18 @@ func (c *Client) parseCaskFromAPI(apiData map[string]interface{}) (*cask.Cask, e
 		caskData.Caveats = caveats
 	}
 
+	if autoUpdates, ok := apiData["auto_updates"].(bool); ok {
+		caskData.AutoUpdates = autoUpdates
+	}
+
+	if deprecated, ok := apiData["deprecated"].(bool); ok {
+		caskData.Deprecated = deprecated
+	}
+
+	if disabled, ok := apiData["disabled"].(bool); ok {
+		caskData.Disabled = disabled
+	}
+
 	// Extract URL information
 	if urlData, ok := apiData["url"].([]interface{}); ok && len(urlData) > 0 {
 		for _, urlItem := range urlData {
@@ -686,6 +1186,15 @@ func (c *Client) parseCaskFromAPI(apiData map[string]interface{}) (*cask.Cask, e
 				if url, ok := urlMap["url"].(string); ok {
 					caskURL.URL = url
 				}
+				if arch, ok := urlMap["arch"].(string); ok {
+					caskURL.Arch = arch
+				}
+				if language, ok := urlMap["language"].(string); ok {
+					caskURL.Language = language
+				}
+				if sha256, ok := urlMap["sha256"].(string); ok {
+					caskURL.Sha256 = sha256
+				}
 				caskData.URL = append(caskData.URL, caskURL)
 			}
 		}
@@ -693,6 +1202,8 @@ func (c *Client) parseCaskFromAPI(apiData map[string]interface{}) (*cask.Cask, e
 		// Handle simple string URL
 		caskData.URL = []cask.CaskURL{{URL: urlStr}}
 	}
+	caskData.URL = append(caskData.URL, parseCaskVariations(apiData["variations"], c.platformOS())...)
+	caskData.PreferArch(EffectiveArch(c.config))
 
 	// Extract artifacts
 	if artifactsData, ok := apiData["artifacts"].([]interface{}); ok && len(artifactsData) > 0 {
@@ -744,6 +1255,15 @@ func (c *Client) parseCaskFromAPI(apiData map[string]interface{}) (*cask.Cask, e
 						}
 					}
 				}
+
+				// Extract installers
+				if installers, ok := artifactMap["installer"].([]interface{}); ok {
+					for _, installerItem := range installers {
+						if installerMap, ok := installerItem.(map[string]interface{}); ok {
+							artifact.Installer = append(artifact.Installer, parseCaskInstaller(installerMap))
+						}
+					}
+				}
 			}
 		}
 
@@ -776,6 +1296,9 @@ func (c *Client) parseCaskFromAPI(apiData map[string]interface{}) (*cask.Cask, e
 			}
 		}
 
+		dep.Formula = parseStringList(depsData["formula"])
+		dep.Cask = parseStringList(depsData["cask"])
+
 		caskData.Depends = []cask.CaskDependency{dep}
 	}
 
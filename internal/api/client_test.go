@@ -1,14 +1,19 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -16,6 +21,7 @@ import (
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/formula"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
 )
 
 func TestNewClient(t *testing.T) {
@@ -46,13 +52,76 @@ func TestNewClient(t *testing.T) {
 		t.Error("User agent not set")
 	}
 
-	expectedUserAgent := fmt.Sprintf("Homebrew-Go/3.0.0 (%s; %s) Go/%s",
-		runtime.GOOS, runtime.GOARCH, "1.20")
+	expectedUserAgent := fmt.Sprintf("Homebrew-Go/dev (%s; %s) Go/%s",
+		runtime.GOOS, runtime.GOARCH, strings.TrimPrefix(runtime.Version(), "go"))
 	if client.userAgent != expectedUserAgent {
 		t.Errorf("Expected user agent %s, got %s", expectedUserAgent, client.userAgent)
 	}
 }
 
+func TestUserAgent(t *testing.T) {
+	client := NewClient(&config.Config{AppVersion: "4.5.6"})
+	for _, want := range []string{"Homebrew-Go/4.5.6 ", "Go/" + strings.TrimPrefix(runtime.Version(), "go")} {
+		if !strings.Contains(client.userAgent, want) {
+			t.Errorf("Expected user agent %q to contain %q", client.userAgent, want)
+		}
+	}
+
+	client = NewClient(&config.Config{AppVersion: "4.5.6", UserAgent: "custom-agent/1.0"})
+	if client.userAgent != "custom-agent/1.0" {
+		t.Errorf("Expected the configured user agent, got %q", client.userAgent)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	var userAgents, requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		requestIDs = append(requestIDs, r.Header.Get("X-Request-ID"))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	for _, enabled := range []bool{false, true} {
+		userAgents, requestIDs = nil, nil
+		client := NewClient(&config.Config{AppVersion: "4.5.6", RequestID: enabled, NoCache: true})
+		client.apiDomain = server.URL
+
+		_, _ = client.GetFormula("wget")
+		_, _ = client.GetCask("firefox")
+		_, _ = client.SearchCasks("fire", 0)
+
+		if len(requestIDs) != 3 {
+			t.Fatalf("Expected 3 requests, got %d", len(requestIDs))
+		}
+		for _, ua := range userAgents {
+			if ua != client.userAgent {
+				t.Errorf("Expected User-Agent %q, got %q", client.userAgent, ua)
+			}
+		}
+		if !enabled {
+			for _, id := range requestIDs {
+				if id != "" {
+					t.Errorf("Expected no X-Request-ID by default, got %v", requestIDs)
+				}
+			}
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, id := range requestIDs {
+			if !uuidPattern.MatchString(id) {
+				t.Errorf("X-Request-ID %q is not a UUID", id)
+			}
+			if seen[id] {
+				t.Errorf("Expected a new X-Request-ID per request, got %v twice", id)
+			}
+			seen[id] = true
+		}
+	}
+}
+
 func TestNewClientWithCustomDomain(t *testing.T) {
 	_ = os.Setenv("HOMEBREW_API_DOMAIN", "https://custom.api.domain")
 	defer func() { _ = os.Unsetenv("HOMEBREW_API_DOMAIN") }()
@@ -71,12 +140,15 @@ func TestGetFormula(t *testing.T) {
 		if r.URL.Path == "/formula/wget.json" {
 			w.Header().Set("Content-Type", "application/json")
 			response := FormulaAPIResponse{
-				Name:         "wget",
-				FullName:     "wget",
-				Desc:         "Internet file retriever",
-				Homepage:     "https://www.gnu.org/software/wget/",
-				License:      "GPL-3.0",
-				Dependencies: []string{"openssl@1.1"},
+				Name:              "wget",
+				FullName:          "wget",
+				Desc:              "Internet file retriever",
+				Homepage:          "https://www.gnu.org/software/wget/",
+				License:           "GPL-3.0",
+				Dependencies:      []string{"openssl@1.1"},
+				Deprecated:        true,
+				DeprecationDate:   "2024-01-01",
+				DeprecationReason: "unmaintained",
 				Versions: map[string]interface{}{
 					"stable": "1.21.3",
 				},
@@ -137,6 +209,10 @@ func TestGetFormula(t *testing.T) {
 		t.Errorf("Expected dependencies [openssl@1.1], got %v", formula.Dependencies)
 	}
 
+	if !formula.Deprecated || formula.DeprecationDate != "2024-01-01" || formula.DeprecationReason != "unmaintained" {
+		t.Errorf("Expected deprecation since 2024-01-01 for unmaintained, got %v %q %q", formula.Deprecated, formula.DeprecationDate, formula.DeprecationReason)
+	}
+
 	if formula.URL != "https://ftp.gnu.org/gnu/wget/wget-1.21.3.tar.gz" {
 		t.Errorf("Expected URL, got '%s'", formula.URL)
 	}
@@ -176,6 +252,10 @@ func TestGetFormulaNotFound(t *testing.T) {
 	if !strings.Contains(err.Error(), "not found") {
 		t.Errorf("Expected 'not found' error, got: %v", err)
 	}
+	var notFound *FormulaNotFoundError
+	if !errors.As(err, &notFound) || notFound.Name != "nonexistent" {
+		t.Errorf("Expected a FormulaNotFoundError, got: %T", err)
+	}
 }
 
 func TestGetFormulaServerError(t *testing.T) {
@@ -198,6 +278,92 @@ func TestGetFormulaServerError(t *testing.T) {
 	}
 }
 
+func TestGetFormulaMemoized(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"name": "x", "versions": {"stable": "1.0"}, "dependencies": ["y"]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{}
+	client := NewClient(cfg)
+	client.apiDomain = server.URL
+
+	first, err := client.GetFormula("x")
+	if err != nil {
+		t.Fatalf("GetFormula() error = %v", err)
+	}
+	first.Version = "modified"
+
+	second, err := client.GetFormula("x")
+	if err != nil {
+		t.Fatalf("GetFormula() error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected one request for repeated lookups, got %d", requests)
+	}
+	if second.Version != "1.0" {
+		t.Errorf("Memoized formula was altered by a caller: version %q", second.Version)
+	}
+
+	// --no-cache always asks the API
+	cfg.NoCache = true
+	if _, err := client.GetFormula("x"); err != nil {
+		t.Fatalf("GetFormula() error = %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected --no-cache to bypass the memo, got %d requests", requests)
+	}
+}
+
+func TestClientReusesConnections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{})
+	client.SetTransport(utils.NewTransport())
+
+	var reused []bool
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			reused = append(reused, info.Reused)
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), "GET", server.URL, http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i+1, err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	if len(reused) != 2 {
+		t.Fatalf("Expected 2 connections to be traced, got %d", len(reused))
+	}
+	if reused[0] {
+		t.Error("First request should open a new connection")
+	}
+	if !reused[1] {
+		t.Error("Second request should reuse the idle connection")
+	}
+}
+
+func TestNewClientSharesTransport(t *testing.T) {
+	client := NewClient(&config.Config{})
+	if client.httpClient.Transport != utils.Transport {
+		t.Error("NewClient should use the shared transport")
+	}
+}
+
 func TestSearchFormulae(t *testing.T) {
 	// Create temporary directory for cache
 	tempDir, err := os.MkdirTemp("", "brew-test-cache")
@@ -242,7 +408,7 @@ func TestSearchFormulae(t *testing.T) {
 	client := NewClient(cfg)
 	client.apiDomain = server.URL
 
-	results, err := client.SearchFormulae("wget")
+	results, err := client.SearchFormulae("wget", DefaultSearchLimit)
 	if err != nil {
 		t.Fatalf("SearchFormulae failed: %v", err)
 	}
@@ -266,6 +432,100 @@ func TestSearchFormulae(t *testing.T) {
 	}
 }
 
+func TestSearchFormulaeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/formula.json" {
+			formulae := []map[string]interface{}{
+				{"name": "python@3.12"},
+				{"name": "python@3.10"},
+				{"name": "python@3.11"},
+				{"name": "wget"},
+			}
+			_ = json.NewEncoder(w).Encode(formulae)
+			return
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/formula/"), ".json")
+		_ = json.NewEncoder(w).Encode(FormulaAPIResponse{
+			Name:     name,
+			Versions: map[string]interface{}{"stable": "1.0.0"},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{HomebrewCache: t.TempDir()})
+	client.apiDomain = server.URL
+
+	tests := []struct {
+		limit    int
+		expected []string
+	}{
+		{limit: 2, expected: []string{"python@3.10", "python@3.11"}},
+		{limit: 0, expected: []string{"python@3.10", "python@3.11", "python@3.12"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("limit %d", tt.limit), func(t *testing.T) {
+			results, err := client.SearchFormulae("python", tt.limit)
+			if err != nil {
+				t.Fatalf("SearchFormulae() error = %v", err)
+			}
+
+			var names []string
+			for _, result := range results {
+				names = append(names, result.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.expected) {
+				t.Errorf("SearchFormulae() = %v, want %v", names, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSearchFormulaeRanking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/formula.json" {
+			formulae := []map[string]interface{}{
+				{"name": "libgit2"},
+				{"name": "git-lfs"},
+				{"name": "legit"},
+				{"name": "git"},
+				{"name": "wget"},
+			}
+			_ = json.NewEncoder(w).Encode(formulae)
+			return
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/formula/"), ".json")
+		_ = json.NewEncoder(w).Encode(FormulaAPIResponse{
+			Name:     name,
+			Versions: map[string]interface{}{"stable": "1.0.0"},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{HomebrewCache: t.TempDir()})
+	client.apiDomain = server.URL
+
+	results, err := client.SearchFormulae("Git", 0)
+	if err != nil {
+		t.Fatalf("SearchFormulae() error = %v", err)
+	}
+
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	expected := []string{"git", "git-lfs", "legit", "libgit2"}
+	if fmt.Sprint(names) != fmt.Sprint(expected) {
+		t.Errorf("SearchFormulae() = %v, want exact, prefix then substring matches %v", names, expected)
+	}
+}
+
 func TestGetPlatformTag(t *testing.T) {
 	cfg := &config.Config{}
 	client := NewClient(cfg)
@@ -305,6 +565,22 @@ func TestGetPlatformTag(t *testing.T) {
 	}
 }
 
+func TestGetPlatformTagArchOverride(t *testing.T) {
+	suffix := "_linux"
+	if runtime.GOOS == "darwin" {
+		suffix = "_sequoia"
+	} else if runtime.GOOS != "linux" {
+		t.Skip("bottle tags are only defined for macOS and Linux")
+	}
+
+	for arch, want := range map[string]string{"arm64": "arm64", "aarch64": "arm64", "x86_64": "x86_64", "intel": "x86_64"} {
+		client := NewClient(&config.Config{Arch: arch})
+		if got := client.GetPlatformTag(); got != want+suffix {
+			t.Errorf("GetPlatformTag() with Arch=%s = %s, want %s", arch, got, want+suffix)
+		}
+	}
+}
+
 func TestIsCacheValid(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "brew-test-cache")
 	if err != nil {
@@ -489,6 +765,87 @@ func TestDownloadBottle(t *testing.T) {
 	}
 }
 
+func TestDownloadBottleStrictSize(t *testing.T) {
+	logger.Init(false, false, true)
+
+	f := &formula.Formula{
+		Name:    "test",
+		Version: "1.0.0",
+		Bottle: &formula.Bottle{Stable: &formula.BottleSpec{Files: map[string]formula.BottleFile{
+			"monterey": {URL: "https://example.com/test.tar.gz"},
+		}}},
+	}
+
+	for _, strict := range []bool{false, true} {
+		client := NewClient(&config.Config{HomebrewCache: t.TempDir()})
+		client.SetStrictVerification(strict)
+		client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				ContentLength: 100,
+				Body:          io.NopCloser(strings.NewReader("data")),
+				Request:       req,
+			}, nil
+		})
+
+		path, err := client.DownloadBottle(f, "monterey")
+		if strict {
+			if err == nil || !strings.Contains(err.Error(), "size verification failed") {
+				t.Errorf("strict DownloadBottle() error = %v, want a size mismatch", err)
+			}
+		} else if err != nil || path == "" {
+			t.Errorf("DownloadBottle() = %q, %v, want only a warning", path, err)
+		}
+	}
+}
+
+func TestDownloadSize(t *testing.T) {
+	logger.Init(false, false, true)
+	t.Setenv("GITHUB_TOKEN", "test-token")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("Expected a HEAD request, got %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/ghcr.io/v2/homebrew/core/wget/blobs/sha256:abc":
+			if r.Header.Get("Authorization") != "Bearer test-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Length", "4321")
+		case "/slow":
+			time.Sleep(300 * time.Millisecond)
+			w.Header().Set("Content-Length", "1")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{})
+	if size := client.DownloadSize(server.URL+"/ghcr.io/v2/homebrew/core/wget/blobs/sha256:abc", time.Second); size != 4321 {
+		t.Errorf("DownloadSize() of a GHCR blob = %d, want 4321", size)
+	}
+	if size := client.DownloadSize(server.URL+"/missing", time.Second); size != 0 {
+		t.Errorf("DownloadSize() of a missing file = %d, want 0", size)
+	}
+
+	start := time.Now()
+	if size := client.DownloadSize(server.URL+"/slow", 50*time.Millisecond); size != 0 {
+		t.Errorf("DownloadSize() past the timeout = %d, want 0", size)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("DownloadSize() took %v, want it to give up after the timeout", elapsed)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
 func TestAddGHCRAuth(t *testing.T) {
 	// Test with GitHub token from environment
 	_ = os.Setenv("GITHUB_TOKEN", "test-token")
@@ -549,6 +906,72 @@ func TestDownloadWithRetry(t *testing.T) {
 	}
 }
 
+func TestServiceFromAPI(t *testing.T) {
+	osKey := "linux"
+	if runtime.GOOS == "darwin" {
+		osKey = "macos"
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantRun []string
+		want    func(*formula.Service) bool
+	}{
+		{
+			name:    "no service",
+			data:    `{}`,
+			wantRun: nil,
+		},
+		{
+			name:    "run list with options",
+			data:    `{"run": ["$HOMEBREW_PREFIX/opt/redis/bin/redis-server", "$HOMEBREW_PREFIX/etc/redis.conf"], "keep_alive": {"always": true}, "working_dir": "$HOMEBREW_PREFIX/var", "log_path": "$HOMEBREW_PREFIX/var/log/redis.log", "environment_variables": {"LANG": "C"}}`,
+			wantRun: []string{"$HOMEBREW_PREFIX/opt/redis/bin/redis-server", "$HOMEBREW_PREFIX/etc/redis.conf"},
+			want: func(svc *formula.Service) bool {
+				return svc.KeepAlive && svc.WorkingDir == "$HOMEBREW_PREFIX/var" &&
+					svc.LogPath == "$HOMEBREW_PREFIX/var/log/redis.log" && svc.Environment["LANG"] == "C"
+			},
+		},
+		{
+			name:    "run string",
+			data:    `{"run": "$HOMEBREW_PREFIX/opt/tor/bin/tor", "keep_alive": {"successful_exit": false}}`,
+			wantRun: []string{"$HOMEBREW_PREFIX/opt/tor/bin/tor"},
+			want:    func(svc *formula.Service) bool { return !svc.KeepAlive },
+		},
+		{
+			name:    "run per OS",
+			data:    `{"run": {"` + osKey + `": ["this-os"], "other": ["other-os"]}}`,
+			wantRun: []string{"this-os"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatal(err)
+			}
+
+			svc := serviceFromAPI(data)
+			if tt.wantRun == nil {
+				if svc != nil {
+					t.Errorf("serviceFromAPI() = %+v, want nil", svc)
+				}
+				return
+			}
+			if svc == nil {
+				t.Fatal("serviceFromAPI() = nil")
+			}
+			if !slices.Equal(svc.Run, tt.wantRun) {
+				t.Errorf("Run = %q, want %q", svc.Run, tt.wantRun)
+			}
+			if tt.want != nil && !tt.want(svc) {
+				t.Errorf("serviceFromAPI() = %+v", svc)
+			}
+		})
+	}
+}
+
 func TestParseCaskFromAPI(t *testing.T) {
 	cfg := &config.Config{}
 	client := NewClient(cfg)
@@ -599,6 +1022,119 @@ func TestParseCaskFromAPI(t *testing.T) {
 	}
 }
 
+func TestParseCaskFromAPIInstallerScript(t *testing.T) {
+	client := NewClient(&config.Config{})
+
+	apiData := map[string]interface{}{
+		"token": "script-cask",
+		"artifacts": []interface{}{
+			map[string]interface{}{
+				"installer": []interface{}{
+					map[string]interface{}{
+						"script": map[string]interface{}{
+							"executable": "Installer.app/Contents/MacOS/install",
+							"args":       []interface{}{"--silent"},
+							"sudo":       true,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	c, err := client.parseCaskFromAPI(apiData)
+	if err != nil {
+		t.Fatalf("parseCaskFromAPI failed: %v", err)
+	}
+
+	if len(c.Artifacts) == 0 || len(c.Artifacts[0].Installer) != 1 {
+		t.Fatalf("Expected one installer artifact, got %+v", c.Artifacts)
+	}
+	script := c.Artifacts[0].Installer[0].Script
+	if script == nil {
+		t.Fatal("Expected installer script")
+	}
+	if script.Executable != "Installer.app/Contents/MacOS/install" || !script.Sudo ||
+		len(script.Args) != 1 || script.Args[0] != "--silent" {
+		t.Errorf("Unexpected installer script %+v", script)
+	}
+}
+
+func TestParseCaskFromAPIVariations(t *testing.T) {
+	client := NewClient(&config.Config{})
+
+	apiData := map[string]interface{}{
+		"token":  "variant-cask",
+		"url":    "https://example.com/app-intel.dmg",
+		"sha256": "intel-sha",
+		"variations": map[string]interface{}{
+			"arm64_" + client.platformOS(): map[string]interface{}{"url": "https://example.com/app-arm.dmg", "sha256": "arm-sha"},
+			"arm64_big_sur":                map[string]interface{}{"url": "https://example.com/app-arm-old.dmg", "sha256": "old-sha"},
+			client.platformOS():            map[string]interface{}{"sha256": "ignored"},
+		},
+	}
+
+	c, err := client.parseCaskFromAPI(apiData)
+	if err != nil {
+		t.Fatalf("parseCaskFromAPI failed: %v", err)
+	}
+
+	if len(c.URL) != 2 {
+		t.Fatalf("Expected the base URL and one arm64 variant, got %+v", c.URL)
+	}
+	arm := c.SelectURL("arm64", "")
+	if arm == nil || arm.URL != "https://example.com/app-arm.dmg" || arm.Sha256 != "arm-sha" {
+		t.Fatalf("Unexpected arm64 variant %+v", arm)
+	}
+	intel := c.SelectURL("x86_64", "")
+	if intel == nil || intel.URL != "https://example.com/app-intel.dmg" {
+		t.Fatalf("Unexpected intel variant %+v", intel)
+	}
+
+	// Downloads follow the --arch override rather than the host
+	for arch, want := range map[string]string{"arm64": arm.URL, "intel": intel.URL} {
+		c, err := NewClient(&config.Config{Arch: arch}).parseCaskFromAPI(apiData)
+		if err != nil {
+			t.Fatalf("parseCaskFromAPI failed: %v", err)
+		}
+		if got := c.GetDownloadURL(); got != want {
+			t.Errorf("Expected the %s download %s, got %s", arch, want, got)
+		}
+	}
+}
+
+func TestParseCaskVariations(t *testing.T) {
+	variations := map[string]interface{}{
+		"arm64_sonoma":  map[string]interface{}{"url": "https://example.com/arm-sonoma.dmg"},
+		"arm64_big_sur": map[string]interface{}{"url": "https://example.com/arm-big-sur.dmg"},
+		"big_sur":       map[string]interface{}{"url": "https://example.com/intel-big-sur.dmg"},
+		"x86_64_linux":  map[string]interface{}{"url": "https://example.com/linux.tar.gz"},
+	}
+
+	tests := []struct {
+		osTag string
+		want  map[string]string
+	}{
+		{"sonoma", map[string]string{"arm64": "https://example.com/arm-sonoma.dmg"}},
+		{"big_sur", map[string]string{"arm64": "https://example.com/arm-big-sur.dmg", "x86_64": "https://example.com/intel-big-sur.dmg"}},
+		{"linux", map[string]string{"x86_64": "https://example.com/linux.tar.gz"}},
+		// Overrides for other releases never replace the base URL
+		{"sequoia", map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.osTag, func(t *testing.T) {
+			got := make(map[string]string)
+			for _, u := range parseCaskVariations(variations, tt.osTag) {
+				got[u.Arch] = u.URL
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("parseCaskVariations(%q) = %v, want %v", tt.osTag, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseCaskFromAPIInvalid(t *testing.T) {
 	cfg := &config.Config{}
 	client := NewClient(cfg)
@@ -768,7 +1304,7 @@ func TestSearchCasks(t *testing.T) {
 	client.apiDomain = server.URL
 
 	// Test search by token
-	results, err := client.SearchCasks("fire")
+	results, err := client.SearchCasks("fire", 0)
 	if err != nil {
 		t.Fatalf("SearchCasks failed: %v", err)
 	}
@@ -791,7 +1327,7 @@ func TestSearchCasks(t *testing.T) {
 	}
 
 	// Test search by name
-	results, err = client.SearchCasks("chrome")
+	results, err = client.SearchCasks("chrome", 0)
 	if err != nil {
 		t.Fatalf("SearchCasks failed: %v", err)
 	}
@@ -807,7 +1343,7 @@ func TestSearchCasks(t *testing.T) {
 	}
 
 	// Test search with no results
-	results, err = client.SearchCasks("nonexistentcask")
+	results, err = client.SearchCasks("nonexistentcask", 0)
 	if err != nil {
 		t.Fatalf("SearchCasks failed: %v", err)
 	}
@@ -816,15 +1352,22 @@ func TestSearchCasks(t *testing.T) {
 		t.Errorf("Expected no results for 'nonexistentcask', got %d", len(results))
 	}
 
-	// Test search limiting (should not exceed 50 results)
-	results, err = client.SearchCasks("")
+	// Test search limiting
+	results, err = client.SearchCasks("", 2)
 	if err != nil {
 		t.Fatalf("SearchCasks failed: %v", err)
 	}
 
-	// Should get all 4 casks since we search for empty string
-	if len(results) > 50 {
-		t.Errorf("Expected at most 50 results, got %d", len(results))
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results with limit 2, got %d", len(results))
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i-1].Token > results[i].Token {
+			t.Errorf("Results not sorted: %s before %s", results[i-1].Token, results[i].Token)
+		}
+	}
+	if len(results) > 0 && results[0].Token != "chrome" {
+		t.Errorf("Expected first result chrome, got %s", results[0].Token)
 	}
 }
 
@@ -839,7 +1382,7 @@ func TestSearchCasksAPIError(t *testing.T) {
 	client := NewClient(cfg)
 	client.apiDomain = server.URL
 
-	_, err := client.SearchCasks("test")
+	_, err := client.SearchCasks("test", 0)
 	if err == nil {
 		t.Error("Expected error for API failure")
 	}
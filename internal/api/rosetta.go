@@ -0,0 +1,80 @@
+package api
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// sysctl reads a kernel value by name; tests replace it
+var sysctl = func(name string) (string, error) {
+	out, err := exec.Command("sysctl", "-n", name).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+var (
+	bottleArchOnce sync.Once
+	bottleArch     string
+)
+
+// isTranslated reports whether the process runs under Rosetta 2. The
+// sysctl is missing on Intel Macs, which is treated as not translated.
+func isTranslated(read func(string) (string, error)) bool {
+	value, err := read("sysctl.proc_translated")
+	return err == nil && value == "1"
+}
+
+// nativeArch picks the architecture to select bottles for. An x86_64
+// process translated by Rosetta on Apple Silicon gets arm64 bottles
+// unless x86 was asked for explicitly.
+func nativeArch(goos, goarch string, translated, forceX86 bool) string {
+	if goos == "darwin" && goarch == "amd64" && translated && !forceX86 {
+		return archARM64
+	}
+	return goarch
+}
+
+// hostArch returns the architecture used for bottle selection, detecting
+// Rosetta once per process
+func hostArch() string {
+	bottleArchOnce.Do(func() {
+		bottleArch = runtime.GOARCH
+		if runtime.GOOS != "darwin" || runtime.GOARCH != "amd64" || !isTranslated(sysctl) {
+			return
+		}
+
+		forceX86 := wantsX86(os.Getenv("HOMEBREW_ARCH"))
+		bottleArch = nativeArch(runtime.GOOS, runtime.GOARCH, true, forceX86)
+		if forceX86 {
+			logger.Warn("Running under Rosetta 2 with HOMEBREW_ARCH=%s: using x86_64 bottles", os.Getenv("HOMEBREW_ARCH"))
+		} else {
+			logger.Warn("Running under Rosetta 2 on Apple Silicon: using native arm64 bottles (set HOMEBREW_ARCH=x86_64 to use Intel bottles)")
+		}
+	})
+	return bottleArch
+}
+
+// wantsX86 reports whether HOMEBREW_ARCH deliberately asks for Intel
+func wantsX86(arch string) bool {
+	return config.NormalizeArch(arch) == archX86_64
+}
+
+// EffectiveArch returns the architecture bottles are selected for: the
+// --arch/HOMEBREW_ARCH override when set, otherwise the host's after
+// Rosetta detection
+func EffectiveArch(cfg *config.Config) string {
+	if cfg != nil && cfg.Arch != "" {
+		if arch := config.NormalizeArch(cfg.Arch); arch != "" {
+			return arch
+		}
+	}
+	if arch := config.NormalizeArch(hostArch()); arch != "" {
+		return arch
+	}
+	return hostArch()
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsTranslated(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		err   error
+		want  bool
+	}{
+		{"translated", "1", nil, true},
+		{"native", "0", nil, false},
+		{"intel mac without sysctl", "", errors.New("unknown oid"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var asked string
+			read := func(name string) (string, error) {
+				asked = name
+				return tt.value, tt.err
+			}
+			if got := isTranslated(read); got != tt.want {
+				t.Errorf("isTranslated() = %v, want %v", got, tt.want)
+			}
+			if asked != "sysctl.proc_translated" {
+				t.Errorf("Expected sysctl.proc_translated to be read, got %q", asked)
+			}
+		})
+	}
+}
+
+func TestNativeArch(t *testing.T) {
+	tests := []struct {
+		name       string
+		goos       string
+		goarch     string
+		translated bool
+		forceX86   bool
+		want       string
+	}{
+		{"rosetta prefers arm64", "darwin", "amd64", true, false, "arm64"},
+		{"rosetta deliberately x86", "darwin", "amd64", true, true, "amd64"},
+		{"intel mac", "darwin", "amd64", false, false, "amd64"},
+		{"native arm64", "darwin", "arm64", false, false, "arm64"},
+		{"linux ignores translation", "linux", "amd64", true, false, "amd64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nativeArch(tt.goos, tt.goarch, tt.translated, tt.forceX86); got != tt.want {
+				t.Errorf("nativeArch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWantsX86(t *testing.T) {
+	for arch, want := range map[string]bool{"x86_64": true, "Intel": true, "arm64": false, "": false} {
+		if got := wantsX86(arch); got != want {
+			t.Errorf("wantsX86(%q) = %v, want %v", arch, got, want)
+		}
+	}
+}
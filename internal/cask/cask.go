@@ -2,10 +2,13 @@ package cask
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
 )
 
 const (
@@ -13,6 +16,10 @@ const (
 	extensionDMG   = ".dmg"
 )
 
+// downloadExtensions are the archive types GetFileExtension recognizes, in
+// the order they are checked
+var downloadExtensions = []string{extensionDMG, ".pkg", ".zip", ".tar.gz", ".tar.bz2", ".tar.xz"}
+
 // Cask represents a Homebrew cask for GUI applications
 type Cask struct {
 	Name        string           `json:"name"`
@@ -38,11 +45,23 @@ type Cask struct {
 	TapGitHead  string           `json:"tap_git_head"`
 	InstallTime *time.Time       `json:"installed,omitempty"`
 	InstalledBy string           `json:"installed_by,omitempty"`
+
+	// languages are tried in order when picking a localized download
+	languages []string
+
+	// arch is the architecture downloads are picked for; empty means the
+	// host's
+	arch string
 }
 
-// CaskURL represents download URLs for different versions/platforms
+// CaskURL represents download URLs for different versions/platforms. Arch
+// and Language restrict a variant to a machine or locale; Sha256 overrides
+// the cask-level checksum for that variant.
 type CaskURL struct {
 	URL      string                 `json:"url"`
+	Arch     string                 `json:"arch,omitempty"`
+	Language string                 `json:"language,omitempty"`
+	Sha256   string                 `json:"sha256,omitempty"`
 	Branch   string                 `json:"branch,omitempty"`
 	Tag      string                 `json:"tag,omitempty"`
 	Revision string                 `json:"revision,omitempty"`
@@ -87,10 +106,17 @@ type CaskBinary struct {
 
 // CaskInstaller represents a pkg installer
 type CaskInstaller struct {
-	Manual  string                 `json:"manual,omitempty"`
-	Script  map[string]interface{} `json:"script,omitempty"`
-	Allow   []string               `json:"allow_untrusted,omitempty"`
-	Choices []CaskChoice           `json:"choices,omitempty"`
+	Manual  string       `json:"manual,omitempty"`
+	Script  *CaskScript  `json:"script,omitempty"`
+	Allow   []string     `json:"allow_untrusted,omitempty"`
+	Choices []CaskChoice `json:"choices,omitempty"`
+}
+
+// CaskScript represents an executable run to install a cask
+type CaskScript struct {
+	Executable string   `json:"executable"`
+	Args       []string `json:"args,omitempty"`
+	Sudo       bool     `json:"sudo,omitempty"`
 }
 
 // CaskChoice represents installer choices
@@ -180,12 +206,120 @@ type CaskAppcast struct {
 
 // GetDownloadURL returns the primary download URL for the cask
 func (c *Cask) GetDownloadURL() string {
-	if len(c.URL) > 0 {
-		return c.URL[0].URL
+	if u := c.selectDownload(); u != nil {
+		return u.URL
 	}
 	return ""
 }
 
+// GetDownloadSHA256 returns the checksum expected for the URL GetDownloadURL
+// selects, falling back to the cask-level checksum
+func (c *Cask) GetDownloadSHA256() string {
+	if u := c.selectDownload(); u != nil && u.Sha256 != "" {
+		return u.Sha256
+	}
+	return c.Sha256
+}
+
+// PreferLanguages sets the languages, such as "de" or "en-GB", tried in
+// order before the system language and English when a cask has localized
+// downloads
+func (c *Cask) PreferLanguages(languages ...string) {
+	c.languages = languages
+}
+
+// PreferArch sets the architecture, such as "arm64" or "x86_64", whose
+// downloads are picked in place of the host's
+func (c *Cask) PreferArch(arch string) {
+	c.arch = arch
+}
+
+// selectDownload picks the variant for this machine in the first preferred
+// language that has one, or the default variant if none does
+func (c *Cask) selectDownload() *CaskURL {
+	arch := c.effectiveArch()
+	for _, language := range c.languagePreferences() {
+		if u := c.SelectURL(arch, language); u != nil && strings.EqualFold(u.Language, language) {
+			return u
+		}
+	}
+	return c.SelectURL(arch, "")
+}
+
+// languagePreferences returns the preferred languages, then the system
+// language, then English. Regional tags such as de-DE are followed by their
+// base language.
+func (c *Cask) languagePreferences() []string {
+	candidates := append([]string{}, c.languages...)
+	if language := systemLanguage(); language != "" {
+		candidates = append(candidates, language)
+	}
+	candidates = append(candidates, "en")
+
+	var preferences []string
+	for _, language := range candidates {
+		preferences = append(preferences, language)
+		if base, _, ok := strings.Cut(language, "-"); ok {
+			preferences = append(preferences, base)
+		}
+	}
+	return preferences
+}
+
+// systemLanguage returns the user's locale as a language tag, such as de-DE
+// for de_DE.UTF-8, or "" for the C locale
+func systemLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := os.Getenv(name)
+		if locale == "" {
+			continue
+		}
+		locale, _, _ = strings.Cut(locale, ".")
+		locale, _, _ = strings.Cut(locale, "@")
+		if locale == "C" || locale == "POSIX" {
+			return ""
+		}
+		return strings.ReplaceAll(locale, "_", "-")
+	}
+	return ""
+}
+
+// SelectURL picks the variant for an architecture and language. Variants
+// without an arch or language match any; exact matches are preferred and
+// earlier entries win ties. It returns nil if no variant matches.
+func (c *Cask) SelectURL(arch, language string) *CaskURL {
+	var best *CaskURL
+	bestScore := -1
+	for idx := range c.URL {
+		u := &c.URL[idx]
+		score := 0
+		if u.Arch != "" {
+			if config.NormalizeArch(u.Arch) != config.NormalizeArch(arch) {
+				continue
+			}
+			score += 2
+		}
+		if u.Language != "" && language != "" {
+			if !strings.EqualFold(u.Language, language) {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = u, score
+		}
+	}
+	return best
+}
+
+// effectiveArch returns the architecture set with PreferArch, or the host's
+func (c *Cask) effectiveArch() string {
+	if arch := config.NormalizeArch(c.arch); arch != "" {
+		return arch
+	}
+	return config.NormalizeArch(runtime.GOARCH)
+}
+
 // GetApplications returns all app artifacts
 func (c *Cask) GetApplications() []CaskApp {
 	return c.Artifacts[0].App
@@ -224,14 +358,11 @@ func (c *Cask) GetPrimaryAppName() string {
 func (c *Cask) IsCompatibleWithPlatform() bool {
 	// Check architecture requirements
 	if len(c.Depends) > 0 && len(c.Depends[0].Arch) > 0 {
-		currentArch := runtime.GOARCH
-		if currentArch == "amd64" {
-			currentArch = "x86_64"
-		}
+		currentArch := c.effectiveArch()
 
 		compatible := false
 		for _, arch := range c.Depends[0].Arch {
-			if arch == currentArch {
+			if config.NormalizeArch(arch) == currentArch {
 				compatible = true
 				break
 			}
@@ -303,18 +434,10 @@ func (c *Cask) GetFileExtension() string {
 	}
 
 	// Extract extension from URL
-	if strings.Contains(url, extensionDMG) {
-		return extensionDMG
-	} else if strings.Contains(url, ".pkg") {
-		return ".pkg"
-	} else if strings.Contains(url, ".zip") {
-		return ".zip"
-	} else if strings.Contains(url, ".tar.gz") {
-		return ".tar.gz"
-	} else if strings.Contains(url, ".tar.bz2") {
-		return ".tar.bz2"
-	} else if strings.Contains(url, ".tar.xz") {
-		return ".tar.xz"
+	for _, ext := range downloadExtensions {
+		if strings.Contains(url, ext) {
+			return ext
+		}
 	}
 
 	return extensionDMG // Default
@@ -322,16 +445,33 @@ func (c *Cask) GetFileExtension() string {
 
 // GetCacheFileName returns the filename for caching downloads
 func (c *Cask) GetCacheFileName() string {
-	ext := c.GetFileExtension()
+	name := c.Token
 	if c.Version != "" {
-		return fmt.Sprintf("%s-%s%s", c.Token, c.Version, ext)
+		name += "-" + c.Version
+	}
+	// Localized downloads of the same version are cached separately
+	if u := c.selectDownload(); u != nil && u.Language != "" {
+		name += "-" + u.Language
 	}
-	return fmt.Sprintf("%s%s", c.Token, ext)
+	return name + c.GetFileExtension()
+}
+
+// IsCacheFile reports whether name is the cached download GetCacheFileName
+// would produce for this cask with any of the recognized archive types
+func (c *Cask) IsCacheFile(name string) bool {
+	stem := strings.TrimSuffix(c.GetCacheFileName(), c.GetFileExtension())
+	for _, ext := range downloadExtensions {
+		if name == stem+ext {
+			return true
+		}
+	}
+	return false
 }
 
-// IsInstalled checks if the cask is currently installed
-func (c *Cask) IsInstalled() bool {
-	return c.InstallTime != nil
+// IsInstalled checks if the cask has an install receipt in the caskroom
+func (c *Cask) IsInstalled(caskroom string) bool {
+	_, err := ReadReceipt(caskroom, c.Token)
+	return err == nil
 }
 
 // RequiresManualInstallation checks if manual installation steps are needed
@@ -371,7 +511,7 @@ func (c *Cask) Validate() error {
 		return fmt.Errorf("cask download URL is required")
 	}
 
-	if c.Sha256 == "" {
+	if c.GetDownloadSHA256() == "" {
 		return fmt.Errorf("cask SHA256 checksum is required")
 	}
 
@@ -1,6 +1,8 @@
 package cask
 
 import (
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
@@ -48,6 +50,99 @@ func TestCask_GetDownloadURL(t *testing.T) {
 	}
 }
 
+func TestCask_SelectURL(t *testing.T) {
+	c := &Cask{
+		Sha256: "cask-level",
+		URL: []CaskURL{
+			{URL: "https://example.com/app-intel.dmg", Arch: "intel", Sha256: "intel-sha"},
+			{URL: "https://example.com/app-arm.dmg", Arch: "arm64", Sha256: "arm-sha"},
+			{URL: "https://example.com/app-arm-de.dmg", Arch: "arm64", Language: "de"},
+		},
+	}
+
+	tests := []struct {
+		arch     string
+		language string
+		expected string
+	}{
+		{"arm64", "", "https://example.com/app-arm.dmg"},
+		{"arm64", "de", "https://example.com/app-arm-de.dmg"},
+		{"arm64", "fr", "https://example.com/app-arm.dmg"},
+		{"x86_64", "", "https://example.com/app-intel.dmg"},
+		{"amd64", "de", "https://example.com/app-intel.dmg"},
+		{"ppc", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arch+"/"+tt.language, func(t *testing.T) {
+			result := ""
+			if u := c.SelectURL(tt.arch, tt.language); u != nil {
+				result = u.URL
+			}
+			if result != tt.expected {
+				t.Errorf("SelectURL(%q, %q) = %v, want %v", tt.arch, tt.language, result, tt.expected)
+			}
+		})
+	}
+
+	want := map[string]string{"arm64": "arm-sha", "amd64": "intel-sha"}[runtime.GOARCH]
+	if want == "" {
+		want = "cask-level"
+	}
+	if got := c.GetDownloadSHA256(); got != want {
+		t.Errorf("GetDownloadSHA256() = %v, want %v", got, want)
+	}
+}
+
+func TestCask_GetDownloadURLLanguage(t *testing.T) {
+	newCask := func() *Cask {
+		return &Cask{
+			Token:   "mock-app",
+			Version: "1.0.0",
+			URL: []CaskURL{
+				{URL: "https://example.com/app-en.dmg", Language: "en"},
+				{URL: "https://example.com/app-de.dmg", Language: "de"},
+				{URL: "https://example.com/app-fr.dmg", Language: "fr"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		locale    string
+		preferred []string
+		expected  string
+	}{
+		{"system language", "fr_FR.UTF-8", nil, "https://example.com/app-fr.dmg"},
+		{"--language de", "fr_FR.UTF-8", []string{"de"}, "https://example.com/app-de.dmg"},
+		{"regional tag falls back to base", "C", []string{"de-AT"}, "https://example.com/app-de.dmg"},
+		{"unavailable language falls back to system", "fr_FR.UTF-8", []string{"ja"}, "https://example.com/app-fr.dmg"},
+		{"English by default", "C", nil, "https://example.com/app-en.dmg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", "")
+			t.Setenv("LC_MESSAGES", "")
+			t.Setenv("LANG", tt.locale)
+
+			c := newCask()
+			c.PreferLanguages(tt.preferred...)
+			if got := c.GetDownloadURL(); got != tt.expected {
+				t.Errorf("GetDownloadURL() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	// Localized downloads don't share a cache entry
+	t.Setenv("LANG", "C")
+	c := newCask()
+	c.PreferLanguages("de")
+	if got := c.GetCacheFileName(); got != "mock-app-1.0.0-de.dmg" {
+		t.Errorf("GetCacheFileName() = %v, want mock-app-1.0.0-de.dmg", got)
+	}
+}
+
 func TestCask_HasApplication(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -375,7 +470,17 @@ func TestCask_GetCacheFileName(t *testing.T) {
 }
 
 func TestCask_IsInstalled(t *testing.T) {
-	now := time.Now()
+	caskroom := t.TempDir()
+
+	receipt := &CaskReceipt{Token: "installed-cask", Version: "1.0.0", InstalledOn: time.Now()}
+	if err := receipt.Write(caskroom); err != nil {
+		t.Fatalf("Failed to write receipt: %v", err)
+	}
+
+	// A caskroom directory without a receipt does not count as installed
+	if err := os.MkdirAll(filepath.Join(caskroom, "partial-cask", "1.0.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
 
 	tests := []struct {
 		name     string
@@ -383,24 +488,25 @@ func TestCask_IsInstalled(t *testing.T) {
 		expected bool
 	}{
 		{
-			name: "installed",
-			cask: &Cask{
-				InstallTime: &now,
-			},
+			name:     "installed",
+			cask:     &Cask{Token: "installed-cask"},
 			expected: true,
 		},
 		{
-			name: "not installed",
-			cask: &Cask{
-				InstallTime: nil,
-			},
+			name:     "partial install",
+			cask:     &Cask{Token: "partial-cask"},
+			expected: false,
+		},
+		{
+			name:     "not installed",
+			cask:     &Cask{Token: "missing-cask"},
 			expected: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := tt.cask.IsInstalled()
+			result := tt.cask.IsInstalled(caskroom)
 			if result != tt.expected {
 				t.Errorf("IsInstalled() = %v, want %v", result, tt.expected)
 			}
@@ -2,21 +2,29 @@ package cask
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/errors"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
 	"github.com/pilshchikov/homebrew-go/internal/verification"
 )
 
 // Installer handles installation of casks
 type Installer struct {
-	config   *config.Config
-	verifier *verification.PackageVerifier
+	config     *config.Config
+	verifier   *verification.PackageVerifier
+	downloader func(url, path string) error
+
+	// runCommand executes installer scripts and returns their combined output
+	runCommand func(name string, args ...string) ([]byte, error)
 }
 
 // CaskInstallOptions contains options for cask installation
@@ -28,6 +36,7 @@ type CaskInstallOptions struct {
 	DryRun             bool
 	NoQuarantine       bool
 	AdoptOrphanedCasks bool
+	Languages          []string
 }
 
 // CaskInstallResult contains the result of a cask installation
@@ -43,10 +52,50 @@ type CaskInstallResult struct {
 
 // NewCaskInstaller creates a new cask installer
 func NewCaskInstaller(cfg *config.Config) *Installer {
-	return &Installer{
+	ci := &Installer{
 		config:   cfg,
 		verifier: verification.NewPackageVerifier(false), // Non-strict for casks
 	}
+	ci.downloader = ci.downloadFile
+	ci.runCommand = func(name string, args ...string) ([]byte, error) {
+		// #nosec G204 - executable and args come from the cask definition
+		return exec.Command(name, args...).CombinedOutput()
+	}
+	return ci
+}
+
+// SetDownloader replaces the function used to download cask artifacts
+func (ci *Installer) SetDownloader(fn func(url, path string) error) {
+	ci.downloader = fn
+}
+
+// CachePath returns where the cask artifact is stored in the download cache
+func (ci *Installer) CachePath(cask *Cask) string {
+	return filepath.Join(ci.config.HomebrewCache, "cask", cask.GetCacheFileName())
+}
+
+// FetchCask downloads and verifies the cask artifact without installing it
+func (ci *Installer) FetchCask(cask *Cask, force bool) (string, error) {
+	if force {
+		if err := os.Remove(ci.CachePath(cask)); err != nil && !os.IsNotExist(err) {
+			return "", errors.NewPermissionError("remove cached download", ci.CachePath(cask), err)
+		}
+	}
+
+	downloadPath, err := ci.downloadCask(cask)
+	if err != nil {
+		return "", err
+	}
+
+	// "no_check" casks publish no checksum
+	if sha := cask.GetDownloadSHA256(); sha != "" && sha != "no_check" {
+		if err := ci.verifier.VerifySource(downloadPath, sha, 0); err != nil {
+			_ = os.Remove(downloadPath)
+			return "", fmt.Errorf("cask verification failed: %w", err)
+		}
+	}
+
+	return downloadPath, nil
 }
 
 // InstallCask installs a cask
@@ -59,12 +108,24 @@ func (ci *Installer) InstallCask(cask *Cask, opts *CaskInstallOptions) (*CaskIns
 
 	logger.PrintHeader(fmt.Sprintf("Installing Cask: %s", cask.Token))
 
+	if len(opts.Languages) > 0 {
+		cask.PreferLanguages(opts.Languages...)
+	}
+
 	// Validate cask
 	if err := cask.Validate(); err != nil {
 		result.Error = fmt.Errorf("invalid cask: %w", err)
 		return result, result.Error
 	}
 
+	// "no_check" casks publish no checksum; variants may carry their own
+	expectedSHA := cask.GetDownloadSHA256()
+	unverified := expectedSHA == "" || expectedSHA == "no_check"
+	if unverified && (opts.RequireSHA || ci.config.RequireSHA) {
+		result.Error = fmt.Errorf("cask %s has no SHA256 checksum and --require-sha is set", cask.Token)
+		return result, result.Error
+	}
+
 	// Check platform compatibility
 	if !cask.IsCompatibleWithPlatform() {
 		result.Error = fmt.Errorf("cask %s is not compatible with this platform", cask.Token)
@@ -72,7 +133,7 @@ func (ci *Installer) InstallCask(cask *Cask, opts *CaskInstallOptions) (*CaskIns
 	}
 
 	// Check if already installed
-	if cask.IsInstalled() && !opts.Force {
+	if cask.IsInstalled(ci.config.HomebrewCaskroom) && !opts.Force {
 		logger.Info("Cask %s is already installed", cask.Token)
 		result.Success = true
 		return result, nil
@@ -92,9 +153,11 @@ func (ci *Installer) InstallCask(cask *Cask, opts *CaskInstallOptions) (*CaskIns
 	}
 
 	// Verify download
-	if cask.Sha256 != "" && opts.RequireSHA {
+	if unverified {
+		logger.Warn("Cask %s has no SHA256 checksum; skipping verification", cask.Token)
+	} else {
 		logger.Debug("Verifying cask checksum")
-		if err := ci.verifier.VerifySource(downloadPath, cask.Sha256, 0); err != nil {
+		if err := ci.verifier.VerifySource(downloadPath, expectedSHA, 0); err != nil {
 			result.Error = fmt.Errorf("cask verification failed: %w", err)
 			return result, result.Error
 		}
@@ -123,7 +186,7 @@ func (ci *Installer) InstallCask(cask *Cask, opts *CaskInstallOptions) (*CaskIns
 	}
 
 	// Create install receipt
-	if err := ci.createInstallReceipt(cask); err != nil {
+	if err := ci.createInstallReceipt(cask, artifacts, opts); err != nil {
 		logger.Warn("Failed to create install receipt: %v", err)
 	}
 
@@ -139,13 +202,11 @@ func (ci *Installer) downloadCask(cask *Cask) (string, error) {
 		return "", fmt.Errorf("no download URL available")
 	}
 
-	cacheDir := filepath.Join(ci.config.HomebrewCache, "cask")
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		return "", errors.NewPermissionError("create cache directory", cacheDir, err)
+	downloadPath := ci.CachePath(cask)
+	if err := os.MkdirAll(filepath.Dir(downloadPath), 0755); err != nil {
+		return "", errors.NewPermissionError("create cache directory", filepath.Dir(downloadPath), err)
 	}
 
-	downloadPath := filepath.Join(cacheDir, cask.GetCacheFileName())
-
 	// Check if already downloaded
 	if _, err := os.Stat(downloadPath); err == nil {
 		logger.Debug("Using cached download: %s", downloadPath)
@@ -153,21 +214,33 @@ func (ci *Installer) downloadCask(cask *Cask) (string, error) {
 	}
 
 	logger.Step("Downloading %s", filepath.Base(downloadPath))
-	return downloadPath, ci.downloadFile(url, downloadPath)
+	return downloadPath, ci.downloader(url, downloadPath)
 }
 
 // downloadFile downloads a file from URL
 func (ci *Installer) downloadFile(url, path string) error {
-	// This is a simplified download - in practice would use the same
-	// download logic as the main installer with progress tracking
-	cmd := exec.Command("curl", "-L", "-o", path, url)
+	resp, err := utils.DownloadClient().Get(url)
+	if err != nil {
+		return errors.NewNetworkError("download", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
 
-	output, err := cmd.CombinedOutput()
+	if resp.StatusCode != http.StatusOK {
+		return errors.NewDownloadError("download", url, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status))
+	}
+
+	file, err := os.Create(path)
 	if err != nil {
-		return errors.NewDownloadError("download", url, fmt.Errorf("curl failed: %s", string(output)))
+		return errors.NewPermissionError("create file", path, err)
 	}
 
-	return nil
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return errors.NewDownloadError("save file", url, err)
+	}
+
+	return file.Close()
 }
 
 // extractCask extracts the downloaded cask if needed
@@ -179,7 +252,7 @@ func (ci *Installer) extractCask(cask *Cask, downloadPath string) (string, error
 		return downloadPath, nil
 	}
 
-	extractDir := filepath.Join(ci.config.HomebrewCache, "cask", "extract", cask.Token)
+	extractDir := ci.extractPath(cask)
 	if err := os.MkdirAll(extractDir, 0755); err != nil {
 		return "", errors.NewPermissionError("create extract directory", extractDir, err)
 	}
@@ -197,6 +270,11 @@ func (ci *Installer) extractCask(cask *Cask, downloadPath string) (string, error
 	}
 }
 
+// extractPath returns the directory a cask's archive is extracted into
+func (ci *Installer) extractPath(cask *Cask) string {
+	return filepath.Join(ci.config.HomebrewCache, "cask", "extract", cask.Token)
+}
+
 // extractDMG mounts and extracts a DMG file
 func (ci *Installer) extractDMG(dmgPath, extractDir string) (string, error) {
 	logger.Step("Mounting DMG")
@@ -337,14 +415,10 @@ func (ci *Installer) installApp(app CaskApp, sourcePath string, opts *CaskInstal
 
 // installBinary installs a binary symlink
 func (ci *Installer) installBinary(binary CaskBinary, sourcePath string, opts *CaskInstallOptions) error {
-	sourcePath = filepath.Join(sourcePath, binary.Source)
+	root := filepath.Clean(sourcePath)
+	sourcePath = filepath.Join(root, binary.Source)
 
-	target := binary.Target
-	if target == "" {
-		target = filepath.Join(filepath.Join(string(filepath.Separator)+"usr", "local", "bin"), filepath.Base(binary.Source))
-	} else if !filepath.IsAbs(target) {
-		target = filepath.Join(filepath.Join(string(filepath.Separator)+"usr", "local", "bin"), target)
-	}
+	target := ci.binaryTarget(binary)
 
 	logger.Step("Installing binary: %s → %s", binary.Source, target)
 
@@ -352,6 +426,48 @@ func (ci *Installer) installBinary(binary CaskBinary, sourcePath string, opts *C
 		return nil
 	}
 
+	// The source must exist inside the extracted bundle
+	if !isWithin(root, sourcePath) {
+		return fmt.Errorf("binary source %s is outside the cask bundle", binary.Source)
+	}
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return fmt.Errorf("binary source %s not found in cask bundle: %w", binary.Source, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("binary source %s is a directory", binary.Source)
+	}
+	if info.Mode()&0111 == 0 {
+		if err := os.Chmod(sourcePath, info.Mode()|0755); err != nil {
+			return errors.NewPermissionError("make binary executable", sourcePath, err)
+		}
+	}
+
+	if existing, err := os.Lstat(target); err == nil {
+		if existing.Mode()&os.ModeSymlink != 0 {
+			if dest, err := os.Readlink(target); err == nil && dest == sourcePath {
+				// Already linked to this bundle
+				return nil
+			}
+		}
+
+		// Dangling symlinks are stale and always replaced
+		_, statErr := os.Stat(target)
+		stale := existing.Mode()&os.ModeSymlink != 0 && os.IsNotExist(statErr)
+		if !stale && !opts.Force {
+			return fmt.Errorf("binary already exists at %s (use --force to overwrite)", target)
+		}
+
+		logger.Debug("Removing existing binary at %s", target)
+		if err := os.Remove(target); err != nil {
+			return errors.NewPermissionError("remove existing binary", target, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return errors.NewPermissionError("create binary directory", filepath.Dir(target), err)
+	}
+
 	// Create symlink
 	if err := os.Symlink(sourcePath, target); err != nil {
 		return fmt.Errorf("failed to create binary symlink: %w", err)
@@ -360,6 +476,28 @@ func (ci *Installer) installBinary(binary CaskBinary, sourcePath string, opts *C
 	return nil
 }
 
+// binaryTarget returns where a cask binary is linked; relative targets are
+// resolved against the prefix bin directory, like formula links
+func (ci *Installer) binaryTarget(binary CaskBinary) string {
+	binDir := filepath.Join(ci.config.HomebrewPrefix, "bin")
+	if binary.Target == "" {
+		return filepath.Join(binDir, filepath.Base(binary.Source))
+	}
+	if !filepath.IsAbs(binary.Target) {
+		return filepath.Join(binDir, binary.Target)
+	}
+	return binary.Target
+}
+
+// isWithin reports whether path is root or lies beneath it
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // installPkg installs a package file
 func (ci *Installer) installPkg(pkg, sourcePath string, opts *CaskInstallOptions) error {
 	pkgPath := filepath.Join(sourcePath, pkg)
@@ -396,52 +534,99 @@ func (ci *Installer) runInstaller(installer CaskInstaller, sourcePath string, op
 
 	// Handle script-based installers
 	if installer.Script != nil {
-		logger.Step("Running installer script")
+		argv, err := scriptCommand(installer.Script, sourcePath)
+		if err != nil {
+			return err
+		}
+
+		logger.Step("Running installer script: %s", installer.Script.Executable)
 
 		if opts.DryRun {
+			logger.Info("Would run: %s", strings.Join(argv, " "))
 			return nil
 		}
 
-		// This would need more complex implementation based on script type
-		logger.Warn("Script-based installers not fully implemented")
+		output, err := ci.runCommand(argv[0], argv[1:]...)
+		if len(output) > 0 {
+			logger.Debug("Installer script output:\n%s", strings.TrimSpace(string(output)))
+		}
+		if err != nil {
+			return fmt.Errorf("installer script %s failed: %w: %s", installer.Script.Executable, err, strings.TrimSpace(string(output)))
+		}
 	}
 
 	return nil
 }
 
-// createInstallReceipt creates an installation receipt
-func (ci *Installer) createInstallReceipt(cask *Cask) error {
-	receiptDir := filepath.Join(ci.config.HomebrewCaskroom, cask.Token, cask.Version)
-	if err := os.MkdirAll(receiptDir, 0755); err != nil {
-		return err
+// scriptCommand builds the command line for an installer script, resolving
+// relative executables inside the extracted bundle
+func scriptCommand(script *CaskScript, sourcePath string) ([]string, error) {
+	if script.Executable == "" {
+		return nil, fmt.Errorf("installer script has no executable")
 	}
 
-	receiptPath := filepath.Join(receiptDir, ".metadata")
-	receiptData := fmt.Sprintf(`{
-  "token": "%s",
-  "name": "%s",
-  "version": "%s",
-  "installed_on": "%s",
-  "installed_by": "brew-go"
-}`, cask.Token, cask.Name, cask.Version, fmt.Sprintf("%d", os.Getpid()))
+	executable := script.Executable
+	if !filepath.IsAbs(executable) {
+		root := filepath.Clean(sourcePath)
+		executable = filepath.Join(root, executable)
+		if !isWithin(root, executable) {
+			return nil, fmt.Errorf("installer script %s is outside the cask bundle", script.Executable)
+		}
+	}
 
-	return os.WriteFile(receiptPath, []byte(receiptData), 0600)
+	argv := []string{executable}
+	if script.Sudo {
+		argv = []string{"sudo", "-E", "--", executable}
+	}
+	return append(argv, script.Args...), nil
+}
+
+// createInstallReceipt creates an installation receipt
+func (ci *Installer) createInstallReceipt(cask *Cask, artifacts []string, opts *CaskInstallOptions) error {
+	receipt := &CaskReceipt{
+		Token:        cask.Token,
+		Name:         cask.Name,
+		Version:      cask.Version,
+		InstalledOn:  time.Now(),
+		InstalledBy:  "brew-go",
+		Artifacts:    artifacts,
+		Sha256:       cask.GetDownloadSHA256(),
+		NoQuarantine: opts.NoQuarantine,
+	}
+	if u := cask.selectDownload(); u != nil {
+		receipt.Language = u.Language
+	}
+
+	return receipt.Write(ci.config.HomebrewCaskroom)
 }
 
 // UninstallCask uninstalls a cask
 func (ci *Installer) UninstallCask(cask *Cask, opts *CaskInstallOptions) error {
 	logger.PrintHeader(fmt.Sprintf("Uninstalling Cask: %s", cask.Token))
 
-	if !cask.IsInstalled() {
+	installPath := cask.GetInstallPath(ci.config.HomebrewCaskroom)
+	if _, err := os.Stat(installPath); err != nil {
 		return fmt.Errorf("cask %s is not installed", cask.Token)
 	}
 
+	var err error
 	if len(cask.Artifacts) > 0 && len(cask.Artifacts[0].Uninstall) > 0 {
-		return ci.runUninstallSteps(cask.Artifacts[0].Uninstall, opts)
+		err = ci.runUninstallSteps(cask.Artifacts[0].Uninstall, opts)
+	} else {
+		// Default uninstall - remove applications
+		err = ci.removeDefaultArtifacts(cask, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !opts.DryRun {
+		if err := os.RemoveAll(installPath); err != nil {
+			return errors.NewPermissionError("remove caskroom directory", installPath, err)
+		}
 	}
 
-	// Default uninstall - remove applications
-	return ci.removeDefaultArtifacts(cask, opts)
+	return nil
 }
 
 // runUninstallSteps runs custom uninstall steps
@@ -502,9 +687,13 @@ func (ci *Installer) removeDefaultArtifacts(cask *Cask, opts *CaskInstallOptions
 
 	// Remove binaries
 	for _, binary := range artifacts.Binary {
-		target := binary.Target
-		if target == "" {
-			target = filepath.Join(filepath.Join(string(filepath.Separator)+"usr", "local", "bin"), filepath.Base(binary.Source))
+		target := ci.binaryTarget(binary)
+
+		// Only remove links that still point into this cask's bundle
+		dest, err := os.Readlink(target)
+		if err != nil || !isWithin(ci.extractPath(cask), dest) {
+			logger.Debug("Skipping binary %s: not linked to %s", target, cask.Token)
+			continue
 		}
 
 		logger.Step("Removing binary: %s", target)
@@ -0,0 +1,478 @@
+package cask
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
+)
+
+func TestInstaller_CreateInstallReceipt(t *testing.T) {
+	cfg := &config.Config{HomebrewCaskroom: t.TempDir()}
+	installer := NewCaskInstaller(cfg)
+
+	c := &Cask{
+		Token:   "test-cask",
+		Name:    `Test "Quoted" App`,
+		Version: "1.2.3",
+		URL: []CaskURL{
+			{URL: "https://example.com/test-en.dmg", Language: "en"},
+			{URL: "https://example.com/test-de.dmg", Language: "de"},
+		},
+	}
+	c.PreferLanguages("de")
+
+	before := time.Now().Add(-time.Second)
+	if err := installer.createInstallReceipt(c, []string{"Test.app"}, &CaskInstallOptions{NoQuarantine: true}); err != nil {
+		t.Fatalf("createInstallReceipt() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.HomebrewCaskroom, "test-cask", "1.2.3", ".metadata"))
+	if err != nil {
+		t.Fatalf("Failed to read receipt: %v", err)
+	}
+
+	var receipt CaskReceipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		t.Fatalf("Receipt is not valid JSON: %v", err)
+	}
+
+	if receipt.Name != c.Name {
+		t.Errorf("receipt.Name = %v, want %v", receipt.Name, c.Name)
+	}
+	if receipt.Version != "1.2.3" {
+		t.Errorf("receipt.Version = %v, want 1.2.3", receipt.Version)
+	}
+	if len(receipt.Artifacts) != 1 || receipt.Artifacts[0] != "Test.app" {
+		t.Errorf("receipt.Artifacts = %v, want [Test.app]", receipt.Artifacts)
+	}
+	if receipt.InstalledOn.Before(before) {
+		t.Errorf("receipt.InstalledOn = %v, want a current timestamp", receipt.InstalledOn)
+	}
+	if !receipt.NoQuarantine {
+		t.Error("receipt.NoQuarantine = false, want the install option recorded")
+	}
+	if receipt.Language != "de" {
+		t.Errorf("receipt.Language = %q, want the installed download's language", receipt.Language)
+	}
+}
+
+func TestInstaller_FetchCask(t *testing.T) {
+	logger.Init(false, false, true)
+	content := []byte("mock cask artifact")
+	sum := sha256.Sum256(content)
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
+	installer := NewCaskInstaller(cfg)
+
+	c := &Cask{
+		Token:   "mock-cask",
+		Version: "1.0.0",
+		URL:     []CaskURL{{URL: server.URL + "/MockApp-1.0.0.zip"}},
+		Sha256:  hex.EncodeToString(sum[:]),
+	}
+
+	path, err := installer.FetchCask(c, false)
+	if err != nil {
+		t.Fatalf("FetchCask() error = %v", err)
+	}
+
+	expected := filepath.Join(cfg.HomebrewCache, "cask", c.GetCacheFileName())
+	if path != expected {
+		t.Errorf("FetchCask() path = %v, want %v", path, expected)
+	}
+	if err := utils.VerifySHA256(path, c.Sha256); err != nil {
+		t.Errorf("Fetched file has invalid checksum: %v", err)
+	}
+
+	// Cached downloads are reused unless forced
+	if _, err := installer.FetchCask(c, false); err != nil {
+		t.Fatalf("FetchCask() error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected cached download to be reused, got %d requests", requests)
+	}
+
+	if _, err := installer.FetchCask(c, true); err != nil {
+		t.Fatalf("FetchCask(force) error = %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected --force to re-download, got %d requests", requests)
+	}
+}
+
+func TestInstaller_FetchCaskArchVariants(t *testing.T) {
+	logger.Init(false, false, true)
+
+	contents := map[string][]byte{
+		"arm64":  []byte("arm64 build"),
+		"x86_64": []byte("intel build"),
+	}
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arch := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/MockApp-"), ".zip")
+		requested = append(requested, arch)
+		_, _ = w.Write(contents[arch])
+	}))
+	defer server.Close()
+
+	digest := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+
+	newCask := func(shas map[string]string) *Cask {
+		return &Cask{
+			Token:   "mock-cask",
+			Version: "1.0.0",
+			// The cask-level checksum matches neither variant
+			Sha256: "0000000000000000000000000000000000000000000000000000000000000000",
+			URL: []CaskURL{
+				{URL: server.URL + "/MockApp-x86_64.zip", Arch: "x86_64", Sha256: shas["x86_64"]},
+				{URL: server.URL + "/MockApp-arm64.zip", Arch: "arm64", Sha256: shas["arm64"]},
+			},
+		}
+	}
+
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
+	installer := NewCaskInstaller(cfg)
+
+	for _, arch := range []string{"arm64", "x86_64"} {
+		requested = nil
+		c := newCask(map[string]string{"arm64": digest(contents["arm64"]), "x86_64": digest(contents["x86_64"])})
+		c.PreferArch(arch)
+		path, err := installer.FetchCask(c, true)
+		if err != nil {
+			t.Fatalf("FetchCask(%s) error = %v", arch, err)
+		}
+		if len(requested) != 1 || requested[0] != arch {
+			t.Errorf("Expected only the %s variant to be downloaded, got %v", arch, requested)
+		}
+		if err := utils.VerifySHA256(path, digest(contents[arch])); err != nil {
+			t.Errorf("Fetched the wrong variant for %s: %v", arch, err)
+		}
+
+		// Only the selected variant's digest is checked
+		tampered := newCask(map[string]string{"arm64": digest(contents["x86_64"]), "x86_64": digest(contents["arm64"])})
+		tampered.PreferArch(arch)
+		if _, err := installer.FetchCask(tampered, true); err == nil {
+			t.Errorf("FetchCask() expected the %s variant's checksum to be enforced", arch)
+		}
+	}
+}
+
+func TestInstaller_FetchCaskChecksumMismatch(t *testing.T) {
+	logger.Init(false, false, true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tampered"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
+	installer := NewCaskInstaller(cfg)
+
+	c := &Cask{
+		Token:   "mock-cask",
+		Version: "1.0.0",
+		URL:     []CaskURL{{URL: server.URL + "/MockApp.dmg"}},
+		Sha256:  "0000000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	if _, err := installer.FetchCask(c, false); err == nil {
+		t.Fatal("FetchCask() expected checksum error")
+	}
+	if _, err := os.Stat(installer.CachePath(c)); !os.IsNotExist(err) {
+		t.Error("FetchCask() should remove a download that fails verification")
+	}
+}
+
+func TestInstaller_InstallCaskRequireSHA(t *testing.T) {
+	logger.Init(false, false, true)
+
+	cfg := &config.Config{HomebrewCache: t.TempDir(), HomebrewCaskroom: t.TempDir()}
+	installer := NewCaskInstaller(cfg)
+	downloads := 0
+	installer.SetDownloader(func(url, path string) error {
+		downloads++
+		return errors.New("unexpected download")
+	})
+
+	c := &Cask{
+		Token:     "unchecked",
+		Version:   "1.0.0",
+		URL:       []CaskURL{{URL: "https://example.com/app.zip"}},
+		Sha256:    "no_check",
+		Artifacts: []CaskArtifact{{App: []CaskApp{{Source: "Unchecked.app"}}}},
+	}
+	_, err := installer.InstallCask(c, &CaskInstallOptions{RequireSHA: true})
+	if err == nil || !strings.Contains(err.Error(), "--require-sha") {
+		t.Errorf("InstallCask() error = %v, want --require-sha refusal", err)
+	}
+
+	// HOMEBREW_REQUIRE_SHA is honored without the option
+	cfg.RequireSHA = true
+	_, err = installer.InstallCask(c, &CaskInstallOptions{})
+	if err == nil || !strings.Contains(err.Error(), "--require-sha") {
+		t.Errorf("InstallCask() with HOMEBREW_REQUIRE_SHA error = %v, want --require-sha refusal", err)
+	}
+	if downloads != 0 {
+		t.Errorf("Expected refusal before downloading, got %d downloads", downloads)
+	}
+}
+
+func TestInstaller_InstallBinary(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, target string)
+		force   bool
+		wantErr bool
+	}{
+		{
+			name:  "fresh install creates parent directory",
+			setup: func(t *testing.T, target string) {},
+		},
+		{
+			name: "conflicting file without force",
+			setup: func(t *testing.T, target string) {
+				writeTestFile(t, target, 0755)
+			},
+			wantErr: true,
+		},
+		{
+			name: "conflicting file with force",
+			setup: func(t *testing.T, target string) {
+				writeTestFile(t, target, 0755)
+			},
+			force: true,
+		},
+		{
+			name: "stale symlink is replaced",
+			setup: func(t *testing.T, target string) {
+				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(filepath.Join(t.TempDir(), "missing"), target); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := t.TempDir()
+			source := filepath.Join(bundle, "tool")
+			writeTestFile(t, source, 0644)
+
+			target := filepath.Join(t.TempDir(), "bin", "tool")
+			tt.setup(t, target)
+
+			installer := NewCaskInstaller(&config.Config{})
+			err := installer.installBinary(CaskBinary{Source: "tool", Target: target}, bundle, &CaskInstallOptions{Force: tt.force})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("installBinary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			dest, err := os.Readlink(target)
+			if err != nil || dest != source {
+				t.Errorf("target links to %q, want %q", dest, source)
+			}
+			info, _ := os.Stat(source)
+			if info.Mode()&0111 == 0 {
+				t.Error("installBinary() should make the source executable")
+			}
+		})
+	}
+}
+
+func TestInstaller_InstallBinaryMissingSource(t *testing.T) {
+	logger.Init(false, false, true)
+	installer := NewCaskInstaller(&config.Config{})
+	target := filepath.Join(t.TempDir(), "tool")
+
+	for _, source := range []string{"missing", "../escape"} {
+		if err := installer.installBinary(CaskBinary{Source: source, Target: target}, t.TempDir(), &CaskInstallOptions{}); err == nil {
+			t.Errorf("installBinary(%q) expected error", source)
+		}
+	}
+}
+
+func TestInstaller_BinaryDefaultsToPrefixBin(t *testing.T) {
+	logger.Init(false, false, true)
+
+	root := t.TempDir()
+	cfg := &config.Config{
+		HomebrewPrefix: filepath.Join(root, "opt", "homebrew"),
+		HomebrewCache:  filepath.Join(root, "cache"),
+	}
+	installer := NewCaskInstaller(cfg)
+
+	c := &Cask{
+		Token:     "arm-cask",
+		Artifacts: []CaskArtifact{{Binary: []CaskBinary{{Source: "tool"}}}},
+	}
+	bundle := installer.extractPath(c)
+	writeTestFile(t, filepath.Join(bundle, "tool"), 0755)
+
+	if err := installer.installBinary(c.Artifacts[0].Binary[0], bundle, &CaskInstallOptions{}); err != nil {
+		t.Fatalf("installBinary() error = %v", err)
+	}
+
+	target := filepath.Join(cfg.HomebrewPrefix, "bin", "tool")
+	if dest, err := os.Readlink(target); err != nil || dest != filepath.Join(bundle, "tool") {
+		t.Fatalf("%s links to %q (%v), want bundle binary", target, dest, err)
+	}
+
+	if err := installer.removeDefaultArtifacts(c, &CaskInstallOptions{}); err != nil {
+		t.Fatalf("removeDefaultArtifacts() error = %v", err)
+	}
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		t.Error("Binary link under the prefix should be removed on uninstall")
+	}
+}
+
+func TestInstaller_RemoveDefaultArtifactsKeepsForeignLinks(t *testing.T) {
+	logger.Init(false, false, true)
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
+	installer := NewCaskInstaller(cfg)
+
+	binDir := t.TempDir()
+	owned := filepath.Join(binDir, "owned")
+	foreign := filepath.Join(binDir, "foreign")
+
+	c := &Cask{
+		Token: "mock-cask",
+		Artifacts: []CaskArtifact{{
+			Binary: []CaskBinary{
+				{Source: "owned", Target: owned},
+				{Source: "foreign", Target: foreign},
+			},
+		}},
+	}
+
+	if err := os.Symlink(filepath.Join(installer.extractPath(c), "owned"), owned); err != nil {
+		t.Fatal(err)
+	}
+	// Another cask has since taken over this link
+	if err := os.Symlink(filepath.Join(cfg.HomebrewCache, "cask", "extract", "other-cask", "foreign"), foreign); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := installer.removeDefaultArtifacts(c, &CaskInstallOptions{}); err != nil {
+		t.Fatalf("removeDefaultArtifacts() error = %v", err)
+	}
+
+	if _, err := os.Lstat(owned); !os.IsNotExist(err) {
+		t.Error("Link into this cask's bundle should be removed")
+	}
+	if _, err := os.Lstat(foreign); err != nil {
+		t.Error("Link owned by another cask should be kept")
+	}
+}
+
+func TestInstaller_RunInstallerScript(t *testing.T) {
+	logger.Init(false, false, true)
+
+	bundle := t.TempDir()
+	script := filepath.Join(bundle, "Setup.app", "install.sh")
+
+	tests := []struct {
+		name     string
+		script   CaskScript
+		dryRun   bool
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "runs script from bundle with args",
+			script:   CaskScript{Executable: "Setup.app/install.sh", Args: []string{"--silent", "--accept-eula"}},
+			expected: []string{script, "--silent", "--accept-eula"},
+		},
+		{
+			name:     "runs through sudo when requested",
+			script:   CaskScript{Executable: "Setup.app/install.sh", Args: []string{"--silent"}, Sudo: true},
+			expected: []string{"sudo", "-E", "--", script, "--silent"},
+		},
+		{
+			name:   "dry run does not execute",
+			script: CaskScript{Executable: "Setup.app/install.sh"},
+			dryRun: true,
+		},
+		{
+			name:    "executable outside bundle is rejected",
+			script:  CaskScript{Executable: "../escape.sh"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			installer := NewCaskInstaller(&config.Config{})
+			installer.runCommand = func(name string, args ...string) ([]byte, error) {
+				got = append([]string{name}, args...)
+				return []byte("installed"), nil
+			}
+
+			s := tt.script
+			err := installer.runInstaller(CaskInstaller{Script: &s}, bundle, &CaskInstallOptions{DryRun: tt.dryRun})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runInstaller() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("runInstaller() ran %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInstaller_RunInstallerScriptFailure(t *testing.T) {
+	logger.Init(false, false, true)
+
+	installer := NewCaskInstaller(&config.Config{})
+	installer.runCommand = func(name string, args ...string) ([]byte, error) {
+		return []byte("license not accepted"), errors.New("exit status 1")
+	}
+
+	err := installer.runInstaller(CaskInstaller{Script: &CaskScript{Executable: "install.sh"}}, t.TempDir(), &CaskInstallOptions{})
+	if err == nil {
+		t.Fatal("runInstaller() expected error")
+	}
+	if !strings.Contains(err.Error(), "license not accepted") {
+		t.Errorf("runInstaller() error = %v, want script output included", err)
+	}
+}
+
+func writeTestFile(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+}
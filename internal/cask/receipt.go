@@ -0,0 +1,122 @@
+package cask
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+)
+
+// receiptFileName is the name of the receipt stored in each caskroom version directory
+const receiptFileName = ".metadata"
+
+// CaskReceipt records an installed cask in the caskroom
+type CaskReceipt struct {
+	Token       string    `json:"token"`
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	InstalledOn time.Time `json:"installed_on"`
+	InstalledBy string    `json:"installed_by"`
+	Artifacts   []string  `json:"artifacts,omitempty"`
+	Sha256      string    `json:"sha256,omitempty"`
+
+	// NoQuarantine records that the cask was installed with --no-quarantine
+	NoQuarantine bool `json:"no_quarantine,omitempty"`
+
+	// Language is the language of the localized download that was
+	// installed, empty for casks without localized downloads
+	Language string `json:"language,omitempty"`
+}
+
+// Write atomically writes the receipt to caskroom/token/version
+func (r *CaskReceipt) Write(caskroom string) error {
+	receiptDir := filepath.Join(caskroom, r.Token, r.Version)
+	if err := os.MkdirAll(receiptDir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal receipt: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(receiptDir, receiptFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(receiptDir, receiptFileName))
+}
+
+// ReadReceipt returns the receipt of the most recently installed version of a cask
+func ReadReceipt(caskroom, token string) (*CaskReceipt, error) {
+	entries, err := os.ReadDir(filepath.Join(caskroom, token))
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *CaskReceipt
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(caskroom, token, entry.Name(), receiptFileName))
+		if err != nil {
+			continue
+		}
+
+		var receipt CaskReceipt
+		if err := json.Unmarshal(data, &receipt); err != nil {
+			continue
+		}
+
+		if latest == nil || receipt.InstalledOn.After(latest.InstalledOn) {
+			latest = &receipt
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no install receipt found for cask %s", token)
+	}
+
+	return latest, nil
+}
+
+// InstalledVersions returns the installed versions of a cask. The receipt's
+// version is preferred; without one, the caskroom version directories are used.
+func InstalledVersions(caskroom, token string) []string {
+	if receipt, err := ReadReceipt(caskroom, token); err == nil && receipt.Version != "" {
+		return []string{receipt.Version}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(caskroom, token))
+	if err != nil {
+		return nil
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return (&formula.Formula{Version: versions[i]}).IsOlder(&formula.Formula{Version: versions[j]})
+	})
+	return versions
+}
@@ -0,0 +1,90 @@
+package cask
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaskReceipt_RoundTrip(t *testing.T) {
+	caskroom := t.TempDir()
+
+	original := &CaskReceipt{
+		Token:       "test-cask",
+		Name:        `Test "Quoted" App`,
+		Version:     "1.2.3",
+		InstalledOn: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		InstalledBy: "brew-go",
+		Artifacts:   []string{"/Applications/Test.app"},
+		Sha256:      "abc123",
+	}
+
+	if err := original.Write(caskroom); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Join(caskroom, "test-cask", "1.2.3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != receiptFileName {
+		t.Errorf("Unexpected files in receipt directory: %v", entries)
+	}
+
+	receipt, err := ReadReceipt(caskroom, "test-cask")
+	if err != nil {
+		t.Fatalf("ReadReceipt() error = %v", err)
+	}
+
+	if receipt.Name != original.Name || receipt.Version != original.Version || receipt.Sha256 != original.Sha256 {
+		t.Errorf("ReadReceipt() = %+v, want %+v", receipt, original)
+	}
+	if !receipt.InstalledOn.Equal(original.InstalledOn) {
+		t.Errorf("ReadReceipt().InstalledOn = %v, want %v", receipt.InstalledOn, original.InstalledOn)
+	}
+	if len(receipt.Artifacts) != 1 || receipt.Artifacts[0] != original.Artifacts[0] {
+		t.Errorf("ReadReceipt().Artifacts = %v, want %v", receipt.Artifacts, original.Artifacts)
+	}
+}
+
+func TestReadReceipt_LatestVersion(t *testing.T) {
+	caskroom := t.TempDir()
+
+	if _, err := ReadReceipt(caskroom, "test-cask"); err == nil {
+		t.Error("ReadReceipt() expected error for missing cask")
+	}
+
+	older := &CaskReceipt{Token: "test-cask", Version: "1.0.0", InstalledOn: time.Now().Add(-time.Hour)}
+	newer := &CaskReceipt{Token: "test-cask", Version: "2.0.0", InstalledOn: time.Now()}
+	for _, r := range []*CaskReceipt{newer, older} {
+		if err := r.Write(caskroom); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	receipt, err := ReadReceipt(caskroom, "test-cask")
+	if err != nil {
+		t.Fatalf("ReadReceipt() error = %v", err)
+	}
+	if receipt.Version != "2.0.0" {
+		t.Errorf("ReadReceipt().Version = %v, want 2.0.0", receipt.Version)
+	}
+}
+
+func TestInstalledVersionsWithoutReceipt(t *testing.T) {
+	caskroom := t.TempDir()
+	for _, version := range []string{"10.0", "9.1", "9.0.2"} {
+		if err := os.MkdirAll(filepath.Join(caskroom, "test-cask", version), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Versions compare numerically, not as strings
+	versions := InstalledVersions(caskroom, "test-cask")
+	if want := []string{"9.0.2", "9.1", "10.0"}; strings.Join(versions, " ") != strings.Join(want, " ") {
+		t.Errorf("InstalledVersions() = %v, want %v", versions, want)
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pilshchikov/homebrew-go/internal/api"
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/spf13/cobra"
+)
+
+// NewAuditCmd creates the audit command
+func NewAuditCmd(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit FORMULA|FILE...",
+		Short: "Check formulae for structural problems",
+		Long: `Check formulae for structural problems: missing required fields, non-https
+URLs, malformed checksums, invalid names, self-referential dependencies and
+invalid homepages. Formula files are audited without being installed.
+Exits non-zero if any error is found.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAudit(cfg, cmd.OutOrStdout(), args)
+		},
+	}
+
+	return cmd
+}
+
+func runAudit(cfg *config.Config, w io.Writer, args []string) error {
+	var apiClient *api.Client
+	problemCount, failed := 0, 0
+
+	for _, name := range args {
+		var f *formula.Formula
+		var problems []formula.AuditProblem
+
+		if formula.IsLocalPath(name) {
+			var err error
+			if f, problems, err = formula.AuditFile(name); err != nil {
+				return err
+			}
+		} else {
+			if apiClient == nil {
+				apiClient = api.NewClient(cfg)
+			}
+			var err error
+			if f, err = apiClient.GetFormula(name); err != nil {
+				return fmt.Errorf("failed to get formula %s: %w", name, err)
+			}
+			problems = formula.Audit(f)
+		}
+
+		if len(problems) == 0 {
+			continue
+		}
+
+		label := f.Name
+		if label == "" {
+			label = name
+		}
+		_, _ = fmt.Fprintf(w, "%s:\n", label)
+		for _, p := range problems {
+			_, _ = fmt.Fprintf(w, "  * %s\n", p)
+		}
+
+		problemCount += len(problems)
+		if formula.HasAuditErrors(problems) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		problemWord, formulaWord := "problems", "formulae"
+		if problemCount == 1 {
+			problemWord = "problem"
+		}
+		if failed == 1 {
+			formulaWord = "formula"
+		}
+		return fmt.Errorf("%d %s in %d %s detected", problemCount, problemWord, failed, formulaWord)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestRunAudit(t *testing.T) {
+	logger.Init(false, false, true)
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.yaml")
+	bad := filepath.Join(dir, "bad.yaml")
+	_ = os.WriteFile(good, []byte(`name: good
+version: 1.0.0
+homepage: https://example.com
+url: https://example.com/good-1.0.0.tar.gz
+sha256: `+strings.Repeat("0", 64)+`
+`), 0644)
+	_ = os.WriteFile(bad, []byte(`name: bad
+version: 1.0.0
+homepage: https://example.com
+url: http://example.com/bad-1.0.0.tar.gz
+sha256: `+strings.Repeat("0", 64)+`
+`), 0644)
+
+	var out bytes.Buffer
+	if err := runAudit(&config.Config{}, &out, []string{good}); err != nil {
+		t.Errorf("runAudit(good) error = %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no output for a clean formula, got %q", out.String())
+	}
+
+	err := runAudit(&config.Config{}, &out, []string{good, bad})
+	if err == nil || !strings.Contains(err.Error(), "1 problem in 1 formula") {
+		t.Errorf("runAudit(bad) error = %v", err)
+	}
+	if !strings.Contains(out.String(), "bad:\n  * error: url:") {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pilshchikov/homebrew-go/internal/api"
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// CachedFile describes a download cache entry belonging to a formula
+type CachedFile struct {
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	Kind     string `json:"kind"`
+	Checksum string `json:"checksum"`
+}
+
+// NewCacheFilesCmd creates the --cache-files command
+func NewCacheFilesCmd(cfg *config.Config) *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:    "cache-files FORMULA...",
+		Hidden: true,
+		Short:  "List every cached download for a formula",
+		Args:   cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiClient := api.NewClient(cfg)
+			result := make(map[string][]CachedFile)
+
+			for _, name := range args {
+				// Checksums can only be verified against known formula metadata
+				f, err := apiClient.GetFormula(name)
+				if err != nil {
+					logger.Debug("Could not fetch formula %s, skipping checksums: %v", name, err)
+					f = nil
+				}
+
+				files, err := listCachedFiles(cfg, name, f)
+				if err != nil {
+					return err
+				}
+				result[name] = files
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal cache files to JSON: %w", err)
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return err
+			}
+
+			for _, name := range args {
+				printCachedFiles(cmd.OutOrStdout(), name, result[name])
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output in JSON format")
+
+	return cmd
+}
+
+// listCachedFiles returns the bottles, sources and partial downloads cached for a formula
+func listCachedFiles(cfg *config.Config, name string, f *formula.Formula) ([]CachedFile, error) {
+	downloadDir := filepath.Join(cfg.HomebrewCache, "downloads")
+	entries, err := os.ReadDir(downloadDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read download cache: %w", err)
+	}
+
+	var files []CachedFile
+	for _, entry := range entries {
+		if entry.IsDir() || !isCachedFileFor(entry.Name(), name) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Join(downloadDir, entry.Name())
+		kind, expected := classifyCachedFile(entry.Name(), f)
+		file := CachedFile{
+			Path:     path,
+			Size:     info.Size(),
+			Kind:     kind,
+			Checksum: "unknown",
+		}
+
+		if expected != "" {
+			if err := utils.VerifySHA256(path, expected); err != nil {
+				file.Checksum = "mismatch"
+			} else {
+				file.Checksum = "ok"
+			}
+		}
+
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files, nil
+}
+
+// isCachedFileFor matches the "<name>-<version>..." download naming convention
+func isCachedFileFor(filename, name string) bool {
+	rest, ok := strings.CutPrefix(filename, name+"-")
+	return ok && rest != "" && rest[0] >= '0' && rest[0] <= '9'
+}
+
+// classifyCachedFile returns the kind of a cached file and its expected checksum, if known
+func classifyCachedFile(filename string, f *formula.Formula) (string, string) {
+	for _, suffix := range []string{".incomplete", ".part"} {
+		if strings.HasSuffix(filename, suffix) {
+			return "partial", ""
+		}
+	}
+
+	if strings.HasSuffix(filename, ".bottle.tar.gz") {
+		if f == nil || f.Bottle == nil || f.Bottle.Stable == nil {
+			return "bottle", ""
+		}
+		for platform, file := range f.Bottle.Stable.Files {
+			if filename == fmt.Sprintf("%s-%s.%s.bottle.tar.gz", f.Name, f.Version, platform) {
+				return "bottle", file.SHA256
+			}
+		}
+		return "bottle", ""
+	}
+
+	if f != nil && filename == fmt.Sprintf("%s-%s.tar.gz", f.Name, f.Version) {
+		return "source", f.SHA256
+	}
+	return "source", ""
+}
+
+func printCachedFiles(w io.Writer, name string, files []CachedFile) {
+	_, _ = fmt.Fprintf(w, "==> %s\n", name)
+	if len(files) == 0 {
+		_, _ = fmt.Fprintln(w, "No cached files")
+		return
+	}
+
+	for _, file := range files {
+		_, _ = fmt.Fprintf(w, "%-10s %10s  %-8s %s\n", file.Kind, formatFileSize(file.Size), file.Checksum, file.Path)
+	}
+}
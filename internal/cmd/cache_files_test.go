@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+)
+
+func TestListCachedFiles(t *testing.T) {
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
+	downloadDir := filepath.Join(cfg.HomebrewCache, "downloads")
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	source := []byte("source archive")
+	sum := sha256.Sum256(source)
+
+	files := map[string][]byte{
+		"wget-1.21.tar.gz":                     source,
+		"wget-1.21.x86_64_linux.bottle.tar.gz": []byte("corrupt bottle"),
+		"wget-1.20.tar.gz.incomplete":          []byte("part"),
+		"wget-plugin-2.0.tar.gz":               []byte("different formula"),
+		"curl-8.0.tar.gz":                      []byte("another formula"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(downloadDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := &formula.Formula{
+		Name:    "wget",
+		Version: "1.21",
+		SHA256:  hex.EncodeToString(sum[:]),
+		Bottle: &formula.Bottle{Stable: &formula.BottleSpec{Files: map[string]formula.BottleFile{
+			"x86_64_linux": {SHA256: hex.EncodeToString(sum[:])},
+		}}},
+	}
+
+	cached, err := listCachedFiles(cfg, "wget", f)
+	if err != nil {
+		t.Fatalf("listCachedFiles() error = %v", err)
+	}
+
+	expected := map[string]CachedFile{
+		"wget-1.20.tar.gz.incomplete":          {Size: 4, Kind: "partial", Checksum: "unknown"},
+		"wget-1.21.tar.gz":                     {Size: int64(len(source)), Kind: "source", Checksum: "ok"},
+		"wget-1.21.x86_64_linux.bottle.tar.gz": {Size: 14, Kind: "bottle", Checksum: "mismatch"},
+	}
+
+	if len(cached) != len(expected) {
+		t.Fatalf("listCachedFiles() returned %d files, want %d: %+v", len(cached), len(expected), cached)
+	}
+
+	for _, file := range cached {
+		want, ok := expected[filepath.Base(file.Path)]
+		if !ok {
+			t.Errorf("Unexpected cached file %s", file.Path)
+			continue
+		}
+		if file.Size != want.Size || file.Kind != want.Kind || file.Checksum != want.Checksum {
+			t.Errorf("%s = {%d %s %s}, want {%d %s %s}", filepath.Base(file.Path),
+				file.Size, file.Kind, file.Checksum, want.Size, want.Kind, want.Checksum)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// CacheUsage is the disk used by one part of the cache
+type CacheUsage struct {
+	Category string `json:"category"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+}
+
+// CacheInfo summarises cache disk usage by category
+type CacheInfo struct {
+	Categories []CacheUsage `json:"categories"`
+	Total      int64        `json:"total"`
+}
+
+// usage returns the named category, or nil if there is none
+func (info *CacheInfo) usage(category string) *CacheUsage {
+	for idx := range info.Categories {
+		if info.Categories[idx].Category == category {
+			return &info.Categories[idx]
+		}
+	}
+	return nil
+}
+
+// NewCacheInfoCmd creates the --cache-info command
+func NewCacheInfoCmd(cfg *config.Config) *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:    "cache-info",
+		Hidden: true,
+		Short:  "Show disk space used by the download cache",
+		Long: `Show how much disk space the cache uses for formula downloads, cask
+downloads, extracted casks and API data, to help decide when to run
+brew cleanup.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := cacheInfo(cfg)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(info, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal cache info to JSON: %w", err)
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return err
+			}
+
+			printCacheInfo(cmd.OutOrStdout(), info)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output in JSON format")
+
+	return cmd
+}
+
+// cacheInfo measures each part of the cache; anything not in a known
+// category is reported as "other" so the categories add up to the total
+func cacheInfo(cfg *config.Config) (*CacheInfo, error) {
+	caskDir := filepath.Join(cfg.HomebrewCache, "cask")
+	extractDir := filepath.Join(caskDir, "extract")
+
+	total, err := cacheDirSize(cfg.HomebrewCache)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &CacheInfo{Total: total}
+	var categorized int64
+	for _, c := range []struct{ category, path string }{
+		{"downloads", filepath.Join(cfg.HomebrewCache, "downloads")},
+		{"casks", caskDir},
+		{"cask extractions", extractDir},
+		{"api", filepath.Join(cfg.HomebrewCache, "api")},
+	} {
+		size, err := cacheDirSize(c.path)
+		if err != nil {
+			return nil, err
+		}
+		info.Categories = append(info.Categories, CacheUsage{Category: c.category, Path: c.path, Size: size})
+	}
+
+	// Extracted casks live inside the cask cache but are counted separately
+	info.usage("casks").Size -= info.usage("cask extractions").Size
+	for _, usage := range info.Categories {
+		categorized += usage.Size
+	}
+	info.Categories = append(info.Categories, CacheUsage{Category: "other", Path: cfg.HomebrewCache, Size: total - categorized})
+
+	return info, nil
+}
+
+// cacheDirSize is dirSize treating a missing directory as empty
+func cacheDirSize(path string) (int64, error) {
+	size, err := dirSize(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to measure %s: %w", path, err)
+	}
+	return size, nil
+}
+
+func printCacheInfo(w io.Writer, info *CacheInfo) {
+	for _, usage := range info.Categories {
+		_, _ = fmt.Fprintf(w, "%-18s %10s\n", usage.Category, formatFileSize(usage.Size))
+	}
+	_, _ = fmt.Fprintf(w, "%-18s %10s\n", "total", formatFileSize(info.Total))
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+)
+
+func TestCacheInfo(t *testing.T) {
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
+
+	files := map[string]int{
+		"downloads/wget-1.21.tar.gz":             1000,
+		"downloads/curl-8.0.x86_64_linux.tar.gz": 500,
+		"cask/firefox-120.0.dmg":                 2048,
+		"cask/extract/firefox/Firefox.app/bin":   300,
+		"api/formula.json":                       64,
+		"stray.log":                              10,
+	}
+	for name, size := range files {
+		path := filepath.Join(cfg.HomebrewCache, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := cacheInfo(cfg)
+	if err != nil {
+		t.Fatalf("cacheInfo() error = %v", err)
+	}
+
+	expected := map[string]int64{
+		"downloads":        1500,
+		"casks":            2048,
+		"cask extractions": 300,
+		"api":              64,
+		"other":            10,
+	}
+	if len(info.Categories) != len(expected) {
+		t.Fatalf("Expected %d categories, got %+v", len(expected), info.Categories)
+	}
+	for _, usage := range info.Categories {
+		if usage.Size != expected[usage.Category] {
+			t.Errorf("%s size = %d, want %d", usage.Category, usage.Size, expected[usage.Category])
+		}
+	}
+	if info.Total != 3922 {
+		t.Errorf("Total = %d, want 3922", info.Total)
+	}
+
+	cmd := NewCacheInfoCmd(cfg)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cache-info --json error = %v", err)
+	}
+	var decoded CacheInfo
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, out.String())
+	}
+	if decoded.Total != info.Total {
+		t.Errorf("JSON total = %d, want %d", decoded.Total, info.Total)
+	}
+
+	out.Reset()
+	cmd = NewCacheInfoCmd(cfg)
+	cmd.SetOut(&out)
+	cmd.SetArgs(nil)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cache-info error = %v", err)
+	}
+	if !strings.Contains(out.String(), "total") || !strings.Contains(out.String(), "3.8 KB") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+}
+
+func TestCacheInfoMissingCache(t *testing.T) {
+	cfg := &config.Config{HomebrewCache: filepath.Join(t.TempDir(), "missing")}
+
+	info, err := cacheInfo(cfg)
+	if err != nil {
+		t.Fatalf("cacheInfo() error = %v", err)
+	}
+	if info.Total != 0 {
+		t.Errorf("Total = %d, want 0", info.Total)
+	}
+}
@@ -130,7 +130,7 @@ func runCasks(cfg *config.Config, opts *casksOptions) error {
 
 	// Get all available casks - this is a simplified implementation
 	// In practice, this would scan all tapped repositories
-	casks, err := client.SearchCasks("")
+	casks, err := client.SearchCasks("", 0)
 	if err != nil {
 		return fmt.Errorf("failed to get casks: %w", err)
 	}
@@ -166,7 +166,7 @@ func runFormulae(cfg *config.Config, opts *formulaeOptions) error {
 	client := api.NewClient(cfg)
 
 	// Get all available formulae
-	formulae, err := client.SearchFormulae("")
+	formulae, err := client.SearchFormulae("", 0)
 	if err != nil {
 		return fmt.Errorf("failed to get formulae: %w", err)
 	}
@@ -227,10 +227,12 @@ func getBuiltinCommands() []string {
 	// List of built-in commands
 	return []string{
 		"analytics",
+		"audit",
 		"autoremove",
 		"cleanup",
 		"commands",
 		"config",
+		"create",
 		"deps",
 		"desc",
 		"doctor",
@@ -245,6 +247,7 @@ func getBuiltinCommands() []string {
 		"options",
 		"outdated",
 		"pin",
+		"postinstall",
 		"reinstall",
 		"search",
 		"services",
@@ -258,6 +261,8 @@ func getBuiltinCommands() []string {
 		"upgrade",
 		"uses",
 		"--cache",
+		"--cache-files",
+		"--cache-info",
 		"--cellar",
 		"--env",
 		"--prefix",
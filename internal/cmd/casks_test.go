@@ -3,6 +3,9 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -12,6 +15,16 @@ import (
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 )
 
+// unavailableAPI points the API client at a server that fails every request
+func unavailableAPI(t *testing.T) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+}
+
 func TestNewCasksCmd(t *testing.T) {
 	logger.Init(false, false, true)
 	cfg := &config.Config{}
@@ -103,7 +116,8 @@ func TestRunCasks(t *testing.T) {
 func TestRunFormulae(t *testing.T) {
 	logger.Init(false, false, true)
 
-	cfg := &config.Config{}
+	unavailableAPI(t)
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
 
 	opts := &formulaeOptions{
 		eval:       false,
@@ -111,11 +125,48 @@ func TestRunFormulae(t *testing.T) {
 		onePerLine: false,
 	}
 
-	// This will fail due to API call, but test the structure
-	err := runFormulae(cfg, opts)
-	// Expected to fail due to API call in test environment
-	if err == nil {
-		t.Log("runFormulae succeeded (unexpected in test environment)")
+	if err := runFormulae(cfg, opts); err == nil {
+		t.Error("Expected runFormulae to fail when the API is unavailable")
+	}
+}
+
+func TestRunFormulaeListsEveryFormula(t *testing.T) {
+	logger.Init(false, false, true)
+
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
+	var names []string
+	for i := 0; i < api.DefaultSearchLimit+5; i++ {
+		names = append(names, fmt.Sprintf("mock-%02d", i))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/formula.json" {
+			var list []map[string]string
+			for _, name := range names {
+				list = append(list, map[string]string{"name": name})
+			}
+			_ = json.NewEncoder(w).Encode(list)
+			return
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/formula/"), ".json")
+		_, _ = fmt.Fprintf(w, `{"name": %q, "versions": {"stable": "1.0.0"}}`, name)
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := runFormulae(cfg, &formulaeOptions{onePerLine: true})
+	_ = w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("runFormulae() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	if got := strings.Fields(buf.String()); len(got) != len(names) {
+		t.Errorf("runFormulae() listed %d formulae, want %d", len(got), len(names))
 	}
 }
 
@@ -509,13 +560,12 @@ func TestCasksCommandExecution(t *testing.T) {
 
 func TestFormulaeCommandExecution(t *testing.T) {
 	logger.Init(false, false, true)
-	cfg := &config.Config{}
+	unavailableAPI(t)
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
 	cmd := NewFormulaeCmd(cfg)
 
-	// This will likely fail due to API call, but test the structure
-	err := cmd.RunE(cmd, []string{})
-	if err == nil {
-		t.Log("formulae command succeeded (unexpected in test environment)")
+	if err := cmd.RunE(cmd, []string{}); err == nil {
+		t.Error("Expected the formulae command to fail when the API is unavailable")
 	}
 }
 
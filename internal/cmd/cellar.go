@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+)
+
+// cellarVersions returns the installed versions of a formula from its cellar
+// directory, oldest first. Stray files and hidden staging directories are
+// skipped, and a formula that is not installed has no versions.
+func cellarVersions(cfg *config.Config, name string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(cfg.HomebrewCellar, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	versions := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sortVersions(versions)
+
+	return versions, nil
+}
+
+// sortVersions sorts versions in place with the formula version comparator,
+// so 1.2.10 comes after 1.2.9
+func sortVersions(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		a := &formula.Formula{Version: versions[i]}
+		return a.IsOlder(&formula.Formula{Version: versions[j]})
+	})
+}
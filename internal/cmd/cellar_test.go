@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+)
+
+func TestCellarVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		dirs     []string
+		files    []string
+		expected []string
+	}{
+		{"not installed", nil, nil, []string{}},
+		{"numeric ordering", []string{"1.2.10", "1.2.9", "1.10.0"}, nil, []string{"1.2.9", "1.2.10", "1.10.0"}},
+		{"stray file skipped", []string{"2.0", "1.0"}, []string{".DS_Store", "notes.txt"}, []string{"1.0", "2.0"}},
+		{"staging directory skipped", []string{"1.0", ".brew-go-pour-2.0"}, nil, []string{"1.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{HomebrewCellar: t.TempDir()}
+			formulaPath := filepath.Join(cfg.HomebrewCellar, "tool")
+			for _, dir := range tt.dirs {
+				if err := os.MkdirAll(filepath.Join(formulaPath, dir), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, file := range tt.files {
+				if err := os.WriteFile(filepath.Join(formulaPath, file), []byte("stray"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			versions, err := cellarVersions(cfg, "tool")
+			if err != nil {
+				t.Fatalf("cellarVersions() error = %v", err)
+			}
+			if strings.Join(versions, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("cellarVersions() = %v, want %v", versions, tt.expected)
+			}
+		})
+	}
+}
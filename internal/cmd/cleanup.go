@@ -1,13 +1,15 @@
 package cmd
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
 
+	"github.com/pilshchikov/homebrew-go/internal/cask"
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 	"github.com/spf13/cobra"
 )
@@ -15,8 +17,10 @@ import (
 // NewCleanupCmd creates the cleanup command
 func NewCleanupCmd(cfg *config.Config) *cobra.Command {
 	var (
-		dryRun bool
-		prune  string
+		dryRun      bool
+		prune       string
+		prunePrefix bool
+		caskOnly    bool
 	)
 
 	cmd := &cobra.Command{
@@ -24,12 +28,32 @@ func NewCleanupCmd(cfg *config.Config) *cobra.Command {
 		Short: "Remove stale lock files and outdated downloads",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			logger.Progress("Running cleanup")
-			return runCleanup(cfg, dryRun)
+			if caskOnly {
+				runCaskCleanup(cfg, dryRun)
+			} else if err := runCleanup(cfg, dryRun); err != nil {
+				return err
+			}
+
+			if prunePrefix {
+				logger.Step("Removing broken symlinks from the prefix")
+				count, err := pruneBrokenPrefixLinks(cfg, dryRun)
+				if err != nil {
+					return err
+				}
+				if dryRun {
+					logger.Info("Would remove %d broken symlinks", count)
+				} else {
+					logger.Success("Removed %d broken symlinks", count)
+				}
+			}
+			return nil
 		},
 	}
 
 	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Show what would be removed")
 	cmd.Flags().StringVar(&prune, "prune", "0", "Remove all cache files older than specified days")
+	cmd.Flags().BoolVar(&prunePrefix, "prune-prefix", false, "Remove broken symlinks from the prefix")
+	cmd.Flags().BoolVar(&caskOnly, "cask", false, "Only remove old cask downloads and versions")
 
 	return cmd
 }
@@ -49,7 +73,7 @@ func runCleanup(cfg *config.Config, dryRun bool) error {
 	}
 
 	logger.Step("Removing outdated versions")
-	cellarFreed, cellarItems, err := cleanupCellar(cfg.HomebrewCellar, dryRun)
+	cellarFreed, cellarItems, err := cleanupCellar(cfg, dryRun)
 	if err != nil {
 		logger.Warn("Failed to cleanup cellar: %v", err)
 	} else {
@@ -57,6 +81,15 @@ func runCleanup(cfg *config.Config, dryRun bool) error {
 		itemsRemoved += cellarItems
 	}
 
+	logger.Step("Removing stale build directories")
+	tempFreed, tempItems, err := cleanupTemp(cfg.HomebrewTemp, dryRun)
+	if err != nil {
+		logger.Warn("Failed to cleanup temporary files: %v", err)
+	} else {
+		totalFreed += tempFreed
+		itemsRemoved += tempItems
+	}
+
 	logger.Step("Removing lock files")
 	lockItems, err := cleanupLockFiles(cfg, dryRun)
 	if err != nil {
@@ -71,9 +104,180 @@ func runCleanup(cfg *config.Config, dryRun bool) error {
 		logger.Success("Removed %d items, freed %s", itemsRemoved, formatFileSize(totalFreed))
 	}
 
+	runCaskCleanup(cfg, dryRun)
+
 	return nil
 }
 
+// runCaskCleanup removes cask downloads and caskroom versions other than the
+// installed ones, reporting what it reclaimed on its own
+func runCaskCleanup(cfg *config.Config, dryRun bool) {
+	var totalFreed int64
+	var itemsRemoved int
+
+	logger.Step("Removing old cask downloads")
+	cacheFreed, cacheItems, err := cleanupCaskCache(filepath.Join(cfg.HomebrewCache, "cask"), cfg.HomebrewCaskroom, dryRun)
+	if err != nil {
+		logger.Warn("Failed to cleanup cask downloads: %v", err)
+	} else {
+		totalFreed += cacheFreed
+		itemsRemoved += cacheItems
+	}
+
+	logger.Step("Removing outdated cask versions")
+	caskroomFreed, caskroomItems, err := cleanupCaskroom(cfg.HomebrewCaskroom, dryRun)
+	if err != nil {
+		logger.Warn("Failed to cleanup caskroom: %v", err)
+	} else {
+		totalFreed += caskroomFreed
+		itemsRemoved += caskroomItems
+	}
+
+	if dryRun {
+		logger.Info("Would remove %d cask items, freeing %s", itemsRemoved, formatFileSize(totalFreed))
+	} else {
+		logger.Success("Removed %d cask items, freed %s", itemsRemoved, formatFileSize(totalFreed))
+	}
+}
+
+// installedCaskReceipts maps each cask in the caskroom to its receipt.
+// Casks without a receipt, or whose receipt has no version, are left out.
+func installedCaskReceipts(caskroom string) (map[string]*cask.CaskReceipt, error) {
+	entries, err := os.ReadDir(caskroom)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	receipts := make(map[string]*cask.CaskReceipt)
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		receipt, err := cask.ReadReceipt(caskroom, entry.Name())
+		if err != nil || receipt.Version == "" {
+			continue
+		}
+		receipts[entry.Name()] = receipt
+	}
+	return receipts, nil
+}
+
+// cleanupCaskroom removes caskroom version directories other than the one
+// the cask's receipt records as installed
+func cleanupCaskroom(caskroom string, dryRun bool) (int64, int, error) {
+	installed, err := installedCaskReceipts(caskroom)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var totalSize int64
+	var itemCount int
+
+	for token, receipt := range installed {
+		current := receipt.Version
+		entries, err := os.ReadDir(filepath.Join(caskroom, token))
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || entry.Name() == current {
+				continue
+			}
+
+			versionPath := filepath.Join(caskroom, token, entry.Name())
+			size, err := dirSize(versionPath)
+			if err != nil {
+				continue
+			}
+			totalSize += size
+			itemCount++
+
+			if dryRun {
+				logger.Debug("Would remove: %s/%s (%s)", token, entry.Name(), formatFileSize(size))
+				continue
+			}
+			if err := os.RemoveAll(versionPath); err != nil {
+				logger.Debug("Failed to remove %s: %v", versionPath, err)
+			} else {
+				logger.Debug("Removed: %s/%s (%s)", token, entry.Name(), formatFileSize(size))
+			}
+		}
+	}
+
+	return totalSize, itemCount, nil
+}
+
+// cleanupCaskCache removes downloads in the cask cache that aren't for the
+// installed version of an installed cask
+func cleanupCaskCache(cacheDir, caskroom string, dryRun bool) (int64, int, error) {
+	if !filepath.IsAbs(cacheDir) {
+		return 0, 0, nil
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+
+	installed, err := installedCaskReceipts(caskroom)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var totalSize int64
+	var itemCount int
+
+	for _, entry := range entries {
+		// Extraction directories are managed by the installer
+		if entry.IsDir() || isCurrentCaskDownload(entry.Name(), installed) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(cacheDir, entry.Name())
+		totalSize += info.Size()
+		itemCount++
+
+		if dryRun {
+			logger.Debug("Would remove: %s (%s)", path, formatFileSize(info.Size()))
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			logger.Debug("Failed to remove %s: %v", path, err)
+		} else {
+			logger.Debug("Removed: %s (%s)", path, formatFileSize(info.Size()))
+		}
+	}
+
+	return totalSize, itemCount, nil
+}
+
+// isCurrentCaskDownload reports whether a cask cache file is the download
+// of an installed cask's current version, in the language it was installed in
+func isCurrentCaskDownload(name string, installed map[string]*cask.CaskReceipt) bool {
+	for token, receipt := range installed {
+		c := &cask.Cask{Token: token, Version: receipt.Version}
+		if receipt.Language != "" {
+			c.URL = []cask.CaskURL{{Language: receipt.Language}}
+			c.PreferLanguages(receipt.Language)
+		}
+		if c.IsCacheFile(name) {
+			return true
+		}
+	}
+	return false
+}
+
 // cleanupCache removes old cache files
 func cleanupCache(cacheDir string, dryRun bool) (int64, int, error) {
 	if _, err := os.Stat(cacheDir); os.IsNotExist(err) {
@@ -115,7 +319,8 @@ func cleanupCache(cacheDir string, dryRun bool) (int64, int, error) {
 }
 
 // cleanupCellar removes outdated formula versions (keeps latest 2)
-func cleanupCellar(cellarDir string, dryRun bool) (int64, int, error) {
+func cleanupCellar(cfg *config.Config, dryRun bool) (int64, int, error) {
+	cellarDir := cfg.HomebrewCellar
 	if _, err := os.Stat(cellarDir); os.IsNotExist(err) {
 		return 0, 0, nil
 	}
@@ -134,20 +339,16 @@ func cleanupCellar(cellarDir string, dryRun bool) (int64, int, error) {
 		}
 
 		formulaPath := filepath.Join(cellarDir, formula.Name())
-		versions, err := os.ReadDir(formulaPath)
+		versions, err := cellarVersions(cfg, formula.Name())
 		if err != nil {
 			continue
 		}
 
-		// Sort versions and keep only the latest 2
+		// Keep only the latest 2 versions
 		if len(versions) > 2 {
-			sort.Slice(versions, func(i, j int) bool {
-				return versions[i].Name() < versions[j].Name()
-			})
-
 			// Remove old versions (keep last 2)
 			for i := 0; i < len(versions)-2; i++ {
-				versionPath := filepath.Join(formulaPath, versions[i].Name())
+				versionPath := filepath.Join(formulaPath, versions[i])
 
 				// Calculate size
 				size, err := dirSize(versionPath)
@@ -156,12 +357,12 @@ func cleanupCellar(cellarDir string, dryRun bool) (int64, int, error) {
 					itemCount++
 
 					if dryRun {
-						logger.Debug("Would remove: %s/%s (%s)", formula.Name(), versions[i].Name(), formatFileSize(size))
+						logger.Debug("Would remove: %s/%s (%s)", formula.Name(), versions[i], formatFileSize(size))
 					} else {
 						if err := os.RemoveAll(versionPath); err != nil {
 							logger.Debug("Failed to remove %s: %v", versionPath, err)
 						} else {
-							logger.Debug("Removed: %s/%s (%s)", formula.Name(), versions[i].Name(), formatFileSize(size))
+							logger.Debug("Removed: %s/%s (%s)", formula.Name(), versions[i], formatFileSize(size))
 						}
 					}
 				}
@@ -172,6 +373,86 @@ func cleanupCellar(cellarDir string, dryRun bool) (int64, int, error) {
 	return totalSize, itemCount, nil
 }
 
+// prefixLinkDirs are the prefix directories brew creates symlinks in
+var prefixLinkDirs = []string{"bin", "sbin", "lib", "include", "share", "etc", "opt"}
+
+// pruneBrokenPrefixLinks removes symlinks into the Cellar in the linked
+// prefix directories whose targets no longer exist. Links pointing elsewhere
+// aren't ours and are left alone. It returns how many were removed, or would
+// be on a dry run.
+func pruneBrokenPrefixLinks(cfg *config.Config, dryRun bool) (int, error) {
+	var count int
+
+	for _, dir := range prefixLinkDirs {
+		root := filepath.Join(cfg.HomebrewPrefix, dir)
+		if _, err := os.Lstat(root); os.IsNotExist(err) {
+			continue
+		}
+
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil // Keep going past unreadable directories
+			}
+			if d.Type()&os.ModeSymlink == 0 {
+				return nil
+			}
+
+			// Stat follows the link; only a missing target makes it broken
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				return nil
+			}
+			if installer.ClassifyPrefixPath(path, cfg.HomebrewCellar) != installer.PathManagedSymlink {
+				return nil
+			}
+
+			if dryRun {
+				logger.Info("Would remove broken symlink: %s", path)
+				count++
+				return nil
+			}
+			if err := os.Remove(path); err != nil {
+				logger.Warn("Failed to remove %s: %v", path, err)
+				return nil
+			}
+			logger.Debug("Removed broken symlink: %s", path)
+			count++
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// cleanupTemp removes build directories left in HOMEBREW_TEMP by failed or
+// interrupted installs
+func cleanupTemp(tempDir string, dryRun bool) (int64, int, error) {
+	stale, err := installer.StaleTempPaths(tempDir, installer.StaleTempAge)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var totalSize int64
+	for _, path := range stale {
+		size, _ := dirSize(path)
+		totalSize += size
+
+		if dryRun {
+			logger.Debug("Would remove: %s (%s)", path, formatFileSize(size))
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			logger.Warn("Failed to remove %s: %v", path, err)
+			continue
+		}
+		logger.Debug("Removed: %s", path)
+	}
+
+	return totalSize, len(stale), nil
+}
+
 // cleanupLockFiles removes stale lock files
 func cleanupLockFiles(cfg *config.Config, dryRun bool) (int, error) {
 	lockDirs := []string{
@@ -220,17 +501,34 @@ func cleanupLockFiles(cfg *config.Config, dryRun bool) (int, error) {
 	return itemCount, nil
 }
 
-// dirSize calculates the total size of a directory
+// dirSize calculates the total size of the regular files in a directory.
+// Symlinks are not followed, so cycles end and targets aren't counted twice.
+// Unreadable entries are skipped and the total is best-effort; only a path
+// that can't be read at all is an error.
 func dirSize(path string) (int64, error) {
 	var size int64
 
-	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return err
+			if p == path {
+				return err
+			}
+			logger.Debug("Skipping %s: %v", p, err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
-		if !info.IsDir() {
-			size += info.Size()
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			logger.Debug("Skipping %s: %v", p, err)
+			return nil
 		}
+		size += info.Size()
 		return nil
 	})
 
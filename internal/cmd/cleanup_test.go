@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/cask"
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestPruneBrokenPrefixLinks(t *testing.T) {
+	logger.Init(false, false, true)
+
+	prefix := t.TempDir()
+	cfg := &config.Config{
+		HomebrewPrefix: prefix,
+		HomebrewCellar: filepath.Join(prefix, "Cellar"),
+	}
+
+	kegBinary := filepath.Join(cfg.HomebrewCellar, "wget", "1.21", "bin", "wget")
+	binDir := filepath.Join(prefix, "bin")
+	for _, dir := range []string{filepath.Dir(kegBinary), binDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(kegBinary, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := filepath.Join(binDir, "wget")
+	broken := filepath.Join(binDir, "curl")
+	if err := os.Symlink(kegBinary, valid); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(cfg.HomebrewCellar, "curl", "8.0", "bin", "curl"), broken); err != nil {
+		t.Fatal(err)
+	}
+	// A dangling link the user made outside the Cellar isn't ours to remove
+	foreign := filepath.Join(binDir, "mine")
+	if err := os.Symlink(filepath.Join(prefix, "elsewhere", "mine"), foreign); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dry run reports without removing
+	count, err := pruneBrokenPrefixLinks(cfg, true)
+	if err != nil || count != 1 {
+		t.Fatalf("pruneBrokenPrefixLinks(dry run) = %d, %v; want 1", count, err)
+	}
+	if _, err := os.Lstat(broken); err != nil {
+		t.Fatal("Dry run should not remove anything")
+	}
+
+	count, err = pruneBrokenPrefixLinks(cfg, false)
+	if err != nil || count != 1 {
+		t.Fatalf("pruneBrokenPrefixLinks() = %d, %v; want 1", count, err)
+	}
+	if _, err := os.Lstat(broken); !os.IsNotExist(err) {
+		t.Error("Broken symlink should be removed")
+	}
+	if _, err := os.Lstat(valid); err != nil {
+		t.Error("Valid symlink should be kept")
+	}
+	if _, err := os.Lstat(foreign); err != nil {
+		t.Error("Symlink outside the Cellar should be kept")
+	}
+}
+
+func TestCleanupTemp(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	stale := filepath.Join(tempDir, installer.TempPrefix+"wget-1.21")
+	if err := os.MkdirAll(stale, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stale, "source.tar.gz"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * installer.StaleTempAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	freed, items, err := cleanupTemp(tempDir, true)
+	if err != nil || freed != 100 || items != 1 {
+		t.Fatalf("cleanupTemp() dry run = %d, %d, %v; want 100, 1, nil", freed, items, err)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Errorf("Dry run should not remove anything: %v", err)
+	}
+
+	if _, _, err := cleanupTemp(tempDir, false); err != nil {
+		t.Fatalf("cleanupTemp() error = %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("Expected the stale build directory to be removed")
+	}
+}
+
+func TestCleanupCasks(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	caskroom := filepath.Join(tempDir, "Caskroom")
+	cacheDir := filepath.Join(tempDir, "cache", "cask")
+
+	receipt := &cask.CaskReceipt{Token: "firefox", Version: "120.0", InstalledOn: time.Now()}
+	if err := receipt.Write(caskroom); err != nil {
+		t.Fatal(err)
+	}
+	localized := &cask.CaskReceipt{Token: "thunderbird", Version: "115.0", InstalledOn: time.Now(), Language: "de"}
+	if err := localized.Write(caskroom); err != nil {
+		t.Fatal(err)
+	}
+	oldVersion := filepath.Join(caskroom, "firefox", "119.0")
+	if err := os.MkdirAll(oldVersion, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(oldVersion, "Firefox.app"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(cacheDir, "extract", "firefox"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	downloads := map[string]bool{
+		"firefox-119.0.dmg":             false,
+		"firefox-120.0.dmg":             true,
+		"firefox-120.0.1.dmg":           false,
+		"firefox-developer-120.0.dmg":   false,
+		"visual-studio-code-1.85.0.zip": false,
+		"thunderbird-115.0-de.dmg":      true,
+		"thunderbird-115.0-fr.dmg":      false,
+		"thunderbird-115.0.dmg":         false,
+	}
+	for name := range downloads {
+		if err := os.WriteFile(filepath.Join(cacheDir, name), make([]byte, 10), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	freed, items, err := cleanupCaskroom(caskroom, true)
+	if err != nil || freed != 100 || items != 1 {
+		t.Fatalf("cleanupCaskroom() dry run = %d, %d, %v; want 100, 1, nil", freed, items, err)
+	}
+	if _, err := os.Stat(oldVersion); err != nil {
+		t.Errorf("Dry run should not remove anything: %v", err)
+	}
+
+	if _, _, err := cleanupCaskroom(caskroom, false); err != nil {
+		t.Fatalf("cleanupCaskroom() error = %v", err)
+	}
+	if _, err := os.Stat(oldVersion); !os.IsNotExist(err) {
+		t.Error("Expected the old cask version to be removed")
+	}
+	if _, err := cask.ReadReceipt(caskroom, "firefox"); err != nil {
+		t.Errorf("Expected the installed version to be kept: %v", err)
+	}
+
+	freed, items, err = cleanupCaskCache(cacheDir, caskroom, false)
+	if err != nil || freed != 60 || items != 6 {
+		t.Fatalf("cleanupCaskCache() = %d, %d, %v; want 60, 6, nil", freed, items, err)
+	}
+	for name, kept := range downloads {
+		_, err := os.Stat(filepath.Join(cacheDir, name))
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s: exists = %v, want %v", name, exists, kept)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "extract", "firefox")); err != nil {
+		t.Errorf("Expected extraction directories to be left alone: %v", err)
+	}
+}
+
+func TestDirSizeAndCountDirItemsSkipProblemEntries(t *testing.T) {
+	logger.Init(false, false, true)
+
+	t.Run("symlink loop", func(t *testing.T) {
+		root := t.TempDir()
+		if err := os.WriteFile(filepath.Join(root, "file"), []byte("12345"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Mkdir(filepath.Join(root, "sub"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		// sub/loop points back at root, and the file is linked a second time
+		if err := os.Symlink(root, filepath.Join(root, "sub", "loop")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join(root, "file"), filepath.Join(root, "file-link")); err != nil {
+			t.Fatal(err)
+		}
+
+		size, err := dirSize(root)
+		if err != nil {
+			t.Fatalf("dirSize() error = %v", err)
+		}
+		if size != 5 {
+			t.Errorf("dirSize() = %d, want 5 with symlinks not followed", size)
+		}
+		if count := countDirItems(filepath.Join(root, "sub")); count != 1 {
+			t.Errorf("countDirItems() = %d, want 1", count)
+		}
+	})
+
+	t.Run("unreadable subdirectory", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root can read unreadable directories")
+		}
+
+		root := t.TempDir()
+		if err := os.WriteFile(filepath.Join(root, "file"), []byte("12345"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		locked := filepath.Join(root, "locked")
+		if err := os.Mkdir(locked, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(locked, "hidden"), []byte("secret"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(locked, 0); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = os.Chmod(locked, 0755) })
+
+		size, err := dirSize(root)
+		if err != nil {
+			t.Fatalf("dirSize() error = %v", err)
+		}
+		if size != 5 {
+			t.Errorf("dirSize() = %d, want the readable 5 bytes", size)
+		}
+		if count := countDirItems(locked); count != 0 {
+			t.Errorf("countDirItems() = %d, want 0 for an unreadable directory", count)
+		}
+	})
+}
@@ -2,10 +2,15 @@ package cmd
 
 import (
 	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/pilshchikov/homebrew-go/internal/api"
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -46,6 +51,110 @@ func TestNewRootCmd(t *testing.T) {
 	}
 }
 
+func TestChangesPrefix(t *testing.T) {
+	rootCmd := NewRootCmd(&config.Config{}, "1.0.0", "abc123", "2023-01-01")
+
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"install"}, true},
+		{[]string{"cleanup"}, true},
+		{[]string{"doctor"}, false},
+		{[]string{"config"}, false},
+		{[]string{"services", "list"}, false},
+	}
+
+	for _, tt := range tests {
+		cmd, _, err := rootCmd.Find(tt.args)
+		if err != nil {
+			t.Fatalf("Find(%v) error = %v", tt.args, err)
+		}
+		if got := changesPrefix(cmd); got != tt.want {
+			t.Errorf("changesPrefix(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidPrefixOnlyBlocksWritingCommands(t *testing.T) {
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmp := t.TempDir()
+	newCfg := func() *config.Config {
+		return &config.Config{
+			HomebrewPrefix:   filepath.Join(blocker, "prefix"),
+			HomebrewCellar:   filepath.Join(tmp, "Cellar"),
+			HomebrewCaskroom: filepath.Join(tmp, "Caskroom"),
+			HomebrewCache:    filepath.Join(tmp, "cache"),
+			HomebrewLogs:     filepath.Join(tmp, "logs"),
+			HomebrewTemp:     filepath.Join(tmp, "tmp"),
+			DryRun:           true,
+		}
+	}
+
+	rootCmd := NewRootCmd(newCfg(), "1.0.0", "abc123", "2023-01-01")
+	rootCmd.SetArgs([]string{"prefix"})
+	rootCmd.SetOut(io.Discard)
+	if err := rootCmd.Execute(); err != nil {
+		t.Errorf("prefix with an invalid prefix: %v", err)
+	}
+
+	rootCmd = NewRootCmd(newCfg(), "1.0.0", "abc123", "2023-01-01")
+	rootCmd.SetArgs([]string{"install", "hello"})
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "HOMEBREW_PREFIX") {
+		t.Errorf("install with an invalid prefix: error = %v, want HOMEBREW_PREFIX problem", err)
+	}
+}
+
+func TestArchFlag(t *testing.T) {
+	newCfg := func() *config.Config {
+		tmp := t.TempDir()
+		return &config.Config{
+			HomebrewPrefix: filepath.Join(tmp, "prefix"),
+			HomebrewCellar: filepath.Join(tmp, "prefix", "Cellar"),
+			HomebrewCache:  filepath.Join(tmp, "cache"),
+			HomebrewTemp:   filepath.Join(tmp, "tmp"),
+			DryRun:         true,
+		}
+	}
+
+	// The architecture may be given as a separate argument
+	cfg := newCfg()
+	rootCmd := NewRootCmd(cfg, "1.0.0", "abc123", "2023-01-01")
+	rootCmd.SetArgs([]string{"--arch", "x86_64", "prefix"})
+	rootCmd.SetOut(io.Discard)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("--arch x86_64 prefix: %v", err)
+	}
+	if cfg.Arch != "x86_64" {
+		t.Errorf("cfg.Arch = %q, want x86_64", cfg.Arch)
+	}
+
+	var out strings.Builder
+	rootCmd = NewRootCmd(newCfg(), "1.0.0", "abc123", "2023-01-01")
+	rootCmd.SetArgs([]string{"--arch=x86_64", "--print-arch"})
+	rootCmd.SetOut(&out)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("--print-arch: %v", err)
+	}
+	if strings.TrimSpace(out.String()) != "x86_64" {
+		t.Errorf("--print-arch printed %q, want x86_64", out.String())
+	}
+
+	rootCmd = NewRootCmd(newCfg(), "1.0.0", "abc123", "2023-01-01")
+	rootCmd.SetArgs([]string{"--arch", "sparc", "prefix"})
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "unknown architecture") {
+		t.Errorf("--arch sparc: error = %v, want unknown architecture", err)
+	}
+}
+
 func TestParseFormulaArgs(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -132,32 +241,6 @@ func TestValidateArgs(t *testing.T) {
 	}
 }
 
-func TestIsCaskName(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected bool
-	}{
-		{"app suffix with dash", "firefox-browser.app", true},
-		{"desktop in name", "desktop-app", true},
-		{"app in name", "myapp-tool", true},
-		{"hyphenated", "multi-word-app", true},
-		{"simple formula", "wget", false},
-		{"no special patterns", "simple", false},
-		{"underscore only", "my_tool", false},
-		{"app suffix no dash", "firefox.app", false},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := isCaskName(tt.input)
-			if result != tt.expected {
-				t.Errorf("isCaskName(%s) = %v, want %v", tt.input, result, tt.expected)
-			}
-		})
-	}
-}
-
 func TestGetPlatform(t *testing.T) {
 	platform := getPlatform()
 
@@ -219,7 +302,7 @@ func TestShowEnv(t *testing.T) {
 	}
 
 	// Test that showEnv doesn't panic
-	err := showEnv(cfg, false)
+	err := showEnv(io.Discard, cfg, "")
 	if err != nil {
 		t.Errorf("showEnv() error = %v", err)
 	}
@@ -239,7 +322,7 @@ func TestAskForConfirmation(t *testing.T) {
 	}()
 
 	// Test with empty input (will return false but shouldn't panic)
-	_ = askForConfirmation("test", "formula")
+	_ = askForConfirmation("Install", "test", "formula")
 }
 
 func TestInstallOptions(t *testing.T) {
@@ -280,6 +363,18 @@ func TestInstallOptions(t *testing.T) {
 }
 
 func TestParseInstallArgs(t *testing.T) {
+	// Names missing from the cached lists are looked up in the API, which knows none
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	cfg := &config.Config{HomebrewCache: t.TempDir()}
+	apiCache := filepath.Join(cfg.HomebrewCache, "api")
+	_ = os.MkdirAll(apiCache, 0755)
+	_ = os.WriteFile(filepath.Join(apiCache, "formula_names.txt"), []byte("wget\ncurl\ndocker"), 0644)
+	_ = os.WriteFile(filepath.Join(apiCache, "cask_names.txt"), []byte("firefox\ndocker"), 0644)
+	classifier := api.NewClassifier(api.NewClient(cfg))
+
 	tests := []struct {
 		name             string
 		args             []string
@@ -303,10 +398,24 @@ func TestParseInstallArgs(t *testing.T) {
 		},
 		{
 			name:             "auto detect mode",
-			args:             []string{"wget", "curl", "firefox-app"},
+			args:             []string{"wget", "curl", "firefox"},
 			opts:             &installOptions{},
 			expectedFormulae: 2, // wget, curl
-			expectedCasks:    1, // firefox-app (contains hyphen and app)
+			expectedCasks:    1, // firefox is only in the cask list
+		},
+		{
+			name:             "formula and cask with the same name",
+			args:             []string{"docker"},
+			opts:             &installOptions{},
+			expectedFormulae: 1,
+			expectedCasks:    0,
+		},
+		{
+			name:             "unknown names default to formulae",
+			args:             []string{"desktop-app"},
+			opts:             &installOptions{},
+			expectedFormulae: 1,
+			expectedCasks:    0,
 		},
 		{
 			name:             "tap qualified formula",
@@ -319,7 +428,7 @@ func TestParseInstallArgs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			formulae, casks, err := parseInstallArgs(tt.args, tt.opts)
+			formulae, casks, err := parseInstallArgs(tt.args, tt.opts, classifier)
 			if err != nil {
 				t.Errorf("parseInstallArgs() error = %v", err)
 			}
@@ -480,7 +589,7 @@ func TestSearchCommandFlags(t *testing.T) {
 	}
 
 	// Test that flags exist
-	flags := []string{"formulae", "casks", "desc"}
+	flags := []string{"formulae", "casks", "desc", "homepage", "limit"}
 	for _, flag := range flags {
 		if cmd.Flags().Lookup(flag) == nil {
 			t.Errorf("Search command should have --%s flag", flag)
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -189,13 +190,13 @@ func TestShowEnvIntegration(t *testing.T) {
 	}
 
 	// Test regular output
-	err := showEnv(cfg, false)
+	err := showEnv(io.Discard, cfg, "")
 	if err != nil {
 		t.Errorf("showEnv failed: %v", err)
 	}
 
 	// Test JSON output
-	err = showEnv(cfg, true)
+	err = showEnv(io.Discard, cfg, "json")
 	if err != nil {
 		t.Errorf("showEnv JSON failed: %v", err)
 	}
@@ -229,31 +230,31 @@ func TestListInstalled(t *testing.T) {
 	_ = os.MkdirAll(caskDir, 0755)
 
 	// Test listing all
-	err = listInstalled(cfg, false, false, false, false)
+	err = listInstalled(cfg, &listOptions{})
 	if err != nil {
 		t.Errorf("listInstalled failed: %v", err)
 	}
 
 	// Test listing only formulae
-	err = listInstalled(cfg, false, true, false, false)
+	err = listInstalled(cfg, &listOptions{Casks: true})
 	if err != nil {
 		t.Errorf("listInstalled formulae only failed: %v", err)
 	}
 
 	// Test listing only casks
-	err = listInstalled(cfg, true, false, false, false)
+	err = listInstalled(cfg, &listOptions{Formulae: true})
 	if err != nil {
 		t.Errorf("listInstalled casks only failed: %v", err)
 	}
 
 	// Test with versions
-	err = listInstalled(cfg, false, false, true, false)
+	err = listInstalled(cfg, &listOptions{Versions: true})
 	if err != nil {
 		t.Errorf("listInstalled with versions failed: %v", err)
 	}
 
 	// Test with full names
-	err = listInstalled(cfg, false, false, false, true)
+	err = listInstalled(cfg, &listOptions{FullName: true})
 	if err != nil {
 		t.Errorf("listInstalled with full names failed: %v", err)
 	}
@@ -385,7 +386,7 @@ func TestCleanupCellar(t *testing.T) {
 	defer func() { _ = os.RemoveAll(tempDir) }()
 
 	// Test non-existent cellar directory
-	size, count, err := cleanupCellar("/nonexistent", false)
+	size, count, err := cleanupCellar(&config.Config{HomebrewCellar: "/nonexistent"}, false)
 	if err != nil {
 		t.Errorf("cleanupCellar should handle non-existent directory gracefully: %v", err)
 	}
@@ -407,7 +408,7 @@ func TestCleanupCellar(t *testing.T) {
 	}
 
 	// Test cleanup (should remove old versions)
-	_, count, err = cleanupCellar(tempDir, false)
+	_, count, err = cleanupCellar(&config.Config{HomebrewCellar: tempDir}, false)
 	if err != nil {
 		t.Errorf("cleanupCellar failed: %v", err)
 	}
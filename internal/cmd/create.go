@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/tap"
+	"github.com/spf13/cobra"
+)
+
+type createOptions struct {
+	name    string
+	version string
+	tap     string
+	ruby    bool
+}
+
+// NewCreateCmd creates the create command
+func NewCreateCmd(cfg *config.Config) *cobra.Command {
+	var (
+		name    string
+		version string
+		tapName string
+		ruby    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create [OPTIONS] URL",
+		Short: "Generate a formula for the downloadable file at URL",
+		Long: `Generate a YAML formula for the source tarball at URL. The tarball is
+downloaded to compute its SHA256, the name and version are guessed from the
+filename and build dependencies follow from the detected build system. The
+formula is written to the Formula directory of the target tap.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCreate(cfg, cmd.OutOrStdout(), args[0], &createOptions{
+				name:    name,
+				version: version,
+				tap:     tapName,
+				ruby:    ruby,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "set-name", "", "Explicitly set the formula name")
+	cmd.Flags().StringVar(&version, "set-version", "", "Explicitly set the formula version")
+	cmd.Flags().StringVar(&tapName, "tap", "homebrew/core", "Generate the formula in the specified tap")
+	cmd.Flags().BoolVar(&ruby, "ruby", false, "Also write a Ruby formula stub")
+
+	return cmd
+}
+
+func runCreate(cfg *config.Config, w io.Writer, sourceURL string, opts *createOptions) error {
+	// Check the target tap before downloading anything
+	tapPath, err := createTapPath(cfg, opts.tap)
+	if err != nil {
+		return err
+	}
+
+	inst := installer.New(cfg, &installer.Options{})
+	scaffold, err := inst.Scaffold(sourceURL, installer.ScaffoldOptions{Name: opts.name, Version: opts.version})
+	if err != nil {
+		return err
+	}
+	f := scaffold.Formula
+
+	formulaDir := filepath.Join(tapPath, "Formula")
+	yamlPath := filepath.Join(formulaDir, f.Name+".yaml")
+	if _, err := os.Stat(yamlPath); err == nil && !cfg.Force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", yamlPath)
+	}
+
+	if err := os.MkdirAll(formulaDir, 0755); err != nil {
+		return fmt.Errorf("failed to create formula directory: %w", err)
+	}
+
+	data, err := f.ToYAML()
+	if err != nil {
+		return fmt.Errorf("failed to render formula: %w", err)
+	}
+	header := scaffoldHeader(scaffold, f.GetCellarPath(cfg.HomebrewCellar))
+	if err := os.WriteFile(yamlPath, append([]byte(header), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write formula: %w", err)
+	}
+	_, _ = fmt.Fprintf(w, "Created %s\n", yamlPath)
+
+	if opts.ruby {
+		rubyPath := filepath.Join(formulaDir, f.Name+".rb")
+		stub := rubyFormulaStub(scaffold, f.GetCellarPath(cfg.HomebrewCellar))
+		if err := os.WriteFile(rubyPath, []byte(stub), 0644); err != nil {
+			return fmt.Errorf("failed to write Ruby stub: %w", err)
+		}
+		_, _ = fmt.Fprintf(w, "Created %s\n", rubyPath)
+	}
+
+	logger.Info("Fill in desc, homepage and license, then run: brew audit %s", yamlPath)
+	return nil
+}
+
+// createTapPath returns the directory of the tap named by --tap, which must
+// be in user/repo form and already tapped
+func createTapPath(cfg *config.Config, name string) (string, error) {
+	user, repo, ok := strings.Cut(name, "/")
+	if !ok || user == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", fmt.Errorf("invalid tap name %q: expected user/repo", name)
+	}
+
+	tapPath := tap.NewManager(cfg).TapPath(name)
+	if info, err := os.Stat(tapPath); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("tap %s is not installed; run: brew tap %s", name, name)
+	}
+	return tapPath, nil
+}
+
+// scaffoldHeader comments the detected build steps at the top of the YAML
+func scaffoldHeader(s *installer.Scaffold, cellarPath string) string {
+	var b strings.Builder
+	b.WriteString("# Fill in desc, homepage and license before publishing.\n")
+	if s.BuildSystem == "" {
+		b.WriteString("# No build system was detected.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "# Detected build system: %s\n", s.BuildSystem)
+	for _, command := range s.BuildCommands {
+		fmt.Fprintf(&b, "#   %s\n", strings.ReplaceAll(strings.Join(command, " "), cellarPath, "$PREFIX"))
+	}
+	return b.String()
+}
+
+// rubyFormulaStub renders the scaffold as a Ruby formula for upstream Homebrew
+func rubyFormulaStub(s *installer.Scaffold, cellarPath string) string {
+	f := s.Formula
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "class %s < Formula\n", rubyClassName(f.Name))
+	b.WriteString("  desc \"\"\n")
+	b.WriteString("  homepage \"\"\n")
+	fmt.Fprintf(&b, "  url %q\n", f.URL)
+	fmt.Fprintf(&b, "  sha256 %q\n", f.SHA256)
+	b.WriteString("  license \"\"\n")
+
+	if len(f.BuildDependencies) > 0 {
+		b.WriteString("\n")
+		for _, dep := range f.BuildDependencies {
+			fmt.Fprintf(&b, "  depends_on %q => :build\n", dep)
+		}
+	}
+
+	b.WriteString("\n  def install\n")
+	for _, command := range s.BuildCommands {
+		args := make([]string, len(command))
+		for idx, arg := range command {
+			args[idx] = fmt.Sprintf("%q", strings.ReplaceAll(arg, cellarPath, "#{prefix}"))
+		}
+		fmt.Fprintf(&b, "    system %s\n", strings.Join(args, ", "))
+	}
+	b.WriteString("  end\n\n")
+	b.WriteString("  test do\n    system \"false\"\n  end\nend\n")
+	return b.String()
+}
+
+// rubyClassName converts a formula name like "foo-bar@2" to "FooBarAT2"
+func rubyClassName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(strings.ReplaceAll(name, "@", "-AT-"), func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	}) {
+		if part == "AT" {
+			b.WriteString(part)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestRunCreateFromLocalFile(t *testing.T) {
+	logger.Init(false, false, true)
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for name, content := range map[string]string{
+		"hello-2.12/CMakeLists.txt": "project(hello)\n",
+		"hello-2.12/hello.c":        "int main(void) { return 0; }\n",
+	} {
+		_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		_, _ = tw.Write([]byte(content))
+	}
+	_ = tw.Close()
+	_ = gzw.Close()
+
+	srcDir := t.TempDir()
+	tarball := filepath.Join(srcDir, "hello-2.12.tar.gz")
+	if err := os.WriteFile(tarball, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+
+	root := t.TempDir()
+	cfg := &config.Config{
+		HomebrewRepository: root,
+		HomebrewCellar:     filepath.Join(root, "Cellar"),
+		HomebrewTemp:       t.TempDir(),
+	}
+
+	var out bytes.Buffer
+	for _, name := range []string{"tools", "test/", "test/tools/extra", "test/missing"} {
+		if err := runCreate(cfg, &out, "file://"+tarball, &createOptions{tap: name}); err == nil {
+			t.Errorf("Expected runCreate to reject tap %q", name)
+		}
+	}
+
+	tapDir := filepath.Join(root, "Library", "Taps", "test", "homebrew-tools")
+	if err := os.MkdirAll(tapDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCreate(cfg, &out, "file://"+tarball, &createOptions{tap: "test/tools", ruby: true}); err != nil {
+		t.Fatalf("runCreate() error = %v", err)
+	}
+
+	formulaDir := filepath.Join(tapDir, "Formula")
+	f, err := formula.LoadFile(filepath.Join(formulaDir, "hello.yaml"))
+	if err != nil {
+		t.Fatalf("Generated formula does not load: %v", err)
+	}
+	if f.Name != "hello" || f.Version != "2.12" {
+		t.Errorf("Formula = %s %s, want hello 2.12", f.Name, f.Version)
+	}
+	if f.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("SHA256 = %s, want %s", f.SHA256, hex.EncodeToString(sum[:]))
+	}
+	if len(f.BuildDependencies) != 1 || f.BuildDependencies[0] != "cmake" {
+		t.Errorf("BuildDependencies = %v, want [cmake]", f.BuildDependencies)
+	}
+
+	stub, err := os.ReadFile(filepath.Join(formulaDir, "hello.rb"))
+	if err != nil {
+		t.Fatalf("Ruby stub not written: %v", err)
+	}
+	if !strings.Contains(string(stub), "class Hello < Formula") || !strings.Contains(string(stub), `depends_on "cmake" => :build`) {
+		t.Errorf("Unexpected Ruby stub:\n%s", stub)
+	}
+
+	// Existing formulae are not overwritten, and overrides take precedence
+	if err := runCreate(cfg, &out, "file://"+tarball, &createOptions{tap: "test/tools"}); err == nil {
+		t.Error("Expected error when the formula already exists")
+	}
+	if err := runCreate(cfg, &out, "file://"+tarball, &createOptions{tap: "test/tools", name: "hi", version: "3.0"}); err != nil {
+		t.Fatalf("runCreate() with overrides error = %v", err)
+	}
+	if f, err := formula.LoadFile(filepath.Join(formulaDir, "hi.yaml")); err != nil || f.Version != "3.0" {
+		t.Errorf("Override formula = %+v, %v", f, err)
+	}
+}
+
+func TestRubyClassName(t *testing.T) {
+	for name, want := range map[string]string{"wget": "Wget", "foo-bar": "FooBar", "python@3.12": "PythonAT312", "lib_x": "LibX"} {
+		if got := rubyClassName(name); got != want {
+			t.Errorf("rubyClassName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 	"github.com/spf13/cobra"
 )
@@ -21,6 +26,7 @@ func NewDepsCmd(cfg *config.Config) *cobra.Command {
 		tree            bool
 		topLevel        bool
 		annotate        bool
+		jsonOutput      bool
 	)
 
 	cmd := &cobra.Command{
@@ -31,8 +37,10 @@ arguments, show the intersection of their dependencies.
 
 By default, deps shows required dependencies for the given formulae.
 State-based options like --installed can filter out/in formulae based on their
-installation state.`,
-		Args: cobra.MinimumNArgs(1),
+installation state.
+
+With --installed --tree and no formula arguments, show the dependency tree of
+everything installed, read from install receipts.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runDeps(cfg, args, &depsOptions{
 				showInstalled:   showInstalled,
@@ -44,6 +52,7 @@ installation state.`,
 				tree:            tree,
 				topLevel:        topLevel,
 				annotate:        annotate,
+				jsonOutput:      jsonOutput,
 			})
 		},
 	}
@@ -57,6 +66,7 @@ installation state.`,
 	cmd.Flags().BoolVar(&tree, "tree", false, "Show dependencies as a tree")
 	cmd.Flags().BoolVar(&topLevel, "top-level", false, "Show only top-level dependencies")
 	cmd.Flags().BoolVar(&annotate, "annotate", false, "Mark any build, test, optional, or recommended dependencies")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the installed dependency graph as JSON")
 
 	return cmd
 }
@@ -71,9 +81,14 @@ type depsOptions struct {
 	tree            bool
 	topLevel        bool
 	annotate        bool
+	jsonOutput      bool
 }
 
 func runDeps(cfg *config.Config, formulaNames []string, opts *depsOptions) error {
+	if len(formulaNames) == 0 && opts.showInstalled && (opts.tree || opts.jsonOutput) {
+		return showInstalledDepsTree(cfg, os.Stdout, opts)
+	}
+
 	if len(formulaNames) == 0 {
 		return fmt.Errorf("no formulae specified")
 	}
@@ -82,6 +97,10 @@ func runDeps(cfg *config.Config, formulaNames []string, opts *depsOptions) error
 		return showDependents(cfg, formulaNames, opts)
 	}
 
+	if opts.showMissing {
+		return showMissingDeps(cfg, os.Stdout, formulaNames, opts)
+	}
+
 	if opts.tree {
 		return showDepsTree(cfg, formulaNames, opts)
 	}
@@ -111,6 +130,24 @@ func showDependents(cfg *config.Config, formulaNames []string, opts *depsOptions
 	return nil
 }
 
+// showMissingDeps prints the dependencies installing the formulae would pull
+// in, in the order they would be installed
+func showMissingDeps(cfg *config.Config, w io.Writer, formulaNames []string, opts *depsOptions) error {
+	inst := installer.New(cfg, &installer.Options{
+		BuildFromSource: opts.includeBuild,
+		IncludeTest:     opts.includeTest,
+	})
+
+	missing, err := inst.MissingDependencies(formulaNames)
+	if err != nil {
+		return fmt.Errorf("failed to resolve dependencies: %w", err)
+	}
+	for _, name := range missing {
+		_, _ = fmt.Fprintln(w, name)
+	}
+	return nil
+}
+
 func showDepsTree(cfg *config.Config, formulaNames []string, opts *depsOptions) error {
 	logger.Info("Dependency tree analysis not yet fully implemented")
 	for _, name := range formulaNames {
@@ -121,7 +158,87 @@ func showDepsTree(cfg *config.Config, formulaNames []string, opts *depsOptions)
 	return nil
 }
 
-// Removed recursive tree function - not needed for simplified implementation
+// showInstalledDepsTree renders the dependency forest of every installed
+// formula, or its adjacency map with --json
+func showInstalledDepsTree(cfg *config.Config, w io.Writer, opts *depsOptions) error {
+	installed, err := getInstalledFormulae(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to get installed formulae: %w", err)
+	}
+
+	depMap, err := buildDependencyMap(cfg, installed)
+	if err != nil {
+		return fmt.Errorf("failed to build dependency map: %w", err)
+	}
+
+	if opts.jsonOutput {
+		data, err := json.MarshalIndent(depMap, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal dependencies to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	renderDepsForest(w, depMap)
+	return nil
+}
+
+// renderDepsForest prints a tree for each formula nothing else depends on.
+// Subtrees already shown are collapsed and marked with (*).
+func renderDepsForest(w io.Writer, depMap map[string][]string) {
+	isDep := make(map[string]bool)
+	for _, deps := range depMap {
+		for _, dep := range deps {
+			isDep[dep] = true
+		}
+	}
+
+	names := make([]string, 0, len(depMap))
+	for name := range depMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	expanded := make(map[string]bool)
+	var roots []string
+	for _, name := range names {
+		if !isDep[name] {
+			roots = append(roots, name)
+		}
+	}
+
+	for _, root := range roots {
+		renderDepsNode(w, depMap, root, "", "", expanded)
+	}
+
+	// Formulae only reachable through a cycle have no root; show them too
+	for _, name := range names {
+		if !expanded[name] {
+			renderDepsNode(w, depMap, name, "", "", expanded)
+		}
+	}
+}
+
+func renderDepsNode(w io.Writer, depMap map[string][]string, name, branch, indent string, expanded map[string]bool) {
+	deps := depMap[name]
+	if expanded[name] && len(deps) > 0 {
+		_, _ = fmt.Fprintf(w, "%s%s (*)\n", branch, name)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "%s%s\n", branch, name)
+	expanded[name] = true
+
+	sorted := append([]string(nil), deps...)
+	sort.Strings(sorted)
+	for i, dep := range sorted {
+		if i == len(sorted)-1 {
+			renderDepsNode(w, depMap, dep, indent+"└── ", indent+"    ", expanded)
+		} else {
+			renderDepsNode(w, depMap, dep, indent+"├── ", indent+"│   ", expanded)
+		}
+	}
+}
 
 func isFormulaInstalledDeps(cfg *config.Config, name string) bool {
 	// Check if formula is installed by looking in cellar
@@ -2,8 +2,12 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/pilshchikov/homebrew-go/internal/config"
@@ -216,3 +220,95 @@ func TestDepsWithTempDir(t *testing.T) {
 		t.Errorf("runDeps with temp dir failed: %v", err)
 	}
 }
+
+func TestShowInstalledDepsTree(t *testing.T) {
+	logger.Init(false, false, true)
+	cfg := &config.Config{HomebrewCellar: t.TempDir()}
+
+	receipts := map[string][]string{
+		"wget":            {"openssl", "libidn2"},
+		"curl":            {"openssl"},
+		"openssl":         {"ca-certificates"},
+		"ca-certificates": nil,
+		"libidn2":         nil,
+	}
+	for name, deps := range receipts {
+		dir := filepath.Join(cfg.HomebrewCellar, name, "1.0")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(map[string]interface{}{"name": name, "dependencies": deps})
+		if err := os.WriteFile(filepath.Join(dir, "INSTALL_RECEIPT.json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := showInstalledDepsTree(cfg, &buf, &depsOptions{showInstalled: true, tree: true}); err != nil {
+		t.Fatalf("showInstalledDepsTree() error = %v", err)
+	}
+
+	expected := `curl
+└── openssl
+    └── ca-certificates
+wget
+├── libidn2
+└── openssl (*)
+`
+	if buf.String() != expected {
+		t.Errorf("showInstalledDepsTree() output:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	if err := showInstalledDepsTree(cfg, &buf, &depsOptions{showInstalled: true, jsonOutput: true}); err != nil {
+		t.Fatalf("showInstalledDepsTree() JSON error = %v", err)
+	}
+	var graph map[string][]string
+	if err := json.Unmarshal(buf.Bytes(), &graph); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(graph) != len(receipts) || len(graph["wget"]) != 2 || graph["openssl"][0] != "ca-certificates" {
+		t.Errorf("Unexpected dependency graph %v", graph)
+	}
+}
+
+func TestDepsMissing(t *testing.T) {
+	logger.Init(false, false, true)
+
+	formulae := map[string]string{
+		"app":  `{"name": "app", "versions": {"stable": "1.0"}, "dependencies": ["lib1", "lib2"]}`,
+		"lib1": `{"name": "lib1", "versions": {"stable": "1.0"}}`,
+		"lib2": `{"name": "lib2", "versions": {"stable": "1.0"}, "dependencies": ["lib3"]}`,
+		"lib3": `{"name": "lib3", "versions": {"stable": "1.0"}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/formula/"), ".json")
+		if data, ok := formulae[name]; ok {
+			_, _ = w.Write([]byte(data))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewCellar: filepath.Join(tempDir, "Cellar"),
+		HomebrewCache:  filepath.Join(tempDir, "Cache"),
+	}
+	if err := os.MkdirAll(filepath.Join(cfg.HomebrewCellar, "lib1", "1.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.HomebrewCellar, "lib1", "1.0", "INSTALL_RECEIPT.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := showMissingDeps(cfg, &out, []string{"app"}, &depsOptions{showMissing: true}); err != nil {
+		t.Fatalf("showMissingDeps() error = %v", err)
+	}
+	if got, want := out.String(), "lib3\nlib2\n"; got != want {
+		t.Errorf("Missing dependencies = %q, want %q", got, want)
+	}
+}
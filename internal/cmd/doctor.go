@@ -129,6 +129,36 @@ func runDoctor(cfg *config.Config, jsonOutput bool) error {
 
 	result.Checks = append(result.Checks, macportsCheck)
 
+	// Check installed kegs
+	for _, check := range scanKegs(cfg.HomebrewCellar, kegScanWorkers) {
+		result.Warnings = append(result.Warnings, check.Message)
+		result.HasIssues = true
+		result.Checks = append(result.Checks, check)
+	}
+
+	// Check for kegs that can no longer be upgraded
+	for _, check := range checkOrphanedKegs(cfg, newFormulaResolver(cfg)) {
+		result.Warnings = append(result.Warnings, check.Message)
+		result.HasIssues = true
+		result.Checks = append(result.Checks, check)
+	}
+
+	// Check for commands that shadow each other
+	for _, check := range checkDuplicateBinaries(cfg.HomebrewPrefix, cfg.HomebrewCellar) {
+		result.Warnings = append(result.Warnings, check.Message)
+		result.HasIssues = true
+		result.Checks = append(result.Checks, check)
+	}
+
+	// Check which brew the shell would run
+	if check, ok := brewPathCheck(); ok {
+		if check.Status != "ok" {
+			result.Warnings = append(result.Warnings, check.Message)
+			result.HasIssues = true
+		}
+		result.Checks = append(result.Checks, check)
+	}
+
 	// Add environment information
 	result.Environment["go_version"] = runtime.Version()
 	result.Environment["platform"] = runtime.GOOS + "/" + runtime.GOARCH
@@ -163,6 +193,15 @@ func runDoctor(cfg *config.Config, jsonOutput bool) error {
 			if strings.Contains(check.Name, "conflict") {
 				continue // Skip conflicts in directory section
 			}
+			if strings.HasPrefix(check.Name, "keg_") {
+				continue // Skip kegs in directory section
+			}
+			if strings.HasPrefix(check.Name, "path_") {
+				continue // Skip PATH checks in directory section
+			}
+			if strings.HasPrefix(check.Name, "bin_") {
+				continue // Skip binary checks in directory section
+			}
 			if check.Status == "warning" || check.Status == "error" {
 				fmt.Printf("Warning: %s (%s) %s.\n", check.Name, check.Path, check.Message)
 			}
@@ -188,6 +227,32 @@ func runDoctor(cfg *config.Config, jsonOutput bool) error {
 			}
 		}
 
+		fmt.Printf("==> Checking installed kegs\n")
+		for _, check := range result.Checks {
+			if !strings.HasPrefix(check.Name, "keg_") {
+				continue // Only show keg checks
+			}
+			fmt.Printf("Warning: %s: %s\n", check.Message, check.Path)
+		}
+
+		fmt.Printf("==> Checking linked binaries\n")
+		for _, check := range result.Checks {
+			if !strings.HasPrefix(check.Name, "bin_") {
+				continue // Only show binary checks
+			}
+			fmt.Printf("Warning: %s.\n", check.Message)
+		}
+
+		fmt.Printf("==> Checking PATH\n")
+		for _, check := range result.Checks {
+			if !strings.HasPrefix(check.Name, "path_") {
+				continue // Only show PATH checks
+			}
+			if check.Status == "warning" || check.Status == "error" {
+				fmt.Printf("Warning: %s.\n", check.Message)
+			}
+		}
+
 		fmt.Printf("==> Checking Go environment\n")
 		fmt.Printf("Go version: %s\n", result.Environment["go_version"])
 
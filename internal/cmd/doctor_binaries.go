@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// binaryDirs are the keg directories whose contents are linked as commands
+var binaryDirs = []string{"bin", "sbin"}
+
+// checkDuplicateBinaries reports commands that more than one installed
+// formula provides, naming the keg the prefix symlink currently points at.
+// Commands that are not linked into the prefix shadow nothing and are skipped.
+func checkDuplicateBinaries(prefix, cellar string) []DoctorCheck {
+	resolvedCellar := cellar
+	if resolved, err := filepath.EvalSymlinks(cellar); err == nil {
+		resolvedCellar = resolved
+	}
+
+	// dir/command -> formulae with a keg providing it
+	providers := make(map[string]map[string]bool)
+	for _, keg := range listKegs(cellar) {
+		name := filepath.Base(filepath.Dir(keg))
+		for _, dir := range binaryDirs {
+			entries, err := os.ReadDir(filepath.Join(keg, dir))
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				rel := filepath.Join(dir, entry.Name())
+				if providers[rel] == nil {
+					providers[rel] = make(map[string]bool)
+				}
+				providers[rel][name] = true
+			}
+		}
+	}
+
+	var checks []DoctorCheck
+	for rel, formulae := range providers {
+		if len(formulae) < 2 {
+			continue
+		}
+
+		linkPath := filepath.Join(prefix, rel)
+		target, err := filepath.EvalSymlinks(linkPath)
+		if err != nil {
+			continue
+		}
+		owner, version, ok := kegOwning(resolvedCellar, target)
+		if !ok {
+			continue
+		}
+
+		names := make([]string, 0, len(formulae))
+		for name := range formulae {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		checks = append(checks, DoctorCheck{
+			Name:        "bin_duplicate",
+			Description: "Duplicate linked binary check",
+			Status:      "warning",
+			Message:     fmt.Sprintf("%s is provided by %s; the linked one is from %s %s", rel, strings.Join(names, ", "), owner, version),
+			Path:        linkPath,
+		})
+	}
+
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].Path < checks[j].Path
+	})
+
+	return checks
+}
+
+// kegOwning returns the formula and version of the keg containing path
+func kegOwning(cellar, path string) (string, string, bool) {
+	rel, err := filepath.Rel(cellar, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", false
+	}
+	parts := strings.SplitN(rel, string(filepath.Separator), 3)
+	if len(parts) < 3 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// kegScanWorkers bounds how many kegs are inspected concurrently
+var kegScanWorkers = runtime.NumCPU()
+
+// scanKegs inspects every installed keg for missing receipts and broken links.
+// Kegs are checked concurrently; the returned checks are sorted by path.
+func scanKegs(cellar string, workers int) []DoctorCheck {
+	kegs := listKegs(cellar)
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		checks []DoctorCheck
+	)
+
+	jobs := make(chan string)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for keg := range jobs {
+				issues := checkKeg(keg)
+				if len(issues) == 0 {
+					continue
+				}
+				mu.Lock()
+				checks = append(checks, issues...)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, keg := range kegs {
+		jobs <- keg
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].Path != checks[j].Path {
+			return checks[i].Path < checks[j].Path
+		}
+		return checks[i].Name < checks[j].Name
+	})
+
+	return checks
+}
+
+// listKegs returns the paths of all Cellar/<formula>/<version> directories
+func listKegs(cellar string) []string {
+	formulae, err := os.ReadDir(cellar)
+	if err != nil {
+		return nil
+	}
+
+	var kegs []string
+	for _, f := range formulae {
+		if !f.IsDir() {
+			continue
+		}
+		versions, err := os.ReadDir(filepath.Join(cellar, f.Name()))
+		if err != nil {
+			continue
+		}
+		for _, v := range versions {
+			if v.IsDir() {
+				kegs = append(kegs, filepath.Join(cellar, f.Name(), v.Name()))
+			}
+		}
+	}
+
+	return kegs
+}
+
+// checkKeg returns the issues found in a single keg
+func checkKeg(keg string) []DoctorCheck {
+	var issues []DoctorCheck
+	name := filepath.Base(filepath.Dir(keg)) + " " + filepath.Base(keg)
+
+	if _, err := os.Stat(filepath.Join(keg, "INSTALL_RECEIPT.json")); os.IsNotExist(err) {
+		issues = append(issues, DoctorCheck{
+			Name:        "keg_missing_receipt",
+			Description: "Keg install receipt check",
+			Status:      "warning",
+			Message:     fmt.Sprintf("%s has no install receipt", name),
+			Path:        keg,
+		})
+	}
+
+	_ = filepath.WalkDir(keg, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.Type()&os.ModeSymlink == 0 {
+			return nil
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			issues = append(issues, DoctorCheck{
+				Name:        "keg_broken_link",
+				Description: "Keg symlink check",
+				Status:      "warning",
+				Message:     fmt.Sprintf("%s contains a broken symlink", name),
+				Path:        path,
+			})
+		}
+		return nil
+	})
+
+	return issues
+}
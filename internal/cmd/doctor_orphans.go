@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/pilshchikov/homebrew-go/internal/api"
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/tap"
+)
+
+// orphanCheckWorkers bounds how many formulae are looked up concurrently
+var orphanCheckWorkers = runtime.NumCPU()
+
+// formulaResolver reports whether a formula can still be found in the tap it
+// was installed from. An error means it couldn't be determined, such as when
+// offline, and the keg is not flagged.
+type formulaResolver func(name, tapName string) (bool, error)
+
+// checkOrphanedKegs flags installed formulae that no longer resolve from
+// the tap recorded in their receipt, e.g. because the tap was removed or
+// the formula renamed upstream. Such kegs can't be upgraded. Formulae
+// installed from a file are orphaned once that file is gone.
+func checkOrphanedKegs(cfg *config.Config, resolves formulaResolver) []DoctorCheck {
+	entries, err := os.ReadDir(cfg.HomebrewCellar)
+	if err != nil {
+		return nil
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		checks []DoctorCheck
+	)
+
+	jobs := make(chan string)
+	for w := 0; w < max(orphanCheckWorkers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				if check, ok := checkOrphanedKeg(cfg, name, resolves); ok {
+					mu.Lock()
+					checks = append(checks, check)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			jobs <- entry.Name()
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Path < checks[j].Path })
+	return checks
+}
+
+// checkOrphanedKeg checks the newest installed version of one formula
+func checkOrphanedKeg(cfg *config.Config, name string, resolves formulaResolver) (DoctorCheck, bool) {
+	versions, err := cellarVersions(cfg, name)
+	if err != nil || len(versions) == 0 {
+		return DoctorCheck{}, false
+	}
+	formulaPath := filepath.Join(cfg.HomebrewCellar, name)
+	receipt := newestReceipt(formulaPath, versions)
+
+	var source string
+	if receipt.Tap == formula.LocalTap {
+		// Older receipts don't say which file the formula came from
+		if receipt.SourcePath == "" {
+			return DoctorCheck{}, false
+		}
+		if _, err := os.Stat(receipt.SourcePath); err == nil {
+			return DoctorCheck{}, false
+		}
+		source = receipt.SourcePath
+	} else {
+		tapName := receipt.Tap
+		if tapName == "" {
+			tapName = "homebrew/core"
+		}
+		found, err := resolves(name, tapName)
+		if err != nil {
+			logger.Debug("Skipping orphan check for %s: %v", name, err)
+			return DoctorCheck{}, false
+		}
+		if found {
+			return DoctorCheck{}, false
+		}
+		source = tapName
+	}
+
+	return DoctorCheck{
+		Name:        "keg_orphaned",
+		Description: "Orphaned formula check",
+		Status:      "warning",
+		Message: fmt.Sprintf("%s is an orphaned or unknown formula: %s no longer provides it; reinstall it from another source or pin it (brew pin %s)",
+			name, source, name),
+		Path: formulaPath,
+	}, true
+}
+
+// newFormulaResolver resolves core formulae through the API and everything
+// else through the installed taps
+func newFormulaResolver(cfg *config.Config) formulaResolver {
+	client := api.NewClient(cfg)
+	manager := tap.NewManager(cfg)
+
+	var mu sync.Mutex
+	tapFormulae := make(map[string][]string)
+
+	return func(name, tapName string) (bool, error) {
+		if tapName == "homebrew/core" {
+			_, err := client.GetFormula(name)
+			var notFound *api.FormulaNotFoundError
+			if errors.As(err, &notFound) {
+				return false, nil
+			}
+			return err == nil, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		names, ok := tapFormulae[tapName]
+		if !ok {
+			if t, err := manager.GetTap(tapName); err == nil {
+				names, _ = t.ListFormulae()
+			}
+			tapFormulae[tapName] = names
+		}
+		return slices.Contains(names, name), nil
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// checkBrewOnPath warns when running "brew" from the shell would not run
+// this executable, e.g. because Ruby Homebrew comes earlier in PATH
+func checkBrewOnPath(executable string, lookPath func(string) (string, error)) DoctorCheck {
+	check := DoctorCheck{
+		Name:        "path_brew",
+		Description: "brew executable on PATH check",
+		Path:        executable,
+	}
+
+	found, err := lookPath("brew")
+	if err != nil {
+		check.Status = "warning"
+		check.Message = fmt.Sprintf("brew is not on your PATH; this brew is %s", executable)
+		return check
+	}
+
+	if !samePath(found, executable) {
+		check.Status = "warning"
+		check.Message = fmt.Sprintf("running brew would invoke %s instead of this brew (%s)", found, executable)
+		check.Path = found
+		return check
+	}
+
+	check.Status = "ok"
+	check.Message = "brew on PATH is this executable"
+	return check
+}
+
+// samePath reports whether two paths name the same file once symlinks are
+// resolved
+func samePath(a, b string) bool {
+	if resolved, err := filepath.EvalSymlinks(a); err == nil {
+		a = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(b); err == nil {
+		b = resolved
+	}
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+
+	aInfo, aErr := os.Stat(a)
+	bInfo, bErr := os.Stat(b)
+	return aErr == nil && bErr == nil && os.SameFile(aInfo, bInfo)
+}
+
+// brewPathCheck runs checkBrewOnPath for the running executable
+func brewPathCheck() (DoctorCheck, bool) {
+	executable, err := os.Executable()
+	if err != nil {
+		return DoctorCheck{}, false
+	}
+	return checkBrewOnPath(executable, exec.LookPath), true
+}
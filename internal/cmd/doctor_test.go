@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+)
+
+// createFakeKegs creates count kegs; every third keg lacks a receipt and
+// every fifth keg contains a broken symlink
+func createFakeKegs(t testing.TB, cellar string, count int) (missingReceipts, brokenLinks int) {
+	t.Helper()
+
+	for i := 0; i < count; i++ {
+		keg := filepath.Join(cellar, fmt.Sprintf("formula%03d", i), "1.0.0")
+		if err := os.MkdirAll(filepath.Join(keg, "bin"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if i%3 == 0 {
+			missingReceipts++
+		} else {
+			_ = os.WriteFile(filepath.Join(keg, "INSTALL_RECEIPT.json"), []byte("{}"), 0644)
+		}
+
+		if i%5 == 0 {
+			brokenLinks++
+			_ = os.Symlink(filepath.Join(keg, "missing"), filepath.Join(keg, "bin", "tool"))
+		}
+	}
+
+	return missingReceipts, brokenLinks
+}
+
+func TestScanKegsConcurrent(t *testing.T) {
+	cellar := t.TempDir()
+	missingReceipts, brokenLinks := createFakeKegs(t, cellar, 200)
+
+	sequential := scanKegs(cellar, 1)
+	concurrent := scanKegs(cellar, 16)
+
+	if len(concurrent) != missingReceipts+brokenLinks {
+		t.Fatalf("scanKegs() found %d issues, want %d", len(concurrent), missingReceipts+brokenLinks)
+	}
+
+	// Output order must not depend on the number of workers
+	if len(sequential) != len(concurrent) {
+		t.Fatalf("Sequential scan found %d issues, concurrent found %d", len(sequential), len(concurrent))
+	}
+	for i := range sequential {
+		if sequential[i] != concurrent[i] {
+			t.Errorf("Issue %d differs: %+v vs %+v", i, sequential[i], concurrent[i])
+		}
+	}
+
+	counts := map[string]int{}
+	for _, check := range concurrent {
+		counts[check.Name]++
+	}
+	if counts["keg_missing_receipt"] != missingReceipts {
+		t.Errorf("Missing receipts = %d, want %d", counts["keg_missing_receipt"], missingReceipts)
+	}
+	if counts["keg_broken_link"] != brokenLinks {
+		t.Errorf("Broken links = %d, want %d", counts["keg_broken_link"], brokenLinks)
+	}
+}
+
+func TestScanKegsEmptyCellar(t *testing.T) {
+	if checks := scanKegs(filepath.Join(t.TempDir(), "missing"), 4); len(checks) != 0 {
+		t.Errorf("Expected no issues for missing cellar, got %d", len(checks))
+	}
+}
+
+func BenchmarkScanKegs(b *testing.B) {
+	cellar := b.TempDir()
+	createFakeKegs(b, cellar, 500)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scanKegs(cellar, kegScanWorkers)
+	}
+}
+
+func TestCheckBrewOnPath(t *testing.T) {
+	dir := t.TempDir()
+	self := filepath.Join(dir, "prefix", "bin", "brew")
+	other := filepath.Join(dir, "usr", "local", "bin", "brew")
+	link := filepath.Join(dir, "link", "brew")
+	for _, path := range []string{self, other} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(self, link); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		found      string
+		err        error
+		wantStatus string
+		wantPaths  []string
+	}{
+		{"same executable", self, nil, "ok", nil},
+		{"symlink to executable", link, nil, "ok", nil},
+		{"shadowed by another brew", other, nil, "warning", []string{other, self}},
+		{"not on PATH", "", errors.New("executable file not found in $PATH"), "warning", []string{self}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookPath := func(name string) (string, error) {
+				if name != "brew" {
+					t.Errorf("Expected lookup of brew, got %q", name)
+				}
+				return tt.found, tt.err
+			}
+
+			check := checkBrewOnPath(self, lookPath)
+			if check.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q (%s)", check.Status, tt.wantStatus, check.Message)
+			}
+			for _, path := range tt.wantPaths {
+				if !strings.Contains(check.Message, path) {
+					t.Errorf("Expected message to mention %s, got %q", path, check.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckDuplicateBinaries(t *testing.T) {
+	prefix := t.TempDir()
+	cellar := filepath.Join(prefix, "Cellar")
+	for _, keg := range []string{"foo-classic/1.0", "foo-ng/2.1"} {
+		bin := filepath.Join(cellar, keg, "bin")
+		if err := os.MkdirAll(bin, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"foo", filepath.Base(filepath.Dir(keg))} {
+			if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(prefix, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../Cellar/foo-ng/2.1/bin/foo", filepath.Join(prefix, "bin", "foo")); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := checkDuplicateBinaries(prefix, cellar)
+	if len(checks) != 1 {
+		t.Fatalf("Expected 1 duplicate binary, got %d: %+v", len(checks), checks)
+	}
+	check := checks[0]
+	if check.Name != "bin_duplicate" || check.Path != filepath.Join(prefix, "bin", "foo") {
+		t.Errorf("Unexpected check: %+v", check)
+	}
+	for _, want := range []string{"foo-classic, foo-ng", "foo-ng 2.1"} {
+		if !strings.Contains(check.Message, want) {
+			t.Errorf("Expected message to mention %q, got %q", want, check.Message)
+		}
+	}
+}
+
+func TestCheckOrphanedKegs(t *testing.T) {
+	cellar := t.TempDir()
+	source := filepath.Join(t.TempDir(), "kept.rb")
+	if err := os.WriteFile(source, []byte("class Kept < Formula\nend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	kegs := map[string]string{
+		"wget/1.0.0":    `{"name": "wget", "tap": "homebrew/core"}`,
+		"mytool/1.0.0":  `{"name": "mytool", "tap": "user/gone"}`,
+		"renamed/1.0.0": `{"name": "renamed"}`,
+		"offline/1.0.0": `{"name": "offline", "tap": "homebrew/core"}`,
+		// 1.10 is newer than 1.9, so only its tap is looked up
+		"moved/1.9":  `{"name": "moved", "tap": "user/gone"}`,
+		"moved/1.10": `{"name": "moved", "tap": "homebrew/core"}`,
+		// Formulae installed from a file are checked against that file
+		"kept/1.0.0":    fmt.Sprintf(`{"name": "kept", "tap": "local/formula", "source_path": %q}`, source),
+		"deleted/1.0.0": fmt.Sprintf(`{"name": "deleted", "tap": "local/formula", "source_path": %q}`, filepath.Join(t.TempDir(), "deleted.rb")),
+		"legacy/1.0.0":  `{"name": "legacy", "tap": "local/formula"}`,
+	}
+	for path, receipt := range kegs {
+		keg := filepath.Join(cellar, path)
+		if err := os.MkdirAll(keg, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(keg, "INSTALL_RECEIPT.json"), []byte(receipt), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		lookups []string
+	)
+	resolves := func(name, tapName string) (bool, error) {
+		mu.Lock()
+		lookups = append(lookups, tapName+"/"+name)
+		mu.Unlock()
+		switch name {
+		case "offline":
+			return false, errors.New("network unreachable")
+		case "wget", "moved":
+			return true, nil
+		}
+		return false, nil
+	}
+
+	checks := checkOrphanedKegs(&config.Config{HomebrewCellar: cellar}, resolves)
+	orphans := []string{"deleted", "mytool", "renamed"}
+	if len(checks) != len(orphans) {
+		t.Fatalf("Expected %d orphaned kegs, got %d: %+v", len(orphans), len(checks), checks)
+	}
+	for i, name := range orphans {
+		if checks[i].Name != "keg_orphaned" || checks[i].Path != filepath.Join(cellar, name) {
+			t.Errorf("Unexpected check for %s: %+v", name, checks[i])
+		}
+		if !strings.Contains(checks[i].Message, "orphaned") || !strings.Contains(checks[i].Message, "brew pin "+name) {
+			t.Errorf("Expected orphan message with a pin suggestion, got %q", checks[i].Message)
+		}
+	}
+
+	// The receipt's tap is used, with kegs lacking one assumed to be core
+	want := []string{"homebrew/core/moved", "homebrew/core/offline", "homebrew/core/renamed", "homebrew/core/wget", "user/gone/mytool"}
+	sort.Strings(lookups)
+	if strings.Join(lookups, ",") != strings.Join(want, ",") {
+		t.Errorf("Resolver lookups = %v, want %v", lookups, want)
+	}
+}
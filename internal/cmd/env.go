@@ -3,9 +3,15 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 
+	"github.com/pilshchikov/homebrew-go/internal/api"
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
 	"github.com/spf13/cobra"
 )
 
@@ -41,32 +47,83 @@ func NewCellarCmd(cfg *config.Config) *cobra.Command {
 
 // NewCacheCmd creates the --cache command
 func NewCacheCmd(cfg *config.Config) *cobra.Command {
+	var cask bool
+
 	cmd := &cobra.Command{
-		Use:    "cache",
+		Use:    "cache [FORMULA|CASK...]",
 		Hidden: true,
 		Short:  "Display Homebrew's download cache",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			_, err := fmt.Fprintln(cmd.OutOrStdout(), cfg.HomebrewCache)
-			return err
+			if len(args) == 0 {
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), cfg.HomebrewCache)
+				return err
+			}
+
+			return showCachePaths(cmd.OutOrStdout(), cfg, args, cask)
 		},
 	}
 
+	cmd.Flags().BoolVar(&cask, "cask", false, "Treat all named arguments as casks")
+
 	return cmd
 }
 
+// showCachePaths prints the expected download cache path for each package
+func showCachePaths(w io.Writer, cfg *config.Config, names []string, cask bool) error {
+	inst := installer.New(cfg, &installer.Options{})
+	apiClient := api.NewClient(cfg)
+
+	for _, name := range names {
+		var path string
+		if cask {
+			p, err := inst.CaskCachePath(name)
+			if err != nil {
+				return err
+			}
+			path = p
+		} else {
+			f, err := apiClient.GetFormula(name)
+			if err != nil {
+				return fmt.Errorf("failed to get formula %s: %w", name, err)
+			}
+			path = inst.FormulaCachePath(f)
+		}
+
+		if _, err := fmt.Fprintln(w, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // NewEnvCmd creates the env command
 func NewEnvCmd(cfg *config.Config) *cobra.Command {
-	var jsonOutput bool
+	var (
+		jsonOutput bool
+		plain      bool
+		shell      string
+	)
 
 	cmd := &cobra.Command{
-		Use:   "env",
-		Short: "Show a summary of the Homebrew build environment",
+		Use:     "env",
+		Short:   "Show a summary of the Homebrew build environment",
+		PreRunE: exclusiveFlags([]string{"json"}, []string{"plain"}, []string{"shell"}),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return showEnv(cfg, jsonOutput)
+			format := shell
+			switch {
+			case jsonOutput:
+				format = "json"
+			case plain:
+				format = "plain"
+			}
+			return showEnv(cmd.OutOrStdout(), cfg, format)
 		},
 	}
 
 	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output in JSON format")
+	cmd.Flags().BoolVar(&plain, "plain", false, "Output KEY=value pairs for tools other than shells")
+	cmd.Flags().StringVar(&shell, "shell", "", "Output for the given shell: bash, zsh, fish or plain")
 
 	return cmd
 }
@@ -85,10 +142,30 @@ type EnvironmentInfo struct {
 	GoVersion          string `json:"go_version"`
 }
 
-func showEnv(cfg *config.Config, jsonOutput bool) error {
-	pathValue := fmt.Sprintf("%s/bin:%s/sbin:$PATH", cfg.HomebrewPrefix, cfg.HomebrewPrefix)
+// envVar is a variable printed by env
+type envVar struct {
+	Name  string
+	Value string
+}
+
+// homebrewEnvVars returns the HOMEBREW_* variables printed by env, in order
+func homebrewEnvVars(cfg *config.Config) []envVar {
+	return []envVar{
+		{"HOMEBREW_PREFIX", cfg.HomebrewPrefix},
+		{"HOMEBREW_REPOSITORY", cfg.HomebrewRepository},
+		{"HOMEBREW_CELLAR", cfg.HomebrewCellar},
+		{"HOMEBREW_CASKROOM", cfg.HomebrewCaskroom},
+	}
+}
+
+// showEnv prints the environment in format, which is json, plain or the name
+// of a shell; shells default to bash
+func showEnv(w io.Writer, cfg *config.Config, format string) error {
+	binDirs := []string{filepath.Join(cfg.HomebrewPrefix, "bin"), filepath.Join(cfg.HomebrewPrefix, "sbin")}
+	pathValue := strings.Join(append(binDirs, "$PATH"), ":")
 
-	if jsonOutput {
+	switch format {
+	case "json":
 		env := EnvironmentInfo{
 			HomebrewPrefix:     cfg.HomebrewPrefix,
 			HomebrewRepository: cfg.HomebrewRepository,
@@ -106,14 +183,33 @@ func showEnv(cfg *config.Config, jsonOutput bool) error {
 		if err != nil {
 			return fmt.Errorf("failed to marshal environment to JSON: %w", err)
 		}
-		fmt.Println(string(data))
-	} else {
-		// Traditional shell export format
-		fmt.Printf("export HOMEBREW_PREFIX=%s\n", cfg.HomebrewPrefix)
-		fmt.Printf("export HOMEBREW_REPOSITORY=%s\n", cfg.HomebrewRepository)
-		fmt.Printf("export HOMEBREW_CELLAR=%s\n", cfg.HomebrewCellar)
-		fmt.Printf("export HOMEBREW_CASKROOM=%s\n", cfg.HomebrewCaskroom)
-		fmt.Printf("export PATH=%s\n", pathValue)
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "plain":
+		// Nothing expands $PATH for other tools, so it is spelled out
+		path := strings.Join(append(binDirs, os.Getenv("PATH")), string(os.PathListSeparator))
+		for _, v := range append(homebrewEnvVars(cfg), envVar{"PATH", path}) {
+			if _, err := fmt.Fprintf(w, "%s=%s\n", v.Name, v.Value); err != nil {
+				return err
+			}
+		}
+	case "", "bash", "zsh", "sh":
+		for _, v := range append(homebrewEnvVars(cfg), envVar{"PATH", pathValue}) {
+			if _, err := fmt.Fprintf(w, "export %s=%s\n", v.Name, v.Value); err != nil {
+				return err
+			}
+		}
+	case "fish":
+		for _, v := range homebrewEnvVars(cfg) {
+			if _, err := fmt.Fprintf(w, "set -gx %s %s\n", v.Name, v.Value); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "set -gx PATH %s $PATH\n", strings.Join(binDirs, " ")); err != nil {
+			return err
+		}
+	default:
+		return &UsageError{Message: fmt.Sprintf("unsupported shell %q; use bash, zsh, fish or plain", format)}
 	}
 
 	return nil
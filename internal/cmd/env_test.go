@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+)
+
+func TestShowEnvFormats(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin:/bin")
+	cfg := &config.Config{
+		HomebrewPrefix:     "/test/prefix",
+		HomebrewRepository: "/test/repository",
+		HomebrewCellar:     "/test/cellar",
+		HomebrewCaskroom:   "/test/caskroom",
+	}
+
+	t.Run("plain", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := showEnv(&buf, cfg, "plain"); err != nil {
+			t.Fatalf("showEnv() error = %v", err)
+		}
+
+		vars := map[string]string{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			name, value, ok := strings.Cut(line, "=")
+			if !ok || strings.Contains(name, " ") {
+				t.Fatalf("Expected KEY=value, got %q", line)
+			}
+			vars[name] = value
+		}
+
+		want := map[string]string{
+			"HOMEBREW_PREFIX":     "/test/prefix",
+			"HOMEBREW_REPOSITORY": "/test/repository",
+			"HOMEBREW_CELLAR":     "/test/cellar",
+			"HOMEBREW_CASKROOM":   "/test/caskroom",
+			"PATH":                "/test/prefix/bin:/test/prefix/sbin:/usr/bin:/bin",
+		}
+		for name, value := range want {
+			if vars[name] != value {
+				t.Errorf("%s = %q, want %q", name, vars[name], value)
+			}
+		}
+		if len(vars) != len(want) {
+			t.Errorf("Expected %d variables, got %v", len(want), vars)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := showEnv(&buf, cfg, "json"); err != nil {
+			t.Fatalf("showEnv() error = %v", err)
+		}
+
+		var env map[string]string
+		if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v\n%s", err, buf.String())
+		}
+		if env["HOMEBREW_PREFIX"] != "/test/prefix" || env["PATH"] != "/test/prefix/bin:/test/prefix/sbin:$PATH" {
+			t.Errorf("Unexpected JSON environment: %v", env)
+		}
+		if env["platform"] == "" || env["go_version"] == "" {
+			t.Errorf("Expected platform and go_version in JSON, got %v", env)
+		}
+	})
+
+	shells := []struct {
+		shell string
+		want  []string
+	}{
+		{"", []string{"export HOMEBREW_PREFIX=/test/prefix\n", "export PATH=/test/prefix/bin:/test/prefix/sbin:$PATH\n"}},
+		{"zsh", []string{"export HOMEBREW_CELLAR=/test/cellar\n"}},
+		{"fish", []string{"set -gx HOMEBREW_PREFIX /test/prefix\n", "set -gx PATH /test/prefix/bin /test/prefix/sbin $PATH\n"}},
+	}
+	for _, tt := range shells {
+		t.Run("shell "+tt.shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := showEnv(&buf, cfg, tt.shell); err != nil {
+				t.Fatalf("showEnv() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+
+	t.Run("unknown shell", func(t *testing.T) {
+		var usageErr *UsageError
+		if err := showEnv(&bytes.Buffer{}, cfg, "tcsh"); !errors.As(err, &usageErr) {
+			t.Errorf("Expected a usage error, got %v", err)
+		}
+	})
+}
+
+func TestEnvCmdRejectsCombinedFormats(t *testing.T) {
+	cmd := NewEnvCmd(&config.Config{})
+	cmd.SetArgs([]string{"--json", "--plain"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	var usageErr *UsageError
+	if err := cmd.Execute(); !errors.As(err, &usageErr) {
+		t.Errorf("Expected a usage error, got %v", err)
+	}
+}
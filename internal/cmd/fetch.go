@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+// NewFetchCmd creates the fetch command
+func NewFetchCmd(cfg *config.Config) *cobra.Command {
+	var (
+		force           bool
+		cask            bool
+		buildFromSource bool
+		bottleTag       string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "fetch [OPTIONS] FORMULA|CASK...",
+		Short: "Download a bottle, source or cask artifact into the cache",
+		Long: `Download a bottle (if available) or source packages for formulae,
+or the artifact for casks, into the download cache without installing them.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFetch(cfg, args, &fetchOptions{
+				force:           force || cfg.Force,
+				cask:            cask,
+				buildFromSource: buildFromSource,
+				bottleTag:       bottleTag,
+				ctx:             cmd.Context(),
+			})
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Remove a previously cached version and re-fetch")
+	cmd.Flags().BoolVar(&cask, "cask", false, "Treat all named arguments as casks")
+	cmd.Flags().BoolVarP(&buildFromSource, "build-from-source", "s", false, "Download source packages rather than bottles")
+	cmd.Flags().StringVar(&bottleTag, "bottle-tag", "", "Download the bottle for this platform tag (e.g. arm64_linux) instead of the host's")
+
+	return cmd
+}
+
+type fetchOptions struct {
+	force           bool
+	cask            bool
+	buildFromSource bool
+	bottleTag       string
+	ctx             context.Context
+}
+
+func runFetch(cfg *config.Config, names []string, opts *fetchOptions) error {
+	inst := installer.New(cfg, &installer.Options{
+		Force:           opts.force,
+		BuildFromSource: opts.buildFromSource,
+		BottleTag:       opts.bottleTag,
+	})
+	inst.SetContext(opts.ctx)
+
+	var failed []string
+	for _, name := range names {
+		var (
+			path string
+			err  error
+		)
+
+		if opts.cask {
+			path, err = inst.FetchCask(name)
+		} else {
+			path, err = inst.FetchFormula(name)
+		}
+
+		if err != nil {
+			logger.Error("Failed to fetch %s: %v", name, err)
+			failed = append(failed, name)
+			continue
+		}
+
+		logger.Success("Downloaded to: %s", path)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to fetch %d package(s)", len(failed))
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestFetchBottleTag(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tags := []string{"x86_64_linux", "arm64_sequoia"}
+	var requested []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/formula/mytool.json" {
+			files := make([]string, 0, len(tags))
+			for _, tag := range tags {
+				sum := sha256.Sum256([]byte("bottle for " + tag))
+				files = append(files, fmt.Sprintf(`%q: {"url": "%s/bottles/%s", "sha256": %q}`,
+					tag, server.URL, tag, hex.EncodeToString(sum[:])))
+			}
+			_, _ = fmt.Fprintf(w, `{"name": "mytool", "versions": {"stable": "1.0"}, "bottle": {"stable": {"files": {%s}}}}`,
+				strings.Join(files, ", "))
+			return
+		}
+		if tag, ok := strings.CutPrefix(r.URL.Path, "/bottles/"); ok {
+			requested = append(requested, tag)
+			_, _ = w.Write([]byte("bottle for " + tag))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	for _, tag := range tags {
+		t.Run(tag, func(t *testing.T) {
+			requested = nil
+			tempDir := t.TempDir()
+			cfg := &config.Config{
+				HomebrewCache:  filepath.Join(tempDir, "Cache"),
+				HomebrewCellar: filepath.Join(tempDir, "Cellar"),
+			}
+
+			cmd := NewFetchCmd(cfg)
+			cmd.SetArgs([]string{"--bottle-tag", tag, "mytool"})
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("fetch --bottle-tag %s error = %v", tag, err)
+			}
+
+			if len(requested) != 1 || requested[0] != tag {
+				t.Errorf("Expected only the %s bottle to be downloaded, got %v", tag, requested)
+			}
+			bottlePath := filepath.Join(cfg.HomebrewCache, "downloads", "mytool-1.0."+tag+".bottle.tar.gz")
+			data, err := os.ReadFile(bottlePath)
+			if err != nil {
+				t.Fatalf("Expected bottle at %s: %v", bottlePath, err)
+			}
+			if string(data) != "bottle for "+tag {
+				t.Errorf("Unexpected bottle content %q", data)
+			}
+		})
+	}
+}
+
+func TestInstallForeignBottleTagRequiresForce(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewCache:  filepath.Join(tempDir, "Cache"),
+		HomebrewCellar: filepath.Join(tempDir, "Cellar"),
+	}
+
+	inst := installer.New(cfg, &installer.Options{BottleTag: "sparc_solaris"})
+	_, err := inst.InstallFormula("mytool")
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("Expected a foreign bottle tag to require --force, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// UsageErrorExitCode is the exit status for commands invoked incorrectly
+const UsageErrorExitCode = 2
+
+// UsageError reports a command invoked with an invalid combination of
+// arguments or flags
+type UsageError struct {
+	Message string
+}
+
+// Error implements the error interface
+func (e *UsageError) Error() string {
+	return "invalid usage: " + e.Message
+}
+
+// ExitCode returns the process exit status for the error
+func (e *UsageError) ExitCode() int {
+	return UsageErrorExitCode
+}
+
+// formulaCaskFlags groups the spellings of the flags that restrict a
+// command to formulae or to casks
+var formulaCaskFlags = [][]string{
+	{"formula", "formulae"},
+	{"cask", "casks"},
+}
+
+// exclusiveFlags returns a PreRunE hook rejecting flags from more than one
+// group. Flags within a group are aliases and may be combined.
+func exclusiveFlags(groups ...[]string) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		return checkExclusiveFlags(cmd, groups...)
+	}
+}
+
+// checkExclusiveFlags returns a UsageError naming the conflicting flags when
+// flags from more than one group were set. Flags the command doesn't define
+// are ignored.
+func checkExclusiveFlags(cmd *cobra.Command, groups ...[]string) error {
+	var given []string
+	for _, group := range groups {
+		for _, name := range group {
+			if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+				given = append(given, "--"+name)
+				break
+			}
+		}
+	}
+
+	if len(given) > 1 {
+		return &UsageError{
+			Message: fmt.Sprintf("%s cannot be used together", strings.Join(given, " and ")),
+		}
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"strings"
@@ -17,9 +19,13 @@ import (
 // NewInfoCmd creates the info command
 func NewInfoCmd(cfg *config.Config) *cobra.Command {
 	var (
-		json      bool
-		installed bool
-		analytics bool
+		json          bool
+		installed     bool
+		analytics     bool
+		analyticsJSON bool
+		days          int
+		github        bool
+		variations    bool
 	)
 
 	cmd := &cobra.Command{
@@ -30,13 +36,32 @@ func NewInfoCmd(cfg *config.Config) *cobra.Command {
 			if len(args) == 0 {
 				return showSystemInfo(cfg, json)
 			}
+			if err := api.ValidateAnalyticsPeriod(days); err != nil {
+				return &UsageError{Message: "--days: " + err.Error()}
+			}
 
 			apiClient := api.NewClient(cfg)
+			analyticsClient := api.NewAnalyticsClient(cfg)
 			for _, name := range args {
 				logger.Step("Getting info for %s", name)
 
 				if formula, err := apiClient.GetFormula(name); err == nil {
-					showFormulaInfo(formula, json)
+					if variations && json {
+						showVariations(os.Stdout, formula, apiClient.GetPlatformTag(), true)
+						continue
+					}
+					showFormulaInfo(cfg, formula, json)
+					if variations {
+						showVariations(os.Stdout, formula, apiClient.GetPlatformTag(), false)
+					}
+					if analyticsJSON {
+						showAnalyticsJSON(os.Stdout, analyticsClient, formula.Name)
+					} else if analytics && !json {
+						showAnalytics(os.Stdout, analyticsClient, formula.Name, days)
+					}
+					if github && !json {
+						showGitHubStats(os.Stdout, formula)
+					}
 				} else {
 					formErr := errors.NewFormulaNotFoundError(name)
 					logger.LogDetailedError(logger.ErrorContext{
@@ -54,6 +79,10 @@ func NewInfoCmd(cfg *config.Config) *cobra.Command {
 	cmd.Flags().BoolVar(&json, "json", false, "Print output in JSON format")
 	cmd.Flags().BoolVar(&installed, "installed", false, "Print installed versions only")
 	cmd.Flags().BoolVar(&analytics, "analytics", false, "List analytics data")
+	cmd.Flags().BoolVar(&analyticsJSON, "analytics-json", false, "Print the formula's analytics data as JSON")
+	cmd.Flags().IntVar(&days, "days", 30, "With --analytics, the window to show counts for (30, 90 or 365)")
+	cmd.Flags().BoolVar(&github, "github", false, "Show upstream GitHub repository stats")
+	cmd.Flags().BoolVar(&variations, "variations", false, "List the platforms the formula has bottles for")
 
 	return cmd
 }
@@ -112,7 +141,7 @@ func showSystemInfo(cfg *config.Config, jsonOutput bool) error {
 	return nil
 }
 
-func showFormulaInfo(formula *formula.Formula, jsonOutput bool) {
+func showFormulaInfo(cfg *config.Config, formula *formula.Formula, jsonOutput bool) {
 	if jsonOutput {
 		// TODO: Implement JSON output
 		fmt.Printf("JSON output not yet implemented\n")
@@ -127,6 +156,13 @@ func showFormulaInfo(formula *formula.Formula, jsonOutput bool) {
 	if formula.Version != "" {
 		fmt.Printf("Version: %s\n", formula.Version)
 	}
+	if version, pinned := pinnedVersion(cfg, formula.Name); pinned {
+		if version != "" {
+			fmt.Printf("Pinned at %s; run `brew unpin %s` to allow upgrades\n", version, formula.Name)
+		} else {
+			fmt.Printf("Pinned; run `brew unpin %s` to allow upgrades\n", formula.Name)
+		}
+	}
 
 	if len(formula.Dependencies) > 0 {
 		fmt.Printf("Dependencies: %s\n", strings.Join(formula.Dependencies, ", "))
@@ -150,3 +186,54 @@ func showFormulaInfo(formula *formula.Formula, jsonOutput bool) {
 
 	fmt.Println()
 }
+
+// showAnalytics prints install and build error counts for a formula over
+// the last days; unavailable analytics are reported but never fatal
+func showAnalytics(w io.Writer, client *api.AnalyticsClient, name string, days int) {
+	categories := []string{api.AnalyticsInstall, api.AnalyticsInstallOnRequest}
+	// Homebrew only reports recent build errors
+	if days == 30 {
+		categories = append(categories, api.AnalyticsBuildError)
+	}
+
+	var lines []string
+	for _, category := range categories {
+		count, err := client.Count(category, name, days)
+		if err != nil {
+			logger.Warn("Could not fetch analytics for %s: %v", name, err)
+			return
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s (%d days)", category, formatCount(count), days))
+	}
+
+	_, _ = fmt.Fprintln(w, "==> Analytics")
+	for _, line := range lines {
+		_, _ = fmt.Fprintln(w, line)
+	}
+	_, _ = fmt.Fprintln(w)
+}
+
+// showAnalyticsJSON prints every analytics category and period for a formula
+func showAnalyticsJSON(w io.Writer, client *api.AnalyticsClient, name string) {
+	data, err := client.FormulaAnalytics(name)
+	if err != nil {
+		logger.Warn("Could not fetch analytics for %s: %v", name, err)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(map[string]interface{}{"name": name, "analytics": data})
+}
+
+// formatCount groups digits with commas as formulae.brew.sh does
+func formatCount(n int) string {
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	s := fmt.Sprintf("%d", n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
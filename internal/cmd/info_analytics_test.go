@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/api"
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestShowAnalyticsDays(t *testing.T) {
+	logger.Init(false, false, true)
+
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		category, period, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/analytics/"), ".json"), "/")
+		// Counts encode the period so each window is distinguishable
+		_, _ = fmt.Fprintf(w, `{"category": %q, "items": [{"number": 1, "formula": "wget", "count": "1,0%s"}]}`,
+			category, strings.TrimSuffix(period, "d"))
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	var buf bytes.Buffer
+	showAnalytics(&buf, api.NewAnalyticsClient(&config.Config{}), "wget", 90)
+
+	for _, path := range requested {
+		if !strings.HasSuffix(path, "/90d.json") {
+			t.Errorf("Requested %s, want only 90 day datasets", path)
+		}
+	}
+	output := buf.String()
+	for _, want := range []string{"install: 1,090 (90 days)", "install-on-request: 1,090 (90 days)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "30 days") || strings.Contains(output, "build-error") {
+		t.Errorf("Expected only the 90 day window, got:\n%s", output)
+	}
+}
+
+func TestInfoRejectsUnpublishedDays(t *testing.T) {
+	cmd := NewInfoCmd(&config.Config{})
+	cmd.SetArgs([]string{"wget", "--analytics", "--days", "7"})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	var usageErr *UsageError
+	if err := cmd.Execute(); !errors.As(err, &usageErr) {
+		t.Errorf("Expected a usage error for --days 7, got %v", err)
+	}
+}
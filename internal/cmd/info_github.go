@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
+)
+
+// githubAPIURL is the GitHub REST API endpoint, replaced in tests
+var githubAPIURL = "https://api.github.com"
+
+// GitHubStats holds upstream repository metadata shown by info --github
+type GitHubStats struct {
+	Repository    string `json:"repository"`
+	Stars         int    `json:"stars"`
+	OpenIssues    int    `json:"open_issues"`
+	LatestRelease string `json:"latest_release,omitempty"`
+}
+
+// showGitHubStats prints upstream repository stats when the formula is
+// hosted on GitHub; failures are reported but never fatal
+func showGitHubStats(w io.Writer, f *formula.Formula) {
+	owner, repo, ok := formulaGitHubRepo(f)
+	if !ok {
+		logger.Debug("%s is not hosted on GitHub, skipping repository stats", f.Name)
+		return
+	}
+
+	stats, err := fetchGitHubStats(owner, repo)
+	if err != nil {
+		logger.Warn("Could not fetch GitHub stats for %s/%s: %v", owner, repo, err)
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "==> GitHub: %s\n", stats.Repository)
+	_, _ = fmt.Fprintf(w, "Stars: %d\n", stats.Stars)
+	_, _ = fmt.Fprintf(w, "Open issues: %d\n", stats.OpenIssues)
+	if stats.LatestRelease != "" {
+		_, _ = fmt.Fprintf(w, "Latest release: %s\n", stats.LatestRelease)
+	}
+	_, _ = fmt.Fprintln(w)
+}
+
+// formulaGitHubRepo finds a GitHub repository in the homepage, head or
+// source URL of a formula
+func formulaGitHubRepo(f *formula.Formula) (string, string, bool) {
+	candidates := []string{f.Homepage}
+	if f.Head != nil {
+		candidates = append(candidates, f.Head.URL)
+	}
+	candidates = append(candidates, f.URL)
+
+	for _, url := range candidates {
+		if owner, repo, ok := parseGitHubRepo(url); ok {
+			return owner, repo, true
+		}
+	}
+	return "", "", false
+}
+
+// parseGitHubRepo extracts owner and repository from https, git and SSH
+// GitHub URLs
+func parseGitHubRepo(url string) (string, string, bool) {
+	path, ok := strings.CutPrefix(url, "git@github.com:")
+	if !ok {
+		rest := url
+		for _, scheme := range []string{"https://", "http://", "git://", "ssh://git@"} {
+			rest = strings.TrimPrefix(rest, scheme)
+		}
+		rest = strings.TrimPrefix(rest, "www.")
+		if path, ok = strings.CutPrefix(rest, "github.com/"); !ok {
+			return "", "", false
+		}
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), true
+}
+
+// fetchGitHubStats queries the GitHub API for repository metadata
+func fetchGitHubStats(owner, repo string) (*GitHubStats, error) {
+	client := &http.Client{Timeout: 10 * time.Second, Transport: utils.Transport, CheckRedirect: utils.CheckRedirect}
+
+	var repoInfo struct {
+		FullName        string `json:"full_name"`
+		StargazersCount int    `json:"stargazers_count"`
+		OpenIssuesCount int    `json:"open_issues_count"`
+	}
+	if err := getGitHubJSON(client, fmt.Sprintf("%s/repos/%s/%s", githubAPIURL, owner, repo), &repoInfo); err != nil {
+		return nil, err
+	}
+
+	stats := &GitHubStats{
+		Repository: owner + "/" + repo,
+		Stars:      repoInfo.StargazersCount,
+		OpenIssues: repoInfo.OpenIssuesCount,
+	}
+	if repoInfo.FullName != "" {
+		stats.Repository = repoInfo.FullName
+	}
+
+	// Repositories without releases answer 404 here; that's not an error
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := getGitHubJSON(client, fmt.Sprintf("%s/repos/%s/%s/releases/latest", githubAPIURL, owner, repo), &release); err == nil {
+		stats.LatestRelease = release.TagName
+	} else {
+		logger.Debug("No latest release for %s/%s: %v", owner, repo, err)
+	}
+
+	return stats, nil
+}
+
+func getGitHubJSON(client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("GitHub API request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0"):
+		return fmt.Errorf("GitHub API rate limit exceeded (set GITHUB_TOKEN to raise it)")
+	default:
+		return fmt.Errorf("GitHub API returned %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse GitHub API response: %w", err)
+	}
+	return nil
+}
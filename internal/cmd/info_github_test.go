@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestParseGitHubRepo(t *testing.T) {
+	tests := []struct {
+		url   string
+		owner string
+		repo  string
+		ok    bool
+	}{
+		{url: "https://github.com/jqlang/jq", owner: "jqlang", repo: "jq", ok: true},
+		{url: "https://www.github.com/jqlang/jq/releases", owner: "jqlang", repo: "jq", ok: true},
+		{url: "https://github.com/git/git.git", owner: "git", repo: "git", ok: true},
+		{url: "git@github.com:neovim/neovim.git", owner: "neovim", repo: "neovim", ok: true},
+		{url: "https://github.com/jqlang", ok: false},
+		{url: "https://www.gnu.org/software/wget/", ok: false},
+		{url: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			owner, repo, ok := parseGitHubRepo(tt.url)
+			if ok != tt.ok || owner != tt.owner || repo != tt.repo {
+				t.Errorf("parseGitHubRepo(%q) = %q, %q, %v; want %q, %q, %v", tt.url, owner, repo, ok, tt.owner, tt.repo, tt.ok)
+			}
+		})
+	}
+}
+
+func TestShowGitHubStats(t *testing.T) {
+	logger.Init(false, false, true)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/repos/jqlang/jq":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"full_name":         "jqlang/jq",
+				"stargazers_count":  31000,
+				"open_issues_count": 512,
+			})
+		case "/repos/jqlang/jq/releases/latest":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"tag_name": "jq-1.7.1"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	oldURL := githubAPIURL
+	githubAPIURL = server.URL
+	defer func() { githubAPIURL = oldURL }()
+
+	var buf bytes.Buffer
+	showGitHubStats(&buf, &formula.Formula{Name: "jq", Homepage: "https://jqlang.github.io/jq/", URL: "https://github.com/jqlang/jq/releases/download/jq-1.7.1/jq-1.7.1.tar.gz"})
+
+	for _, want := range []string{"==> GitHub: jqlang/jq", "Stars: 31000", "Open issues: 512", "Latest release: jq-1.7.1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("showGitHubStats() output missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	requests = 0
+	buf.Reset()
+	showGitHubStats(&buf, &formula.Formula{Name: "wget", Homepage: "https://www.gnu.org/software/wget/", URL: "https://ftp.gnu.org/gnu/wget/wget-1.21.tar.gz"})
+	if requests != 0 || buf.Len() != 0 {
+		t.Errorf("Non-GitHub formula made %d requests and printed %q", requests, buf.String())
+	}
+}
+
+func TestShowGitHubStatsRateLimited(t *testing.T) {
+	logger.Init(false, false, true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	oldURL := githubAPIURL
+	githubAPIURL = server.URL
+	defer func() { githubAPIURL = oldURL }()
+
+	var buf bytes.Buffer
+	showGitHubStats(&buf, &formula.Formula{Name: "jq", Homepage: "https://github.com/jqlang/jq"})
+	if buf.Len() != 0 {
+		t.Errorf("Rate-limited lookup should print nothing, got %q", buf.String())
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+)
+
+// showVariations lists the platforms a formula publishes bottles for,
+// marking hostTag so it's clear whether this machine is covered
+func showVariations(w io.Writer, f *formula.Formula, hostTag string, jsonOutput bool) {
+	files := map[string]formula.BottleFile{}
+	if f.Bottle != nil && f.Bottle.Stable != nil && f.Bottle.Stable.Files != nil {
+		files = f.Bottle.Stable.Files
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(files)
+		return
+	}
+
+	_, _ = fmt.Fprintln(w, "==> Variations")
+	if len(files) == 0 {
+		_, _ = fmt.Fprintf(w, "%s has no bottles; it is always built from source\n\n", f.Name)
+		return
+	}
+
+	tags := make([]string, 0, len(files))
+	for tag := range files {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		marker := ""
+		if tag == hostTag {
+			marker = " (this platform)"
+		}
+		_, _ = fmt.Fprintf(w, "%s%s\n", tag, marker)
+		_, _ = fmt.Fprintf(w, "  URL: %s\n", files[tag].URL)
+		_, _ = fmt.Fprintf(w, "  SHA256: %s\n", files[tag].SHA256)
+	}
+	if _, ok := files[hostTag]; !ok {
+		_, _ = fmt.Fprintf(w, "No bottle for this platform (%s)\n", hostTag)
+	}
+	_, _ = fmt.Fprintln(w)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+)
+
+func TestShowVariations(t *testing.T) {
+	f := &formula.Formula{
+		Name: "wget",
+		Bottle: &formula.Bottle{Stable: &formula.BottleSpec{Files: map[string]formula.BottleFile{
+			"arm64_sequoia": {URL: "https://ghcr.io/v2/homebrew/core/wget/blobs/sha256:aaa", SHA256: "aaa"},
+			"x86_64_linux":  {URL: "https://ghcr.io/v2/homebrew/core/wget/blobs/sha256:bbb", SHA256: "bbb"},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	showVariations(&buf, f, "x86_64_linux", false)
+	output := buf.String()
+	for _, want := range []string{
+		"arm64_sequoia\n",
+		"SHA256: aaa",
+		"x86_64_linux (this platform)",
+		"URL: https://ghcr.io/v2/homebrew/core/wget/blobs/sha256:bbb",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "No bottle for this platform") {
+		t.Errorf("Host platform is covered, got:\n%s", output)
+	}
+
+	buf.Reset()
+	showVariations(&buf, f, "x86_64_linux", true)
+	var files map[string]formula.BottleFile
+	if err := json.Unmarshal(buf.Bytes(), &files); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", buf.String(), err)
+	}
+	if len(files) != 2 || files["arm64_sequoia"].SHA256 != "aaa" || files["x86_64_linux"].SHA256 != "bbb" {
+		t.Errorf("Unexpected variations JSON: %+v", files)
+	}
+}
@@ -1,16 +1,23 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/pilshchikov/homebrew-go/internal/api"
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
 	"github.com/pilshchikov/homebrew-go/internal/installer"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -29,7 +36,17 @@ func NewInstallCmd(cfg *config.Config) *cobra.Command {
 		debugSymbols       bool
 		displayTimes       bool
 		ask                bool
+		requireSHA         bool
 		cc                 string
+		bottleTag          string
+		jsonOutput         bool
+		interactive        bool
+		gitRepo            bool
+		noLink             bool
+		overwrite          bool
+		noQuarantine       bool
+		strictVerification bool
+		language           string
 	)
 
 	cmd := &cobra.Command{
@@ -45,8 +62,11 @@ the installed formulae or, every 30 days, for all formulae.
 
 Unless HOMEBREW_NO_INSTALL_UPGRADE is set, brew install <formula> will upgrade
 <formula> if it is already installed but outdated.`,
-		Args: cobra.MinimumNArgs(1),
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: exclusiveFlags(formulaCaskFlags...),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			checkForUpdates(cfg)
+
 			return runInstall(cfg, args, &installOptions{
 				FormulaOnly:        formulaOnly,
 				CaskOnly:           caskOnly,
@@ -60,7 +80,19 @@ Unless HOMEBREW_NO_INSTALL_UPGRADE is set, brew install <formula> will upgrade
 				DebugSymbols:       debugSymbols,
 				DisplayTimes:       displayTimes,
 				Ask:                ask,
+				RequireSHA:         requireSHA,
 				CC:                 cc,
+				BottleTag:          bottleTag,
+				JSON:               jsonOutput,
+				Interactive:        interactive,
+				Git:                gitRepo,
+				NoLink:             noLink,
+				Overwrite:          overwrite,
+				NoQuarantine:       noQuarantine,
+				StrictVerification: strictVerification,
+				Language:           language,
+				Out:                cmd.OutOrStdout(),
+				Context:            cmd.Context(),
 				Force:              cfg.Force,
 				DryRun:             cfg.DryRun,
 				Verbose:            cfg.Verbose,
@@ -83,7 +115,18 @@ Unless HOMEBREW_NO_INSTALL_UPGRADE is set, brew install <formula> will upgrade
 	cmd.Flags().BoolVar(&debugSymbols, "debug-symbols", false, "Generate debug symbols on build")
 	cmd.Flags().BoolVar(&displayTimes, "display-times", false, "Print install times for each package")
 	cmd.Flags().BoolVar(&ask, "ask", false, "Ask for confirmation before downloading and installing")
+	cmd.Flags().BoolVar(&requireSHA, "require-sha", false, "Require all stable downloads to have a checksum (HOMEBREW_REQUIRE_SHA)")
+	cmd.Flags().BoolVar(&strictVerification, "strict-verification", false, "Fail on any verification problem, such as a size mismatch (HOMEBREW_STRICT_VERIFICATION)")
 	cmd.Flags().StringVar(&cc, "cc", "", "Attempt to compile using the specified compiler")
+	cmd.Flags().StringVar(&bottleTag, "bottle-tag", "", "Install the bottle for this platform tag instead of the host's (requires --force)")
+	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Download and patch the formula, then open a shell in its sources instead of building")
+	cmd.Flags().BoolVarP(&gitRepo, "git", "g", false, "With --interactive, create a git repository of the sources")
+	cmd.Flags().BoolVar(&noLink, "no-link", false, "Install into the Cellar without linking into the prefix")
+	cmd.Flags().BoolVar(&noLink, "skip-link", false, "Install into the Cellar without linking into the prefix")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Replace existing files in the prefix that conflict with the formula's links")
+	cmd.Flags().BoolVar(&noQuarantine, "no-quarantine", false, "Install casks without the macOS quarantine attribute")
+	cmd.Flags().StringVar(&language, "language", "", "Comma-separated languages to prefer for localized cask downloads (HOMEBREW_CASK_OPTS)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "With --dry-run, print the install plan as JSON")
 
 	return cmd
 }
@@ -101,10 +144,65 @@ type installOptions struct {
 	DebugSymbols       bool
 	DisplayTimes       bool
 	Ask                bool
+	RequireSHA         bool
 	CC                 string
+	BottleTag          string
+	JSON               bool
+	Interactive        bool
+	Git                bool
+	NoLink             bool
+	Overwrite          bool
+	NoQuarantine       bool
+	StrictVerification bool
+	Language           string
+	Out                io.Writer
+	Context            context.Context
 	Force              bool
 	DryRun             bool
 	Verbose            bool
+
+	// Reinstall replaces installed kegs without the other effects of Force
+	Reinstall bool
+}
+
+// caskLanguages returns the languages to prefer for localized cask downloads:
+// those given with --language, or else with --language in HOMEBREW_CASK_OPTS
+func caskLanguages(cfg *config.Config, language string) []string {
+	if language == "" {
+		for idx, opt := range cfg.CaskOpts {
+			if value, ok := strings.CutPrefix(opt, "--language="); ok {
+				language = value
+			} else if opt == "--language" && idx+1 < len(cfg.CaskOpts) {
+				language = cfg.CaskOpts[idx+1]
+			}
+		}
+	}
+
+	var languages []string
+	for _, lang := range strings.Split(language, ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			languages = append(languages, lang)
+		}
+	}
+	return languages
+}
+
+// printInstallPlan writes what an install would do as JSON without doing it
+func printInstallPlan(inst *installer.Installer, formulae, casks []string, w io.Writer) error {
+	plan, err := inst.Plan(formulae)
+	if err != nil {
+		return fmt.Errorf("failed to plan install: %w", err)
+	}
+	for _, caskName := range casks {
+		plan.Steps = append(plan.Steps, installer.PlanStep{Name: caskName, Method: installer.PlanCask})
+	}
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal install plan: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
 }
 
 func runInstall(cfg *config.Config, args []string, opts *installOptions) error {
@@ -112,7 +210,7 @@ func runInstall(cfg *config.Config, args []string, opts *installOptions) error {
 	defer timer.Stop()
 
 	// Parse arguments to separate formulae/casks from options
-	formulae, casks, err := parseInstallArgs(args, opts)
+	formulae, casks, err := parseInstallArgs(args, opts, api.NewClassifier(api.NewClient(cfg)))
 	if err != nil {
 		return fmt.Errorf("failed to parse arguments: %w", err)
 	}
@@ -121,6 +219,13 @@ func runInstall(cfg *config.Config, args []string, opts *installOptions) error {
 		return fmt.Errorf("no formulae or casks specified")
 	}
 
+	if opts.JSON && !opts.DryRun {
+		return fmt.Errorf("--json requires --dry-run")
+	}
+	if opts.Git && !opts.Interactive {
+		return fmt.Errorf("--git requires --interactive")
+	}
+
 	// Initialize installer
 	inst := installer.New(cfg, &installer.Options{
 		BuildFromSource:    opts.BuildFromSource || cfg.BuildFromSource,
@@ -132,14 +237,32 @@ func runInstall(cfg *config.Config, args []string, opts *installOptions) error {
 		KeepTmp:            opts.KeepTmp || cfg.KeepTmp,
 		DebugSymbols:       opts.DebugSymbols,
 		Force:              opts.Force,
+		Reinstall:          opts.Reinstall,
 		DryRun:             opts.DryRun,
 		Verbose:            opts.Verbose,
 		CC:                 opts.CC,
+		RequireSHA:         opts.RequireSHA,
+		StrictVerification: opts.StrictVerification,
+		BottleTag:          opts.BottleTag,
+		Interactive:        opts.Interactive,
+		Git:                opts.Git,
+		NoLink:             opts.NoLink,
+		Overwrite:          opts.Overwrite,
+		NoQuarantine:       opts.NoQuarantine,
+		CaskLanguages:      caskLanguages(cfg, opts.Language),
 	})
+	inst.SetContext(opts.Context)
+
+	if opts.JSON {
+		return printInstallPlan(inst, formulae, casks, opts.Out)
+	}
 
 	// Install formulae
 	var installTimes []installer.InstallResult
 
+	// Formulae that installed but conflicted with files in the prefix
+	var unlinked []string
+
 	for _, formulaName := range formulae {
 		logger.Progress("Installing formula: %s", formulaName)
 
@@ -148,10 +271,29 @@ func runInstall(cfg *config.Config, args []string, opts *installOptions) error {
 			continue
 		}
 
-		// Check if formula is already installed
-		if installed, err := isFormulaInstalled(cfg, formulaName); err != nil {
+		// Local bottle files are poured directly, without resolving a formula
+		if installer.IsBottlePath(formulaName) {
+			result, err := inst.InstallBottleFile(formulaName)
+			if err != nil {
+				return fmt.Errorf("failed to install bottle %s: %w", formulaName, err)
+			}
+			if result.AlreadyInstalled {
+				continue
+			}
+			installTimes = append(installTimes, *result)
+			if linkConflicted(result) {
+				unlinked = append(unlinked, result.Name)
+				continue
+			}
+			logger.Success("Successfully installed %s %s", result.Name, result.Version)
+			continue
+		}
+
+		// Check if formula is already installed; a keg an interrupted install
+		// left without a receipt is installed again
+		if installed, err := installer.HasCommittedKeg(cfg.HomebrewCellar, formulaName); err != nil {
 			logger.Warn("Failed to check if %s is installed: %v", formulaName, err)
-		} else if installed && !opts.Force {
+		} else if installed && !opts.Force && !opts.Reinstall && !opts.Interactive {
 			if !cfg.NoInstallUpgrade {
 				logger.Info("Formula %s is already installed, checking for updates...", formulaName)
 
@@ -174,7 +316,7 @@ func runInstall(cfg *config.Config, args []string, opts *installOptions) error {
 
 		// Ask for confirmation if needed
 		if opts.Ask {
-			if !askForConfirmation(formulaName, "formula") {
+			if !askForConfirmation("Install", formulaName, "formula") {
 				logger.Info("Skipping %s", formulaName)
 				continue
 			}
@@ -185,8 +327,15 @@ func runInstall(cfg *config.Config, args []string, opts *installOptions) error {
 		if err != nil {
 			return fmt.Errorf("failed to install formula %s: %w", formulaName, err)
 		}
+		if result.AlreadyInstalled || opts.Interactive {
+			continue
+		}
 
 		installTimes = append(installTimes, *result)
+		if linkConflicted(result) {
+			unlinked = append(unlinked, formulaName)
+			continue
+		}
 		logger.Success("Successfully installed %s", formulaName)
 	}
 
@@ -201,7 +350,7 @@ func runInstall(cfg *config.Config, args []string, opts *installOptions) error {
 
 		// Ask for confirmation if needed
 		if opts.Ask {
-			if !askForConfirmation(caskName, "cask") {
+			if !askForConfirmation("Install", caskName, "cask") {
 				logger.Info("Skipping %s", caskName)
 				continue
 			}
@@ -221,8 +370,13 @@ func runInstall(cfg *config.Config, args []string, opts *installOptions) error {
 	if opts.DisplayTimes && len(installTimes) > 0 {
 		logger.PrintDivider()
 		logger.PrintHeader("Install Times")
+		logger.Info("  %-20s %10s %10s %10s %10s", "", "download", "build", "link", "total")
 		for _, result := range installTimes {
-			logger.Info("  %-20s %v", result.Name, result.Duration)
+			logger.Info("  %-20s %10s %10s %10s %10s", result.Name,
+				formatPhaseDuration(result.DownloadDuration),
+				formatPhaseDuration(result.BuildDuration),
+				formatPhaseDuration(result.LinkDuration),
+				formatPhaseDuration(result.Duration))
 		}
 	}
 
@@ -234,10 +388,31 @@ func runInstall(cfg *config.Config, args []string, opts *installOptions) error {
 		}
 	}
 
+	if len(unlinked) > 0 {
+		return fmt.Errorf("installed but could not link %s; rerun with --overwrite to replace the conflicting files", strings.Join(unlinked, ", "))
+	}
 	return nil
 }
 
-func parseInstallArgs(args []string, opts *installOptions) ([]string, []string, error) {
+// linkConflicted reports whether an installed keg was left unlinked because
+// files in the prefix were in the way
+func linkConflicted(result *installer.InstallResult) bool {
+	var conflict *installer.LinkConflictError
+	return errors.As(result.LinkError, &conflict)
+}
+
+// formatPhaseDuration renders an install phase timing, or "-" if the phase
+// did not run
+func formatPhaseDuration(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// parseInstallArgs splits args into formulae and casks; unless --formula or
+// --cask is given, classifier decides which bare names are casks
+func parseInstallArgs(args []string, opts *installOptions, classifier *api.Classifier) ([]string, []string, error) {
 	var formulae []string
 	var casks []string
 
@@ -252,10 +427,12 @@ func parseInstallArgs(args []string, opts *installOptions) ([]string, []string,
 			formulae = append(formulae, arg)
 		} else {
 			// Auto-detect based on name or check both
-			if strings.Contains(arg, "/") {
+			if formula.IsLocalPath(arg) || installer.IsBottlePath(arg) {
+				formulae = append(formulae, arg)
+			} else if strings.Contains(arg, "/") {
 				// Tap-qualified name, assume formula
 				formulae = append(formulae, arg)
-			} else if isCaskName(arg) {
+			} else if classifier.IsCask(arg) {
 				casks = append(casks, arg)
 			} else {
 				formulae = append(formulae, arg)
@@ -266,15 +443,6 @@ func parseInstallArgs(args []string, opts *installOptions) ([]string, []string,
 	return formulae, casks, nil
 }
 
-func isCaskName(name string) bool {
-	// Simple heuristic: casks often have different naming patterns
-	// This is a placeholder - in practice, we'd check the cask repository
-	return strings.Contains(name, "-") &&
-		(strings.Contains(name, "app") ||
-			strings.Contains(name, "desktop") ||
-			strings.HasSuffix(name, ".app"))
-}
-
 func isFormulaInstalled(cfg *config.Config, name string) (bool, error) {
 	formulaPath := filepath.Join(cfg.HomebrewCellar, name)
 	_, err := os.Stat(formulaPath)
@@ -284,8 +452,15 @@ func isFormulaInstalled(cfg *config.Config, name string) (bool, error) {
 	return err == nil, err
 }
 
-func askForConfirmation(name, typ string) bool {
-	return logger.Confirm("Install %s %s?", typ, name)
+// askForConfirmation asks whether to carry out action on the named formula
+// or cask
+func askForConfirmation(action, name, typ string) bool {
+	return logger.Confirm("%s %s %s?", action, typ, name)
+}
+
+// stdinIsTerminal reports whether brew can prompt the user, replaced in tests
+var stdinIsTerminal = func() bool {
+	return utils.IsTerminal(os.Stdin)
 }
 
 // getPlatform returns the current platform identifier for bottles
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestInstallDryRunJSONPlan(t *testing.T) {
+	logger.Init(false, false, true)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/formula/app.json":
+			_, _ = fmt.Fprintf(w, `{"name": "app", "versions": {"stable": "2.0"}, "dependencies": ["lib"],
+				"urls": {"stable": {"url": "%s/src/app-2.0.tar.gz"}}}`, server.URL)
+		case "/formula/lib.json":
+			_, _ = fmt.Fprintf(w, `{"name": "lib", "versions": {"stable": "1.0"}, "keg_only": true,
+				"bottle": {"stable": {"files": {"x86_64_linux": {"url": "%s/bottles/lib", "sha256": "abc"}}}}}`, server.URL)
+		case "/src/app-2.0.tar.gz":
+			if r.Method != http.MethodHead {
+				t.Errorf("Dry run downloaded %s", r.URL.Path)
+			}
+			w.Header().Set("Content-Length", "1234")
+		case "/bottles/lib":
+			if r.Method != http.MethodHead {
+				t.Errorf("Dry run downloaded %s", r.URL.Path)
+			}
+			w.Header().Set("Content-Length", "5678")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewPrefix: tempDir,
+		HomebrewCache:  filepath.Join(tempDir, "Cache"),
+		HomebrewCellar: filepath.Join(tempDir, "Cellar"),
+		DryRun:         true,
+		Force:          true,
+	}
+
+	var out bytes.Buffer
+	cmd := NewInstallCmd(cfg)
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--json", "--bottle-tag", "x86_64_linux", "app"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install --dry-run --json error = %v", err)
+	}
+
+	var plan installer.Plan
+	if err := json.Unmarshal(out.Bytes(), &plan); err != nil {
+		t.Fatalf("Invalid plan JSON %q: %v", out.String(), err)
+	}
+	if len(plan.Steps) != 2 || plan.Steps[0].Name != "lib" || plan.Steps[1].Name != "app" {
+		t.Fatalf("Expected steps [lib app], got %+v", plan.Steps)
+	}
+
+	lib, app := plan.Steps[0], plan.Steps[1]
+	if !lib.Dependency || lib.Method != installer.PlanBottle || lib.URL != server.URL+"/bottles/lib" || lib.Size != 5678 {
+		t.Errorf("Unexpected dependency step %+v", lib)
+	}
+	if len(lib.LinkTargets) != 1 || lib.LinkTargets[0] != filepath.Join(tempDir, "opt", "lib") {
+		t.Errorf("Expected keg-only lib to link only into opt, got %v", lib.LinkTargets)
+	}
+	if app.Dependency || app.Method != installer.PlanSource || app.Version != "2.0" || app.Size != 1234 {
+		t.Errorf("Unexpected formula step %+v", app)
+	}
+	if app.Keg != filepath.Join(tempDir, "Cellar", "app", "2.0") {
+		t.Errorf("Unexpected keg path %s", app.Keg)
+	}
+}
+
+// newCoreTapConfig returns a config whose formulae come from YAML files in a
+// local homebrew/core tap, with the API answering 404 for everything
+func newCoreTapConfig(t *testing.T, formulae map[string]string) *config.Config {
+	t.Helper()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewRepository: filepath.Join(tempDir, "repo"),
+		HomebrewPrefix:     filepath.Join(tempDir, "prefix"),
+		HomebrewCellar:     filepath.Join(tempDir, "prefix", "Cellar"),
+		HomebrewCache:      filepath.Join(tempDir, "cache"),
+		HomebrewTemp:       filepath.Join(tempDir, "tmp"),
+		NoInstallUpgrade:   true,
+	}
+
+	formulaDir := filepath.Join(cfg.HomebrewRepository, "Library", "Taps", "homebrew", "homebrew-core", "Formula")
+	if err := os.MkdirAll(formulaDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range formulae {
+		if err := os.WriteFile(filepath.Join(formulaDir, name+".yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+// writeScriptSource writes a bare executable source and returns its formula
+// fields
+func writeScriptSource(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	script := []byte("#!/bin/sh\necho " + name + "\n")
+	path := filepath.Join(dir, name+"-1.0.sh")
+	if err := os.WriteFile(path, script, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(script)
+	return fmt.Sprintf("url: file://%s\nsha256: %s\nbinary: %s\n", path, hex.EncodeToString(sum[:]), name)
+}
+
+func TestInstallRedoesKegWithoutReceipt(t *testing.T) {
+	logger.Init(false, false, true)
+
+	srcDir := t.TempDir()
+	cfg := newCoreTapConfig(t, map[string]string{
+		"hello": "name: hello\nversion: 1.0\n" + writeScriptSource(t, srcDir, "hello"),
+	})
+
+	// An interrupted install left files in the keg but never wrote its receipt
+	keg := filepath.Join(cfg.HomebrewCellar, "hello", "1.0")
+	if err := os.MkdirAll(filepath.Join(keg, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(keg, "bin", "hello"), []byte("partial"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewInstallCmd(cfg)
+	cmd.SetArgs([]string{"hello"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(keg, "INSTALL_RECEIPT.json")); err != nil {
+		t.Fatalf("Expected the keg to be installed again with a receipt: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(keg, "bin", "hello")); string(data) != "#!/bin/sh\necho hello\n" {
+		t.Errorf("Expected the partial binary to be replaced, got %q", data)
+	}
+}
+
+func TestInstallDryRunPlansKegWithoutReceipt(t *testing.T) {
+	logger.Init(false, false, true)
+
+	srcDir := t.TempDir()
+	cfg := newCoreTapConfig(t, map[string]string{
+		"app": "name: app\nversion: 1.0\ndependencies: [lib]\n" + writeScriptSource(t, srcDir, "app"),
+		"lib": "name: lib\nversion: 1.0\n" + writeScriptSource(t, srcDir, "lib"),
+	})
+	cfg.DryRun = true
+
+	libKeg := filepath.Join(cfg.HomebrewCellar, "lib", "1.0")
+	if err := os.MkdirAll(libKeg, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := func() installer.PlanStep {
+		t.Helper()
+		var out bytes.Buffer
+		cmd := NewInstallCmd(cfg)
+		cmd.SetOut(&out)
+		cmd.SetArgs([]string{"--json", "app"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("install --dry-run --json error = %v", err)
+		}
+		var p installer.Plan
+		if err := json.Unmarshal(out.Bytes(), &p); err != nil {
+			t.Fatalf("Invalid plan JSON %q: %v", out.String(), err)
+		}
+		for _, step := range p.Steps {
+			if step.Name == "lib" {
+				return step
+			}
+		}
+		t.Fatalf("Expected a step for lib, got %+v", p.Steps)
+		return installer.PlanStep{}
+	}
+
+	if step := plan(); step.Method == installer.PlanInstalled {
+		t.Errorf("Expected a keg without a receipt to be planned for install, got %+v", step)
+	}
+
+	if err := os.WriteFile(filepath.Join(libKeg, "INSTALL_RECEIPT.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if step := plan(); step.Method != installer.PlanInstalled {
+		t.Errorf("Expected a committed keg to be planned as installed, got %+v", step)
+	}
+}
+
+func TestInstallJSONRequiresDryRun(t *testing.T) {
+	logger.Init(false, false, true)
+
+	cfg := &config.Config{HomebrewCellar: t.TempDir(), NoAutoUpdate: true}
+	cmd := NewInstallCmd(cfg)
+	cmd.SetArgs([]string{"--json", "app"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected --json without --dry-run to fail")
+	}
+}
+
+func TestCaskLanguages(t *testing.T) {
+	tests := []struct {
+		name     string
+		caskOpts []string
+		flag     string
+		expected []string
+	}{
+		{"none", nil, "", nil},
+		{"--language de", nil, "de", []string{"de"}},
+		{"comma-separated", nil, "de, fr", []string{"de", "fr"}},
+		{"HOMEBREW_CASK_OPTS", []string{"--no-quarantine", "--language=ja"}, "", []string{"ja"}},
+		{"HOMEBREW_CASK_OPTS separate value", []string{"--language", "es"}, "", []string{"es"}},
+		{"flag wins over HOMEBREW_CASK_OPTS", []string{"--language=ja"}, "de", []string{"de"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CaskOpts: tt.caskOpts}
+			if got := caskLanguages(cfg, tt.flag); strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("caskLanguages() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sort"
 
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 	"github.com/spf13/cobra"
 )
@@ -162,13 +163,39 @@ func buildDependencyMap(cfg *config.Config, formulae []string) (map[string][]str
 	return dependencyMap, nil
 }
 
+// getFormulaDependencies returns the runtime dependencies recorded in the
+// receipts of every installed version of a formula. Kegs without a receipt
+// record none.
 func getFormulaDependencies(cfg *config.Config, formulaName string) ([]string, error) {
-	// Try to read install receipt for dependency information
-	// receiptPath := filepath.Join(cfg.HomebrewCellar, formulaName, "INSTALL_RECEIPT.json")
+	formulaPath := filepath.Join(cfg.HomebrewCellar, formulaName)
+	versions, err := cellarVersions(cfg, formulaName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
 
-	// For now, return empty dependencies as we don't have receipt parsing implemented
-	// In a full implementation, this would parse the JSON receipt file
-	return []string{}, nil
+	seen := make(map[string]bool)
+	deps := []string{}
+	for _, version := range versions {
+		receipt, err := installer.ReadReceipt(filepath.Join(formulaPath, version))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, dep := range receipt.Dependencies {
+			// Receipts may record tap-qualified names
+			dep = filepath.Base(dep)
+			if dep != formulaName && !seen[dep] {
+				seen[dep] = true
+				deps = append(deps, dep)
+			}
+		}
+	}
+	return deps, nil
 }
 
 func isInstalledOnRequest(cfg *config.Config, formulaName string) bool {
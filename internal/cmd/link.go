@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 	"github.com/spf13/cobra"
 )
@@ -26,7 +27,8 @@ func NewLinkCmd(cfg *config.Config) *cobra.Command {
 done automatically when you install formulae but can be useful if you need to
 re-link after certain changes.
 
-If the formula is keg-only, its files will not be symlinked into the prefix.`,
+If the formula is keg-only, its files will not be symlinked into the prefix
+unless --force is given, since they may shadow software provided by the system.`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runLink(cfg, args, &linkOptions{
@@ -92,16 +94,27 @@ func runLink(cfg *config.Config, formulaNames []string, opts *linkOptions) error
 			continue
 		}
 
-		// Check if formula is keg-only
-		if isKegOnly(cfg, formulaName) && !opts.force {
-			logger.Info("Formula %s is keg-only and won't be linked unless forced", formulaName)
-			continue
+		if kegOnly, reason := isKegOnly(cfg, formulaName); kegOnly {
+			if !opts.force {
+				errors = append(errors, kegOnlyCaveat(cfg, formulaName, reason))
+				continue
+			}
+			logger.Warn("%s is keg-only; linking it may shadow software provided by the system", formulaName)
+			if !opts.dryRun && stdinIsTerminal() && !askForConfirmation("Link keg-only", formulaName, "formula") {
+				logger.Info("Not linking %s", formulaName)
+				continue
+			}
 		}
 
 		if err := linkFormula(cfg, formulaName, opts); err != nil {
 			errors = append(errors, fmt.Sprintf("Failed to link %s: %v", formulaName, err))
 			continue
 		}
+		if !opts.dryRun {
+			if err := installer.New(cfg, &installer.Options{}).CompleteLink(formulaName); err != nil {
+				logger.Warn("Failed to finish linking %s: %v", formulaName, err)
+			}
+		}
 
 		linked = append(linked, formulaName)
 		if !opts.dryRun {
@@ -149,6 +162,7 @@ func runUnlink(cfg *config.Config, formulaNames []string, opts *unlinkOptions) e
 
 		unlinked = append(unlinked, formulaName)
 		if !opts.dryRun {
+			reportOverwritten(cfg, formulaName)
 			logger.Success("Unlinked %s", formulaName)
 		}
 	}
@@ -174,7 +188,7 @@ func runUnlink(cfg *config.Config, formulaNames []string, opts *unlinkOptions) e
 
 func linkFormula(cfg *config.Config, formulaName string, opts *linkOptions) error {
 	// Get the latest installed version
-	installedVersions, err := getInstalledVersions(cfg, formulaName)
+	installedVersions, err := cellarVersions(cfg, formulaName)
 	if err != nil {
 		return err
 	}
@@ -189,7 +203,13 @@ func linkFormula(cfg *config.Config, formulaName string, opts *linkOptions) erro
 	logger.Debug("Linking %s from %s", formulaName, formulaPath)
 
 	// Link common directories
-	return linkDirectories(cfg, formulaPath, opts)
+	overwritten, err := linkDirectories(cfg, formulaPath, opts)
+	if len(overwritten) > 0 {
+		if recordErr := installer.RecordOverwritten(filepath.Join(formulaPath, "INSTALL_RECEIPT.json"), overwritten); recordErr != nil {
+			logger.Warn("Failed to record overwritten files: %v", recordErr)
+		}
+	}
+	return err
 }
 
 func unlinkFormulaLink(cfg *config.Config, formulaName string, opts *unlinkOptions) error {
@@ -220,7 +240,21 @@ func unlinkFormulaLink(cfg *config.Config, formulaName string, opts *unlinkOptio
 	return nil
 }
 
-func linkDirectories(cfg *config.Config, sourcePath string, opts *linkOptions) error {
+// reportOverwritten warns about the prefix files that linking a formula with
+// --overwrite replaced. They were removed, so unlinking can't bring them back.
+func reportOverwritten(cfg *config.Config, formulaName string) {
+	overwritten, err := installer.TakeOverwritten(cfg.HomebrewCellar, formulaName)
+	if err != nil {
+		logger.Warn("Failed to read overwritten files of %s: %v", formulaName, err)
+	}
+	for _, path := range overwritten {
+		logger.Warn("%s was overwritten when %s was linked and has not been restored", path, formulaName)
+	}
+}
+
+// linkDirectories links a keg's directories into the prefix, returning the
+// paths --overwrite replaced
+func linkDirectories(cfg *config.Config, sourcePath string, opts *linkOptions) ([]string, error) {
 	// Common directories to link
 	linkDirs := map[string]string{
 		"bin":     "bin",
@@ -231,6 +265,7 @@ func linkDirectories(cfg *config.Config, sourcePath string, opts *linkOptions) e
 		"etc":     "etc",
 	}
 
+	var overwritten []string
 	for sourceDir, targetDir := range linkDirs {
 		sourceDirPath := filepath.Join(sourcePath, sourceDir)
 		targetDirPath := filepath.Join(cfg.HomebrewPrefix, targetDir)
@@ -239,22 +274,25 @@ func linkDirectories(cfg *config.Config, sourcePath string, opts *linkOptions) e
 			continue // Source directory doesn't exist, skip
 		}
 
-		if err := linkDirectory(sourceDirPath, targetDirPath, opts); err != nil {
-			return fmt.Errorf("failed to link %s: %w", sourceDir, err)
+		replaced, err := linkDirectory(sourceDirPath, targetDirPath, cfg.HomebrewCellar, opts)
+		overwritten = append(overwritten, replaced...)
+		if err != nil {
+			return overwritten, fmt.Errorf("failed to link %s: %w", sourceDir, err)
 		}
 	}
 
-	return nil
+	return overwritten, nil
 }
 
-func linkDirectory(sourceDir, targetDir string, opts *linkOptions) error {
+func linkDirectory(sourceDir, targetDir, cellar string, opts *linkOptions) ([]string, error) {
 	// Ensure target directory exists
 	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Walk source directory and create symlinks
-	return filepath.Walk(sourceDir, func(sourcePath string, info os.FileInfo, err error) error {
+	var overwritten []string
+	err := filepath.Walk(sourceDir, func(sourcePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -281,32 +319,49 @@ func linkDirectory(sourceDir, targetDir string, opts *linkOptions) error {
 		}
 
 		// Create symlink for files
-		return createSymlink(sourcePath, targetPath, opts)
+		replaced, err := createSymlink(sourcePath, targetPath, cellar, opts)
+		if replaced {
+			overwritten = append(overwritten, targetPath)
+		}
+		return err
 	})
+	return overwritten, err
 }
 
-func createSymlink(sourcePath, targetPath string, opts *linkOptions) error {
-	// Check if target already exists
-	if _, err := os.Lstat(targetPath); err == nil {
+// createSymlink links targetPath to sourcePath, reporting whether it replaced
+// a file that wasn't ours
+func createSymlink(sourcePath, targetPath, cellar string, opts *linkOptions) (bool, error) {
+	// Links into the Cellar are ours to replace; anything else needs --overwrite
+	replaced := false
+	switch kind := installer.ClassifyPrefixPath(targetPath, cellar); kind {
+	case installer.PathAbsent:
+	case installer.PathManagedSymlink:
+		if !opts.dryRun {
+			if err := os.Remove(targetPath); err != nil {
+				return false, fmt.Errorf("failed to remove existing link %s: %w", targetPath, err)
+			}
+		}
+	default:
 		if !opts.overwrite {
-			logger.Debug("Skipping existing file: %s", targetPath)
-			return nil
+			logger.Warn("Skipping existing %s %s (use --overwrite to replace it)", kind, targetPath)
+			return false, nil
 		}
 
 		if !opts.dryRun {
 			if err := os.Remove(targetPath); err != nil {
-				return fmt.Errorf("failed to remove existing file %s: %w", targetPath, err)
+				return false, fmt.Errorf("failed to remove existing file %s: %w", targetPath, err)
 			}
+			replaced = true
 		}
 	}
 
 	if opts.dryRun {
 		logger.Info("Would link: %s -> %s", targetPath, sourcePath)
-		return nil
+		return false, nil
 	}
 
 	logger.Debug("Creating symlink: %s -> %s", targetPath, sourcePath)
-	return os.Symlink(sourcePath, targetPath)
+	return replaced, os.Symlink(sourcePath, targetPath)
 }
 
 func findFormulaSymlinks(cfg *config.Config, formulaName string) ([]string, error) {
@@ -357,11 +412,29 @@ func findFormulaSymlinks(cfg *config.Config, formulaName string) ([]string, erro
 	return symlinks, nil
 }
 
-func isKegOnly(cfg *config.Config, formulaName string) bool {
-	// Check if formula is keg-only
-	// This would typically be determined by the formula definition
-	// For now, return false as a placeholder
-	return false
+// isKegOnly reports whether the newest keg of an installed formula was
+// installed keg-only, and why
+func isKegOnly(cfg *config.Config, formulaName string) (bool, string) {
+	versions, err := cellarVersions(cfg, formulaName)
+	if err != nil || len(versions) == 0 {
+		return false, ""
+	}
+	receipt := newestReceipt(filepath.Join(cfg.HomebrewCellar, formulaName), []string{getLatestVersion(versions)})
+	return receipt.KegOnly, receipt.KegOnlyReason
+}
+
+// kegOnlyCaveat explains why a keg-only formula wasn't linked and how to use
+// it anyway
+func kegOnlyCaveat(cfg *config.Config, formulaName, reason string) string {
+	caveat := fmt.Sprintf("%s is keg-only and was not linked", formulaName)
+	if reason != "" {
+		caveat += ": " + reason
+	}
+	return caveat + fmt.Sprintf(`
+Linking it can shadow software provided by the system. To link it anyway, run:
+  brew link --force %s
+To use it without linking, add it to your PATH:
+  export PATH="%s:$PATH"`, formulaName, filepath.Join(cfg.HomebrewPrefix, "opt", formulaName, "bin"))
 }
 
 func isFormulaInstalledSimple(cfg *config.Config, formulaName string) bool {
@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 )
 
@@ -309,18 +312,84 @@ func TestIsFormulaInstalledSimple(t *testing.T) {
 	}
 }
 
+// installKegOnlyFormula creates an installed keg whose receipt marks it keg-only
+func installKegOnlyFormula(t *testing.T, cfg *config.Config, name string) string {
+	t.Helper()
+
+	kegPath := filepath.Join(cfg.HomebrewCellar, name, "3.2.0")
+	if err := os.MkdirAll(filepath.Join(kegPath, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	binary := filepath.Join(kegPath, "bin", name)
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	receipt := `{"name": "` + name + `", "version": "3.2.0", "keg_only": true, "keg_only_reason": "provided by macOS"}`
+	if err := os.WriteFile(filepath.Join(kegPath, "INSTALL_RECEIPT.json"), []byte(receipt), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return binary
+}
+
 func TestIsKegOnly(t *testing.T) {
 	logger.Init(false, false, true)
 
+	tempDir := t.TempDir()
+	cfg := &config.Config{HomebrewCellar: filepath.Join(tempDir, "Cellar")}
+	installKegOnlyFormula(t, cfg, "openssl")
+	if err := os.MkdirAll(filepath.Join(cfg.HomebrewCellar, "wget", "1.21"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if kegOnly, reason := isKegOnly(cfg, "openssl"); !kegOnly || reason != "provided by macOS" {
+		t.Errorf("isKegOnly(openssl) = %v, %q; want true, provided by macOS", kegOnly, reason)
+	}
+	if kegOnly, _ := isKegOnly(cfg, "wget"); kegOnly {
+		t.Error("Expected a keg without a keg-only receipt to be linkable")
+	}
+	if kegOnly, _ := isKegOnly(cfg, "missing"); kegOnly {
+		t.Error("Expected a missing formula not to be keg-only")
+	}
+}
+
+func TestRunLinkKegOnly(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
 	cfg := &config.Config{
-		HomebrewCellar: "/tmp/test-cellar",
+		HomebrewCellar: filepath.Join(tempDir, "Cellar"),
+		HomebrewPrefix: tempDir,
+	}
+	binary := installKegOnlyFormula(t, cfg, "openssl")
+	linkPath := filepath.Join(cfg.HomebrewPrefix, "bin", "openssl")
+
+	// Without a terminal there is nobody to confirm --force
+	oldStdinIsTerminal := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	defer func() { stdinIsTerminal = oldStdinIsTerminal }()
+
+	err := runLink(cfg, []string{"openssl"}, &linkOptions{})
+	if err == nil {
+		t.Fatal("Expected linking a keg-only formula without --force to fail")
+	}
+	if _, err := os.Lstat(linkPath); !os.IsNotExist(err) {
+		t.Error("Keg-only formula should not be linked without --force")
+	}
+
+	if err := runLink(cfg, []string{"openssl"}, &linkOptions{force: true}); err != nil {
+		t.Fatalf("runLink() with --force error = %v", err)
 	}
+	if target, err := os.Readlink(linkPath); err != nil || target != binary {
+		t.Errorf("Expected %s to link to %s, got %q, %v", linkPath, binary, target, err)
+	}
+}
 
-	// Test keg-only check (simplified implementation)
-	kegOnly := isKegOnly(cfg, "test-formula")
-	// Current implementation returns false
-	if kegOnly {
-		t.Log("Formula is marked as keg-only")
+func TestKegOnlyCaveat(t *testing.T) {
+	caveat := kegOnlyCaveat(&config.Config{HomebrewPrefix: "/opt/homebrew"}, "openssl", "provided by macOS")
+	for _, want := range []string{"openssl is keg-only", "provided by macOS", "brew link --force openssl", "/opt/homebrew/opt/openssl/bin"} {
+		if !strings.Contains(caveat, want) {
+			t.Errorf("Expected %q in caveat:\n%s", want, caveat)
+		}
 	}
 }
 
@@ -512,3 +581,51 @@ func TestLinkMultipleFormulae(t *testing.T) {
 		t.Errorf("runUnlink with multiple formulae failed: %v", err)
 	}
 }
+
+func TestLinkOverwriteRoundTrip(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewCellar: filepath.Join(tempDir, "Cellar"),
+		HomebrewPrefix: filepath.Join(tempDir, "usr", "local"),
+	}
+
+	kegPath := filepath.Join(cfg.HomebrewCellar, "tool", "1.0.0")
+	_ = os.MkdirAll(filepath.Join(kegPath, "bin"), 0755)
+	_ = os.WriteFile(filepath.Join(kegPath, "bin", "tool"), []byte("#!/bin/sh\n"), 0755)
+	receiptPath := filepath.Join(kegPath, "INSTALL_RECEIPT.json")
+	_ = os.WriteFile(receiptPath, []byte(`{"name": "tool", "version": "1.0.0"}`), 0644)
+
+	// A file the keg doesn't own sits where its link goes
+	foreign := filepath.Join(cfg.HomebrewPrefix, "bin", "tool")
+	_ = os.MkdirAll(filepath.Dir(foreign), 0755)
+	_ = os.WriteFile(foreign, []byte("not ours"), 0755)
+
+	readOverwritten := func() []string {
+		t.Helper()
+		data, err := os.ReadFile(receiptPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var receipt installer.InstallReceipt
+		if err := json.Unmarshal(data, &receipt); err != nil {
+			t.Fatal(err)
+		}
+		return receipt.Overwritten
+	}
+
+	if err := runLink(cfg, []string{"tool"}, &linkOptions{overwrite: true}); err != nil {
+		t.Fatalf("runLink failed: %v", err)
+	}
+	if overwritten := readOverwritten(); len(overwritten) != 1 || overwritten[0] != foreign {
+		t.Fatalf("Expected the receipt to record %s, got %v", foreign, overwritten)
+	}
+
+	if err := runUnlink(cfg, []string{"tool"}, &unlinkOptions{}); err != nil {
+		t.Fatalf("runUnlink failed: %v", err)
+	}
+	if overwritten := readOverwritten(); len(overwritten) != 0 {
+		t.Errorf("Expected unlinking to account for the overwritten files, got %v", overwritten)
+	}
+}
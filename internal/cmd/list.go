@@ -1,16 +1,43 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
+	"github.com/pilshchikov/homebrew-go/internal/cask"
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 	"github.com/spf13/cobra"
 )
 
+// listOptions holds the flags accepted by the list command
+type listOptions struct {
+	Formulae bool
+	Casks    bool
+	Versions bool
+	FullName bool
+	Multiple bool
+	Unbrewed bool
+	JSON     bool
+}
+
+// listJSON is the --json output of list
+type listJSON struct {
+	Formulae []installedJSON `json:"formulae"`
+	Casks    []installedJSON `json:"casks"`
+}
+
+// installedJSON is an installed formula or cask and its versions, oldest first
+type installedJSON struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"`
+}
+
 // NewListCmd creates the list command
 func NewListCmd(cfg *config.Config) *cobra.Command {
 	var (
@@ -18,15 +45,38 @@ func NewListCmd(cfg *config.Config) *cobra.Command {
 		casks    bool
 		versions bool
 		full     bool
+		multiple bool
+		unbrewed bool
+		jsonOut  bool
 	)
 
 	cmd := &cobra.Command{
 		Use:     "list [OPTIONS] [FORMULA|CASK...]",
 		Aliases: []string{"ls"},
 		Short:   "List installed formulae and casks",
+		PreRunE: exclusiveFlags(formulaCaskFlags...),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if unbrewed {
+				files, err := listUnbrewed(cfg)
+				if err != nil {
+					return err
+				}
+				for _, file := range files {
+					_, _ = fmt.Fprintln(cmd.OutOrStdout(), file)
+				}
+				return nil
+			}
+
 			if len(args) == 0 {
-				return listInstalled(cfg, formulae, casks, versions, full)
+				opts := &listOptions{
+					Formulae: formulae,
+					Casks:    casks,
+					Versions: versions,
+					FullName: full,
+					Multiple: multiple,
+					JSON:     jsonOut,
+				}
+				return listInstalled(cfg, opts)
 			}
 
 			for _, name := range args {
@@ -41,18 +91,29 @@ func NewListCmd(cfg *config.Config) *cobra.Command {
 
 	cmd.Flags().BoolVar(&formulae, "formulae", false, "List formulae only")
 	cmd.Flags().BoolVar(&casks, "casks", false, "List casks only")
+	cmd.Flags().BoolVar(&formulae, "formula", false, "List formulae only")
+	cmd.Flags().BoolVar(&casks, "cask", false, "List casks only")
+	_ = cmd.Flags().MarkHidden("formula")
+	_ = cmd.Flags().MarkHidden("cask")
 	cmd.Flags().BoolVar(&versions, "versions", false, "Show version numbers")
 	cmd.Flags().BoolVar(&full, "full-name", false, "Print fully-qualified names")
+	cmd.Flags().BoolVar(&multiple, "multiple", false, "Only show formulae with multiple versions installed")
+	cmd.Flags().BoolVar(&unbrewed, "unbrewed", false, "List files in the prefix not installed by brew")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Print installed formulae and casks with their versions as JSON")
 
 	return cmd
 }
 
-func listInstalled(cfg *config.Config, formulae, casks, versions, full bool) error {
+func listInstalled(cfg *config.Config, opts *listOptions) error {
+	if opts.JSON {
+		return listInstalledJSON(cfg, opts)
+	}
+
 	var formulaeList []string
 	var casksList []string
 
 	// Get installed formulae
-	if !casks {
+	if !opts.Casks {
 		files, err := os.ReadDir(cfg.HomebrewCellar)
 		if err != nil {
 			return fmt.Errorf("failed to read cellar: %w", err)
@@ -61,36 +122,46 @@ func listInstalled(cfg *config.Config, formulae, casks, versions, full bool) err
 		for _, file := range files {
 			if file.IsDir() {
 				name := file.Name()
-				if full {
-					name = "homebrew/core/" + name
+				formulaPath := filepath.Join(cfg.HomebrewCellar, name)
+
+				versionDirs, err := cellarVersions(cfg, name)
+				if err != nil {
+					continue
+				}
+				if opts.FullName {
+					name = installedFullName(formulaPath, versionDirs)
+				}
+				if opts.Multiple && len(versionDirs) < 2 {
+					continue
 				}
 
-				if versions {
-					// List versions
-					versionDirs, err := os.ReadDir(filepath.Join(cfg.HomebrewCellar, file.Name()))
-					if err == nil {
-						for _, versionDir := range versionDirs {
-							if versionDir.IsDir() {
-								formulaeList = append(formulaeList, fmt.Sprintf("%s %s", name, versionDir.Name()))
-							}
-						}
-					}
-				} else {
-					formulaeList = append(formulaeList, name)
+				if opts.Versions || opts.Multiple {
+					// List all installed versions on one line
+					name += " " + strings.Join(versionDirs, " ")
 				}
+				// Kegs installed with --no-link stay unlinked until brew link
+				if newestReceipt(formulaPath, versionDirs).Unlinked {
+					name += " (unlinked)"
+				}
+				formulaeList = append(formulaeList, name)
 			}
 		}
 	}
 
 	// Get installed casks
-	if !formulae {
+	if !opts.Formulae && !opts.Multiple {
 		if files, err := os.ReadDir(cfg.HomebrewCaskroom); err == nil {
 			for _, file := range files {
 				if file.IsDir() {
 					name := file.Name()
-					if full {
+					if opts.FullName {
 						name = "homebrew/cask/" + name
 					}
+					if opts.Versions {
+						if versions := cask.InstalledVersions(cfg.HomebrewCaskroom, file.Name()); len(versions) > 0 {
+							name += " " + strings.Join(versions, " ")
+						}
+					}
 					casksList = append(casksList, name)
 				}
 			}
@@ -101,10 +172,15 @@ func listInstalled(cfg *config.Config, formulae, casks, versions, full bool) err
 	sort.Strings(formulaeList)
 	sort.Strings(casksList)
 
-	// Output in Homebrew format
+	// Output in Homebrew format; versioned entries get a line each
+	cols := 4 // 4 columns like original
+	if opts.Versions || opts.Multiple {
+		cols = 1
+	}
+
 	if len(formulaeList) > 0 {
 		fmt.Printf("==> Formulae\n")
-		printColumns(formulaeList, 4) // 4 columns like original
+		printColumns(formulaeList, cols)
 	}
 
 	if len(casksList) > 0 {
@@ -112,7 +188,7 @@ func listInstalled(cfg *config.Config, formulae, casks, versions, full bool) err
 			fmt.Println() // Empty line between sections
 		}
 		fmt.Printf("==> Casks\n")
-		printColumns(casksList, 4)
+		printColumns(casksList, cols)
 	}
 
 	if len(formulaeList) == 0 && len(casksList) == 0 {
@@ -122,6 +198,74 @@ func listInstalled(cfg *config.Config, formulae, casks, versions, full bool) err
 	return nil
 }
 
+// listInstalledJSON prints installed formulae and casks with their versions
+// as JSON, honouring --formulae, --casks and --multiple
+func listInstalledJSON(cfg *config.Config, opts *listOptions) error {
+	output := listJSON{Formulae: []installedJSON{}, Casks: []installedJSON{}}
+
+	if !opts.Casks {
+		files, err := os.ReadDir(cfg.HomebrewCellar)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read cellar: %w", err)
+		}
+		for _, file := range files {
+			if !file.IsDir() {
+				continue
+			}
+			versions, err := cellarVersions(cfg, file.Name())
+			if err != nil || len(versions) == 0 {
+				continue
+			}
+			if opts.Multiple && len(versions) < 2 {
+				continue
+			}
+			output.Formulae = append(output.Formulae, installedJSON{Name: file.Name(), Versions: versions})
+		}
+	}
+
+	if !opts.Formulae && !opts.Multiple {
+		if files, err := os.ReadDir(cfg.HomebrewCaskroom); err == nil {
+			for _, file := range files {
+				if file.IsDir() {
+					versions := cask.InstalledVersions(cfg.HomebrewCaskroom, file.Name())
+					if versions == nil {
+						versions = []string{}
+					}
+					output.Casks = append(output.Casks, installedJSON{Name: file.Name(), Versions: versions})
+				}
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode installed list: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// newestReceipt reads the install receipt of the newest installed version,
+// returning an empty receipt for kegs without one
+func newestReceipt(formulaPath string, versions []string) installer.InstallReceipt {
+	if len(versions) > 0 {
+		if receipt, err := installer.ReadReceipt(filepath.Join(formulaPath, versions[len(versions)-1])); err == nil {
+			return *receipt
+		}
+	}
+	return installer.InstallReceipt{}
+}
+
+// installedFullName qualifies an installed formula with the tap recorded in
+// the receipt of its newest version; kegs without one are assumed to be core
+func installedFullName(formulaPath string, versions []string) string {
+	tap := newestReceipt(formulaPath, versions).Tap
+	if tap == "" {
+		tap = "homebrew/core"
+	}
+	return tap + "/" + filepath.Base(formulaPath)
+}
+
 // listFormulaFiles lists all files installed by a specific formula
 func listFormulaFiles(cfg *config.Config, name string) error {
 	// Check if formula is installed
@@ -183,11 +327,12 @@ func listFormulaFiles(cfg *config.Config, name string) error {
 	})
 }
 
-// countDirItems counts the number of items in a directory
+// countDirItems counts the number of items in a directory, including those
+// read before an error; symlinks among them are counted, not followed
 func countDirItems(dirPath string) int {
 	entries, err := os.ReadDir(dirPath)
 	if err != nil {
-		return 0
+		logger.Debug("Failed to read %s: %v", dirPath, err)
 	}
 	return len(entries)
 }
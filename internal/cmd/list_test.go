@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/cask"
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
+)
+
+func TestListInstalledMultiple(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewCellar:   filepath.Join(tempDir, "Cellar"),
+		HomebrewCaskroom: filepath.Join(tempDir, "Caskroom"),
+	}
+
+	for _, keg := range []string{"openssl/3.1.0", "openssl/3.2.0", "wget/1.21"} {
+		if err := os.MkdirAll(filepath.Join(cfg.HomebrewCellar, keg), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(cfg.HomebrewCaskroom, "firefox"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := listInstalled(cfg, &listOptions{Multiple: true, Versions: true})
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("listInstalled() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	output := buf.String()
+
+	if !strings.Contains(output, "openssl 3.1.0 3.2.0") {
+		t.Errorf("Expected openssl with both versions, got:\n%s", output)
+	}
+	if strings.Contains(output, "wget") {
+		t.Errorf("Formula with a single version should not be listed, got:\n%s", output)
+	}
+	if strings.Contains(output, "firefox") {
+		t.Errorf("Casks should not be listed with --multiple, got:\n%s", output)
+	}
+}
+
+func TestListInstalledCaskVersions(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewCellar:   filepath.Join(tempDir, "Cellar"),
+		HomebrewCaskroom: filepath.Join(tempDir, "Caskroom"),
+	}
+
+	if err := os.MkdirAll(filepath.Join(cfg.HomebrewCaskroom, "firefox", "120.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	receipt := &cask.CaskReceipt{Token: "iterm2", Version: "3.5.0", InstalledOn: time.Now()}
+	if err := receipt.Write(cfg.HomebrewCaskroom); err != nil {
+		t.Fatal(err)
+	}
+
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	cmd := NewListCmd(cfg)
+	cmd.SetArgs([]string{"--cask", "--versions"})
+	err := cmd.Execute()
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("list --cask --versions error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	output := buf.String()
+
+	for _, want := range []string{"firefox 120.0", "iterm2 3.5.0"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestListInstalledFullNameFromReceipt(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewCellar:   filepath.Join(tempDir, "Cellar"),
+		HomebrewCaskroom: filepath.Join(tempDir, "Caskroom"),
+	}
+
+	receipts := map[string]*installer.InstallReceipt{
+		"mytool/2.0": {Name: "mytool", Version: "2.0", Tap: "user/repo"},
+		"wget/1.21":  {Name: "wget", Version: "1.21", Tap: "homebrew/core"},
+		"legacy/1.0": nil,
+	}
+	for keg, receipt := range receipts {
+		kegPath := filepath.Join(cfg.HomebrewCellar, keg)
+		if err := os.MkdirAll(kegPath, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if receipt == nil {
+			continue
+		}
+		data, err := json.Marshal(receipt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(kegPath, "INSTALL_RECEIPT.json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	cmd := NewListCmd(cfg)
+	cmd.SetArgs([]string{"--formula", "--full-name"})
+	err := cmd.Execute()
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("list --formula --full-name error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	output := buf.String()
+
+	for _, want := range []string{"user/repo/mytool", "homebrew/core/wget", "homebrew/core/legacy"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestListFormulaeCasksExclusive(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"formulae and casks", []string{"--formulae", "--casks"}, true},
+		{"singular aliases", []string{"--formula", "--cask"}, true},
+		{"mixed aliases", []string{"--formulae", "--cask"}, true},
+		{"formulae aliases together", []string{"--formulae", "--formula"}, false},
+		{"formulae only", []string{"--formulae"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			cfg := &config.Config{
+				HomebrewCellar:   filepath.Join(tempDir, "Cellar"),
+				HomebrewCaskroom: filepath.Join(tempDir, "Caskroom"),
+			}
+			if err := os.MkdirAll(cfg.HomebrewCellar, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := NewListCmd(cfg)
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+
+			err := cmd.Execute()
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var usageErr *UsageError
+			if !errors.As(err, &usageErr) {
+				t.Fatalf("Expected a usage error, got %v", err)
+			}
+			if usageErr.ExitCode() != 2 {
+				t.Errorf("Expected exit code 2, got %d", usageErr.ExitCode())
+			}
+			if !strings.Contains(err.Error(), "cannot be used together") {
+				t.Errorf("Unexpected error message: %v", err)
+			}
+		})
+	}
+}
+
+func TestListInstalledJSON(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewCellar:   filepath.Join(tempDir, "Cellar"),
+		HomebrewCaskroom: filepath.Join(tempDir, "Caskroom"),
+	}
+
+	for _, dir := range []string{
+		filepath.Join(cfg.HomebrewCellar, "openssl", "3.2.10"),
+		filepath.Join(cfg.HomebrewCellar, "openssl", "3.2.9"),
+		filepath.Join(cfg.HomebrewCellar, "wget", "1.21"),
+		filepath.Join(cfg.HomebrewCaskroom, "firefox", "120.0"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := listInstalled(cfg, &listOptions{JSON: true})
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("listInstalled() error = %v", err)
+	}
+
+	var output listJSON
+	if err := json.NewDecoder(r).Decode(&output); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+
+	want := map[string]string{"openssl": "3.2.9 3.2.10", "wget": "1.21"}
+	if len(output.Formulae) != len(want) {
+		t.Fatalf("Formulae = %v, want %v", output.Formulae, want)
+	}
+	for _, entry := range output.Formulae {
+		if strings.Join(entry.Versions, " ") != want[entry.Name] {
+			t.Errorf("%s versions = %v, want %s", entry.Name, entry.Versions, want[entry.Name])
+		}
+	}
+	if len(output.Casks) != 1 || output.Casks[0].Name != "firefox" || strings.Join(output.Casks[0].Versions, " ") != "120.0" {
+		t.Errorf("Casks = %v, want firefox 120.0", output.Casks)
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"strings"
 
 	"github.com/pilshchikov/homebrew-go/internal/api"
+	"github.com/pilshchikov/homebrew-go/internal/cask"
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 	"github.com/spf13/cobra"
 )
@@ -118,7 +121,7 @@ func getOutdatedFormulae(cfg *config.Config, formulaNames []string, opts *outdat
 
 	for _, formulaName := range formulaeToCheck {
 		// Get installed versions
-		installedVersions, err := getInstalledVersions(cfg, formulaName)
+		installedVersions, err := cellarVersions(cfg, formulaName)
 		if err != nil {
 			logger.Debug("Failed to get installed versions for %s: %v", formulaName, err)
 			continue
@@ -137,7 +140,13 @@ func getOutdatedFormulae(cfg *config.Config, formulaNames []string, opts *outdat
 
 		// Check if outdated
 		latestInstalled := getLatestVersion(installedVersions)
-		isOutdated := isVersionOutdated(latestInstalled, currentFormula.Version)
+		var isOutdated bool
+		if formula.IsHeadVersion(latestInstalled) {
+			isOutdated = isHeadOutdated(latestInstalled, currentFormula, opts.fetchHead)
+		} else {
+			isOutdated = isVersionOutdated(latestInstalled, currentFormula.Version) ||
+				isRebuildOutdated(cfg, formulaName, latestInstalled, currentFormula)
+		}
 
 		if isOutdated || opts.verbose {
 			info := OutdatedInfo{
@@ -162,33 +171,59 @@ func getOutdatedFormulae(cfg *config.Config, formulaNames []string, opts *outdat
 }
 
 func getOutdatedCasks(cfg *config.Config, caskNames []string, opts *outdatedOptions) ([]OutdatedInfo, error) {
-	var outdatedCasks []OutdatedInfo
+	outdatedCasks := make([]OutdatedInfo, 0)
 
-	// For now, return empty list as cask outdated detection is complex
-	logger.Debug("Cask outdated detection not fully implemented")
+	// Get list of installed casks
+	casksToCheck := caskNames
+	if len(casksToCheck) == 0 {
+		entries, err := os.ReadDir(cfg.HomebrewCaskroom)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return outdatedCasks, nil
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				casksToCheck = append(casksToCheck, entry.Name())
+			}
+		}
+	}
 
-	return outdatedCasks, nil
-}
+	logger.Debug("Checking casks: %v", casksToCheck)
 
-func getInstalledVersions(cfg *config.Config, formulaName string) ([]string, error) {
-	formulaPath := filepath.Join(cfg.HomebrewCellar, formulaName)
+	client := api.NewClient(cfg)
 
-	entries, err := os.ReadDir(formulaPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []string{}, nil
+	for _, token := range casksToCheck {
+		receipt, err := cask.ReadReceipt(cfg.HomebrewCaskroom, token)
+		if err != nil {
+			logger.Debug("Failed to get installed version for %s: %v", token, err)
+			continue
 		}
-		return nil, err
-	}
 
-	var versions []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			versions = append(versions, entry.Name())
+		currentCask, err := client.GetCask(token)
+		if err != nil {
+			logger.Debug("Failed to get current version for %s: %v", token, err)
+			continue
+		}
+
+		// Casks that update themselves are skipped unless --greedy is given
+		if currentCask.AutoUpdates && !opts.greedy {
+			continue
+		}
+
+		isOutdated := isVersionOutdated(receipt.Version, currentCask.Version)
+		if isOutdated || opts.verbose {
+			outdatedCasks = append(outdatedCasks, OutdatedInfo{
+				Name:              token,
+				InstalledVersions: []string{receipt.Version},
+				CurrentVersion:    currentCask.Version,
+				Outdated:          isOutdated,
+			})
 		}
 	}
 
-	return versions, nil
+	return outdatedCasks, nil
 }
 
 func getLatestVersion(versions []string) string {
@@ -196,9 +231,7 @@ func getLatestVersion(versions []string) string {
 		return ""
 	}
 
-	// Simple string comparison - in a full implementation,
-	// this would use proper version comparison
-	sort.Strings(versions)
+	sortVersions(versions)
 	return versions[len(versions)-1]
 }
 
@@ -208,6 +241,31 @@ func isVersionOutdated(installed, current string) bool {
 	return installed != current && current != ""
 }
 
+// isHeadOutdated reports whether a HEAD keg was built from a commit other than
+// the one upstream HEAD points to now. Finding out needs the network, so HEAD
+// kegs are only checked with --fetch-HEAD
+func isHeadOutdated(installed string, current *formula.Formula, fetchHead bool) bool {
+	if !fetchHead || current.Head == nil {
+		return false
+	}
+	commit, err := installer.RemoteHeadCommit(current)
+	if err != nil {
+		logger.Debug("Failed to fetch HEAD of %s: %v", current.Name, err)
+		return false
+	}
+	return !strings.HasPrefix(commit, strings.TrimPrefix(installed, "HEAD-"))
+}
+
+// isRebuildOutdated reports whether an installed keg matches the current
+// version but was poured from a bottle older than the current rebuild
+func isRebuildOutdated(cfg *config.Config, formulaName, installed string, current *formula.Formula) bool {
+	if installed != current.Version {
+		return false
+	}
+	receipt := newestReceipt(filepath.Join(cfg.HomebrewCellar, formulaName), []string{installed})
+	return receipt.Source == "bottle" && receipt.Rebuild < current.BottleRebuild()
+}
+
 func isPinned(cfg *config.Config, formulaName string) bool {
 	// Check if formula is pinned
 	pinFile := filepath.Join(cfg.HomebrewLibrary, "PinnedKegs", formulaName)
@@ -3,10 +3,16 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
@@ -143,6 +149,136 @@ func TestGetOutdatedCasks(t *testing.T) {
 	}
 }
 
+func TestGetOutdatedCasksFromReceipt(t *testing.T) {
+	logger.Init(false, false, true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cask/old-cask.json":
+			_, _ = w.Write([]byte(`{"token": "old-cask", "version": "2.0.0"}`))
+		case "/cask/current-cask.json":
+			_, _ = w.Write([]byte(`{"token": "current-cask", "version": "1.0.0"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	cfg := &config.Config{HomebrewCaskroom: t.TempDir()}
+	for _, token := range []string{"old-cask", "current-cask"} {
+		receiptDir := filepath.Join(cfg.HomebrewCaskroom, token, "1.0.0")
+		_ = os.MkdirAll(receiptDir, 0755)
+		receipt := `{"token": "` + token + `", "version": "1.0.0", "installed_on": "2024-01-01T00:00:00Z"}`
+		_ = os.WriteFile(filepath.Join(receiptDir, ".metadata"), []byte(receipt), 0600)
+	}
+
+	outdated, err := getOutdatedCasks(cfg, []string{}, &outdatedOptions{cask: true})
+	if err != nil {
+		t.Fatalf("getOutdatedCasks failed: %v", err)
+	}
+
+	if len(outdated) != 1 {
+		t.Fatalf("Expected 1 outdated cask, got %d", len(outdated))
+	}
+	if outdated[0].Name != "old-cask" || outdated[0].CurrentVersion != "2.0.0" {
+		t.Errorf("Unexpected outdated cask: %+v", outdated[0])
+	}
+}
+
+func TestGetOutdatedFormulaeRebuild(t *testing.T) {
+	logger.Init(false, false, true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(filepath.Base(r.URL.Path), ".json")
+		_, _ = w.Write([]byte(`{"name": "` + name + `", "versions": {"stable": "1.0.0"}, "bottle": {"stable": {"rebuild": 1, "files": {}}}}`))
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	cfg := &config.Config{HomebrewCellar: t.TempDir(), HomebrewLibrary: t.TempDir()}
+	receipts := map[string]string{
+		"stale":   `{"name": "stale", "version": "1.0.0", "source": "bottle"}`,
+		"rebuilt": `{"name": "rebuilt", "version": "1.0.0", "source": "bottle", "rebuild": 1}`,
+		"built":   `{"name": "built", "version": "1.0.0", "source": "source"}`,
+	}
+	for name, receipt := range receipts {
+		kegDir := filepath.Join(cfg.HomebrewCellar, name, "1.0.0")
+		_ = os.MkdirAll(kegDir, 0755)
+		_ = os.WriteFile(filepath.Join(kegDir, "INSTALL_RECEIPT.json"), []byte(receipt), 0644)
+	}
+
+	outdated, err := getOutdatedFormulae(cfg, []string{"built", "rebuilt", "stale"}, &outdatedOptions{})
+	if err != nil {
+		t.Fatalf("getOutdatedFormulae failed: %v", err)
+	}
+
+	if len(outdated) != 1 || outdated[0].Name != "stale" {
+		t.Errorf("Expected only stale to be outdated, got %+v", outdated)
+	}
+}
+
+func TestGetOutdatedFormulaeHead(t *testing.T) {
+	logger.Init(false, false, true)
+
+	// A local repository stands in for the formula's upstream
+	repoDir := filepath.Join(t.TempDir(), "upstream.git")
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(content string) string {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repoDir, "main.c"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add("main.c"); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+		hash, err := worktree.Commit(content, &git.CommitOptions{Author: sig})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash.String()
+	}
+	installed := commit("v1")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"name": "tool", "versions": {"stable": "1.0.0"}, "urls": {"head": {"url": "` + repoDir + `"}}}`))
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	cfg := &config.Config{HomebrewCellar: t.TempDir(), HomebrewLibrary: t.TempDir()}
+	_ = os.MkdirAll(filepath.Join(cfg.HomebrewCellar, "tool", "HEAD-"+installed[:7]), 0755)
+
+	check := func(fetchHead bool) bool {
+		t.Helper()
+		outdated, err := getOutdatedFormulae(cfg, []string{"tool"}, &outdatedOptions{fetchHead: fetchHead})
+		if err != nil {
+			t.Fatalf("getOutdatedFormulae failed: %v", err)
+		}
+		return len(outdated) == 1
+	}
+
+	if check(false) || check(true) {
+		t.Error("Expected a HEAD keg of the upstream HEAD commit to be current")
+	}
+
+	commit("v2")
+	if check(false) {
+		t.Error("Expected HEAD kegs to be skipped without --fetch-HEAD")
+	}
+	if !check(true) {
+		t.Error("Expected a HEAD keg behind upstream to be outdated with --fetch-HEAD")
+	}
+}
+
 func TestGetInstalledVersions(t *testing.T) {
 	logger.Init(false, false, true)
 
@@ -151,9 +287,9 @@ func TestGetInstalledVersions(t *testing.T) {
 		HomebrewCellar: "/non/existent/path",
 	}
 
-	versions, err := getInstalledVersions(cfg, "non-existent")
+	versions, err := cellarVersions(cfg, "non-existent")
 	if err != nil {
-		t.Errorf("getInstalledVersions should not error for non-existent formula: %v", err)
+		t.Errorf("cellarVersions should not error for non-existent formula: %v", err)
 	}
 	if len(versions) != 0 {
 		t.Errorf("Expected empty versions list, got %d", len(versions))
@@ -184,9 +320,9 @@ func TestGetInstalledVersionsWithTemp(t *testing.T) {
 		_ = os.MkdirAll(versionDir, 0755)
 	}
 
-	installedVersions, err := getInstalledVersions(cfg, "test-formula")
+	installedVersions, err := cellarVersions(cfg, "test-formula")
 	if err != nil {
-		t.Errorf("getInstalledVersions failed: %v", err)
+		t.Errorf("cellarVersions failed: %v", err)
 	}
 
 	if len(installedVersions) != len(versions) {
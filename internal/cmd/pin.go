@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
@@ -151,7 +152,7 @@ func createPinFile(pinFile, formulaName string) error {
 
 func createPinFileWithConfig(cfg *config.Config, pinFile, formulaName string) error {
 	// Get current installed version
-	installedVersions, err := getInstalledVersions(cfg, formulaName)
+	installedVersions, err := cellarVersions(cfg, formulaName)
 	if err != nil {
 		return err
 	}
@@ -169,6 +170,25 @@ func createPinFileWithConfig(cfg *config.Config, pinFile, formulaName string) er
 	return os.WriteFile(pinFile, []byte(content), 0644)
 }
 
+// pinnedVersion reports whether a formula is pinned and the version its pin
+// marker records. Markers without one are pinned at the newest installed
+// version.
+func pinnedVersion(cfg *config.Config, formulaName string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(cfg.HomebrewLibrary, "PinnedKegs", formulaName))
+	if err != nil {
+		return "", false
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if version, ok := strings.CutPrefix(line, "# Pinned at version: "); ok {
+			return strings.TrimSpace(version), true
+		}
+	}
+
+	versions, _ := cellarVersions(cfg, formulaName)
+	return getLatestVersion(versions), true
+}
+
 func isFormulaInstalledPin(cfg *config.Config, formulaName string) bool {
 	formulaPath := filepath.Join(cfg.HomebrewCellar, formulaName)
 
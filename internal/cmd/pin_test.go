@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 )
 
@@ -357,3 +360,51 @@ func TestPinMultipleFormulae(t *testing.T) {
 		}
 	}
 }
+
+func TestInfoReportsPin(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewCellar:  filepath.Join(tempDir, "Cellar"),
+		HomebrewLibrary: filepath.Join(tempDir, "Library"),
+	}
+	for _, version := range []string{"1.2.9", "1.2.10"} {
+		if err := os.MkdirAll(filepath.Join(cfg.HomebrewCellar, "tool", version), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info := func() string {
+		oldStdout := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+
+		showFormulaInfo(cfg, &formula.Formula{Name: "tool", Version: "1.3.0"}, false)
+
+		_ = w.Close()
+		os.Stdout = oldStdout
+
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		return buf.String()
+	}
+
+	if output := info(); strings.Contains(output, "Pinned") {
+		t.Errorf("Unpinned formula reported as pinned:\n%s", output)
+	}
+
+	if err := runPin(cfg, []string{"tool"}); err != nil {
+		t.Fatalf("runPin() error = %v", err)
+	}
+	if output := info(); !strings.Contains(output, "Pinned at 1.2.10") {
+		t.Errorf("Expected info to report the pin at 1.2.10, got:\n%s", output)
+	}
+
+	if err := runUnpin(cfg, []string{"tool"}); err != nil {
+		t.Fatalf("runUnpin() error = %v", err)
+	}
+	if output := info(); strings.Contains(output, "Pinned") {
+		t.Errorf("Unpinned formula reported as pinned:\n%s", output)
+	}
+}
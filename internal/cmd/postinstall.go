@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+// NewPostinstallCmd creates the postinstall command
+func NewPostinstallCmd(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "postinstall FORMULA...",
+		Short: "Rerun the post-install steps for formulae",
+		Long: `Rerun the post-install steps of installed formulae in their kegs, for
+example after a system change broke what they set up.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPostinstall(cfg, args)
+		},
+	}
+
+	return cmd
+}
+
+func runPostinstall(cfg *config.Config, formulaNames []string) error {
+	inst := installer.New(cfg, &installer.Options{})
+	for _, name := range formulaNames {
+		if err := inst.PostInstall(name); err != nil {
+			return err
+		}
+		logger.Success("Ran post-install for %s", name)
+	}
+	return nil
+}
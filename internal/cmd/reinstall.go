@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pilshchikov/homebrew-go/internal/api"
+	"github.com/pilshchikov/homebrew-go/internal/cask"
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+// caskReinstaller removes and installs casks; tests replace it
+type caskReinstaller interface {
+	UninstallCask(c *cask.Cask, opts *cask.CaskInstallOptions) error
+	InstallCask(c *cask.Cask, opts *cask.CaskInstallOptions) (*cask.CaskInstallResult, error)
+}
+
+// newCaskReinstaller creates the installer used to reinstall casks
+var newCaskReinstaller = func(cfg *config.Config) caskReinstaller {
+	return cask.NewCaskInstaller(cfg)
+}
+
+// NewReinstallCmd creates the reinstall command
+func NewReinstallCmd(cfg *config.Config) *cobra.Command {
+	var (
+		formulaOnly     bool
+		caskOnly        bool
+		buildFromSource bool
+		noQuarantine    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reinstall [OPTIONS] FORMULA|CASK...",
+		Short: "Uninstall and then reinstall a formula or cask",
+		Long: `Uninstall and then reinstall one or more formulae or casks using the
+same options they were originally installed with.
+
+Casks keep the options recorded in their install receipt, such as
+--no-quarantine.`,
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: exclusiveFlags(formulaCaskFlags...),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReinstall(cfg, args, &reinstallOptions{
+				FormulaOnly:     formulaOnly,
+				CaskOnly:        caskOnly,
+				BuildFromSource: buildFromSource,
+				NoQuarantine:    noQuarantine,
+				Out:             cmd.OutOrStdout(),
+				Context:         cmd.Context(),
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&formulaOnly, "formula", false, "Treat all named arguments as formulae")
+	cmd.Flags().BoolVar(&formulaOnly, "formulae", false, "Treat all named arguments as formulae")
+	cmd.Flags().BoolVar(&caskOnly, "cask", false, "Treat all named arguments as casks")
+	cmd.Flags().BoolVar(&caskOnly, "casks", false, "Treat all named arguments as casks")
+	cmd.Flags().BoolVarP(&buildFromSource, "build-from-source", "s", false, "Compile formulae from source even if a bottle is provided")
+	cmd.Flags().BoolVar(&noQuarantine, "no-quarantine", false, "Reinstall casks without the macOS quarantine attribute")
+
+	return cmd
+}
+
+type reinstallOptions struct {
+	FormulaOnly     bool
+	CaskOnly        bool
+	BuildFromSource bool
+	NoQuarantine    bool
+	Out             io.Writer
+	Context         context.Context
+}
+
+func runReinstall(cfg *config.Config, args []string, opts *reinstallOptions) error {
+	var formulae []string
+	for _, name := range args {
+		formulaInstalled, err := isFormulaInstalled(cfg, name)
+		if err != nil {
+			return fmt.Errorf("failed to check if %s is installed: %w", name, err)
+		}
+		caskInstalled := isCaskInstalled(cfg, name)
+
+		switch {
+		case opts.FormulaOnly:
+			if !formulaInstalled {
+				return fmt.Errorf("formula %s is not installed", name)
+			}
+			formulae = append(formulae, name)
+		case opts.CaskOnly:
+			if err := reinstallCask(cfg, name, opts); err != nil {
+				return err
+			}
+		case formulaInstalled && caskInstalled:
+			return fmt.Errorf("%s is installed as both a formula and a cask; use --formula or --cask to choose which to reinstall", name)
+		case formulaInstalled:
+			formulae = append(formulae, name)
+		case caskInstalled:
+			if err := reinstallCask(cfg, name, opts); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("no installed keg or cask with the name %q", name)
+		}
+	}
+
+	if len(formulae) == 0 {
+		return nil
+	}
+
+	return runInstall(cfg, formulae, &installOptions{
+		FormulaOnly:     true,
+		BuildFromSource: opts.BuildFromSource,
+		Out:             opts.Out,
+		Context:         opts.Context,
+		Reinstall:       true,
+		DryRun:          cfg.DryRun,
+		Verbose:         cfg.Verbose,
+	})
+}
+
+// reinstallCask uninstalls a cask and installs the current version from the
+// API, keeping the options recorded in its receipt
+func reinstallCask(cfg *config.Config, token string, opts *reinstallOptions) error {
+	receipt, err := cask.ReadReceipt(cfg.HomebrewCaskroom, token)
+	if err != nil {
+		return fmt.Errorf("cask %s is not installed: %w", token, err)
+	}
+
+	caskData, err := api.NewClient(cfg).GetCask(token)
+	if err != nil {
+		return fmt.Errorf("failed to fetch cask %s: %w", token, err)
+	}
+
+	// Keep the localized download installed before unless HOMEBREW_CASK_OPTS
+	// asks for another language
+	languages := caskLanguages(cfg, "")
+	if len(languages) == 0 && receipt.Language != "" {
+		languages = []string{receipt.Language}
+	}
+
+	installOpts := &cask.CaskInstallOptions{
+		Force:        true,
+		RequireSHA:   cfg.RequireSHA,
+		Verbose:      cfg.Verbose,
+		DryRun:       cfg.DryRun,
+		NoQuarantine: opts.NoQuarantine || receipt.NoQuarantine,
+		Languages:    languages,
+	}
+
+	if cfg.DryRun {
+		logger.Info("Would reinstall cask %s %s", token, caskData.Version)
+		return nil
+	}
+
+	caskInstaller := newCaskReinstaller(cfg)
+	if err := caskInstaller.UninstallCask(caskData, installOpts); err != nil {
+		return fmt.Errorf("failed to uninstall cask %s: %w", token, err)
+	}
+	if _, err := caskInstaller.InstallCask(caskData, installOpts); err != nil {
+		return fmt.Errorf("failed to install cask %s: %w", token, err)
+	}
+
+	logger.Success("Successfully reinstalled %s", token)
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/cask"
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// recordingCaskInstaller records the cask operations reinstall performs
+type recordingCaskInstaller struct {
+	calls []string
+}
+
+func (r *recordingCaskInstaller) UninstallCask(c *cask.Cask, opts *cask.CaskInstallOptions) error {
+	r.calls = append(r.calls, "uninstall "+c.Token)
+	return nil
+}
+
+func (r *recordingCaskInstaller) InstallCask(c *cask.Cask, opts *cask.CaskInstallOptions) (*cask.CaskInstallResult, error) {
+	r.calls = append(r.calls, fmt.Sprintf("install %s %s no-quarantine=%v languages=%v", c.Token, c.Version, opts.NoQuarantine, opts.Languages))
+	return &cask.CaskInstallResult{Token: c.Token, Version: c.Version, Success: true}, nil
+}
+
+func TestReinstallCask(t *testing.T) {
+	logger.Init(false, false, true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cask/mock-app.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"token": "mock-app", "version": "2.0.0", "url": "https://example.com/MockApp.zip", "sha256": "no_check"}`))
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	recorder := &recordingCaskInstaller{}
+	original := newCaskReinstaller
+	newCaskReinstaller = func(cfg *config.Config) caskReinstaller { return recorder }
+	defer func() { newCaskReinstaller = original }()
+
+	cfg := &config.Config{HomebrewCellar: t.TempDir(), HomebrewCaskroom: t.TempDir()}
+
+	// Without a receipt there is nothing to reinstall
+	if err := runReinstall(cfg, []string{"mock-app"}, &reinstallOptions{CaskOnly: true}); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Fatalf("runReinstall() error = %v, want not installed", err)
+	}
+	if len(recorder.calls) != 0 {
+		t.Fatalf("Expected no cask operations without a receipt, got %v", recorder.calls)
+	}
+
+	receipt := &cask.CaskReceipt{Token: "mock-app", Version: "1.0.0", InstalledOn: time.Now(), NoQuarantine: true, Language: "de"}
+	if err := receipt.Write(cfg.HomebrewCaskroom); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runReinstall(cfg, []string{"mock-app"}, &reinstallOptions{}); err != nil {
+		t.Fatalf("runReinstall() error = %v", err)
+	}
+
+	// The receipt's --no-quarantine and language carry over to the new install
+	want := []string{"uninstall mock-app", "install mock-app 2.0.0 no-quarantine=true languages=[de]"}
+	if fmt.Sprint(recorder.calls) != fmt.Sprint(want) {
+		t.Errorf("Cask operations = %v, want %v", recorder.calls, want)
+	}
+
+	// HOMEBREW_CASK_OPTS still picks another language
+	recorder.calls = nil
+	cfg.CaskOpts = []string{"--language=fr"}
+	if err := runReinstall(cfg, []string{"mock-app"}, &reinstallOptions{}); err != nil {
+		t.Fatalf("runReinstall() error = %v", err)
+	}
+	want = []string{"uninstall mock-app", "install mock-app 2.0.0 no-quarantine=true languages=[fr]"}
+	if fmt.Sprint(recorder.calls) != fmt.Sprint(want) {
+		t.Errorf("Cask operations = %v, want %v", recorder.calls, want)
+	}
+}
@@ -7,14 +7,18 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pilshchikov/homebrew-go/internal/api"
 	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
-	"github.com/pilshchikov/homebrew-go/internal/tap"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
 	"github.com/spf13/cobra"
 )
 
 // NewRootCmd creates the root command for the brew CLI
 func NewRootCmd(cfg *config.Config, version, gitCommit, buildDate string) *cobra.Command {
+	var printArch bool
+
 	cmd := &cobra.Command{
 		Use:   "brew",
 		Short: "The missing package manager for macOS (or Linux)",
@@ -22,15 +26,42 @@ func NewRootCmd(cfg *config.Config, version, gitCommit, buildDate string) *cobra
 and manage software packages with ease. It provides a simple command-line
 interface for installing, updating, and removing packages.`,
 		Version: version,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			// Ensure directories exist
-			if err := cfg.EnsureDirectories(); err != nil {
-				logger.Error("Failed to create directories: %v", err)
-				os.Exit(1)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if printArch {
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), api.EffectiveArch(cfg))
+				return err
+			}
+			return cmd.Help()
+		},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if cfg.Arch != "" && config.NormalizeArch(cfg.Arch) == "" {
+				return &UsageError{Message: fmt.Sprintf("unknown architecture %q; use arm64 or x86_64", cfg.Arch)}
 			}
 
-			// Check for updates (if auto-update is enabled)
-			checkForUpdates(cfg)
+			// --debug is only known once flags are parsed
+			if cfg.Debug {
+				logger.Init(cfg.Debug, cfg.Verbose, cfg.Quiet)
+				utils.Transport = utils.NewLoggingTransport(utils.Transport)
+			}
+
+			// Only commands that write to the prefix need a usable one;
+			// read-only commands like doctor should still run
+			if changesPrefix(cmd) {
+				if err := cfg.Validate(); err != nil {
+					return err
+				}
+				if err := cfg.EnsureDirectories(); err != nil {
+					return fmt.Errorf("failed to create directories: %w", err)
+				}
+			} else if err := cfg.EnsureDirectories(); err != nil {
+				logger.Debug("Failed to create directories: %v", err)
+			}
+
+			// Builds that failed or were killed leave their staging behind
+			if !cfg.DryRun {
+				installer.SweepTemp(cfg.HomebrewTemp)
+			}
+			return nil
 		},
 		CompletionOptions: cobra.CompletionOptions{
 			DisableDefaultCmd: false,
@@ -51,11 +82,17 @@ Platform: %s
 	cmd.PersistentFlags().BoolVarP(&cfg.Quiet, "quiet", "q", cfg.Quiet, "Suppress output")
 	cmd.PersistentFlags().BoolVar(&cfg.Force, "force", cfg.Force, "Force the operation")
 	cmd.PersistentFlags().BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "Show what would be done without actually doing it")
+	cmd.PersistentFlags().BoolVar(&cfg.NoCache, "no-cache", cfg.NoCache, "Fetch formula data from the API instead of reusing cached copies")
+	cmd.PersistentFlags().StringVar(&cfg.Arch, "arch", cfg.Arch, "Select bottles for `arch` (arm64 or x86_64)")
+	cmd.Flags().BoolVar(&printArch, "print-arch", false, "Print the effective architecture")
 
 	// Add subcommands
 	cmd.AddCommand(NewInstallCmd(cfg))
 	cmd.AddCommand(NewUninstallCmd(cfg))
+	cmd.AddCommand(NewReinstallCmd(cfg))
+	cmd.AddCommand(NewPostinstallCmd(cfg))
 	cmd.AddCommand(NewUpgradeCmd(cfg))
+	cmd.AddCommand(NewFetchCmd(cfg))
 	cmd.AddCommand(NewUpdateCmd(cfg))
 	cmd.AddCommand(NewSearchCmd(cfg))
 	cmd.AddCommand(NewInfoCmd(cfg))
@@ -86,11 +123,15 @@ Platform: %s
 	cmd.AddCommand(NewCasksCmd(cfg))
 	cmd.AddCommand(NewFormulaeCmd(cfg))
 	cmd.AddCommand(NewCommandsCmd(cfg))
+	cmd.AddCommand(NewAuditCmd(cfg))
+	cmd.AddCommand(NewCreateCmd(cfg))
 
 	// Environment commands
 	cmd.AddCommand(NewPrefixCmd(cfg))
 	cmd.AddCommand(NewCellarCmd(cfg))
 	cmd.AddCommand(NewCacheCmd(cfg))
+	cmd.AddCommand(NewCacheFilesCmd(cfg))
+	cmd.AddCommand(NewCacheInfoCmd(cfg))
 	cmd.AddCommand(NewEnvCmd(cfg))
 
 	// Help customization
@@ -99,6 +140,33 @@ Platform: %s
 	return cmd
 }
 
+// prefixCommands are the top-level commands that install, remove or link
+// files under the prefix, cellar or repository
+var prefixCommands = map[string]bool{
+	"install":     true,
+	"uninstall":   true,
+	"reinstall":   true,
+	"upgrade":     true,
+	"postinstall": true,
+	"link":        true,
+	"unlink":      true,
+	"cleanup":     true,
+	"pin":         true,
+	"unpin":       true,
+	"tap":         true,
+	"untap":       true,
+	"update":      true,
+}
+
+// changesPrefix reports whether cmd, or the top-level command it belongs
+// to, writes to the prefix
+func changesPrefix(cmd *cobra.Command) bool {
+	for cmd.HasParent() && cmd.Parent().HasParent() {
+		cmd = cmd.Parent()
+	}
+	return prefixCommands[cmd.Name()]
+}
+
 func getHelpTemplate() string {
 	return `{{.Long | trimTrailingWhitespaces}}
 
@@ -118,9 +186,12 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
-// checkForUpdates checks if Homebrew should be updated
+// autoUpdateFunc performs the update run before installs; tests override it
+var autoUpdateFunc = runUpdate
+
+// checkForUpdates auto-updates Homebrew before an install if the last update is stale
 func checkForUpdates(cfg *config.Config) {
-	if cfg.NoAutoUpdate || cfg.CI {
+	if !cfg.AutoUpdate || cfg.NoAutoUpdate || cfg.CI || cfg.DryRun {
 		return
 	}
 
@@ -134,28 +205,11 @@ func checkForUpdates(cfg *config.Config) {
 
 	logger.Step("Auto-updating Homebrew...")
 
-	// Update the main Homebrew repository (brew core)
-	tapManager := tap.NewManager(cfg)
-	if err := tapManager.UpdateTap("homebrew/core"); err != nil {
-		logger.Debug("Failed to auto-update homebrew/core: %v", err)
+	if err := autoUpdateFunc(cfg); err != nil {
+		logger.Debug("Failed to auto-update: %v", err)
 		return
 	}
 
-	// Update homebrew/cask if installed
-	taps, err := tapManager.ListTaps()
-	if err == nil {
-		for _, t := range taps {
-			if t.Name == "homebrew/cask" {
-				if err := tapManager.UpdateTap("homebrew/cask"); err != nil {
-					logger.Debug("Failed to auto-update homebrew/cask: %v", err)
-				}
-				break
-			}
-		}
-	}
-
-	// Update timestamp for next auto-update check
-	updateLastUpdateTime(cfg)
 	logger.Debug("Auto-update completed")
 }
 
@@ -187,7 +241,7 @@ func parseFormulaArgs(args []string) ([]string, []string) {
 // shouldAutoUpdate checks if auto-update should be performed based on time
 func shouldAutoUpdate(cfg *config.Config) bool {
 	// Check if auto-update interval has passed (default 24 hours)
-	interval := 24 * time.Hour
+	interval := time.Duration(cfg.AutoUpdateSecs) * time.Second
 
 	lastUpdate := getLastUpdateTime(cfg)
 	return time.Since(lastUpdate) >= interval
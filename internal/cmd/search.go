@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/pilshchikov/homebrew-go/internal/api"
@@ -16,11 +17,20 @@ func NewSearchCmd(cfg *config.Config) *cobra.Command {
 		formulae bool
 		casks    bool
 		desc     bool
+		homepage bool
+		limit    int
+		evalAll  bool
+		jsonOut  bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "search [OPTIONS] [TEXT|/REGEX/]",
 		Short: "Search for formulae and casks",
+		Long: `Search for formulae and casks by name.
+
+Deprecated and disabled results are marked as such. Disabled results are
+hidden unless --eval-all is passed.`,
+		PreRunE: exclusiveFlags(formulaCaskFlags...),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			query := ""
 			if len(args) > 0 {
@@ -48,47 +58,175 @@ func NewSearchCmd(cfg *config.Config) *cobra.Command {
 				return nil
 			}
 
-			// Search using real API
-			apiClient := api.NewClient(cfg)
-			logger.Step("Searching for %q", query)
-			results, err := apiClient.SearchFormulae(query)
-
-			if err != nil {
-				netErr := errors.NewNetworkError("search", "formulae API", err)
-				logger.LogDetailedError(logger.ErrorContext{
-					Operation:   "search",
-					Error:       netErr,
-					Suggestions: netErr.Suggestions,
-				})
-				fmt.Printf("No formulae found matching %q\n", query)
-				return nil
-			}
-
-			fmt.Printf("==> Formulae\n")
-			if len(results) > 0 {
-				var names []string
-				for _, result := range results {
-					names = append(names, result.Name)
-				}
-				printColumnsSearch(names, 4)
-			} else {
-				fmt.Printf("No formulae found matching %q\n", query)
-			}
-
-			fmt.Printf("\n==> Casks\n")
-			fmt.Printf("No casks found matching %q\n", query)
-
-			return nil
+			return runSearch(cfg, query, &searchOptions{
+				formulae: formulae,
+				casks:    casks,
+				desc:     desc,
+				homepage: homepage,
+				limit:    limit,
+				evalAll:  evalAll,
+				jsonOut:  jsonOut,
+			})
 		},
 	}
 
 	cmd.Flags().BoolVar(&formulae, "formulae", false, "Search formulae only")
 	cmd.Flags().BoolVar(&casks, "casks", false, "Search casks only")
 	cmd.Flags().BoolVar(&desc, "desc", false, "Search descriptions too")
+	cmd.Flags().BoolVar(&homepage, "homepage", false, "Print each result's homepage")
+	cmd.Flags().IntVar(&limit, "limit", api.DefaultSearchLimit, "Maximum number of results per section (0 for unlimited)")
+	cmd.Flags().BoolVar(&evalAll, "eval-all", false, "Include disabled formulae and casks in the results")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Output the results in JSON format")
 
 	return cmd
 }
 
+type searchOptions struct {
+	formulae bool
+	casks    bool
+	desc     bool
+	homepage bool
+	limit    int
+	evalAll  bool
+	jsonOut  bool
+}
+
+// searchJSON is the --json output of search
+type searchJSON struct {
+	Formulae []api.SearchResult `json:"formulae"`
+	Casks    []api.SearchResult `json:"casks"`
+}
+
+func runSearch(cfg *config.Config, query string, opts *searchOptions) error {
+	if opts.limit < 0 {
+		return fmt.Errorf("--limit must not be negative")
+	}
+
+	// Search using real API
+	apiClient := api.NewClient(cfg)
+	logger.Step("Searching for %q", query)
+
+	showFormulae := opts.formulae || !opts.casks
+	showCasks := opts.casks || !opts.formulae
+
+	output := searchJSON{Formulae: []api.SearchResult{}, Casks: []api.SearchResult{}}
+
+	if showFormulae {
+		results, err := apiClient.SearchFormulae(query, opts.limit)
+		if err != nil {
+			netErr := errors.NewNetworkError("search", "formulae API", err)
+			logger.LogDetailedError(logger.ErrorContext{
+				Operation:   "search",
+				Error:       netErr,
+				Suggestions: netErr.Suggestions,
+			})
+		}
+		output.Formulae = append(output.Formulae, filterDisabled(results, opts.evalAll)...)
+	}
+
+	if showCasks {
+		results, err := apiClient.SearchCasks(query, opts.limit)
+		if err != nil {
+			logger.Debug("Cask search failed: %v", err)
+		}
+		var casks []api.SearchResult
+		for _, result := range results {
+			casks = append(casks, api.SearchResult{
+				Name:       result.Token,
+				FullName:   result.FullName,
+				Desc:       result.Description,
+				Homepage:   result.Homepage,
+				Deprecated: result.Deprecated,
+				Disabled:   result.Disabled,
+			})
+		}
+		output.Casks = append(output.Casks, filterDisabled(casks, opts.evalAll)...)
+	}
+
+	if opts.jsonOut {
+		data, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode search results: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if showFormulae {
+		fmt.Printf("==> Formulae\n")
+		printSearchResults(output.Formulae, opts.homepage)
+		if len(output.Formulae) == 0 {
+			fmt.Printf("No formulae found matching %q\n", query)
+		}
+	}
+
+	if showCasks {
+		if showFormulae {
+			fmt.Println()
+		}
+		fmt.Printf("==> Casks\n")
+		printSearchResults(output.Casks, opts.homepage)
+		if len(output.Casks) == 0 {
+			fmt.Printf("No casks found matching %q\n", query)
+		}
+	}
+
+	return nil
+}
+
+// filterDisabled drops disabled results unless evalAll is set
+func filterDisabled(results []api.SearchResult, evalAll bool) []api.SearchResult {
+	if evalAll {
+		return results
+	}
+	var kept []api.SearchResult
+	for _, result := range results {
+		if !result.Disabled {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// searchResultLabel returns a result's name with a [deprecated] or
+// [disabled] marker
+func searchResultLabel(result api.SearchResult) string {
+	switch {
+	case result.Disabled:
+		return result.Name + " [disabled]"
+	case result.Deprecated:
+		return result.Name + " [deprecated]"
+	default:
+		return result.Name
+	}
+}
+
+// printSearchResults prints results in columns, or one per line with their
+// homepage
+func printSearchResults(results []api.SearchResult, homepage bool) {
+	if homepage {
+		for _, result := range results {
+			printWithHomepage(searchResultLabel(result), result.Homepage)
+		}
+		return
+	}
+
+	var names []string
+	for _, result := range results {
+		names = append(names, searchResultLabel(result))
+	}
+	printColumnsSearch(names, 4)
+}
+
+// printWithHomepage prints a search result on its own line with its homepage
+func printWithHomepage(name, homepage string) {
+	if homepage == "" {
+		fmt.Println(name)
+		return
+	}
+	fmt.Printf("%s: %s\n", name, homepage)
+}
+
 // printColumnsSearch prints items in columns like the original Homebrew
 func printColumnsSearch(items []string, columns int) {
 	if len(items) == 0 {
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// captureSearch runs a search and returns what it printed to stdout
+func captureSearch(t *testing.T, cfg *config.Config, query string, opts *searchOptions) string {
+	t.Helper()
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runSearch(cfg, query, opts)
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("runSearch() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	return buf.String()
+}
+
+func TestSearchDeprecatedAndDisabled(t *testing.T) {
+	logger.Init(false, false, true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/formula.json":
+			_, _ = w.Write([]byte(`[{"name": "mock-tool"}, {"name": "mock-old"}, {"name": "mock-gone"}]`))
+		case "/formula/mock-tool.json":
+			_, _ = w.Write([]byte(`{"name": "mock-tool", "versions": {"stable": "1.0.0"}}`))
+		case "/formula/mock-old.json":
+			_, _ = w.Write([]byte(`{"name": "mock-old", "versions": {"stable": "1.0.0"}, "deprecated": true}`))
+		case "/formula/mock-gone.json":
+			_, _ = w.Write([]byte(`{"name": "mock-gone", "versions": {"stable": "1.0.0"}, "disabled": true}`))
+		case "/cask.json":
+			_, _ = w.Write([]byte(`[{"token": "mock-app", "version": "1.0.0"}, {"token": "mock-legacy-app", "version": "1.0.0", "disabled": true}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	tests := []struct {
+		name    string
+		evalAll bool
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "disabled hidden by default",
+			want:    []string{"mock-tool", "mock-old [deprecated]", "mock-app"},
+			notWant: []string{"mock-gone", "mock-legacy-app"},
+		},
+		{
+			name:    "eval-all shows disabled",
+			evalAll: true,
+			want:    []string{"mock-tool", "mock-old [deprecated]", "mock-gone [disabled]", "mock-app", "mock-legacy-app [disabled]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{HomebrewCache: t.TempDir()}
+			output := captureSearch(t, cfg, "mock", &searchOptions{limit: 0, evalAll: tt.evalAll})
+
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(output, notWant) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", notWant, output)
+				}
+			}
+		})
+	}
+
+	t.Run("json includes markers", func(t *testing.T) {
+		cfg := &config.Config{HomebrewCache: t.TempDir()}
+		output := captureSearch(t, cfg, "mock", &searchOptions{evalAll: true, jsonOut: true})
+
+		var results searchJSON
+		if err := json.Unmarshal([]byte(output), &results); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v\n%s", err, output)
+		}
+
+		flags := map[string][2]bool{}
+		for _, result := range append(results.Formulae, results.Casks...) {
+			flags[result.Name] = [2]bool{result.Deprecated, result.Disabled}
+		}
+		want := map[string][2]bool{
+			"mock-tool":       {false, false},
+			"mock-old":        {true, false},
+			"mock-gone":       {false, true},
+			"mock-app":        {false, false},
+			"mock-legacy-app": {false, true},
+		}
+		for name, wantFlags := range want {
+			if got, ok := flags[name]; !ok || got != wantFlags {
+				t.Errorf("%s [deprecated disabled] = %v (present %v), want %v", name, got, ok, wantFlags)
+			}
+		}
+	})
+}
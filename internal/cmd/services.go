@@ -1,11 +1,23 @@
 package cmd
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/services"
 	"github.com/spf13/cobra"
 )
 
+// serviceStopTimeout bounds how long restart waits for a service to exit
+const serviceStopTimeout = 30 * time.Second
+
+// newServiceController returns the controller used by services subcommands
+var newServiceController = func(cfg *config.Config) services.Controller {
+	return services.NewManager(cfg)
+}
+
 // NewServicesCmd creates the services command
 func NewServicesCmd(cfg *config.Config) *cobra.Command {
 	cmd := &cobra.Command{
@@ -21,28 +33,113 @@ func NewServicesCmd(cfg *config.Config) *cobra.Command {
 		Use:   "list",
 		Short: "List all managed services",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			logger.Info("Services list not yet implemented")
-			return nil
+			return runServicesList(cfg)
 		},
 	})
 
-	cmd.AddCommand(&cobra.Command{
-		Use:   "start [SERVICE...]",
-		Short: "Start services",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			logger.Info("Services start not yet implemented")
-			return nil
-		},
-	})
+	cmd.AddCommand(newServicesActionCmd(cfg, "start", "Start services and register them to launch at login"))
+	cmd.AddCommand(newServicesActionCmd(cfg, "stop", "Stop services and unregister them"))
+	cmd.AddCommand(newServicesActionCmd(cfg, "run", "Run services without registering them to launch at login"))
+	cmd.AddCommand(newServicesActionCmd(cfg, "restart", "Stop and then start services"))
 
-	cmd.AddCommand(&cobra.Command{
-		Use:   "stop [SERVICE...]",
-		Short: "Stop services",
+	return cmd
+}
+
+func newServicesActionCmd(cfg *config.Config, action, short string) *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   action + " [SERVICE...]",
+		Short: short,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			logger.Info("Services stop not yet implemented")
-			return nil
+			if !all && len(args) == 0 {
+				return fmt.Errorf("%s requires a service name or --all", action)
+			}
+
+			names := args
+			if all {
+				list, err := services.NewManager(cfg).List()
+				if err != nil {
+					return err
+				}
+				names = nil
+				for _, svc := range list {
+					names = append(names, svc.Name)
+				}
+			}
+
+			return runServicesAction(newServiceController(cfg), action, names)
 		},
-	})
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Apply to every managed service")
 
 	return cmd
 }
+
+func runServicesAction(ctrl services.Controller, action string, names []string) error {
+	var failed []string
+
+	for _, name := range names {
+		var err error
+		switch action {
+		case "start":
+			err = ctrl.Start(name)
+		case "stop":
+			err = ctrl.Stop(name)
+		case "run":
+			err = ctrl.Run(name)
+		case "restart":
+			err = services.Restart(ctrl, name, serviceStopTimeout)
+		default:
+			return fmt.Errorf("unknown services action: %s", action)
+		}
+
+		if err != nil {
+			logger.Error("Failed to %s %s: %v", action, name, err)
+			failed = append(failed, name)
+			continue
+		}
+
+		logger.Success("Successfully %s %s", servicesActionPastTense(action), name)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to %s %d service(s)", action, len(failed))
+	}
+
+	return nil
+}
+
+func servicesActionPastTense(action string) string {
+	switch action {
+	case "start":
+		return "started"
+	case "stop":
+		return "stopped"
+	case "run":
+		return "ran"
+	case "restart":
+		return "restarted"
+	}
+	return action
+}
+
+func runServicesList(cfg *config.Config) error {
+	list, err := services.NewManager(cfg).List()
+	if err != nil {
+		return err
+	}
+
+	if len(list) == 0 {
+		logger.Info("No services available to control with `brew services`")
+		return nil
+	}
+
+	fmt.Printf("%-20s %-8s %-12s %s\n", "Name", "Status", "User", "File")
+	for _, svc := range list {
+		fmt.Printf("%-20s %-8s %-12s %s\n", svc.Name, svc.Status, svc.User, svc.File)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+)
+
+type mockServiceController struct {
+	calls   []string
+	running map[string]bool
+	failing map[string]bool
+}
+
+func (m *mockServiceController) Start(name string) error {
+	m.calls = append(m.calls, "start "+name)
+	if m.failing[name] {
+		return fmt.Errorf("start failed")
+	}
+	m.running[name] = true
+	return nil
+}
+
+func (m *mockServiceController) Run(name string) error {
+	m.calls = append(m.calls, "run "+name)
+	m.running[name] = true
+	return nil
+}
+
+func (m *mockServiceController) Stop(name string) error {
+	m.calls = append(m.calls, "stop "+name)
+	m.running[name] = false
+	return nil
+}
+
+func (m *mockServiceController) IsRunning(name string) bool {
+	return m.running[name]
+}
+
+func TestNewServicesCmd(t *testing.T) {
+	cmd := NewServicesCmd(&config.Config{})
+
+	for _, name := range []string{"list", "start", "stop", "run", "restart"} {
+		sub, _, err := cmd.Find([]string{name})
+		if err != nil || sub.Name() != name {
+			t.Errorf("Subcommand %s not found", name)
+			continue
+		}
+		if name != "list" && sub.Flags().Lookup("all") == nil {
+			t.Errorf("Subcommand %s missing --all flag", name)
+		}
+	}
+}
+
+func TestServicesActionRequiresName(t *testing.T) {
+	cmd := NewServicesCmd(&config.Config{})
+	cmd.SetArgs([]string{"restart"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error when no service name or --all is given")
+	}
+}
+
+func TestRunServicesAction(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   string
+		running  map[string]bool
+		expected []string
+	}{
+		{
+			name:     "run",
+			action:   "run",
+			running:  map[string]bool{},
+			expected: []string{"run redis"},
+		},
+		{
+			name:     "restart running service stops first",
+			action:   "restart",
+			running:  map[string]bool{"redis": true},
+			expected: []string{"stop redis", "start redis"},
+		},
+		{
+			name:     "restart stopped service only starts",
+			action:   "restart",
+			running:  map[string]bool{},
+			expected: []string{"start redis"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &mockServiceController{running: tt.running}
+			if err := runServicesAction(ctrl, tt.action, []string{"redis"}); err != nil {
+				t.Fatalf("runServicesAction() error = %v", err)
+			}
+
+			if fmt.Sprint(ctrl.calls) != fmt.Sprint(tt.expected) {
+				t.Errorf("calls = %v, want %v", ctrl.calls, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunServicesActionFailure(t *testing.T) {
+	ctrl := &mockServiceController{
+		running: map[string]bool{},
+		failing: map[string]bool{"redis": true},
+	}
+
+	err := runServicesAction(ctrl, "start", []string{"redis", "postgresql"})
+	if err == nil {
+		t.Fatal("Expected error when a service fails to start")
+	}
+
+	// Remaining services are still processed
+	if len(ctrl.calls) != 2 {
+		t.Errorf("calls = %v, want both services attempted", ctrl.calls)
+	}
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/tap"
@@ -11,10 +13,15 @@ import (
 // NewTapCmd creates the tap command
 func NewTapCmd(cfg *config.Config) *cobra.Command {
 	var (
-		force   bool
-		shallow bool
-		quiet   bool
-		branch  string
+		force     bool
+		shallow   bool
+		quiet     bool
+		branch    string
+		ssh       bool
+		unshallow bool
+		mirrors   []string
+		jsonOut   bool
+		setRemote bool
 	)
 
 	cmd := &cobra.Command{
@@ -23,7 +30,7 @@ func NewTapCmd(cfg *config.Config) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				// List taps
-				return listTaps(cfg)
+				return listTaps(cfg, cmd.OutOrStdout(), jsonOut)
 			}
 
 			tapName := args[0]
@@ -33,11 +40,23 @@ func NewTapCmd(cfg *config.Config) *cobra.Command {
 			}
 
 			tapManager := tap.NewManager(cfg)
+			if setRemote {
+				if remote == "" {
+					return fmt.Errorf("--set-remote requires a tap and a URL")
+				}
+				return tapManager.SetTapRemote(tapName, remote)
+			}
+			if unshallow {
+				return tapManager.Unshallow(tapName)
+			}
+
 			options := &tap.TapOptions{
 				Force:   force,
 				Quiet:   quiet,
 				Shallow: shallow,
 				Branch:  branch,
+				SSH:     ssh,
+				Mirrors: mirrors,
 			}
 
 			return tapManager.AddTap(tapName, remote, options)
@@ -48,19 +67,36 @@ func NewTapCmd(cfg *config.Config) *cobra.Command {
 	cmd.Flags().BoolVar(&shallow, "shallow", false, "Perform a shallow clone")
 	cmd.Flags().BoolVar(&quiet, "quiet", false, "Suppress output")
 	cmd.Flags().StringVar(&branch, "branch", "", "Clone specific branch")
+	cmd.Flags().BoolVar(&ssh, "ssh", false, "Use SSH for the default GitHub remote")
+	cmd.Flags().BoolVar(&unshallow, "unshallow", false, "Fetch the full history of a shallow tap")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "List taps as JSON")
+	cmd.Flags().BoolVar(&setRemote, "set-remote", false, "Point an installed tap at a new remote URL")
+	cmd.Flags().StringSliceVar(&mirrors, "mirror", nil, "Fallback remote to clone from if the primary remote fails (repeatable)")
 
 	return cmd
 }
 
-func listTaps(cfg *config.Config) error {
+func listTaps(cfg *config.Config, w io.Writer, jsonOutput bool) error {
 	tapManager := tap.NewManager(cfg)
 	taps, err := tapManager.ListTaps()
 	if err != nil {
 		return fmt.Errorf("failed to list taps: %w", err)
 	}
 
+	if jsonOutput {
+		if taps == nil {
+			taps = []*tap.Tap{}
+		}
+		data, err := json.MarshalIndent(taps, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal taps to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
 	for _, t := range taps {
-		fmt.Println(t.Name)
+		_, _ = fmt.Fprintln(w, t.Name)
 	}
 
 	return nil
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/tap"
+)
+
+func TestTapJSON(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{HomebrewRepository: tempDir}
+
+	runTapJSON := func() []*tap.Tap {
+		t.Helper()
+		var out bytes.Buffer
+		cmd := NewTapCmd(cfg)
+		cmd.SetOut(&out)
+		cmd.SetArgs([]string{"--json"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("tap --json error = %v", err)
+		}
+		var taps []*tap.Tap
+		if err := json.Unmarshal(out.Bytes(), &taps); err != nil {
+			t.Fatalf("Invalid JSON %q: %v", out.String(), err)
+		}
+		if taps == nil {
+			t.Fatalf("Expected a JSON array, got %q", out.String())
+		}
+		return taps
+	}
+
+	if taps := runTapJSON(); len(taps) != 0 {
+		t.Errorf("Expected no taps, got %+v", taps)
+	}
+
+	tapPath := filepath.Join(tempDir, "Library", "Taps", "homebrew", "homebrew-core")
+	for _, file := range []string{"Formula/a.rb", "Formula/b.rb", "Casks/c.rb"} {
+		path := filepath.Join(tapPath, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("class A < Formula\nend\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo, err := git.PlainInit(tapPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := "https://github.com/Homebrew/homebrew-core"
+	if _, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+		t.Fatal(err)
+	}
+
+	taps := runTapJSON()
+	if len(taps) != 1 {
+		t.Fatalf("Expected one tap, got %+v", taps)
+	}
+	got := taps[0]
+	if got.Name != "homebrew/core" || got.Remote != remote || !got.Official || !got.Installed {
+		t.Errorf("Unexpected tap %+v", got)
+	}
+	if got.Formulae != 2 || got.Casks != 1 {
+		t.Errorf("Expected 2 formulae and 1 cask, got %d and %d", got.Formulae, got.Casks)
+	}
+	if !strings.HasSuffix(got.Path, filepath.Join("homebrew", "homebrew-core")) {
+		t.Errorf("Unexpected tap path %s", got.Path)
+	}
+}
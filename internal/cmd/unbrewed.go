@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
+)
+
+// unbrewedSkipDirs are prefix directories that belong to Homebrew itself
+var unbrewedSkipDirs = []string{
+	".git",
+	"Caskroom",
+	"Cellar",
+	"Homebrew",
+	"Library",
+	"opt",
+	filepath.Join("var", "homebrew"),
+}
+
+// listUnbrewed returns prefix files and symlinks not created by an installed
+// keg or cask, relative to the prefix and sorted
+func listUnbrewed(cfg *config.Config) ([]string, error) {
+	prefix := filepath.Clean(cfg.HomebrewPrefix)
+
+	skip := make(map[string]bool)
+	for _, dir := range unbrewedSkipDirs {
+		skip[filepath.Join(prefix, dir)] = true
+	}
+	for _, dir := range []string{cfg.HomebrewCellar, cfg.HomebrewCaskroom, cfg.HomebrewCache, cfg.HomebrewLogs, cfg.HomebrewTemp} {
+		if dir != "" {
+			skip[filepath.Clean(dir)] = true
+		}
+	}
+	if repo := filepath.Clean(cfg.HomebrewRepository); cfg.HomebrewRepository != "" && repo != prefix {
+		skip[repo] = true
+	}
+
+	// Links into any of these were created by brew
+	managedRoots := []string{cfg.HomebrewCellar, cfg.HomebrewCaskroom, filepath.Join(cfg.HomebrewCache, "cask", "extract")}
+
+	var unbrewed []string
+	err := filepath.WalkDir(prefix, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsPermission(err) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if path == prefix {
+			return nil
+		}
+		if d.IsDir() {
+			if skip[path] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if d.Name() == ".last_update" && filepath.Dir(path) == prefix {
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 && isManagedLink(path, managedRoots) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(prefix, path)
+		if err != nil {
+			return err
+		}
+		unbrewed = append(unbrewed, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk prefix: %w", err)
+	}
+
+	sort.Strings(unbrewed)
+	return unbrewed, nil
+}
+
+func isManagedLink(path string, roots []string) bool {
+	for _, root := range roots {
+		if root != "" && installer.ClassifyPrefixPath(path, root) == installer.PathManagedSymlink {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+)
+
+func TestListUnbrewed(t *testing.T) {
+	prefix := t.TempDir()
+	cfg := &config.Config{
+		HomebrewPrefix:   prefix,
+		HomebrewCellar:   filepath.Join(prefix, "Cellar"),
+		HomebrewCaskroom: filepath.Join(prefix, "Caskroom"),
+	}
+
+	kegBinary := filepath.Join(cfg.HomebrewCellar, "wget", "1.21", "bin", "wget")
+	for _, dir := range []string{filepath.Dir(kegBinary), filepath.Join(prefix, "bin")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(kegBinary, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(kegBinary, filepath.Join(prefix, "bin", "wget")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(prefix, "bin", "my-script"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := listUnbrewed(cfg)
+	if err != nil {
+		t.Fatalf("listUnbrewed() error = %v", err)
+	}
+
+	expected := []string{filepath.Join("bin", "my-script")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("listUnbrewed() = %v, want %v", files, expected)
+	}
+}
@@ -1,12 +1,13 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/pilshchikov/homebrew-go/internal/api"
+	"github.com/pilshchikov/homebrew-go/internal/cask"
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 	"github.com/spf13/cobra"
@@ -15,9 +16,11 @@ import (
 // NewUninstallCmd creates the uninstall command
 func NewUninstallCmd(cfg *config.Config) *cobra.Command {
 	var (
-		force      bool
-		ignoreDeps bool
-		zap        bool
+		force       bool
+		ignoreDeps  bool
+		zap         bool
+		formulaOnly bool
+		caskOnly    bool
 	)
 
 	cmd := &cobra.Command{
@@ -25,11 +28,14 @@ func NewUninstallCmd(cfg *config.Config) *cobra.Command {
 		Aliases: []string{"remove", "rm"},
 		Short:   "Uninstall a formula or cask",
 		Args:    cobra.MinimumNArgs(1),
+		PreRunE: exclusiveFlags(formulaCaskFlags...),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runUninstall(cfg, args, &uninstallOptions{
-				Force:      force,
-				IgnoreDeps: ignoreDeps,
-				Zap:        zap,
+				Force:       force,
+				IgnoreDeps:  ignoreDeps,
+				Zap:         zap,
+				FormulaOnly: formulaOnly,
+				CaskOnly:    caskOnly,
 			})
 		},
 	}
@@ -37,14 +43,18 @@ func NewUninstallCmd(cfg *config.Config) *cobra.Command {
 	cmd.Flags().BoolVar(&force, "force", false, "Delete all installed versions")
 	cmd.Flags().BoolVar(&ignoreDeps, "ignore-dependencies", false, "Don't fail uninstall if dependencies would be left")
 	cmd.Flags().BoolVar(&zap, "zap", false, "Remove all files associated with a cask")
+	cmd.Flags().BoolVar(&formulaOnly, "formula", false, "Treat all named arguments as formulae")
+	cmd.Flags().BoolVar(&caskOnly, "cask", false, "Treat all named arguments as casks")
 
 	return cmd
 }
 
 type uninstallOptions struct {
-	Force      bool
-	IgnoreDeps bool
-	Zap        bool
+	Force       bool
+	IgnoreDeps  bool
+	Zap         bool
+	FormulaOnly bool
+	CaskOnly    bool
 }
 
 func runUninstall(cfg *config.Config, args []string, opts *uninstallOptions) error {
@@ -52,69 +62,107 @@ func runUninstall(cfg *config.Config, args []string, opts *uninstallOptions) err
 		return fmt.Errorf("no formulae specified for uninstall")
 	}
 
-	for _, formulaName := range args {
-		logger.PrintHeader(fmt.Sprintf("Uninstalling: %s", formulaName))
+	if !opts.IgnoreDeps && !opts.CaskOnly {
+		if err := checkDependents(cfg, args); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range args {
+		logger.PrintHeader(fmt.Sprintf("Uninstalling: %s", name))
 
-		// Check if formula is installed
-		logger.Step("Checking if %s is installed", formulaName)
-		installed, err := isFormulaInstalled(cfg, formulaName)
+		logger.Step("Checking if %s is installed", name)
+		formulaInstalled, err := isFormulaInstalled(cfg, name)
 		if err != nil {
-			return fmt.Errorf("failed to check if %s is installed: %w", formulaName, err)
+			return fmt.Errorf("failed to check if %s is installed: %w", name, err)
+		}
+		caskInstalled := isCaskInstalled(cfg, name)
+
+		var uninstallErr error
+		switch {
+		case opts.FormulaOnly:
+			if !formulaInstalled {
+				uninstallErr = fmt.Errorf("formula %s is not installed", name)
+				break
+			}
+			uninstallErr = uninstallFormula(cfg, name, opts)
+		case opts.CaskOnly:
+			if !caskInstalled {
+				uninstallErr = fmt.Errorf("cask %s is not installed", name)
+				break
+			}
+			uninstallErr = uninstallCask(cfg, name, opts)
+		case formulaInstalled && caskInstalled:
+			return fmt.Errorf("%s is installed as both a formula and a cask; use --formula or --cask to choose which to uninstall", name)
+		case formulaInstalled:
+			uninstallErr = uninstallFormula(cfg, name, opts)
+		case caskInstalled:
+			uninstallErr = uninstallCask(cfg, name, opts)
+		default:
+			uninstallErr = fmt.Errorf("no installed keg or cask with the name %q", name)
 		}
 
-		if !installed {
-			if opts.Force {
-				logger.Warn("Formula %s is not installed", formulaName)
+		if uninstallErr != nil {
+			if opts.Force && !formulaInstalled && !caskInstalled {
+				logger.Warn("%v", uninstallErr)
 				continue
-			} else {
-				return fmt.Errorf("formula %s is not installed", formulaName)
 			}
+			return uninstallErr
 		}
 
-		// Get installed version info
-		version, err := getInstalledVersion(cfg, formulaName)
-		if err == nil && version != "" {
-			logger.Info("Found installed version: %s", version)
-		}
+		logger.Success("Successfully uninstalled %s", name)
+	}
 
-		// Check for dependents if not ignoring dependencies
-		if !opts.IgnoreDeps {
-			logger.Step("Checking for dependents")
-			dependents, err := findDependents(cfg, formulaName)
-			if err != nil {
-				return fmt.Errorf("failed to find dependents of %s: %w", formulaName, err)
-			}
+	return nil
+}
 
-			if len(dependents) > 0 {
-				logger.Warn("Formula %s is required by: %s", formulaName, strings.Join(dependents, ", "))
-				return fmt.Errorf("cannot uninstall %s because it is required by: %s",
-					formulaName, strings.Join(dependents, ", "))
-			} else {
-				logger.Debug("No dependents found")
-			}
-		}
+// isCaskInstalled reports whether a cask has a directory in the caskroom
+func isCaskInstalled(cfg *config.Config, name string) bool {
+	info, err := os.Stat(filepath.Join(cfg.HomebrewCaskroom, name))
+	return err == nil && info.IsDir()
+}
 
-		// Unlink formula
-		logger.Step("Unlinking %s", formulaName)
-		if err := unlinkFormulaUninstall(cfg, formulaName); err != nil {
-			logger.Warn("Failed to unlink %s: %v", formulaName, err)
-		} else {
-			logger.Debug("Successfully unlinked %s", formulaName)
-		}
+func uninstallFormula(cfg *config.Config, formulaName string, opts *uninstallOptions) error {
+	// Get installed version info
+	version, err := getInstalledVersion(cfg, formulaName)
+	if err == nil && version != "" {
+		logger.Info("Found installed version: %s", version)
+	}
 
-		// Remove formula directory
-		logger.Step("Removing %s files", formulaName)
-		if err := removeFormula(cfg, formulaName); err != nil {
-			return fmt.Errorf("failed to remove %s: %w", formulaName, err)
-		}
-		logger.Debug("Removed installation directory")
+	// Unlink formula
+	logger.Step("Unlinking %s", formulaName)
+	if err := unlinkFormulaUninstall(cfg, formulaName); err != nil {
+		logger.Warn("Failed to unlink %s: %v", formulaName, err)
+	} else {
+		logger.Debug("Successfully unlinked %s", formulaName)
+	}
+	reportOverwritten(cfg, formulaName)
 
-		logger.Success("Successfully uninstalled %s", formulaName)
+	// Remove formula directory
+	logger.Step("Removing %s files", formulaName)
+	if err := removeFormula(cfg, formulaName); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", formulaName, err)
 	}
+	logger.Debug("Removed installation directory")
 
 	return nil
 }
 
+func uninstallCask(cfg *config.Config, token string, opts *uninstallOptions) error {
+	// Uninstall stanzas come from the API; fall back to the caskroom alone
+	caskData, err := api.NewClient(cfg).GetCask(token)
+	if err != nil {
+		logger.Debug("Failed to fetch cask %s, removing caskroom only: %v", token, err)
+		caskData = &cask.Cask{Token: token}
+	}
+
+	caskInstaller := cask.NewCaskInstaller(cfg)
+	return caskInstaller.UninstallCask(caskData, &cask.CaskInstallOptions{
+		Force:  opts.Force,
+		DryRun: cfg.DryRun,
+	})
+}
+
 func getInstalledVersion(cfg *config.Config, formulaName string) (string, error) {
 	formulaDir := filepath.Join(cfg.HomebrewCellar, formulaName)
 	entries, err := os.ReadDir(formulaDir)
@@ -132,59 +180,68 @@ func getInstalledVersion(cfg *config.Config, formulaName string) (string, error)
 	return "", fmt.Errorf("no version directory found")
 }
 
-func findDependents(cfg *config.Config, formulaName string) ([]string, error) {
-	var dependents []string
-
-	// Read all installed formulae
-	files, err := os.ReadDir(cfg.HomebrewCellar)
+// checkDependents refuses to remove formulae that installed formulae still
+// depend on. Dependents that are being uninstalled along with them don't count.
+func checkDependents(cfg *config.Config, names []string) error {
+	logger.Step("Checking for dependents")
+	reverse, err := reverseDependencies(cfg)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to find dependents: %w", err)
 	}
 
-	for _, file := range files {
-		if !file.IsDir() || file.Name() == formulaName {
+	removing := make(map[string]bool, len(names))
+	for _, name := range names {
+		removing[name] = true
+	}
+
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, name)); err != nil {
 			continue
 		}
-
-		// Check if this formula depends on the one we want to uninstall
-		if depends, err := checkDependency(cfg, file.Name(), formulaName); err == nil && depends {
-			dependents = append(dependents, file.Name())
+		var dependents []string
+		for _, dependent := range reverse[name] {
+			if !removing[dependent] {
+				dependents = append(dependents, dependent)
+			}
+		}
+		if len(dependents) > 0 {
+			return fmt.Errorf("cannot uninstall %s because it is required by: %s; use --ignore-dependencies to remove it anyway",
+				name, strings.Join(dependents, ", "))
 		}
 	}
 
-	return dependents, nil
+	logger.Debug("No dependents found")
+	return nil
 }
 
-func checkDependency(cfg *config.Config, installedFormula, dependency string) (bool, error) {
-	// Read install receipt to check dependencies
-	receiptPath := filepath.Join(cfg.HomebrewCellar, installedFormula)
+// reverseDependencies maps each formula to the installed formulae whose
+// receipts list it as a runtime dependency, in Cellar order
+func reverseDependencies(cfg *config.Config) (map[string][]string, error) {
+	reverse := make(map[string][]string)
 
-	// Find version directory
-	versionDirs, err := os.ReadDir(receiptPath)
+	formulae, err := os.ReadDir(cfg.HomebrewCellar)
 	if err != nil {
-		return false, err
+		if os.IsNotExist(err) {
+			return reverse, nil
+		}
+		return nil, err
 	}
 
-	for _, versionDir := range versionDirs {
-		if versionDir.IsDir() {
-			receiptFile := filepath.Join(receiptPath, versionDir.Name(), "INSTALL_RECEIPT.json")
-			if data, err := os.ReadFile(receiptFile); err == nil {
-				var receipt struct {
-					Dependencies []string `json:"dependencies"`
-				}
-				if json.Unmarshal(data, &receipt) == nil {
-					for _, dep := range receipt.Dependencies {
-						if dep == dependency {
-							return true, nil
-						}
-					}
-				}
-			}
-			break // Only check first version directory
+	for _, entry := range formulae {
+		if !entry.IsDir() {
+			continue
+		}
+		deps, err := getFormulaDependencies(cfg, entry.Name())
+		if err != nil {
+			logger.Debug("Failed to get dependencies for %s: %v", entry.Name(), err)
+			continue
+		}
+		for _, dep := range deps {
+			reverse[dep] = append(reverse[dep], entry.Name())
 		}
 	}
 
-	return false, nil
+	return reverse, nil
 }
 
 func unlinkFormulaUninstall(cfg *config.Config, formulaName string) error {
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/cask"
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func setupUninstallTest(t *testing.T) *config.Config {
+	t.Helper()
+	logger.Init(false, false, true)
+
+	// Cask metadata lookups fail fast so uninstall falls back to the caskroom
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewPrefix:   tmpDir,
+		HomebrewCellar:   filepath.Join(tmpDir, "Cellar"),
+		HomebrewCaskroom: filepath.Join(tmpDir, "Caskroom"),
+	}
+	_ = os.MkdirAll(cfg.HomebrewCellar, 0755)
+	_ = os.MkdirAll(cfg.HomebrewCaskroom, 0755)
+
+	return cfg
+}
+
+func installFakeFormula(t *testing.T, cfg *config.Config, name string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(cfg.HomebrewCellar, name, "1.0.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func installFakeCask(t *testing.T, cfg *config.Config, token string) {
+	t.Helper()
+	receipt := &cask.CaskReceipt{Token: token, Version: "1.0.0", InstalledOn: time.Now()}
+	if err := receipt.Write(cfg.HomebrewCaskroom); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunUninstallFormulaOnly(t *testing.T) {
+	cfg := setupUninstallTest(t)
+	installFakeFormula(t, cfg, "wget")
+
+	if err := runUninstall(cfg, []string{"wget"}, &uninstallOptions{}); err != nil {
+		t.Fatalf("runUninstall() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "wget")); !os.IsNotExist(err) {
+		t.Error("Expected formula to be removed from the cellar")
+	}
+}
+
+func TestRunUninstallCaskOnly(t *testing.T) {
+	cfg := setupUninstallTest(t)
+	installFakeCask(t, cfg, "firefox")
+
+	if err := runUninstall(cfg, []string{"firefox"}, &uninstallOptions{}); err != nil {
+		t.Fatalf("runUninstall() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCaskroom, "firefox")); !os.IsNotExist(err) {
+		t.Error("Expected cask to be removed from the caskroom")
+	}
+}
+
+func TestRunUninstallAmbiguous(t *testing.T) {
+	cfg := setupUninstallTest(t)
+	installFakeFormula(t, cfg, "docker")
+	installFakeCask(t, cfg, "docker")
+
+	err := runUninstall(cfg, []string{"docker"}, &uninstallOptions{})
+	if err == nil || !strings.Contains(err.Error(), "--formula or --cask") {
+		t.Fatalf("Expected ambiguity error with guidance, got %v", err)
+	}
+
+	// Nothing is removed when the name is ambiguous
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "docker")); err != nil {
+		t.Error("Formula should not be removed on ambiguous uninstall")
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCaskroom, "docker")); err != nil {
+		t.Error("Cask should not be removed on ambiguous uninstall")
+	}
+
+	// An explicit --cask resolves the ambiguity
+	if err := runUninstall(cfg, []string{"docker"}, &uninstallOptions{CaskOnly: true}); err != nil {
+		t.Fatalf("runUninstall(--cask) error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCaskroom, "docker")); !os.IsNotExist(err) {
+		t.Error("Expected cask to be removed with --cask")
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "docker")); err != nil {
+		t.Error("Formula should be kept when uninstalling with --cask")
+	}
+}
+
+func TestRunUninstallNotInstalled(t *testing.T) {
+	cfg := setupUninstallTest(t)
+
+	err := runUninstall(cfg, []string{"missing"}, &uninstallOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no installed keg or cask") {
+		t.Fatalf("Expected not-installed error, got %v", err)
+	}
+
+	if err := runUninstall(cfg, []string{"missing"}, &uninstallOptions{FormulaOnly: true}); err == nil {
+		t.Error("Expected error for --formula with a name that is not installed")
+	}
+
+	// --force downgrades the error to a warning
+	if err := runUninstall(cfg, []string{"missing"}, &uninstallOptions{Force: true}); err != nil {
+		t.Errorf("Expected no error with --force, got %v", err)
+	}
+}
+
+func TestUninstallFormulaCaskFlagsExclusive(t *testing.T) {
+	cfg := setupUninstallTest(t)
+	cmd := NewUninstallCmd(cfg)
+	cmd.SetArgs([]string{"--formula", "--cask", "wget"})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error when both --formula and --cask are given")
+	}
+}
+
+func TestRunUninstallBlockedByDependents(t *testing.T) {
+	cfg := setupUninstallTest(t)
+	installFakeFormula(t, cfg, "b")
+	installFakeFormula(t, cfg, "a")
+	receipt := `{"name": "a", "version": "1.0.0", "dependencies": ["homebrew/core/b"]}`
+	if err := os.WriteFile(filepath.Join(cfg.HomebrewCellar, "a", "1.0.0", "INSTALL_RECEIPT.json"), []byte(receipt), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runUninstall(cfg, []string{"b"}, &uninstallOptions{})
+	if err == nil || !strings.Contains(err.Error(), "required by: a") {
+		t.Fatalf("Expected error listing dependent a, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "b")); err != nil {
+		t.Error("Dependency should not be removed while a dependent is installed")
+	}
+
+	if err := runUninstall(cfg, []string{"b"}, &uninstallOptions{IgnoreDeps: true}); err != nil {
+		t.Fatalf("runUninstall(--ignore-dependencies) error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "b")); !os.IsNotExist(err) {
+		t.Error("Expected b to be removed with --ignore-dependencies")
+	}
+}
+
+func TestRunUninstallWithDependentsTogether(t *testing.T) {
+	cfg := setupUninstallTest(t)
+	installFakeFormula(t, cfg, "b")
+	installFakeFormula(t, cfg, "a")
+	receipt := `{"name": "a", "version": "1.0.0", "dependencies": ["b"]}`
+	if err := os.WriteFile(filepath.Join(cfg.HomebrewCellar, "a", "1.0.0", "INSTALL_RECEIPT.json"), []byte(receipt), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Removing a dependent in the same command frees its dependency
+	if err := runUninstall(cfg, []string{"b", "a"}, &uninstallOptions{}); err != nil {
+		t.Fatalf("runUninstall() error = %v", err)
+	}
+}
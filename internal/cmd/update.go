@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/pilshchikov/homebrew-go/internal/api"
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 	"github.com/pilshchikov/homebrew-go/internal/tap"
@@ -22,18 +23,8 @@ func NewUpdateCmd(cfg *config.Config) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			logger.Progress("Updating Homebrew")
 
-			// Update taps
-			tapManager := tap.NewManager(cfg)
-			taps, err := tapManager.ListTaps()
-			if err != nil {
-				return fmt.Errorf("failed to list taps: %w", err)
-			}
-
-			for _, t := range taps {
-				logger.Step("Updating tap %s", t.Name)
-				if err := tapManager.UpdateTap(t.Name); err != nil {
-					logger.Warn("Failed to update tap %s: %v", t.Name, err)
-				}
+			if err := runUpdate(cfg); err != nil {
+				return err
 			}
 
 			logger.Success("Updated Homebrew")
@@ -46,3 +37,26 @@ func NewUpdateCmd(cfg *config.Config) *cobra.Command {
 
 	return cmd
 }
+
+// runUpdate refreshes the API cache, pulls every tap and records the update time
+func runUpdate(cfg *config.Config) error {
+	if err := api.NewClient(cfg).RefreshCache(); err != nil {
+		logger.Warn("Failed to refresh API cache: %v", err)
+	}
+
+	tapManager := tap.NewManager(cfg)
+	taps, err := tapManager.ListTaps()
+	if err != nil {
+		return fmt.Errorf("failed to list taps: %w", err)
+	}
+
+	for _, t := range taps {
+		logger.Step("Updating tap %s", t.Name)
+		if err := tapManager.UpdateTap(t.Name); err != nil {
+			logger.Warn("Failed to update tap %s: %v", t.Name, err)
+		}
+	}
+
+	updateLastUpdateTime(cfg)
+	return nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestInstallAutoUpdate(t *testing.T) {
+	logger.Init(false, false, true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		noAutoUpdate string
+		lastUpdate   time.Duration
+		expected     bool
+	}{
+		{
+			name:       "stale timestamp triggers update",
+			lastUpdate: 48 * time.Hour,
+			expected:   true,
+		},
+		{
+			name:       "recent update is skipped",
+			lastUpdate: time.Minute,
+			expected:   false,
+		},
+		{
+			name:         "HOMEBREW_NO_AUTO_UPDATE disables update",
+			noAutoUpdate: "1",
+			lastUpdate:   48 * time.Hour,
+			expected:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			t.Setenv("HOMEBREW_PREFIX", tempDir)
+			t.Setenv("HOMEBREW_CACHE", filepath.Join(tempDir, "cache"))
+			t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+			t.Setenv("HOMEBREW_NO_AUTO_UPDATE", tt.noAutoUpdate)
+			t.Setenv("CI", "")
+
+			cfg, err := config.New()
+			if err != nil {
+				t.Fatalf("config.New() error = %v", err)
+			}
+
+			updateLastUpdateTime(cfg)
+			stamp := time.Now().Add(-tt.lastUpdate)
+			if err := os.Chtimes(filepath.Join(tempDir, ".last_update"), stamp, stamp); err != nil {
+				t.Fatal(err)
+			}
+
+			updated := false
+			original := autoUpdateFunc
+			autoUpdateFunc = func(cfg *config.Config) error {
+				updated = true
+				return nil
+			}
+			defer func() { autoUpdateFunc = original }()
+
+			cmd := NewInstallCmd(cfg)
+			cmd.SetArgs([]string{"nonexistent-formula"})
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+			_ = cmd.Execute()
+
+			if updated != tt.expected {
+				t.Errorf("auto-update triggered = %v, want %v", updated, tt.expected)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -18,15 +19,18 @@ func NewUpgradeCmd(cfg *config.Config) *cobra.Command {
 		Use:   "upgrade [FORMULA|CASK...]",
 		Short: "Upgrade formulae and casks",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runUpgrade(cfg, args)
+			checkForUpdates(cfg)
+
+			return runUpgrade(cmd.Context(), cfg, args)
 		},
 	}
 
 	return cmd
 }
 
-func runUpgrade(cfg *config.Config, args []string) error {
+func runUpgrade(ctx context.Context, cfg *config.Config, args []string) error {
 	apiClient := api.NewClient(cfg)
+	apiClient.SetContext(ctx)
 
 	if len(args) == 0 {
 		// Upgrade all outdated formulae
@@ -72,7 +76,7 @@ func runUpgrade(cfg *config.Config, args []string) error {
 				return fmt.Errorf("failed to get latest version of %s: %w", formulaName, err)
 			}
 
-			if currentVersion == latestFormula.Version {
+			if currentVersion == latestFormula.Version && !isRebuildOutdated(cfg, formulaName, currentVersion, latestFormula) {
 				logger.Info("Formula %s is already up to date (%s)", formulaName, currentVersion)
 				continue
 			}
@@ -86,7 +90,7 @@ func runUpgrade(cfg *config.Config, args []string) error {
 		}
 
 		// Install new version
-		if err := installFormula(cfg, formulaName); err != nil {
+		if err := installFormula(ctx, cfg, formulaName); err != nil {
 			return fmt.Errorf("failed to install %s: %w", formulaName, err)
 		}
 
@@ -127,7 +131,7 @@ func findOutdatedFormulae(cfg *config.Config, apiClient *api.Client) ([]string,
 		}
 
 		// Compare versions
-		if currentVersion != latestFormula.Version {
+		if currentVersion != latestFormula.Version || isRebuildOutdated(cfg, formulaName, currentVersion, latestFormula) {
 			logger.Debug("Found outdated formula: %s (%s -> %s)", formulaName, currentVersion, latestFormula.Version)
 			outdated = append(outdated, formulaName)
 		}
@@ -136,7 +140,7 @@ func findOutdatedFormulae(cfg *config.Config, apiClient *api.Client) ([]string,
 	return outdated, nil
 }
 
-func installFormula(cfg *config.Config, formulaName string) error {
+func installFormula(ctx context.Context, cfg *config.Config, formulaName string) error {
 	// Use the install command functionality
 	opts := &installer.Options{
 		BuildFromSource:    false,
@@ -153,6 +157,7 @@ func installFormula(cfg *config.Config, formulaName string) error {
 	}
 
 	inst := installer.New(cfg, opts)
+	inst.SetContext(ctx)
 	_, err := inst.InstallFormula(formulaName)
 	return err
 }
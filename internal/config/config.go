@@ -26,15 +26,30 @@ type Config struct {
 	Verbose                    bool
 	Quiet                      bool
 	AutoUpdate                 bool
+	AutoUpdateSecs             int
 	InstallCleanup             bool
 	NoInstallUpgrade           bool
 	NoInstalledDependentsCheck bool
 	DisplayInstallTimes        bool
 	ForceBottle                bool
+	NoBottleSourceFallback     bool
 	BuildFromSource            bool
+	BuildTimeout               int
+	EnvFilter                  []string
 	KeepTmp                    bool
+	RequireSHA                 bool
+	StrictVerification         bool
 	Force                      bool
 	DryRun                     bool
+	NoCache                    bool
+
+	// Arch overrides the architecture used to select bottles and resolve
+	// dependencies (arm64 or x86_64); empty means the host's
+	Arch string
+
+	// CaskOpts are default cask install options from HOMEBREW_CASK_OPTS,
+	// such as --language=de
+	CaskOpts []string
 
 	// Development flags
 	Developer              bool
@@ -48,6 +63,20 @@ type Config struct {
 	APIAllowlist       []string
 	APIBlocklist       []string
 
+	// UserAgent replaces the User-Agent sent with API requests; AppVersion
+	// is the build version used in the default one
+	UserAgent  string
+	AppVersion string
+
+	// RequestID adds a random X-Request-ID header to API requests so they
+	// can be matched with server logs
+	RequestID bool
+
+	// Git settings
+	GitRemoteRewrite map[string]string
+	GitUseSSH        bool
+	GitCloneRetries  int
+
 	// Analytics
 	NoAnalytics       bool
 	NoGoogleAnalytics bool
@@ -62,8 +91,10 @@ func New() (*Config, error) {
 	cfg := &Config{
 		// Default values
 		AutoUpdate:         true,
+		AutoUpdateSecs:     86400,
 		InstallCleanup:     true,
 		CurlRetries:        3,
+		GitCloneRetries:    3,
 		CurlConnectTimeout: 5,
 		CurlMaxTime:        0,
 	}
@@ -73,6 +104,10 @@ func New() (*Config, error) {
 		return nil, fmt.Errorf("failed to set paths: %w", err)
 	}
 
+	if err := cfg.expandPaths(); err != nil {
+		return nil, err
+	}
+
 	// Load environment variables
 	cfg.loadFromEnv()
 
@@ -163,20 +198,84 @@ func (c *Config) setPaths() error {
 	return nil
 }
 
+// expandPaths expands ~ and environment variables in path settings and
+// requires each of them to be absolute
+func (c *Config) expandPaths() error {
+	paths := []struct {
+		name  string
+		value *string
+	}{
+		{"HOMEBREW_PREFIX", &c.HomebrewPrefix},
+		{"HOMEBREW_REPOSITORY", &c.HomebrewRepository},
+		{"HOMEBREW_LIBRARY", &c.HomebrewLibrary},
+		{"HOMEBREW_CELLAR", &c.HomebrewCellar},
+		{"HOMEBREW_CASKROOM", &c.HomebrewCaskroom},
+		{"HOMEBREW_CACHE", &c.HomebrewCache},
+		{"HOMEBREW_LOGS", &c.HomebrewLogs},
+		{"HOMEBREW_TEMP", &c.HomebrewTemp},
+	}
+
+	for _, p := range paths {
+		if *p.value == "" {
+			continue
+		}
+
+		expanded, err := expandPath(*p.value)
+		if err != nil {
+			return fmt.Errorf("failed to expand %s: %w", p.name, err)
+		}
+		if !filepath.IsAbs(expanded) {
+			return fmt.Errorf("%s must be an absolute path, got %q", p.name, *p.value)
+		}
+		*p.value = filepath.Clean(expanded)
+	}
+
+	return nil
+}
+
+// expandPath replaces a leading ~ with the home directory and expands $VARS
+func expandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get user home directory: %w", err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+
+	return path, nil
+}
+
 func (c *Config) loadFromEnv() {
 	// Behavior flags
 	c.Debug = getBoolEnv("HOMEBREW_DEBUG", c.Debug)
 	c.Verbose = getBoolEnv("HOMEBREW_VERBOSE", c.Verbose)
 	c.Quiet = getBoolEnv("HOMEBREW_QUIET", c.Quiet)
 	c.AutoUpdate = getBoolEnv("HOMEBREW_AUTO_UPDATE", c.AutoUpdate)
+	c.AutoUpdateSecs = getIntEnv("HOMEBREW_AUTO_UPDATE_SECS", c.AutoUpdateSecs)
 	c.InstallCleanup = getBoolEnv("HOMEBREW_INSTALL_CLEANUP", c.InstallCleanup)
 	c.NoInstallUpgrade = getBoolEnv("HOMEBREW_NO_INSTALL_UPGRADE", c.NoInstallUpgrade)
 	c.NoInstalledDependentsCheck = getBoolEnv("HOMEBREW_NO_INSTALLED_DEPENDENTS_CHECK", c.NoInstalledDependentsCheck)
 	c.DisplayInstallTimes = getBoolEnv("HOMEBREW_DISPLAY_INSTALL_TIMES", c.DisplayInstallTimes)
 	c.ForceBottle = getBoolEnv("HOMEBREW_FORCE_BOTTLE", c.ForceBottle)
+	c.NoBottleSourceFallback = getBoolEnv("HOMEBREW_NO_BOTTLE_SOURCE_FALLBACK", c.NoBottleSourceFallback)
 	c.BuildFromSource = getBoolEnv("HOMEBREW_BUILD_FROM_SOURCE", c.BuildFromSource)
+	c.BuildTimeout = getIntEnv("HOMEBREW_BUILD_TIMEOUT", c.BuildTimeout)
+	if filter := os.Getenv("HOMEBREW_ENV_FILTER"); filter != "" {
+		c.EnvFilter = strings.Split(filter, ",")
+	}
 	c.KeepTmp = getBoolEnv("HOMEBREW_KEEP_TMP", c.KeepTmp)
+	c.RequireSHA = getBoolEnv("HOMEBREW_REQUIRE_SHA", c.RequireSHA)
+	c.StrictVerification = getBoolEnv("HOMEBREW_STRICT_VERIFICATION", c.StrictVerification)
 	c.Force = getBoolEnv("HOMEBREW_FORCE", c.Force)
+	if arch := os.Getenv("HOMEBREW_ARCH"); arch != "" {
+		c.Arch = arch
+	}
+	if caskOpts := os.Getenv("HOMEBREW_CASK_OPTS"); caskOpts != "" {
+		c.CaskOpts = strings.Fields(caskOpts)
+	}
 
 	// Development flags
 	c.Developer = getBoolEnv("HOMEBREW_DEVELOPER", c.Developer)
@@ -195,6 +294,17 @@ func (c *Config) loadFromEnv() {
 	if blocklist := os.Getenv("HOMEBREW_API_BLOCKLIST"); blocklist != "" {
 		c.APIBlocklist = strings.Split(blocklist, ",")
 	}
+	if userAgent := os.Getenv("HOMEBREW_USER_AGENT"); userAgent != "" {
+		c.UserAgent = userAgent
+	}
+	c.RequestID = getBoolEnv("HOMEBREW_API_REQUEST_ID", c.RequestID)
+
+	// Git settings
+	if rewrite := os.Getenv("HOMEBREW_GIT_REMOTE_REWRITE"); rewrite != "" {
+		c.GitRemoteRewrite = parseRewriteRules(rewrite)
+	}
+	c.GitUseSSH = getBoolEnv("HOMEBREW_GIT_USE_SSH", c.GitUseSSH)
+	c.GitCloneRetries = getIntEnv("HOMEBREW_GIT_CLONE_RETRIES", c.GitCloneRetries)
 
 	// Analytics
 	c.NoAnalytics = getBoolEnv("HOMEBREW_NO_ANALYTICS", c.NoAnalytics)
@@ -205,6 +315,18 @@ func (c *Config) loadFromEnv() {
 	c.GithubHostedRunner = getBoolEnv("HOMEBREW_GITHUB_HOSTED_RUNNER", c.GithubHostedRunner)
 }
 
+// NormalizeArch maps the accepted spellings of an architecture to the name
+// used in bottle tags, returning "" for anything unrecognised
+func NormalizeArch(arch string) string {
+	switch strings.ToLower(arch) {
+	case "arm64", "aarch64":
+		return "arm64"
+	case "x86_64", "intel", "amd64":
+		return "x86_64"
+	}
+	return ""
+}
+
 func getBoolEnv(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if parsed, err := strconv.ParseBool(value); err == nil {
@@ -230,6 +352,19 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// parseRewriteRules parses comma-separated "from=to" prefix rewrite rules
+func parseRewriteRules(value string) map[string]string {
+	rules := make(map[string]string)
+	for _, rule := range strings.Split(value, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(rule), "=")
+		if !ok || from == "" {
+			continue
+		}
+		rules[from] = to
+	}
+	return rules
+}
+
 // EnsureDirectories creates necessary directories
 func (c *Config) EnsureDirectories() error {
 	dirs := []string{
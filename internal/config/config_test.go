@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -177,3 +179,57 @@ func TestGetIntEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestPathExpansion(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MY_CACHE_ROOT", "/var/tmp/brew")
+	t.Setenv("HOMEBREW_PREFIX", "~/homebrew")
+	t.Setenv("HOMEBREW_CACHE", "$MY_CACHE_ROOT/cache")
+	t.Setenv("HOMEBREW_LOGS", "${HOME}/logs")
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		got      string
+		expected string
+	}{
+		{"prefix", cfg.HomebrewPrefix, filepath.Join(home, "homebrew")},
+		{"derived cellar", cfg.HomebrewCellar, filepath.Join(home, "homebrew", "Cellar")},
+		{"cache", cfg.HomebrewCache, "/var/tmp/brew/cache"},
+		{"logs", cfg.HomebrewLogs, filepath.Join(home, "logs")},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.expected {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.expected)
+		}
+	}
+}
+
+func TestRelativePathRejected(t *testing.T) {
+	t.Setenv("HOMEBREW_PREFIX", "/opt/homebrew")
+	t.Setenv("HOMEBREW_CACHE", "relative/cache")
+
+	_, err := New()
+	if err == nil {
+		t.Fatal("New() expected error for relative HOMEBREW_CACHE")
+	}
+	if !strings.Contains(err.Error(), "HOMEBREW_CACHE must be an absolute path") {
+		t.Errorf("New() error = %v, want absolute path error", err)
+	}
+}
+
+func TestNormalizeArch(t *testing.T) {
+	for arch, want := range map[string]string{
+		"arm64": "arm64", "aarch64": "arm64", "x86_64": "x86_64", "AMD64": "x86_64", "intel": "x86_64", "ppc64": "", "": "",
+	} {
+		if got := NormalizeArch(arch); got != want {
+			t.Errorf("NormalizeArch(%q) = %q, want %q", arch, got, want)
+		}
+	}
+}
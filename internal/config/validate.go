@@ -0,0 +1,167 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Validate checks that the configured directories are usable and returns
+// every problem found in a single error
+func (c *Config) Validate() error {
+	var problems []string
+
+	if err := checkCreatable(c.HomebrewPrefix); err != nil {
+		problems = append(problems, fmt.Sprintf("HOMEBREW_PREFIX %s cannot be created: %v", c.HomebrewPrefix, err))
+	}
+
+	writable := []struct {
+		name string
+		path string
+	}{
+		{"HOMEBREW_CELLAR", c.HomebrewCellar},
+		{"HOMEBREW_CACHE", c.HomebrewCache},
+		{"HOMEBREW_TEMP", c.HomebrewTemp},
+	}
+	for _, dir := range writable {
+		if err := checkDirWritable(dir.name, dir.path); err != nil {
+			var notWritable *NotWritableError
+			if errors.As(err, &notWritable) {
+				problems = append(problems, fmt.Sprintf("%v; take ownership of it with `%s`", err, notWritable.Fix()))
+			} else {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+
+	// Data directories inside each other would let cleanup delete the prefix
+	nested := []struct {
+		inner, innerName string
+		outer, outerName string
+	}{
+		{c.HomebrewPrefix, "HOMEBREW_PREFIX", c.HomebrewCellar, "HOMEBREW_CELLAR"},
+		{c.HomebrewPrefix, "HOMEBREW_PREFIX", c.HomebrewCache, "HOMEBREW_CACHE"},
+		{c.HomebrewRepository, "HOMEBREW_REPOSITORY", c.HomebrewCellar, "HOMEBREW_CELLAR"},
+		{c.HomebrewRepository, "HOMEBREW_REPOSITORY", c.HomebrewCache, "HOMEBREW_CACHE"},
+	}
+	for _, n := range nested {
+		if n.inner != "" && n.outer != "" && isSubpath(n.outer, n.inner) {
+			problems = append(problems, fmt.Sprintf("%s %s must not be inside %s %s", n.innerName, n.inner, n.outerName, n.outer))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// NotWritableError reports a Homebrew directory the current user isn't
+// allowed to write to, which usually means it is owned by someone else
+type NotWritableError struct {
+	Name string
+	Path string
+	Err  error
+}
+
+func (e *NotWritableError) Error() string {
+	return fmt.Sprintf("%s %s is not writable: %v", e.Name, e.Path, e.Err)
+}
+
+func (e *NotWritableError) Unwrap() error {
+	return e.Err
+}
+
+// Fix returns the command that hands the directory back to the current user
+func (e *NotWritableError) Fix() string {
+	return "sudo chown -R $(whoami) " + e.Path
+}
+
+// CheckPrefixWritable verifies that the prefix and cellar can be written,
+// returning a *NotWritableError if the current user lacks permission
+func (c *Config) CheckPrefixWritable() error {
+	if err := checkDirWritable("HOMEBREW_PREFIX", c.HomebrewPrefix); err != nil {
+		return err
+	}
+	return checkDirWritable("HOMEBREW_CELLAR", c.HomebrewCellar)
+}
+
+// checkDirWritable runs checkWritable on a named directory, telling
+// permission problems apart from other failures
+func checkDirWritable(name, path string) error {
+	err := checkWritable(path)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, os.ErrPermission) {
+		return &NotWritableError{Name: name, Path: path, Err: err}
+	}
+	return fmt.Errorf("%s %s is not writable: %w", name, path, err)
+}
+
+// checkCreatable verifies that path is a directory or could be created as one
+func checkCreatable(path string) error {
+	if path == "" {
+		return fmt.Errorf("path is empty")
+	}
+
+	info, err := os.Stat(path)
+	if err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("not a directory")
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Find the closest existing ancestor; MkdirAll needs to write there
+	parent := filepath.Dir(path)
+	for parent != filepath.Dir(parent) {
+		if _, err := os.Lstat(parent); err == nil {
+			break
+		}
+		parent = filepath.Dir(parent)
+	}
+	return checkWritable(parent)
+}
+
+// checkWritable verifies that files can be created in path, or in the
+// directory that would contain it if it doesn't exist yet
+func checkWritable(path string) error {
+	if path == "" {
+		return fmt.Errorf("path is empty")
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return checkCreatable(path)
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory")
+	}
+
+	f, err := os.CreateTemp(path, ".brew-write-test-*")
+	if err != nil {
+		return fmt.Errorf("cannot create files: %w", err)
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+
+	return nil
+}
+
+// isSubpath reports whether path is parent itself or lies beneath it
+func isSubpath(parent, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(parent), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
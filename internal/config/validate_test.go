@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	prefix := t.TempDir()
+	return &Config{
+		HomebrewPrefix:     prefix,
+		HomebrewRepository: prefix,
+		HomebrewCellar:     filepath.Join(prefix, "Cellar"),
+		HomebrewCaskroom:   filepath.Join(prefix, "Caskroom"),
+		HomebrewCache:      filepath.Join(t.TempDir(), "cache"),
+		HomebrewTemp:       t.TempDir(),
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig(t).Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(t *testing.T, cfg *Config)
+		expected []string
+	}{
+		{
+			name: "unwritable cache dir",
+			modify: func(t *testing.T, cfg *Config) {
+				// A regular file in place of the cache can never hold downloads
+				if err := os.WriteFile(cfg.HomebrewCache, []byte{}, 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []string{"HOMEBREW_CACHE", "not writable"},
+		},
+		{
+			name: "prefix that cannot be created",
+			modify: func(t *testing.T, cfg *Config) {
+				blocker := filepath.Join(t.TempDir(), "file")
+				if err := os.WriteFile(blocker, []byte{}, 0644); err != nil {
+					t.Fatal(err)
+				}
+				cfg.HomebrewPrefix = filepath.Join(blocker, "homebrew")
+			},
+			expected: []string{"HOMEBREW_PREFIX", "cannot be created"},
+		},
+		{
+			name: "prefix inside cellar",
+			modify: func(t *testing.T, cfg *Config) {
+				cfg.HomebrewPrefix = filepath.Join(cfg.HomebrewCellar, "nested")
+			},
+			expected: []string{"must not be inside HOMEBREW_CELLAR"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.modify(t, cfg)
+
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Validate() expected error")
+			}
+			for _, want := range tt.expected {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAggregatesErrors(t *testing.T) {
+	cfg := validConfig(t)
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.HomebrewCache = blocker
+	cfg.HomebrewTemp = blocker
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() expected error")
+	}
+	if !strings.Contains(err.Error(), "HOMEBREW_CACHE") || !strings.Contains(err.Error(), "HOMEBREW_TEMP") {
+		t.Errorf("Validate() error = %v, want both problems reported", err)
+	}
+}
+
+func TestValidateSuggestsOwnership(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	cfg := validConfig(t)
+	if err := os.Mkdir(cfg.HomebrewCache, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(cfg.HomebrewCache, 0755) })
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "sudo chown -R $(whoami) "+cfg.HomebrewCache) {
+		t.Errorf("Validate() error = %v, want it to suggest taking ownership of the cache", err)
+	}
+}
@@ -2,6 +2,7 @@ package errors
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 )
 
@@ -39,6 +40,35 @@ type BrewError struct {
 	Cause       error
 	Suggestions []string
 	Recoverable bool
+
+	// Checksum describes the mismatch behind a ChecksumError
+	Checksum *ChecksumMismatch
+}
+
+// ChecksumMismatch records a file whose digest didn't match the expected one
+type ChecksumMismatch struct {
+	Path     string
+	Expected string
+	Actual   string
+	// Size is the file's length in bytes, or -1 when unknown
+	Size int64
+}
+
+// String describes the mismatch, naming the file and its size when known
+func (m *ChecksumMismatch) String() string {
+	var b strings.Builder
+	b.WriteString("checksum mismatch")
+	if m.Path != "" {
+		fmt.Fprintf(&b, " for %s", filepath.Base(m.Path))
+	}
+	if m.Size >= 0 {
+		fmt.Fprintf(&b, " (%d bytes)", m.Size)
+	}
+	fmt.Fprintf(&b, ": expected %s, got %s", m.Expected, m.Actual)
+	if m.Path != "" {
+		b.WriteString("; the cached file may be corrupt, remove it or run `brew cleanup` to download it again")
+	}
+	return b.String()
 }
 
 // Error implements the error interface
@@ -198,7 +228,13 @@ func NewDownloadError(operation, url string, cause error) *BrewError {
 
 // NewChecksumError creates a checksum verification error
 func NewChecksumError(formula, version string, expected, actual string) *BrewError {
-	cause := fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
+	return NewChecksumMismatchError(formula, version, ChecksumMismatch{Expected: expected, Actual: actual, Size: -1})
+}
+
+// NewChecksumMismatchError creates a checksum verification error carrying
+// the digests and the file they were computed from
+func NewChecksumMismatchError(formula, version string, mismatch ChecksumMismatch) *BrewError {
+	cause := fmt.Errorf("%s", mismatch.String())
 
 	suggestions := []string{
 		"The download may be corrupted, try downloading again",
@@ -206,6 +242,11 @@ func NewChecksumError(formula, version string, expected, actual string) *BrewErr
 		"Check if there's a newer version of the formula available",
 		"Report this issue if it persists",
 	}
+	if mismatch.Path != "" {
+		suggestions = append([]string{
+			fmt.Sprintf("Remove %s or run 'brew cleanup' to force a fresh download", mismatch.Path),
+		}, suggestions...)
+	}
 
 	return &BrewError{
 		Type:        ChecksumError,
@@ -215,6 +256,7 @@ func NewChecksumError(formula, version string, expected, actual string) *BrewErr
 		Cause:       cause,
 		Suggestions: suggestions,
 		Recoverable: true,
+		Checksum:    &mismatch,
 	}
 }
 
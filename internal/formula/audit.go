@@ -0,0 +1,174 @@
+package formula
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity classifies an audit problem
+type Severity string
+
+const (
+	// SeverityError marks problems that make a formula unusable
+	SeverityError Severity = "error"
+	// SeverityWarning marks problems worth fixing that don't block installs
+	SeverityWarning Severity = "warning"
+)
+
+// AuditProblem is a single issue found by Audit
+type AuditProblem struct {
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+// String formats the problem for display
+func (p AuditProblem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Rule, p.Message)
+}
+
+// AuditFile parses a YAML formula without validating it and audits it, so
+// broken formulae report every problem rather than the first
+func AuditFile(path string) (*Formula, []AuditProblem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read formula file: %w", err)
+	}
+
+	var f Formula
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse formula: %w", err)
+	}
+
+	return &f, Audit(&f), nil
+}
+
+// Audit runs IsValid's checks and further structural checks over a
+// formula, reporting every problem found
+func Audit(f *Formula) []AuditProblem {
+	var problems []AuditProblem
+	add := func(severity Severity, rule, format string, args ...interface{}) {
+		problems = append(problems, AuditProblem{Severity: severity, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// The checks IsValid stops at the first of, run individually
+	if f.Name == "" {
+		add(SeverityError, "required", "formula name is required")
+	}
+	if f.Version == "" {
+		add(SeverityError, "required", "formula version is required")
+	} else if !IsHeadVersion(f.Version) {
+		if _, err := version.NewVersion(f.Version); err != nil {
+			add(SeverityError, "version", "invalid version format: %v", err)
+		}
+	}
+	if f.URL == "" && f.Head == nil {
+		add(SeverityError, "required", "formula must have either URL or HEAD")
+	}
+	if f.URL != "" && f.SHA256 == "" {
+		add(SeverityError, "required", "formula with URL must have SHA256")
+	}
+
+	if f.Name != "" {
+		if err := ValidateName(f.Name); err != nil {
+			add(SeverityError, "name", "%v", err)
+		}
+	}
+
+	if f.URL != "" {
+		if msg := auditHTTPS(f.URL); msg != "" {
+			add(SeverityError, "url", "%s", msg)
+		}
+	}
+	if f.Head != nil {
+		if f.Head.URL == "" {
+			add(SeverityError, "required", "head must have a URL")
+		} else if msg := auditHTTPS(f.Head.URL); msg != "" {
+			add(SeverityError, "url", "head: %s", msg)
+		}
+	}
+	for _, r := range f.Resources {
+		if r.URL != "" {
+			if msg := auditHTTPS(r.URL); msg != "" {
+				add(SeverityError, "url", "resource %s: %s", r.Name, msg)
+			}
+		}
+	}
+
+	if f.SHA256 != "" && !IsSHA256(f.SHA256) {
+		add(SeverityError, "sha256", "%q is not 64 hexadecimal characters", f.SHA256)
+	}
+	for _, r := range f.Resources {
+		if r.SHA256 != "" && !IsSHA256(r.SHA256) {
+			add(SeverityError, "sha256", "resource %s: %q is not 64 hexadecimal characters", r.Name, r.SHA256)
+		}
+	}
+
+	allDeps := [][]string{f.Dependencies, f.BuildDependencies, f.TestDependencies}
+	for _, platform := range []*PlatformDependencies{f.OnMacOS, f.OnLinux} {
+		if platform != nil {
+			allDeps = append(allDeps, platform.Dependencies, platform.BuildDependencies)
+		}
+	}
+	for _, deps := range allDeps {
+		for _, dep := range deps {
+			if f.Name != "" && dep == f.Name {
+				add(SeverityError, "dependencies", "%s depends on itself", f.Name)
+			}
+		}
+	}
+
+	switch {
+	case f.Homepage == "":
+		add(SeverityWarning, "homepage", "homepage is missing")
+	case !isWebURL(f.Homepage):
+		add(SeverityError, "homepage", "%q is not a valid URL", f.Homepage)
+	case strings.HasPrefix(f.Homepage, "http://"):
+		add(SeverityWarning, "homepage", "%s should use https", f.Homepage)
+	}
+
+	return problems
+}
+
+// HasAuditErrors reports whether any problem is an error
+func HasAuditErrors(problems []AuditProblem) bool {
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// auditHTTPS describes why a download URL is not https, or returns ""
+func auditHTTPS(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Sprintf("%q is not a valid URL", rawURL)
+	}
+	if u.Scheme != "https" {
+		return fmt.Sprintf("%s should use https, not %s", rawURL, u.Scheme)
+	}
+	return ""
+}
+
+func isWebURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// IsSHA256 reports whether s is a hex-encoded SHA256 digest
+func IsSHA256(s string) bool {
+	if len(s) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
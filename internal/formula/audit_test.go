@@ -0,0 +1,107 @@
+package formula
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditFileBrokenFormula(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Broken.yaml")
+	data := `name: Broken_Tool
+version: 1.0
+homepage: not a url
+url: http://example.com/broken-1.0.tar.gz
+sha256: abc123
+head:
+  url: http://example.com/broken.git
+dependencies:
+  - openssl
+  - Broken_Tool
+resources:
+  - name: extra
+    url: ftp://example.com/extra.tar.gz
+    sha256: xyz
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, problems, err := AuditFile(path)
+	if err != nil {
+		t.Fatalf("AuditFile() error = %v", err)
+	}
+	if f.Name != "Broken_Tool" {
+		t.Errorf("Parsed name = %q", f.Name)
+	}
+
+	fired := make(map[string][]string)
+	for _, p := range problems {
+		if p.Severity != SeverityError {
+			t.Errorf("Expected only errors, got %s", p)
+		}
+		fired[p.Rule] = append(fired[p.Rule], p.Message)
+	}
+
+	for _, rule := range []string{"name", "url", "sha256", "dependencies", "homepage"} {
+		if len(fired[rule]) == 0 {
+			t.Errorf("Expected %q rule to fire, problems: %v", rule, problems)
+		}
+	}
+	if len(fired["url"]) != 3 || len(fired["sha256"]) != 2 {
+		t.Errorf("Expected url problems for the formula, its head and its resource and sha256 problems for the formula and its resource, got %v", problems)
+	}
+	if !HasAuditErrors(problems) {
+		t.Error("HasAuditErrors() = false")
+	}
+}
+
+func TestAuditRequiredFields(t *testing.T) {
+	problems := Audit(&Formula{Homepage: "https://example.com", Head: &Head{}})
+	var messages []string
+	for _, p := range problems {
+		if p.Rule != "required" {
+			t.Errorf("Unexpected problem: %s", p)
+		}
+		messages = append(messages, p.Message)
+	}
+	for _, want := range []string{"name is required", "version is required", "head must have a URL"} {
+		if !strings.Contains(strings.Join(messages, "\n"), want) {
+			t.Errorf("Expected a problem containing %q, got %v", want, problems)
+		}
+	}
+
+	problems = Audit(&Formula{Name: "tool", Version: "not a version", Homepage: "https://example.com", URL: "https://example.com/tool.tar.gz"})
+	rules := make(map[string]bool)
+	for _, p := range problems {
+		rules[p.Rule] = true
+	}
+	if len(problems) != 2 || !rules["version"] || !rules["required"] {
+		t.Errorf("Audit() = %v, want a version problem and a missing SHA256 problem", problems)
+	}
+}
+
+func TestAuditValidFormula(t *testing.T) {
+	f := &Formula{
+		Name:         "wget",
+		Version:      "1.21.4",
+		Homepage:     "https://www.gnu.org/software/wget/",
+		URL:          "https://ftp.gnu.org/gnu/wget/wget-1.21.4.tar.gz",
+		SHA256:       strings.Repeat("a", 64),
+		Dependencies: []string{"openssl@3"},
+	}
+	if problems := Audit(f); len(problems) != 0 {
+		t.Errorf("Audit() = %v, want no problems", problems)
+	}
+
+	// Head-only formulae need no checksum
+	head := &Formula{Name: "tool", Version: "HEAD", Homepage: "http://example.com", Head: &Head{URL: "https://github.com/example/tool.git"}}
+	problems := Audit(head)
+	if HasAuditErrors(problems) {
+		t.Errorf("Audit() = %v, want no errors", problems)
+	}
+	if len(problems) != 1 || problems[0].Rule != "homepage" || problems[0].Severity != SeverityWarning {
+		t.Errorf("Expected an http homepage warning, got %v", problems)
+	}
+}
@@ -2,8 +2,11 @@ package formula
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
 	"strings"
 	"time"
 
@@ -23,6 +26,7 @@ type Formula struct {
 	Dependencies      []string      `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
 	BuildDependencies []string      `yaml:"build_dependencies,omitempty" json:"build_dependencies,omitempty"`
 	TestDependencies  []string      `yaml:"test_dependencies,omitempty" json:"test_dependencies,omitempty"`
+	CaskDependencies  []string      `yaml:"cask_dependencies,omitempty" json:"cask_dependencies,omitempty"`
 	Options           []Option      `yaml:"options,omitempty" json:"options,omitempty"`
 	Conflicts         []string      `yaml:"conflicts,omitempty" json:"conflicts,omitempty"`
 	Caveats           string        `yaml:"caveats,omitempty" json:"caveats,omitempty"`
@@ -34,11 +38,26 @@ type Formula struct {
 	Service           *Service      `yaml:"service,omitempty" json:"service,omitempty"`
 	Livecheck         *Livecheck    `yaml:"livecheck,omitempty" json:"livecheck,omitempty"`
 	Deprecated        bool          `yaml:"deprecated,omitempty" json:"deprecated,omitempty"`
+	DeprecationDate   string        `yaml:"deprecation_date,omitempty" json:"deprecation_date,omitempty"`
+	DeprecationReason string        `yaml:"deprecation_reason,omitempty" json:"deprecation_reason,omitempty"`
 	Disabled          bool          `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+	DisableDate       string        `yaml:"disable_date,omitempty" json:"disable_date,omitempty"`
+	DisableReason     string        `yaml:"disable_reason,omitempty" json:"disable_reason,omitempty"`
 	Requirements      []Requirement `yaml:"requirements,omitempty" json:"requirements,omitempty"`
 	Patches           []Patch       `yaml:"patches,omitempty" json:"patches,omitempty"`
 	Resources         []Resource    `yaml:"resources,omitempty" json:"resources,omitempty"`
 
+	// Binary is the command a download that is a single executable rather
+	// than an archive is installed as
+	Binary string `yaml:"binary,omitempty" json:"binary,omitempty"`
+
+	// PostInstall are shell commands run in the keg after it is installed
+	PostInstall []string `yaml:"post_install,omitempty" json:"post_install,omitempty"`
+
+	// OnMacOS and OnLinux add dependencies needed only on that platform
+	OnMacOS *PlatformDependencies `yaml:"on_macos,omitempty" json:"on_macos,omitempty"`
+	OnLinux *PlatformDependencies `yaml:"on_linux,omitempty" json:"on_linux,omitempty"`
+
 	// Runtime information
 	Tap       string    `yaml:"tap,omitempty" json:"tap,omitempty"`
 	FullName  string    `yaml:"full_name,omitempty" json:"full_name,omitempty"`
@@ -51,6 +70,12 @@ type Formula struct {
 	UpdatedAt time.Time `yaml:"updated_at,omitempty" json:"updated_at,omitempty"`
 }
 
+// PlatformDependencies are dependencies declared under on_macos or on_linux
+type PlatformDependencies struct {
+	Dependencies      []string `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+	BuildDependencies []string `yaml:"build_dependencies,omitempty" json:"build_dependencies,omitempty"`
+}
+
 // Option represents a formula option
 type Option struct {
 	Name        string `yaml:"name" json:"name"`
@@ -63,10 +88,26 @@ type PourBottle struct {
 	OnlyIf string `yaml:"only_if,omitempty" json:"only_if,omitempty"`
 }
 
-// Bottle represents a binary bottle
+// Bottle represents a binary bottle. Unneeded marks formulae that are
+// built from source by design (`bottle: unneeded` in YAML).
 type Bottle struct {
-	Stable *BottleSpec `yaml:"stable,omitempty" json:"stable,omitempty"`
-	Head   *BottleSpec `yaml:"head,omitempty" json:"head,omitempty"`
+	Stable   *BottleSpec `yaml:"stable,omitempty" json:"stable,omitempty"`
+	Head     *BottleSpec `yaml:"head,omitempty" json:"head,omitempty"`
+	Unneeded bool        `yaml:"-" json:"unneeded,omitempty"`
+}
+
+// UnmarshalYAML accepts either a bottle block or the scalar "unneeded"
+func (b *Bottle) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.Value != "unneeded" {
+			return fmt.Errorf("invalid bottle %q: expected a bottle block or \"unneeded\"", value.Value)
+		}
+		b.Unneeded = true
+		return nil
+	}
+
+	type plain Bottle
+	return value.Decode((*plain)(b))
 }
 
 // BottleSpec represents bottle specification
@@ -83,10 +124,12 @@ type BottleFile struct {
 	SHA256     string `yaml:"sha256" json:"sha256"`
 }
 
-// Head represents HEAD version information
+// Head represents HEAD version information. Version optionally names HEAD
+// builds when the commit they were built from is unknown.
 type Head struct {
-	URL    string `yaml:"url" json:"url"`
-	Branch string `yaml:"branch,omitempty" json:"branch,omitempty"`
+	URL     string `yaml:"url" json:"url"`
+	Branch  string `yaml:"branch,omitempty" json:"branch,omitempty"`
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
 }
 
 // Service represents a service configuration
@@ -149,7 +192,7 @@ func (f *Formula) IsValid() error {
 	}
 
 	// Validate version format (skip for HEAD versions)
-	if f.Version != "HEAD" {
+	if !IsHeadVersion(f.Version) {
 		if _, err := version.NewVersion(f.Version); err != nil {
 			return fmt.Errorf("invalid version format: %w", err)
 		}
@@ -197,14 +240,60 @@ func (f *Formula) GetOption(name string) *Option {
 }
 
 // GetDependencies returns all dependencies (including build dependencies)
+// on the current platform
 func (f *Formula) GetDependencies(includeBuild bool) []string {
+	return f.DependenciesFor(runtime.GOOS, includeBuild)
+}
+
+// GetBuildDependencies returns the build dependencies on the current platform
+func (f *Formula) GetBuildDependencies() []string {
+	return f.BuildDependenciesFor(runtime.GOOS)
+}
+
+// DependenciesFor returns the dependencies on goos, merging the base ones
+// with that platform's conditional ones
+func (f *Formula) DependenciesFor(goos string, includeBuild bool) []string {
 	deps := make([]string, len(f.Dependencies))
 	copy(deps, f.Dependencies)
+	if platform := f.platformDependencies(goos); platform != nil {
+		deps = appendMissing(deps, platform.Dependencies)
+	}
 
 	if includeBuild {
-		deps = append(deps, f.BuildDependencies...)
+		deps = appendMissing(deps, f.BuildDependenciesFor(goos))
+	}
+
+	return deps
+}
+
+// BuildDependenciesFor returns the build dependencies on goos
+func (f *Formula) BuildDependenciesFor(goos string) []string {
+	deps := make([]string, len(f.BuildDependencies))
+	copy(deps, f.BuildDependencies)
+	if platform := f.platformDependencies(goos); platform != nil {
+		deps = appendMissing(deps, platform.BuildDependencies)
+	}
+	return deps
+}
+
+// platformDependencies returns the conditional dependencies for goos
+func (f *Formula) platformDependencies(goos string) *PlatformDependencies {
+	switch goos {
+	case "darwin":
+		return f.OnMacOS
+	case "linux":
+		return f.OnLinux
 	}
+	return nil
+}
 
+// appendMissing appends the names in extra that deps doesn't contain yet
+func appendMissing(deps, extra []string) []string {
+	for _, name := range extra {
+		if !slices.Contains(deps, name) {
+			deps = append(deps, name)
+		}
+	}
 	return deps
 }
 
@@ -244,16 +333,93 @@ func (f *Formula) HasBottle(platform string) bool {
 	return ok
 }
 
+// IsSourceOnly reports whether the formula has no bottles by design, either
+// because it declares none or is marked `bottle: unneeded`
+func (f *Formula) IsSourceOnly() bool {
+	if f.Bottle == nil || f.Bottle.Unneeded {
+		return true
+	}
+	return f.Bottle.Stable == nil || len(f.Bottle.Stable.Files) == 0
+}
+
+// BottleRebuild returns the rebuild number of the formula's stable bottles
+func (f *Formula) BottleRebuild() int {
+	if f.Bottle == nil || f.Bottle.Stable == nil {
+		return 0
+	}
+	return f.Bottle.Stable.Rebuild
+}
+
 // IsHeadOnly checks if the formula is HEAD-only
 func (f *Formula) IsHeadOnly() bool {
 	return f.URL == "" && f.Head != nil
 }
 
+// HeadVersion returns the version a HEAD build is installed as, so builds of
+// different commits get their own kegs: HEAD-<short commit> when the commit
+// is known, else HEAD-<head version>, else HEAD-<date>
+func (f *Formula) HeadVersion(commit string, now time.Time) string {
+	switch {
+	case commit != "":
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		return "HEAD-" + commit
+	case f.Head != nil && f.Head.Version != "":
+		return "HEAD-" + strings.TrimPrefix(f.Head.Version, "HEAD-")
+	default:
+		return "HEAD-" + now.Format("20060102")
+	}
+}
+
+// IsHeadVersion reports whether an installed version is a HEAD build
+func IsHeadVersion(v string) bool {
+	return v == "HEAD" || strings.HasPrefix(v, "HEAD-")
+}
+
 // IsStable checks if the formula has a stable version
 func (f *Formula) IsStable() bool {
 	return f.URL != "" && f.Version != ""
 }
 
+// lifecycleReasons spells out the reason symbols Homebrew uses for deprecated
+// and disabled formulae
+var lifecycleReasons = map[string]string{
+	"does_not_build":      "does not build",
+	"no_license":          "has no license",
+	"repo_archived":       "has an archived upstream repository",
+	"repo_removed":        "has a removed upstream repository",
+	"unmaintained":        "is not maintained upstream",
+	"unsupported":         "is not supported upstream",
+	"deprecated_upstream": "is deprecated upstream",
+	"versioned_formula":   "is a versioned formula",
+	"checksum_mismatch":   "was built with a source file whose checksum has since changed",
+}
+
+// DeprecationMessage describes why and since when the formula is deprecated
+func (f *Formula) DeprecationMessage() string {
+	return lifecycleMessage(f.Name, "deprecated", f.DeprecationReason, f.DeprecationDate)
+}
+
+// DisableMessage describes why and since when the formula is disabled
+func (f *Formula) DisableMessage() string {
+	return lifecycleMessage(f.Name, "disabled", f.DisableReason, f.DisableDate)
+}
+
+func lifecycleMessage(name, state, reason, date string) string {
+	msg := fmt.Sprintf("%s has been %s", name, state)
+	if reason = strings.TrimSpace(strings.TrimPrefix(reason, ":")); reason != "" {
+		if known, ok := lifecycleReasons[reason]; ok {
+			reason = known
+		}
+		msg += " because it " + reason
+	}
+	if date != "" {
+		msg += fmt.Sprintf(" (since %s)", date)
+	}
+	return msg
+}
+
 // ValidateName validates the formula name
 func ValidateName(name string) error {
 	// Formula names must be lowercase and can contain letters, numbers, and hyphens
@@ -287,6 +453,73 @@ func ParseFormula(data []byte) (*Formula, error) {
 	return &formula, nil
 }
 
+// LocalTap is the synthetic tap assigned to formulae loaded from a file
+const LocalTap = "local/formula"
+
+// IsLocalPath reports whether name refers to a formula file on disk
+func IsLocalPath(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".rb":
+	default:
+		return false
+	}
+
+	info, err := os.Stat(name)
+	return err == nil && !info.IsDir()
+}
+
+// LoadFile parses a formula from a local file outside of any tap
+func LoadFile(path string) (*Formula, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve formula path: %w", err)
+	}
+
+	if filepath.Ext(absPath) == ".rb" {
+		return nil, fmt.Errorf("formula %s is a Ruby file; Ruby DSL parsing not implemented yet", path)
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read formula file: %w", err)
+	}
+
+	f, err := ParseFormula(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Relative URLs point at files next to the formula
+	dir := filepath.Dir(absPath)
+	f.URL = resolveLocalURL(dir, f.URL)
+	if f.Head != nil {
+		f.Head.URL = resolveLocalURL(dir, f.Head.URL)
+	}
+	for i := range f.Patches {
+		f.Patches[i].URL = resolveLocalURL(dir, f.Patches[i].URL)
+	}
+	for i := range f.Resources {
+		f.Resources[i].URL = resolveLocalURL(dir, f.Resources[i].URL)
+	}
+
+	f.Tap = LocalTap
+	f.Path = absPath
+	f.FullName = f.GetFullName()
+
+	return f, nil
+}
+
+// resolveLocalURL turns a relative URL into a file:// URL under dir
+func resolveLocalURL(dir, rawURL string) string {
+	if rawURL == "" || strings.Contains(rawURL, "://") {
+		return rawURL
+	}
+	if !filepath.IsAbs(rawURL) {
+		rawURL = filepath.Join(dir, rawURL)
+	}
+	return "file://" + rawURL
+}
+
 // ToYAML converts the formula to YAML
 func (f *Formula) ToYAML() ([]byte, error) {
 	return yaml.Marshal(f)
@@ -1,7 +1,11 @@
 package formula
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestFormulaValidation(t *testing.T) {
@@ -195,6 +199,34 @@ dependencies:
 	}
 }
 
+func TestIsSourceOnly(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want bool
+	}{
+		{"no bottle block", "name: a\nversion: 1.0\nurl: https://example.com/a.tar.gz\nsha256: abc\n", true},
+		{"bottle unneeded", "name: a\nversion: 1.0\nurl: https://example.com/a.tar.gz\nsha256: abc\nbottle: unneeded\n", true},
+		{"bottle files", "name: a\nversion: 1.0\nurl: https://example.com/a.tar.gz\nsha256: abc\nbottle:\n  stable:\n    files:\n      arm64_linux:\n        url: https://example.com/a\n        sha256: abc\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFormula([]byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("ParseFormula() error = %v", err)
+			}
+			if got := f.IsSourceOnly(); got != tt.want {
+				t.Errorf("IsSourceOnly() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseFormula([]byte("name: a\nversion: 1.0\nurl: https://example.com/a.tar.gz\nsha256: abc\nbottle: maybe\n")); err == nil {
+		t.Error("Expected an unknown bottle scalar to be rejected")
+	}
+}
+
 func TestGetCellarPath(t *testing.T) {
 	formula := Formula{
 		Name:    "test-formula",
@@ -323,6 +355,59 @@ func TestGetDependencies(t *testing.T) {
 	}
 }
 
+func TestDependenciesForPlatform(t *testing.T) {
+	yamlData := `
+name: portable
+version: 1.0.0
+url: https://example.com/portable-1.0.0.tar.gz
+sha256: abcd1234efgh5678
+dependencies:
+  - openssl
+build_dependencies:
+  - pkg-config
+on_macos:
+  dependencies:
+    - gettext
+on_linux:
+  dependencies:
+    - zlib
+    - openssl
+  build_dependencies:
+    - linux-headers
+`
+
+	f, err := ParseFormula([]byte(yamlData))
+	if err != nil {
+		t.Fatalf("ParseFormula() error = %v", err)
+	}
+
+	tests := []struct {
+		goos         string
+		includeBuild bool
+		expected     []string
+	}{
+		{"darwin", false, []string{"openssl", "gettext"}},
+		{"darwin", true, []string{"openssl", "gettext", "pkg-config"}},
+		{"linux", false, []string{"openssl", "zlib"}},
+		{"linux", true, []string{"openssl", "zlib", "pkg-config", "linux-headers"}},
+		{"windows", true, []string{"openssl", "pkg-config"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s build=%v", tt.goos, tt.includeBuild), func(t *testing.T) {
+			got := f.DependenciesFor(tt.goos, tt.includeBuild)
+			if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("DependenciesFor(%s, %v) = %v, want %v", tt.goos, tt.includeBuild, got, tt.expected)
+			}
+		})
+	}
+
+	// Conditional dependencies don't leak into the base lists
+	if fmt.Sprint(f.Dependencies) != "[openssl]" {
+		t.Errorf("Dependencies = %v, want [openssl]", f.Dependencies)
+	}
+}
+
 func TestGetBottleURL(t *testing.T) {
 	formula := Formula{
 		Name:    "test",
@@ -436,6 +521,40 @@ func TestIsHeadOnly(t *testing.T) {
 	}
 }
 
+func TestHeadVersion(t *testing.T) {
+	now := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		head     *Head
+		commit   string
+		expected string
+	}{
+		{"commit", &Head{URL: "https://github.com/user/repo.git"}, "0123456789abcdef", "HEAD-0123456"},
+		{"short commit", &Head{}, "abc", "HEAD-abc"},
+		{"commit wins over version", &Head{Version: "nightly"}, "fedcba9876", "HEAD-fedcba9"},
+		{"declared version", &Head{Version: "nightly"}, "", "HEAD-nightly"},
+		{"declared version with prefix", &Head{Version: "HEAD-nightly"}, "", "HEAD-nightly"},
+		{"date", &Head{}, "", "HEAD-20240309"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Formula{Name: "test", Version: "1.0.0", Head: tt.head}
+			if got := f.HeadVersion(tt.commit, now); got != tt.expected {
+				t.Errorf("HeadVersion() = %v, want %v", got, tt.expected)
+			}
+			if !IsHeadVersion(f.HeadVersion(tt.commit, now)) {
+				t.Error("IsHeadVersion() should accept HEAD versions")
+			}
+		})
+	}
+
+	if IsHeadVersion("1.0.0") || IsHeadVersion("HEADLESS") {
+		t.Error("IsHeadVersion() should reject stable versions")
+	}
+}
+
 func TestIsStable(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -534,3 +653,45 @@ func TestToYAML(t *testing.T) {
 		t.Errorf("ToYAML() -> ParseFormula() Dependencies count = %v, want %v", len(parsedFormula.Dependencies), len(formula.Dependencies))
 	}
 }
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hello.yaml")
+	data := `name: hello
+version: 1.0.0
+url: src/hello-1.0.tar.gz
+sha256: abcd1234
+resources:
+  - name: extra
+    url: https://example.com/extra.tar.gz
+    sha256: ef01
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsLocalPath(path) {
+		t.Errorf("IsLocalPath(%q) = false, want true", path)
+	}
+	if IsLocalPath("hello") {
+		t.Error("IsLocalPath(\"hello\") = true, want false")
+	}
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	if want := "file://" + filepath.Join(dir, "src", "hello-1.0.tar.gz"); f.URL != want {
+		t.Errorf("URL = %v, want %v", f.URL, want)
+	}
+	if f.Resources[0].URL != "https://example.com/extra.tar.gz" {
+		t.Errorf("Absolute resource URL was rewritten: %v", f.Resources[0].URL)
+	}
+	if f.Tap != LocalTap {
+		t.Errorf("Tap = %v, want %v", f.Tap, LocalTap)
+	}
+	if f.Path != path {
+		t.Errorf("Path = %v, want %v", f.Path, path)
+	}
+}
@@ -0,0 +1,47 @@
+package installer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RuntimeDependency is a dependency a bottle was built against
+type RuntimeDependency struct {
+	FullName string `json:"full_name"`
+	Version  string `json:"version,omitempty"`
+}
+
+// bottleTab is the part of the receipt Homebrew embeds in bottles that we
+// carry over into our own receipt
+type bottleTab struct {
+	// RuntimeDependencies is nil when the tab doesn't record any
+	RuntimeDependencies []RuntimeDependency `json:"runtime_dependencies"`
+	Compiler            string              `json:"compiler"`
+}
+
+// readBottleTab reads the tab a poured bottle left at the root of kegPath,
+// if it has one
+func readBottleTab(kegPath string) *bottleTab {
+	data, err := os.ReadFile(filepath.Join(kegPath, "INSTALL_RECEIPT.json"))
+	if err != nil {
+		return nil
+	}
+
+	var tab bottleTab
+	if err := json.Unmarshal(data, &tab); err != nil {
+		return nil
+	}
+	return &tab
+}
+
+// dependencyNames returns the names of the tab's runtime dependencies,
+// without the tap that qualifies formulae from outside homebrew/core
+func (t *bottleTab) dependencyNames() []string {
+	names := make([]string, 0, len(t.RuntimeDependencies))
+	for _, dep := range t.RuntimeDependencies {
+		names = append(names, dep.FullName[strings.LastIndex(dep.FullName, "/")+1:])
+	}
+	return names
+}
@@ -0,0 +1,86 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestInstallBottleRecordsTabDependencies(t *testing.T) {
+	logger.Init(false, false, true)
+
+	cfg := newLocalFormulaConfig(t)
+	inst := New(cfg, &Options{})
+
+	tab := `{"homebrew_version": "4.4.0", "compiler": "clang", "poured_from_bottle": false,
+		"runtime_dependencies": [{"full_name": "openssl@3", "version": "3.3.2"}, {"full_name": "acme/tools/widget", "version": "2.0"}]}`
+	formulaDir := localFormulaDir(cfg)
+	bottle := writeSourceTarball(t, filepath.Join(formulaDir, "hello.bottle.tar.gz"), map[string]string{
+		"hello/1.0.0/bin/hello":            helloScript,
+		"hello/1.0.0/INSTALL_RECEIPT.json": tab,
+	})
+	sum := sha256.Sum256(bottle)
+
+	// The formula declares no dependencies; the bottle's tab is authoritative
+	formulaYAML := fmt.Sprintf("name: hello\nversion: 1.0.0\nurl: https://example.com/hello-1.0.tar.gz\nsha256: %s\n"+
+		"bottle:\n  stable:\n    files:\n      %s:\n        url: file://%s\n        sha256: %s\n",
+		hex.EncodeToString(sum[:]), inst.platformTag(), filepath.Join(formulaDir, "hello.bottle.tar.gz"), hex.EncodeToString(sum[:]))
+	formulaPath := writeLocalFormula(t, cfg, "hello", formulaYAML, nil)
+
+	result, err := inst.InstallFormula(formulaPath)
+	if err != nil {
+		t.Fatalf("InstallFormula() error = %v", err)
+	}
+	if result.Source != "bottle" {
+		t.Fatalf("Expected a bottle install, got %s", result.Source)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.HomebrewCellar, "hello", "1.0.0", "INSTALL_RECEIPT.json"))
+	if err != nil {
+		t.Fatalf("Expected an install receipt: %v", err)
+	}
+	var receipt InstallReceipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		t.Fatal(err)
+	}
+
+	// Homebrew's bottles nest the keg under NAME/VERSION; it is poured un-nested
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "hello", "1.0.0", "bin", "hello")); err != nil {
+		t.Errorf("Expected bin/hello at the keg root: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "hello", "1.0.0", "hello")); !os.IsNotExist(err) {
+		t.Error("Expected the bottle's NAME/VERSION nesting to be removed")
+	}
+
+	if want := []string{"openssl@3", "widget"}; !slices.Equal(receipt.Dependencies, want) {
+		t.Errorf("Dependencies = %v, want %v", receipt.Dependencies, want)
+	}
+	if len(receipt.RuntimeDependencies) != 2 || receipt.RuntimeDependencies[0].Version != "3.3.2" {
+		t.Errorf("Unexpected runtime dependencies: %+v", receipt.RuntimeDependencies)
+	}
+	if !receipt.PouredFromBottle || receipt.Compiler != "clang" || receipt.InstalledBy != "brew-go" {
+		t.Errorf("Unexpected receipt: %+v", receipt)
+	}
+}
+
+func TestReadBottleTab(t *testing.T) {
+	kegPath := t.TempDir()
+	if readBottleTab(kegPath) != nil {
+		t.Error("Expected no tab in an empty keg")
+	}
+
+	if err := os.WriteFile(filepath.Join(kegPath, "INSTALL_RECEIPT.json"), []byte(`{"compiler": "gcc"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tab := readBottleTab(kegPath)
+	if tab == nil || tab.RuntimeDependencies != nil || tab.Compiler != "gcc" {
+		t.Errorf("Expected a tab without recorded dependencies, got %+v", tab)
+	}
+}
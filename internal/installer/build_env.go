@@ -0,0 +1,53 @@
+package installer
+
+import (
+	"strings"
+)
+
+// defaultEnvAllowlist is the part of the user's environment builds inherit.
+// Entries ending in * match by prefix.
+var defaultEnvAllowlist = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "TMPDIR",
+	"LANG", "LC_*",
+	"http_proxy", "https_proxy", "no_proxy", "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"SSH_AUTH_SOCK", "SSL_CERT_FILE", "SSL_CERT_DIR",
+}
+
+// sanitizeEnv keeps only allowed variables from environ. Each filter entry
+// (from HOMEBREW_ENV_FILTER) allows another variable, or denies one when
+// prefixed with "-"; deny entries win over the defaults.
+func sanitizeEnv(environ, filter []string) []string {
+	allow := append([]string{}, defaultEnvAllowlist...)
+	var deny []string
+	for _, entry := range filter {
+		entry = strings.TrimSpace(entry)
+		if name, ok := strings.CutPrefix(entry, "-"); ok {
+			deny = append(deny, name)
+		} else if entry != "" {
+			allow = append(allow, entry)
+		}
+	}
+
+	var env []string
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if matchesEnvPattern(name, allow) && !matchesEnvPattern(name, deny) {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// matchesEnvPattern reports whether name matches one of patterns
+func matchesEnvPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if prefix != "" && strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
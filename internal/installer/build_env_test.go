@@ -0,0 +1,93 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestBuildEnvDropsDisallowedVariables(t *testing.T) {
+	logger.Init(false, false, true)
+	t.Setenv("LD_PRELOAD", "/tmp/evil.so")
+	t.Setenv("CFLAGS", "-O0")
+	t.Setenv("HOME", "/home/builder")
+
+	cfg := &config.Config{HomebrewPrefix: "/opt/brew", HomebrewCellar: "/opt/brew/Cellar"}
+	env := New(cfg, &Options{CC: "clang"}).buildEnv("/opt/brew/Cellar/foo/1.0")
+
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "LD_PRELOAD=") || strings.HasPrefix(kv, "CFLAGS=") {
+			t.Errorf("Expected %s to be filtered from the build environment", kv)
+		}
+	}
+	for _, want := range []string{"HOME=/home/builder", "PREFIX=/opt/brew/Cellar/foo/1.0", "HOMEBREW_PREFIX=/opt/brew", "CC=clang"} {
+		if !slices.Contains(env, want) {
+			t.Errorf("Expected %s in build environment, got %v", want, env)
+		}
+	}
+}
+
+func TestSanitizeEnv(t *testing.T) {
+	environ := []string{"PATH=/usr/bin", "LC_ALL=C", "CFLAGS=-O0", "MAKEFLAGS=-j64", "LD_PRELOAD=x.so", "FOO_A=1", "FOO_B=2"}
+
+	tests := []struct {
+		name   string
+		filter []string
+		want   []string
+	}{
+		{"defaults", nil, []string{"PATH=/usr/bin", "LC_ALL=C"}},
+		{"allow extra", []string{"CFLAGS", " MAKEFLAGS "}, []string{"PATH=/usr/bin", "LC_ALL=C", "CFLAGS=-O0", "MAKEFLAGS=-j64"}},
+		{"allow prefix", []string{"FOO_*"}, []string{"PATH=/usr/bin", "LC_ALL=C", "FOO_A=1", "FOO_B=2"}},
+		{"deny default", []string{"-LC_*"}, []string{"PATH=/usr/bin"}},
+		{"deny wins", []string{"LD_PRELOAD", "-LD_PRELOAD"}, []string{"PATH=/usr/bin", "LC_ALL=C"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeEnv(environ, tt.filter)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("sanitizeEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallFromSourceChecksCompiler(t *testing.T) {
+	logger.Init(false, false, true)
+
+	cfg := newLocalFormulaConfig(t)
+
+	// Building would leave a marker behind
+	marker := filepath.Join(t.TempDir(), "built")
+	tarball := writeSourceTarball(t, filepath.Join(localFormulaDir(cfg), "hello-1.0.tar.gz"), map[string]string{
+		"hello-1.0/Makefile": "all:\n\ttouch " + marker + "\ninstall:\n\ttouch " + marker + "\n",
+	})
+	sum := sha256.Sum256(tarball)
+	formulaPath := writeLocalFormula(t, cfg, "hello", "name: hello\nversion: 1.0.0\nbottle: unneeded\nurl: hello-1.0.tar.gz\nsha256: "+hex.EncodeToString(sum[:])+"\n", nil)
+
+	_, err := New(cfg, &Options{CC: "nonexistent-cc"}).InstallFormula(formulaPath)
+	if err == nil || !strings.Contains(err.Error(), `compiler "nonexistent-cc"`) {
+		t.Fatalf("InstallFormula() error = %v, want missing compiler", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Build commands ran despite the missing compiler")
+	}
+
+	// The compiler is checked before dependencies are resolved
+	withDep := writeLocalFormula(t, cfg, "hello-dep", "name: hello-dep\nversion: 1.0.0\nbottle: unneeded\nurl: hello-1.0.tar.gz\nsha256: "+hex.EncodeToString(sum[:])+"\ndependencies:\n  - no-such-dependency\n", nil)
+	_, err = New(cfg, &Options{CC: "nonexistent-cc"}).InstallFormula(withDep)
+	if err == nil || !strings.Contains(err.Error(), `compiler "nonexistent-cc"`) {
+		t.Fatalf("InstallFormula() with a dependency error = %v, want missing compiler", err)
+	}
+
+	if cc := New(cfg, &Options{CC: "llvm"}).opts.CC; cc != "clang" {
+		t.Errorf("--cc llvm normalized to %q, want clang", cc)
+	}
+}
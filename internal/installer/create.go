@@ -0,0 +1,149 @@
+package installer
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// ScaffoldOptions override what Scaffold guesses from the URL
+type ScaffoldOptions struct {
+	Name    string
+	Version string
+}
+
+// Scaffold is a drafted formula together with how its source builds
+type Scaffold struct {
+	Formula       *formula.Formula
+	BuildSystem   string
+	BuildCommands [][]string
+}
+
+// archiveExtensions are stripped from source filenames, longest first
+var archiveExtensions = []string{".tar.gz", ".tar.bz2", ".tar.xz", ".tar.zst", ".tgz", ".tbz2", ".txz", ".tar", ".zip"}
+
+// versionSuffix matches a trailing "-1.2.3" or "_v1.2.3" in a filename
+var versionSuffix = regexp.MustCompile(`^(.+?)[-_]v?(\d[\w.]*)$`)
+
+// bareVersion matches filenames that are only a version, as in GitHub archives
+var bareVersion = regexp.MustCompile(`^v?(\d[\w.]*)$`)
+
+// buildSystemDependencies are the build dependencies each build system needs
+var buildSystemDependencies = map[string][]string{
+	"autotools-generate": {"autoconf", "automake", "libtool"},
+	"cmake":              {"cmake"},
+	"meson":              {"meson", "ninja"},
+	"ninja":              {"ninja"},
+	"rust-cargo":         {"rust"},
+	"go-modules":         {"go"},
+	"npm":                {"node"},
+	"python-setuptools":  {"python@3"},
+	"python-pip":         {"python@3"},
+	"bazel":              {"bazel"},
+}
+
+// Scaffold downloads a source tarball and drafts a formula for it: the
+// checksum is computed, the name and version are guessed from the URL and
+// build dependencies follow from the detected build system
+func (i *Installer) Scaffold(sourceURL string, opts ScaffoldOptions) (*Scaffold, error) {
+	name, version := GuessNameAndVersion(sourceURL)
+	if opts.Name != "" {
+		name = opts.Name
+	}
+	if opts.Version != "" {
+		version = opts.Version
+	}
+	if name == "" {
+		return nil, fmt.Errorf("could not guess a formula name from %s; use --set-name", sourceURL)
+	}
+	if version == "" {
+		return nil, fmt.Errorf("could not guess a version from %s; use --set-version", sourceURL)
+	}
+	if err := formula.ValidateName(name); err != nil {
+		return nil, fmt.Errorf("%w; use --set-name", err)
+	}
+
+	workDir, err := os.MkdirTemp(i.cfg.HomebrewTemp, TempPrefix+"create-"+name+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	archivePath := filepath.Join(workDir, "source.tar.gz")
+	sha, err := i.downloadFileDigest(sourceURL, archivePath, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download source: %w", err)
+	}
+
+	f := &formula.Formula{
+		Name:    name,
+		Version: version,
+		URL:     sourceURL,
+		SHA256:  sha,
+	}
+	scaffold := &Scaffold{Formula: f}
+
+	// An unrecognised layout still yields a usable draft
+	extractDir := filepath.Join(workDir, "extracted")
+	if err := i.extractArchive(archivePath, extractDir); err != nil {
+		logger.Warn("Could not extract source to detect the build system: %v", err)
+		return scaffold, nil
+	}
+	sourceDir, err := i.findSourceDirectory(extractDir)
+	if err != nil {
+		logger.Warn("Could not find the source directory: %v", err)
+		return scaffold, nil
+	}
+
+	commands, buildSystem, err := i.detectBuildSystem(sourceDir, f.GetCellarPath(i.cfg.HomebrewCellar))
+	if err != nil {
+		logger.Warn("Could not detect the build system: %v", err)
+		return scaffold, nil
+	}
+	scaffold.BuildSystem = buildSystem
+	scaffold.BuildCommands = commands
+	f.BuildDependencies = buildSystemDependencies[buildSystem]
+
+	return scaffold, nil
+}
+
+// GuessNameAndVersion derives a formula name and version from a source URL
+// such as https://example.com/foo-1.2.3.tar.gz or a GitHub archive URL
+func GuessNameAndVersion(sourceURL string) (string, string) {
+	urlPath := sourceURL
+	if u, err := url.Parse(sourceURL); err == nil && u.Path != "" {
+		urlPath = u.Path
+	}
+
+	base := path.Base(urlPath)
+	for _, ext := range archiveExtensions {
+		if trimmed, ok := strings.CutSuffix(base, ext); ok {
+			base = trimmed
+			break
+		}
+	}
+
+	if m := versionSuffix.FindStringSubmatch(base); m != nil {
+		return strings.ToLower(m[1]), m[2]
+	}
+
+	// GitHub archives are named after the tag: <owner>/<repo>/archive/v1.2.3.tar.gz
+	if m := bareVersion.FindStringSubmatch(base); m != nil {
+		parts := strings.Split(strings.Trim(urlPath, "/"), "/")
+		for idx, part := range parts {
+			if part == "archive" && idx > 0 {
+				return strings.ToLower(parts[idx-1]), m[1]
+			}
+		}
+		return "", m[1]
+	}
+
+	return strings.ToLower(base), ""
+}
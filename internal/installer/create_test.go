@@ -0,0 +1,26 @@
+package installer
+
+import "testing"
+
+func TestGuessNameAndVersion(t *testing.T) {
+	tests := []struct {
+		url         string
+		wantName    string
+		wantVersion string
+	}{
+		{"https://ftp.gnu.org/gnu/wget/wget-1.21.4.tar.gz", "wget", "1.21.4"},
+		{"https://example.com/dl/Foo_Bar-v2.0.tgz", "foo_bar", "2.0"},
+		{"https://example.com/libfoo_3.1.tar.xz", "libfoo", "3.1"},
+		{"https://github.com/owner/tool/archive/v0.9.1.tar.gz", "tool", "0.9.1"},
+		{"https://github.com/owner/tool/archive/refs/tags/1.0.tar.gz", "tool", "1.0"},
+		{"file:///tmp/src/hello-2.12.tar.gz", "hello", "2.12"},
+		{"https://example.com/unversioned.tar.gz", "unversioned", ""},
+	}
+
+	for _, tt := range tests {
+		name, version := GuessNameAndVersion(tt.url)
+		if name != tt.wantName || version != tt.wantVersion {
+			t.Errorf("GuessNameAndVersion(%q) = %q, %q; want %q, %q", tt.url, name, version, tt.wantName, tt.wantVersion)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pilshchikov/homebrew-go/internal/cask"
+	"github.com/pilshchikov/homebrew-go/internal/errors"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// Dependency is a formula or cask required by another package
+type Dependency struct {
+	Name string
+	Cask bool
+}
+
+func (d Dependency) String() string {
+	if d.Cask {
+		return d.Name + " (cask)"
+	}
+	return d.Name
+}
+
+// formulaDependencies returns the formula and cask dependencies of a formula.
+// Build dependencies are only needed when building from source and test
+// dependencies only when asked for.
+func formulaDependencies(f *formula.Formula, includeBuild, includeTest bool) []Dependency {
+	var deps []Dependency
+	for _, name := range f.GetDependencies(includeBuild) {
+		deps = append(deps, Dependency{Name: name})
+	}
+	if includeTest {
+		for _, name := range f.TestDependencies {
+			deps = append(deps, Dependency{Name: name})
+		}
+	}
+	for _, name := range f.CaskDependencies {
+		deps = append(deps, Dependency{Name: name, Cask: true})
+	}
+	return deps
+}
+
+// buildDependencies returns only the dependencies needed to build a formula
+func buildDependencies(f *formula.Formula) []Dependency {
+	var deps []Dependency
+	for _, name := range f.GetBuildDependencies() {
+		deps = append(deps, Dependency{Name: name})
+	}
+	return deps
+}
+
+// dependenciesFor returns what must be installed before f, leaving out build
+// dependencies when f will be poured from a bottle
+func (i *Installer) dependenciesFor(f *formula.Formula) []Dependency {
+	return formulaDependencies(f, !i.shouldUseBottle(f), i.opts.IncludeTest)
+}
+
+// caskDependencies returns the formula and cask dependencies declared in depends_on
+func caskDependencies(c *cask.Cask) []Dependency {
+	var deps []Dependency
+	for _, dependsOn := range c.Depends {
+		for _, name := range dependsOn.Formula {
+			deps = append(deps, Dependency{Name: name})
+		}
+		for _, name := range dependsOn.Cask {
+			deps = append(deps, Dependency{Name: name, Cask: true})
+		}
+	}
+	return deps
+}
+
+// installDependency installs a formula dependency. Options that only make
+// sense for the formulae named on the command line, such as --interactive,
+// --no-link and reinstalling, are not passed on to it.
+func (i *Installer) installDependency(name string) (*InstallResult, error) {
+	opts := *i.opts
+	opts.Interactive = false
+	opts.Git = false
+	opts.NoLink = false
+	opts.Reinstall = false
+
+	dep := *i
+	dep.opts = &opts
+	return dep.InstallFormula(name)
+}
+
+// installDependencyList installs each missing dependency with the matching installer
+func (i *Installer) installDependencyList(parent string, deps []Dependency) error {
+	if len(deps) == 0 {
+		logger.Debug("No dependencies to install")
+		return nil
+	}
+
+	names := make([]string, len(deps))
+	for idx, dep := range deps {
+		names[idx] = dep.String()
+	}
+	logger.Step("Installing %d dependencies: %s", len(deps), strings.Join(names, ", "))
+
+	// Dependencies committed by an earlier, interrupted run are not redone
+	var skipped []string
+	for idx, dep := range deps {
+		logger.Progress("Installing dependency %d/%d: %s", idx+1, len(deps), dep)
+
+		// Check if already installed
+		if installed, err := i.isDependencyInstalled(dep); err != nil {
+			return errors.NewDependencyError(parent, dep.Name,
+				fmt.Errorf("failed to check if %s is installed: %w", dep.Name, err))
+		} else if installed {
+			logger.Step("Dependency %s already installed", dep)
+			skipped = append(skipped, dep.String())
+			continue
+		}
+
+		// Recursively install dependency with the installer for its type
+		install := i.installFormulaFunc
+		if dep.Cask {
+			install = i.installCaskFunc
+		}
+
+		if _, err := install(dep.Name); err != nil {
+			// Wrap the error with dependency context
+			if brewErr, ok := err.(*errors.BrewError); ok {
+				return errors.NewDependencyError(parent, dep.Name, brewErr)
+			}
+			return errors.NewDependencyError(parent, dep.Name, err)
+		}
+
+		logger.Success("Dependency %s installed successfully", dep)
+	}
+
+	if len(skipped) > 0 {
+		logger.Info("Skipped dependencies already present: %s", strings.Join(skipped, ", "))
+	}
+	logger.Success("All dependencies installed successfully")
+	return nil
+}
+
+// isDependencyInstalled reports whether a dependency is installed. Formula
+// dependencies need a committed keg; a partial keg or staging directory left
+// behind by an interrupted install does not count.
+func (i *Installer) isDependencyInstalled(dep Dependency) (bool, error) {
+	if dep.Cask {
+		return (&cask.Cask{Token: dep.Name}).IsInstalled(i.cfg.HomebrewCaskroom), nil
+	}
+	return i.hasCommittedKeg(dep.Name)
+}
+
+// hasCommittedKeg reports whether the cellar holds a finished keg of name
+func (i *Installer) hasCommittedKeg(name string) (bool, error) {
+	return HasCommittedKeg(i.cfg.HomebrewCellar, name)
+}
+
+// HasCommittedKeg reports whether cellar holds a finished keg of name. Kegs
+// left behind by an interrupted install have no receipt and don't count;
+// staging directories are hidden and are skipped.
+func HasCommittedKeg(cellar, name string) (bool, error) {
+	entries, err := os.ReadDir(filepath.Join(cellar, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") &&
+			isCommittedKeg(filepath.Join(cellar, name, entry.Name())) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,234 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestInstallCaskWithFormulaDependency(t *testing.T) {
+	logger.Init(false, false, true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cask/needs-formula.json" {
+			_, _ = w.Write([]byte(`{
+				"token": "needs-formula",
+				"version": "1.0.0",
+				"depends_on": {"formula": ["helper-formula"]}
+			}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewCellar:   filepath.Join(tmpDir, "Cellar"),
+		HomebrewCaskroom: filepath.Join(tmpDir, "Caskroom"),
+		HomebrewCache:    filepath.Join(tmpDir, "Cache"),
+	}
+	inst := New(cfg, &Options{DryRun: true})
+
+	var installed []string
+	inst.installFormulaFunc = func(name string) (*InstallResult, error) {
+		installed = append(installed, name)
+		return &InstallResult{Name: name, Success: true}, nil
+	}
+
+	// The cask itself may not be installable on this platform; only the
+	// dependency resolution is under test here
+	_, _ = inst.InstallCask("needs-formula")
+
+	if len(installed) != 1 || installed[0] != "helper-formula" {
+		t.Errorf("Expected formula dependency helper-formula to be installed, got %v", installed)
+	}
+}
+
+func TestInstallDependenciesCrossType(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewCellar:   filepath.Join(tmpDir, "Cellar"),
+		HomebrewCaskroom: filepath.Join(tmpDir, "Caskroom"),
+	}
+	inst := New(cfg, &Options{})
+
+	// Already installed formula dependency is skipped
+	if err := os.MkdirAll(filepath.Join(cfg.HomebrewCellar, "installed-dep", "1.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.HomebrewCellar, "installed-dep", "1.0", "INSTALL_RECEIPT.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var formulae, casks []string
+	inst.installFormulaFunc = func(name string) (*InstallResult, error) {
+		formulae = append(formulae, name)
+		return &InstallResult{Name: name, Success: true}, nil
+	}
+	inst.installCaskFunc = func(name string) (*InstallResult, error) {
+		casks = append(casks, name)
+		return &InstallResult{Name: name, Success: true}, nil
+	}
+
+	f := &formula.Formula{
+		Name:             "needs-cask",
+		Dependencies:     []string{"installed-dep", "missing-dep"},
+		CaskDependencies: []string{"some-cask"},
+	}
+
+	if err := inst.installDependencies(f); err != nil {
+		t.Fatalf("installDependencies() error = %v", err)
+	}
+
+	if len(formulae) != 1 || formulae[0] != "missing-dep" {
+		t.Errorf("Formula installs = %v, want [missing-dep]", formulae)
+	}
+	if len(casks) != 1 || casks[0] != "some-cask" {
+		t.Errorf("Cask installs = %v, want [some-cask]", casks)
+	}
+}
+
+func TestInstallDependenciesBuildOnlyForSource(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"bottle skips build deps", Options{}, []string{"runtime-dep"}},
+		{"source installs build deps", Options{BuildFromSource: true}, []string{"runtime-dep", "build-dep"}},
+		{"test deps only when asked", Options{IncludeTest: true}, []string{"runtime-dep", "test-dep"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{HomebrewCellar: filepath.Join(t.TempDir(), "Cellar")}
+			opts := tt.opts
+			inst := New(cfg, &opts)
+
+			var installed []string
+			inst.installFormulaFunc = func(name string) (*InstallResult, error) {
+				installed = append(installed, name)
+				return &InstallResult{Name: name, Success: true}, nil
+			}
+
+			f := &formula.Formula{
+				Name:              "tool",
+				Version:           "1.0",
+				Dependencies:      []string{"runtime-dep"},
+				BuildDependencies: []string{"build-dep"},
+				TestDependencies:  []string{"test-dep"},
+				Bottle: &formula.Bottle{Stable: &formula.BottleSpec{Files: map[string]formula.BottleFile{
+					inst.platformTag(): {URL: "https://example.com/tool.bottle.tar.gz", SHA256: "abc"},
+				}}},
+			}
+
+			if err := inst.installDependencies(f); err != nil {
+				t.Fatalf("installDependencies() error = %v", err)
+			}
+			if strings.Join(installed, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Installed %v, want %v", installed, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallDependenciesResumesInterruptedInstall(t *testing.T) {
+	logger.Init(false, false, true)
+
+	cfg := &config.Config{HomebrewCellar: filepath.Join(t.TempDir(), "Cellar")}
+	inst := New(cfg, &Options{})
+
+	// first-dep was committed by the interrupted run; second-dep only got
+	// as far as staging its bottle and third-dep was killed mid-build
+	if err := os.MkdirAll(filepath.Join(cfg.HomebrewCellar, "first-dep", "1.0", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.HomebrewCellar, "first-dep", "1.0", "INSTALL_RECEIPT.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(cfg.HomebrewCellar, "second-dep", "."+TempPrefix+"pour-1.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(cfg.HomebrewCellar, "third-dep", "1.0", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var installed []string
+	inst.installFormulaFunc = func(name string) (*InstallResult, error) {
+		installed = append(installed, name)
+		return &InstallResult{Name: name, Success: true}, nil
+	}
+
+	f := &formula.Formula{
+		Name:         "tool",
+		Dependencies: []string{"first-dep", "second-dep", "third-dep"},
+	}
+	if err := inst.installDependencyList(f.Name, formulaDependencies(f, false, false)); err != nil {
+		t.Fatalf("installDependencyList() error = %v", err)
+	}
+
+	if strings.Join(installed, ",") != "second-dep,third-dep" {
+		t.Errorf("Installed %v, want [second-dep third-dep]", installed)
+	}
+}
+
+func TestHasCommittedKegAfterPartialInstall(t *testing.T) {
+	logger.Init(false, false, true)
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not available")
+	}
+
+	cfg := newLocalFormulaConfig(t)
+	inst := New(cfg, &Options{BuildFromSource: true})
+
+	// The build dies after copying files into the keg, as a killed install would
+	for _, tt := range []struct {
+		install   string
+		committed bool
+	}{
+		{"install:\n\tmkdir -p $(PREFIX)/bin\n\tcp hello $(PREFIX)/bin/hello\n\tfalse\n", false},
+		{"install:\n\tmkdir -p $(PREFIX)/bin\n\tcp hello $(PREFIX)/bin/hello\n", true},
+	} {
+		tarball := writeSourceTarball(t, filepath.Join(localFormulaDir(cfg), "dep-1.0.tar.gz"), map[string]string{
+			"dep-1.0/Makefile": "all:\n\t@true\n\n" + tt.install,
+			"dep-1.0/hello":    helloScript,
+		})
+		sum := sha256.Sum256(tarball)
+		formulaPath := writeLocalFormula(t, cfg, "dep", "name: dep\nversion: 1.0.0\nurl: dep-1.0.tar.gz\nsha256: "+hex.EncodeToString(sum[:])+"\n", nil)
+		_ = os.RemoveAll(cfg.HomebrewCache)
+
+		_, err := inst.InstallFormula(formulaPath)
+		if (err == nil) != tt.committed {
+			t.Fatalf("InstallFormula() error = %v", err)
+		}
+		if !tt.committed {
+			if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "dep", "1.0.0", "bin", "hello")); err != nil {
+				t.Fatalf("Expected the failed build to leave a partial keg: %v", err)
+			}
+		}
+
+		committed, err := inst.hasCommittedKeg("dep")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if committed != tt.committed {
+			t.Errorf("hasCommittedKeg() = %v, want %v", committed, tt.committed)
+		}
+	}
+}
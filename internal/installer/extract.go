@@ -0,0 +1,278 @@
+package installer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pilshchikov/homebrew-go/internal/errors"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// archiveKind is the container format of a download
+type archiveKind int
+
+const (
+	// archiveNone is a file that isn't a recognised archive
+	archiveNone archiveKind = iota
+	// archiveTarGz is a gzip-compressed tarball
+	archiveTarGz
+	// archiveTar is an uncompressed tarball
+	archiveTar
+	// archiveTarBz2 is a bzip2-compressed tarball
+	archiveTarBz2
+	// archiveZip is a zip archive
+	archiveZip
+	// archiveTarXz is an xz-compressed tarball, which can't be extracted
+	archiveTarXz
+)
+
+// String returns a human-readable name for the archive kind
+func (k archiveKind) String() string {
+	switch k {
+	case archiveTarGz:
+		return "gzip tarball"
+	case archiveTar:
+		return "tarball"
+	case archiveTarBz2:
+		return "bzip2 tarball"
+	case archiveZip:
+		return "zip archive"
+	case archiveTarXz:
+		return "xz tarball"
+	}
+	return "not an archive"
+}
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zipMagic   = []byte("PK\x03\x04")
+	// tarMagic is found at offset 257 of ustar, pax and GNU tar headers
+	tarMagic       = []byte("ustar")
+	tarMagicOffset = 257
+)
+
+// detectArchive identifies path's format from its leading bytes, falling back
+// to the extension of name (the original file name) for old tar formats that
+// carry no magic
+func detectArchive(path, name string) (archiveKind, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return archiveNone, err
+	}
+	defer func() { _ = file.Close() }()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return archiveNone, err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return archiveTarGz, nil
+	case bytes.HasPrefix(header, bzip2Magic):
+		return archiveTarBz2, nil
+	case bytes.HasPrefix(header, xzMagic):
+		return archiveTarXz, nil
+	case bytes.HasPrefix(header, zipMagic):
+		return archiveZip, nil
+	case len(header) >= tarMagicOffset+len(tarMagic) && bytes.Equal(header[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic):
+		return archiveTar, nil
+	case strings.HasSuffix(name, ".tar"):
+		return archiveTar, nil
+	}
+	return archiveNone, nil
+}
+
+// extractArchive unpacks a tarball or zip archive into destDir
+func (i *Installer) extractArchive(archivePath, destDir string) error {
+	kind, err := detectArchive(archivePath, filepath.Base(archivePath))
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case archiveZip:
+		return extractZip(archivePath, destDir)
+	case archiveTarXz:
+		return fmt.Errorf("unsupported archive format: %s is an %s", filepath.Base(archivePath), kind)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	var r io.Reader = file
+	switch kind {
+	case archiveTarGz:
+		gzr, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = gzr.Close() }()
+		r = gzr
+	case archiveTarBz2:
+		r = bzip2.NewReader(file)
+	case archiveTar:
+	default:
+		return fmt.Errorf("%s is %s", filepath.Base(archivePath), kind)
+	}
+
+	return extractTar(r, destDir)
+}
+
+// archiveTarget returns where an archive entry is written under destDir,
+// rejecting absolute names and names that climb out of it
+func archiveTarget(destDir, name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("archive entry %q is outside the destination", name)
+	}
+	target := filepath.Join(destDir, name)
+	if target != filepath.Clean(destDir) && !strings.HasPrefix(target, filepath.Clean(destDir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("archive entry %q is outside the destination", name)
+	}
+	return target, nil
+}
+
+// extractTar writes the directories and regular files of a tar stream
+// under destDir
+func extractTar(r io.Reader, destDir string) error {
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := archiveTarget(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			// Safely convert header.Mode to avoid integer overflow
+			// #nosec G115 - Intentionally masking mode to safe range
+			mode := os.FileMode(header.Mode & 0777) // Mask to only file permission bits
+			if err := os.MkdirAll(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+
+			// Safely convert header.Mode to avoid integer overflow
+			// #nosec G115 - Intentionally masking mode to safe range
+			mode := os.FileMode(header.Mode & 0777) // Mask to only file permission bits
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+
+			if _, err := io.Copy(f, tr); err != nil {
+				_ = f.Close()
+				return err
+			}
+			_ = f.Close()
+		}
+	}
+
+	return nil
+}
+
+// extractZip writes the directories and regular files of a zip archive
+// under destDir
+func extractZip(archivePath, destDir string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = zr.Close() }()
+
+	for _, zf := range zr.File {
+		target, err := archiveTarget(destDir, zf.Name)
+		if err != nil {
+			return err
+		}
+		mode := zf.Mode()
+
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, mode.Perm()|0700); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := extractZipFile(zf, target); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// extractZipFile copies a single zip entry to target
+func extractZipFile(zf *zip.File, target string) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR|os.O_TRUNC, zf.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// installBareBinary installs a download that is a single executable rather
+// than an archive as the formula's declared binary
+func (i *Installer) installBareBinary(f *formula.Formula, sourcePath string) error {
+	if f.Binary == "" {
+		return fmt.Errorf("source of %s is not an archive; set `binary` in the formula if it is a bare executable", f.Name)
+	}
+	if f.Binary != filepath.Base(f.Binary) || strings.HasPrefix(f.Binary, ".") {
+		return fmt.Errorf("invalid binary name %q for %s", f.Binary, f.Name)
+	}
+
+	binDir := filepath.Join(f.GetCellarPath(i.cfg.HomebrewCellar), "bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return errors.NewPermissionError("create bin directory", binDir, err)
+	}
+
+	target := filepath.Join(binDir, f.Binary)
+	logger.Debug("Installing bare binary %s as %s", sourcePath, target)
+	if err := copyLocalFile(sourcePath, target, io.Discard); err != nil {
+		return err
+	}
+	if err := os.Chmod(target, 0755); err != nil {
+		return errors.NewPermissionError("make executable", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package installer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// writeTarball writes an uncompressed tarball of files to path
+func writeTarball(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeZip writes a zip archive of files to path
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		hdr.SetMode(0755)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetectArchive(t *testing.T) {
+	tempDir := t.TempDir()
+
+	gzPath := filepath.Join(tempDir, "download")
+	writeSourceTarball(t, gzPath, map[string]string{"hello-1.0/hello": "hello\n"})
+	tarPath := filepath.Join(tempDir, "download-tar")
+	writeTarball(t, tarPath, map[string]string{"hello-1.0/hello": "hello\n"})
+	scriptPath := filepath.Join(tempDir, "script")
+	if err := os.WriteFile(scriptPath, []byte(helloScript), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	zipPath := filepath.Join(tempDir, "download-zip")
+	writeZip(t, zipPath, map[string]string{"hello-1.0/hello": "hello\n"})
+	bz2Path := filepath.Join(tempDir, "download-bz2")
+	if err := os.WriteFile(bz2Path, []byte("BZh91AY&SY"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	xzPath := filepath.Join(tempDir, "download-xz")
+	if err := os.WriteFile(xzPath, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00, 0x00, 0x04}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		file string
+		want archiveKind
+	}{
+		{"gzip by magic", gzPath, "hello-1.0.tgz", archiveTarGz},
+		{"zip by magic", zipPath, "hello-1.0.zip", archiveZip},
+		{"bzip2 by magic", bz2Path, "hello-1.0.tar.bz2", archiveTarBz2},
+		{"xz by magic", xzPath, "hello-1.0.tar.xz", archiveTarXz},
+		{"tar by magic", tarPath, "hello-1.0", archiveTar},
+		{"tar by extension", scriptPath, "hello-1.0.tar", archiveTar},
+		{"bare script", scriptPath, "hello", archiveNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectArchive(tt.path, tt.file)
+			if err != nil {
+				t.Fatalf("detectArchive() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("detectArchive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractArchiveUncompressedTar(t *testing.T) {
+	tempDir := t.TempDir()
+	tarPath := filepath.Join(tempDir, "hello-1.0.tar")
+	writeTarball(t, tarPath, map[string]string{
+		"hello-1.0/Makefile": "all:\n",
+		"hello-1.0/hello":    helloScript,
+	})
+
+	inst := New(&config.Config{}, &Options{})
+	destDir := filepath.Join(tempDir, "extracted")
+	if err := inst.extractArchive(tarPath, destDir); err != nil {
+		t.Fatalf("extractArchive() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, "hello-1.0", "hello"))
+	if err != nil || string(data) != helloScript {
+		t.Errorf("Expected hello to be extracted, got %q, %v", data, err)
+	}
+
+	scriptPath := filepath.Join(tempDir, "hello.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := inst.extractArchive(scriptPath, destDir); err == nil {
+		t.Error("Expected extracting a file that isn't an archive to fail")
+	}
+}
+
+func TestExtractArchiveZip(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "hello-1.0.zip")
+	writeZip(t, zipPath, map[string]string{
+		"hello-1.0/Makefile": "all:\n",
+		"hello-1.0/hello":    helloScript,
+	})
+
+	destDir := filepath.Join(tempDir, "extracted")
+	if err := New(&config.Config{}, &Options{}).extractArchive(zipPath, destDir); err != nil {
+		t.Fatalf("extractArchive() error = %v", err)
+	}
+
+	hello := filepath.Join(destDir, "hello-1.0", "hello")
+	data, err := os.ReadFile(hello)
+	if err != nil || string(data) != helloScript {
+		t.Errorf("Expected hello to be extracted, got %q, %v", data, err)
+	}
+	if info, err := os.Stat(hello); err != nil || info.Mode().Perm()&0111 == 0 {
+		t.Errorf("Expected %s to keep its executable mode, got %v, %v", hello, info, err)
+	}
+}
+
+func TestExtractArchiveRejectsEscapingEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(t *testing.T, path string, files map[string]string)
+	}{
+		{"tar", writeTarball},
+		{"zip", writeZip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			archivePath := filepath.Join(tempDir, "evil."+tt.name)
+			tt.write(t, archivePath, map[string]string{"../evil": "pwned\n"})
+
+			destDir := filepath.Join(tempDir, "extracted")
+			err := New(&config.Config{}, &Options{}).extractArchive(archivePath, destDir)
+			if err == nil || !strings.Contains(err.Error(), "outside the destination") {
+				t.Errorf("extractArchive() error = %v, want the entry rejected", err)
+			}
+			if _, err := os.Stat(filepath.Join(tempDir, "evil")); !os.IsNotExist(err) {
+				t.Error("Expected nothing written outside the destination")
+			}
+		})
+	}
+}
+
+func TestExtractArchiveBzip2Tar(t *testing.T) {
+	if _, err := exec.LookPath("bzip2"); err != nil {
+		t.Skip("bzip2 not available")
+	}
+
+	tempDir := t.TempDir()
+	tarPath := filepath.Join(tempDir, "hello-1.0.tar")
+	writeTarball(t, tarPath, map[string]string{"hello-1.0/hello": "hello\n"})
+	if out, err := exec.Command("bzip2", tarPath).CombinedOutput(); err != nil {
+		t.Fatalf("bzip2 failed: %v: %s", err, out)
+	}
+
+	destDir := filepath.Join(tempDir, "extracted")
+	if err := New(&config.Config{}, &Options{}).extractArchive(tarPath+".bz2", destDir); err != nil {
+		t.Fatalf("extractArchive() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(destDir, "hello-1.0", "hello")); err != nil || string(data) != "hello\n" {
+		t.Errorf("Expected hello to be extracted, got %q, %v", data, err)
+	}
+}
+
+func TestInstallFromSourceUnsupportedArchive(t *testing.T) {
+	logger.Init(false, false, true)
+
+	cfg := newLocalFormulaConfig(t)
+
+	writeLocalFormula(t, cfg, "hello", "name: hello\nversion: 1.0\nurl: hello-1.0.tar.xz\n",
+		map[string]string{"hello-1.0.tar.xz": string([]byte{0xfd, '7', 'z', 'X', 'Z', 0x00, 0x00, 0x04})})
+	xzPath := filepath.Join(localFormulaDir(cfg), "hello-1.0.tar.xz")
+
+	f := &formula.Formula{Name: "hello", Version: "1.0", URL: "file://" + xzPath}
+	err := New(cfg, &Options{}).installFromSource(f, &InstallResult{})
+	if err == nil || !strings.Contains(err.Error(), "unsupported archive format") {
+		t.Fatalf("installFromSource() error = %v, want an unsupported archive format error", err)
+	}
+}
+
+func TestInstallFromSourceBareBinary(t *testing.T) {
+	logger.Init(false, false, true)
+
+	cfg := newLocalFormulaConfig(t)
+
+	writeLocalFormula(t, cfg, "hello", "name: hello\nversion: 1.0\nbinary: hello\nurl: hello-1.0.sh\nsha256: "+helloScriptSHA256()+"\n",
+		map[string]string{"hello-1.0.sh": helloScript})
+	scriptPath := filepath.Join(localFormulaDir(cfg), "hello-1.0.sh")
+
+	// Without a declared binary there is nothing to install the file as
+	undeclared := &formula.Formula{Name: "hello", Version: "1.0", URL: "file://" + scriptPath}
+	err := New(cfg, &Options{}).installFromSource(undeclared, &InstallResult{})
+	if err == nil || !strings.Contains(err.Error(), "not an archive") {
+		t.Fatalf("installFromSource() error = %v, want a not-an-archive error", err)
+	}
+
+	f := &formula.Formula{Name: "hello", Version: "1.0", URL: "file://" + scriptPath, Binary: "hello"}
+	result := &InstallResult{}
+	if err := New(cfg, &Options{}).installFromSource(f, result); err != nil {
+		t.Fatalf("installFromSource() error = %v", err)
+	}
+	if result.Version != "1.0" {
+		t.Errorf("result.Version = %q, want 1.0", result.Version)
+	}
+
+	installed := filepath.Join(f.GetCellarPath(cfg.HomebrewCellar), "bin", "hello")
+	info, err := os.Stat(installed)
+	if err != nil {
+		t.Fatalf("Expected the script in the keg's bin: %v", err)
+	}
+	if info.Mode().Perm()&0111 == 0 {
+		t.Errorf("Expected %s to be executable, mode %v", installed, info.Mode())
+	}
+	if data, _ := os.ReadFile(installed); string(data) != helloScript {
+		t.Errorf("Installed script = %q, want %q", data, helloScript)
+	}
+}
+
+func TestInstallFromSourceTimesOnlyTheBuild(t *testing.T) {
+	logger.Init(false, false, true)
+
+	cfg := newLocalFormulaConfig(t)
+
+	writeLocalFormula(t, cfg, "hello", "name: hello\nversion: 1.0\nbinary: hello\nurl: hello-1.0.sh\nsha256: "+helloScriptSHA256()+"\n",
+		map[string]string{"hello-1.0.sh": helloScript})
+	scriptPath := filepath.Join(localFormulaDir(cfg), "hello-1.0.sh")
+
+	// A failed bottle attempt has already spent time extracting
+	result := &InstallResult{BuildDuration: time.Hour}
+	f := &formula.Formula{Name: "hello", Version: "1.0", URL: "file://" + scriptPath, Binary: "hello"}
+	if err := New(cfg, &Options{}).installFromSource(f, result); err != nil {
+		t.Fatalf("installFromSource() error = %v", err)
+	}
+	if result.BuildDuration <= 0 || result.BuildDuration >= time.Hour {
+		t.Errorf("BuildDuration = %v, want only the source build", result.BuildDuration)
+	}
+}
@@ -0,0 +1,190 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestBottleSourceFallback(t *testing.T) {
+	logger.Init(false, false, true)
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not available")
+	}
+
+	goodBottle := []byte("not really a bottle")
+	goodSum := sha256.Sum256(goodBottle)
+
+	tests := []struct {
+		name       string
+		hasBottle  bool
+		status     int
+		body       []byte
+		noFallback bool
+		wantErr    bool
+		wantTries  int32
+	}{
+		{name: "no bottle builds from source", hasBottle: false},
+		{name: "missing bottle builds from source", hasBottle: true, status: http.StatusNotFound, wantTries: 1},
+		{name: "server error retries then builds from source", hasBottle: true, status: http.StatusInternalServerError, wantTries: 2},
+		{name: "checksum mismatch retries then builds from source", hasBottle: true, status: http.StatusOK, body: []byte("tampered"), wantTries: 2},
+		{name: "fallback disabled", hasBottle: true, status: http.StatusInternalServerError, noFallback: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bottleRequests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				bottleRequests.Add(1)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			cfg := newLocalFormulaConfig(t)
+			cfg.NoBottleSourceFallback = tt.noFallback
+			inst := New(cfg, &Options{})
+
+			tarball := writeSourceTarball(t, filepath.Join(localFormulaDir(cfg), "hello-1.0.tar.gz"), map[string]string{
+				"hello-1.0/Makefile": helloMakefile,
+				"hello-1.0/hello":    helloScript,
+			})
+			sum := sha256.Sum256(tarball)
+
+			formulaYAML := "name: hello\nversion: 1.0.0\nurl: hello-1.0.tar.gz\nsha256: " + hex.EncodeToString(sum[:]) + "\n"
+			if tt.hasBottle {
+				formulaYAML += fmt.Sprintf("bottle:\n  stable:\n    files:\n      %s:\n        url: %s/hello.bottle.tar.gz\n        sha256: %s\n",
+					inst.platformTag(), server.URL, hex.EncodeToString(goodSum[:]))
+			}
+			formulaPath := writeLocalFormula(t, cfg, "hello", formulaYAML, nil)
+
+			result, err := inst.InstallFormula(formulaPath)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected the bottle failure to be returned without a source fallback")
+				}
+				if _, statErr := os.Stat(filepath.Join(cfg.HomebrewCellar, "hello", "1.0.0", "bin", "hello")); !os.IsNotExist(statErr) {
+					t.Error("Expected nothing to be built from source")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("InstallFormula() error = %v", err)
+			}
+
+			if result.Source != "source" {
+				t.Errorf("result.Source = %q, want source", result.Source)
+			}
+			if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "hello", "1.0.0", "bin", "hello")); err != nil {
+				t.Errorf("Expected source build in cellar: %v", err)
+			}
+
+			// Each attempt tries the API client download, then a direct one
+			if requests := bottleRequests.Load(); requests != 2*tt.wantTries {
+				t.Errorf("Bottle requested %d times, want %d attempts", requests, tt.wantTries)
+			}
+		})
+	}
+}
+
+func TestIsBottleUnavailable(t *testing.T) {
+	tests := []struct {
+		err  string
+		want bool
+	}{
+		{"failed to download bottle: download failed with status 404 for https://ghcr.io/x", true},
+		{"no bottle available for platform arm64_linux", true},
+		{"failed to download bottle: download failed with status 500 for https://ghcr.io/x", false},
+		{"bottle checksum verification failed: expected abc, got def", false},
+	}
+
+	for _, tt := range tests {
+		if got := isBottleUnavailable(fmt.Errorf("%s", tt.err)); got != tt.want {
+			t.Errorf("isBottleUnavailable(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// captureWarnings returns what fn logs to stderr
+func captureWarnings(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStderr := os.Stderr
+	os.Stderr = w
+	logger.Init(false, false, false)
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+
+	fn()
+
+	os.Stderr = oldStderr
+	_ = w.Close()
+	logger.Init(false, false, true)
+	return string(<-done)
+}
+
+func TestSourceOnlyFormulaHasNoBottleWarning(t *testing.T) {
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not available")
+	}
+
+	tests := []struct {
+		name        string
+		bottle      string
+		wantWarning bool
+	}{
+		{name: "bottle unneeded", bottle: "bottle: unneeded\n"},
+		{name: "no bottle block"},
+		{name: "no bottle for this platform", bottle: "bottle:\n  stable:\n    files:\n      sparc_solaris:\n        url: https://example.com/hello.bottle.tar.gz\n        sha256: abc\n", wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newLocalFormulaConfig(t)
+
+			tarball := writeSourceTarball(t, filepath.Join(localFormulaDir(cfg), "hello-1.0.tar.gz"), map[string]string{
+				"hello-1.0/Makefile": helloMakefile,
+				"hello-1.0/hello":    helloScript,
+			})
+			sum := sha256.Sum256(tarball)
+			formulaYAML := "name: hello\nversion: 1.0.0\nurl: hello-1.0.tar.gz\nsha256: " + hex.EncodeToString(sum[:]) + "\n" + tt.bottle
+			formulaPath := writeLocalFormula(t, cfg, "hello", formulaYAML, nil)
+
+			var result *InstallResult
+			var installErr error
+			warnings := captureWarnings(t, func() {
+				result, installErr = New(cfg, &Options{}).InstallFormula(formulaPath)
+			})
+			if installErr != nil {
+				t.Fatalf("InstallFormula() error = %v", installErr)
+			}
+			if result.Source != "source" {
+				t.Errorf("result.Source = %q, want source", result.Source)
+			}
+
+			hasWarning := strings.Contains(strings.ToLower(warnings), "bottle")
+			if hasWarning != tt.wantWarning {
+				t.Errorf("Bottle warning logged = %v, want %v; stderr:\n%s", hasWarning, tt.wantWarning, warnings)
+			}
+		})
+	}
+}
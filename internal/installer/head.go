@@ -0,0 +1,83 @@
+package installer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// isGitURL reports whether a HEAD URL names a git repository rather than an
+// archive of the latest sources
+func isGitURL(url string) bool {
+	return strings.HasSuffix(url, ".git") || strings.HasPrefix(url, "git@") || strings.HasPrefix(url, "git://")
+}
+
+// cloneHead checks out the formula's HEAD into dir and returns the commit
+func cloneHead(f *formula.Formula, dir string) (string, error) {
+	opts := &git.CloneOptions{URL: f.Head.URL, Depth: 1}
+	if f.Head.Branch != "" {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(f.Head.Branch)
+		opts.SingleBranch = true
+	}
+
+	logger.Debug("Cloning %s", f.Head.URL)
+	repo, err := git.PlainClone(dir, false, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s: %w", f.Head.URL, err)
+	}
+
+	ref, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD of %s: %w", f.Head.URL, err)
+	}
+	return ref.Hash().String(), nil
+}
+
+// RemoteHeadCommit returns the commit the formula's HEAD currently points to
+// upstream, without cloning it
+func RemoteHeadCommit(f *formula.Formula) (string, error) {
+	if f.Head == nil || f.Head.URL == "" {
+		return "", fmt.Errorf("%s has no HEAD", f.Name)
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{f.Head.URL},
+	})
+	refs, err := remote.List(&git.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list %s: %w", f.Head.URL, err)
+	}
+
+	want := plumbing.HEAD
+	if f.Head.Branch != "" {
+		want = plumbing.NewBranchReferenceName(f.Head.Branch)
+	}
+	byName := make(map[plumbing.ReferenceName]*plumbing.Reference, len(refs))
+	for _, ref := range refs {
+		byName[ref.Name()] = ref
+	}
+	ref, ok := byName[want]
+	if ok && ref.Type() == plumbing.SymbolicReference {
+		ref, ok = byName[ref.Target()]
+	}
+	if !ok {
+		return "", fmt.Errorf("%s has no %s", f.Head.URL, want)
+	}
+	return ref.Hash().String(), nil
+}
+
+// setHeadVersion names a HEAD build after its commit so that builds of
+// different commits are installed into separate kegs
+func setHeadVersion(f *formula.Formula, commit string) {
+	f.Version = f.HeadVersion(commit, time.Now())
+	logger.Debug("Installing %s as %s", f.Name, f.Version)
+}
@@ -0,0 +1,83 @@
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestIsGitURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://github.com/user/repo.git":                 true,
+		"git@github.com:user/repo.git":                     true,
+		"git://example.com/repo":                           true,
+		"https://github.com/user/repo/archive/main.tar.gz": false,
+	}
+	for url, expected := range tests {
+		if got := isGitURL(url); got != expected {
+			t.Errorf("isGitURL(%q) = %v, want %v", url, got, expected)
+		}
+	}
+}
+
+func TestCloneHeadDistinctKegs(t *testing.T) {
+	logger.Init(false, false, true)
+
+	// A local repository stands in for the formula's upstream
+	repoDir := filepath.Join(t.TempDir(), "upstream.git")
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repoDir, "main.c"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add("main.c"); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+		if _, err := worktree.Commit(content, &git.CommitOptions{Author: sig}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cellar := t.TempDir()
+	install := func() (string, string) {
+		t.Helper()
+		f := &formula.Formula{Name: "tool", Version: "1.0.0", Head: &formula.Head{URL: repoDir}}
+		sha, err := cloneHead(f, filepath.Join(t.TempDir(), "tool"))
+		if err != nil {
+			t.Fatalf("cloneHead() error = %v", err)
+		}
+		setHeadVersion(f, sha)
+		return sha, f.GetCellarPath(cellar)
+	}
+
+	commit("first")
+	firstSHA, firstKeg := install()
+	commit("second")
+	secondSHA, secondKeg := install()
+
+	if firstSHA == secondSHA {
+		t.Fatal("Expected the second clone to be at a new commit")
+	}
+	if firstKeg == secondKeg {
+		t.Errorf("HEAD builds of different commits share the keg %s", firstKeg)
+	}
+	if want := filepath.Join(cellar, "tool", "HEAD-"+secondSHA[:7]); secondKeg != want {
+		t.Errorf("keg = %s, want %s", secondKeg, want)
+	}
+}
@@ -1,14 +1,18 @@
 package installer
 
 import (
-	"archive/tar"
-	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -21,40 +25,22 @@ import (
 	"github.com/pilshchikov/homebrew-go/internal/formula"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 	"github.com/pilshchikov/homebrew-go/internal/tap"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
 	"github.com/pilshchikov/homebrew-go/internal/verification"
 )
 
-// progressReader wraps an io.Reader to show download progress
+// progressReader wraps an io.Reader to report download progress
 type progressReader struct {
-	reader     io.Reader
-	total      int64
-	current    int64
-	filename   string
-	lastUpdate time.Time
+	reader  io.Reader
+	tracker *progressTracker
 }
 
 func (pr *progressReader) Read(p []byte) (n int, err error) {
 	n, err = pr.reader.Read(p)
-	pr.current += int64(n)
-
-	// Update progress every 100ms to avoid flooding the terminal
-	now := time.Now()
-	if now.Sub(pr.lastUpdate) > 100*time.Millisecond || err == io.EOF {
-		pr.lastUpdate = now
-		percent := float64(pr.current) / float64(pr.total) * 100
-
-		// Format file size
-		currentMB := float64(pr.current) / 1024 / 1024
-		totalMB := float64(pr.total) / 1024 / 1024
-
-		if err == io.EOF {
-			fmt.Printf("\r    Downloaded %s (%.1f MB) - 100%%\n", pr.filename, totalMB)
-		} else {
-			fmt.Printf("\r    Downloading %s (%.1f/%.1f MB) - %.1f%%",
-				pr.filename, currentMB, totalMB, percent)
-		}
+	pr.tracker.Add(int64(n))
+	if err == io.EOF {
+		pr.tracker.Done()
 	}
-
 	return n, err
 }
 
@@ -64,6 +50,13 @@ type Installer struct {
 	opts      *Options
 	apiClient *api.Client
 	verifier  *verification.PackageVerifier
+
+	// ctx stops downloads and builds when the running command is interrupted
+	ctx context.Context
+
+	// installFormulaFunc and installCaskFunc install cross-type dependencies
+	installFormulaFunc func(name string) (*InstallResult, error)
+	installCaskFunc    func(name string) (*InstallResult, error)
 }
 
 // Options contains installation options
@@ -81,6 +74,36 @@ type Options struct {
 	Verbose            bool
 	CC                 string
 	StrictVerification bool
+
+	// RequireSHA refuses stable downloads that have no SHA256 to verify
+	RequireSHA bool
+
+	// BottleTag selects bottles for another platform instead of the host's
+	BottleTag string
+
+	// Interactive opens a shell in the unpacked sources instead of building;
+	// Git also commits the sources to a new repository first
+	Interactive bool
+	Git         bool
+
+	// NoLink installs into the cellar without symlinking into the prefix
+	NoLink bool
+
+	// Overwrite replaces files in the prefix that conflict with the keg's
+	// links instead of failing to link
+	Overwrite bool
+
+	// Reinstall unlinks and removes the installed kegs of the named formulae
+	// before installing them again. Unlike Force it doesn't replace files
+	// brew doesn't manage or allow disabled formulae.
+	Reinstall bool
+
+	// NoQuarantine installs casks without the macOS quarantine attribute
+	NoQuarantine bool
+
+	// CaskLanguages are preferred, in order, for casks with localized
+	// downloads
+	CaskLanguages []string
 }
 
 // InstallResult contains the result of an installation
@@ -91,6 +114,24 @@ type InstallResult struct {
 	Source   string // "bottle" or "source"
 	Success  bool
 	Error    error
+
+	// Phase timings; Duration covers the whole install including dependencies
+	DownloadDuration time.Duration
+	BuildDuration    time.Duration // extracting the bottle or building the source that was installed
+	LinkDuration     time.Duration
+
+	// AlreadyInstalled is set when the keg existed and nothing was done
+	AlreadyInstalled bool
+
+	// LinkError is set when the keg was installed but could not be linked
+	// into the prefix, such as a *LinkConflictError
+	LinkError error
+
+	// tab is the receipt embedded in the poured bottle, if any
+	tab *bottleTab
+
+	// verified is set when the installed download's checksum was checked
+	verified bool
 }
 
 // InstallReceipt contains installation metadata
@@ -100,22 +141,91 @@ type InstallReceipt struct {
 	InstalledOn       time.Time         `json:"installed_on"`
 	InstalledBy       string            `json:"installed_by"`
 	Source            string            `json:"source"`
+	Tap               string            `json:"tap,omitempty"`
 	BuildDependencies []string          `json:"build_dependencies,omitempty"`
 	Dependencies      []string          `json:"dependencies,omitempty"`
 	Options           []string          `json:"options,omitempty"`
 	BuildOptions      map[string]string `json:"build_options,omitempty"`
 	Compiler          string            `json:"compiler,omitempty"`
 	Platform          string            `json:"platform"`
+	Rebuild           int               `json:"rebuild,omitempty"`
+	PouredFromBottle  bool              `json:"poured_from_bottle,omitempty"`
+
+	// RuntimeDependencies are the dependencies a poured bottle was built
+	// against, as recorded in its tab
+	RuntimeDependencies []RuntimeDependency `json:"runtime_dependencies,omitempty"`
+
+	// Unlinked is set for kegs installed with --no-link until they are linked
+	Unlinked bool `json:"unlinked,omitempty"`
+
+	// Overwritten lists prefix paths that linking replaced with --overwrite
+	Overwritten []string `json:"overwritten,omitempty"`
+
+	// KegOnly marks kegs that aren't linked into the prefix by default
+	KegOnly       bool   `json:"keg_only,omitempty"`
+	KegOnlyReason string `json:"keg_only_reason,omitempty"`
+
+	// Verified records whether the checksum of the download the keg was
+	// installed from was checked, and StrictVerification whether the
+	// verifier ran in strict mode
+	Verified           bool `json:"verified"`
+	StrictVerification bool `json:"strict_verification,omitempty"`
+
+	// SourcePath is the formula file a keg was installed from, for formulae
+	// installed by path rather than from a tap
+	SourcePath string `json:"source_path,omitempty"`
+
+	// PostInstall are the formula's post-install steps, kept so that
+	// `brew postinstall` can re-run them
+	PostInstall []string `json:"post_install,omitempty"`
+
+	// Service is the formula's service definition, kept so that
+	// `brew services` can generate its launchd or systemd file
+	Service *formula.Service `json:"service,omitempty"`
+}
+
+// ReadReceipt reads the install receipt of the keg at kegPath. Kegs without
+// one return an error satisfying os.IsNotExist.
+func ReadReceipt(kegPath string) (*InstallReceipt, error) {
+	receiptPath := filepath.Join(kegPath, "INSTALL_RECEIPT.json")
+	data, err := os.ReadFile(receiptPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var receipt InstallReceipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", receiptPath, err)
+	}
+	return &receipt, nil
 }
 
 // New creates a new installer
 func New(cfg *config.Config, opts *Options) *Installer {
-	return &Installer{
+	opts.CC = normalizeCompiler(opts.CC)
+	opts.StrictVerification = opts.StrictVerification || cfg.StrictVerification
+	opts.RequireSHA = opts.RequireSHA || cfg.RequireSHA
+	i := &Installer{
 		cfg:       cfg,
 		opts:      opts,
 		apiClient: api.NewClient(cfg),
 		verifier:  verification.NewPackageVerifier(opts.StrictVerification),
+		ctx:       context.Background(),
+	}
+	i.apiClient.SetStrictVerification(opts.StrictVerification)
+	i.installFormulaFunc = i.installDependency
+	i.installCaskFunc = i.InstallCask
+	return i
+}
+
+// SetContext makes downloads, builds and API requests stop when ctx is
+// cancelled
+func (i *Installer) SetContext(ctx context.Context) {
+	if ctx == nil {
+		return
 	}
+	i.ctx = ctx
+	i.apiClient.SetContext(ctx)
 }
 
 // InstallFormula installs a formula
@@ -127,6 +237,17 @@ func (i *Installer) InstallFormula(name string) (*InstallResult, error) {
 
 	logger.Progress("Installing formula: %s", name)
 
+	if err := i.checkBottleTag(); err != nil {
+		result.Error = err
+		return result, err
+	}
+	if !i.opts.DryRun {
+		if err := i.checkPrefixWritable(); err != nil {
+			result.Error = err
+			return result, err
+		}
+	}
+
 	// Resolve formula
 	f, err := i.resolveFormula(name)
 	if err != nil {
@@ -146,6 +267,32 @@ func (i *Installer) InstallFormula(name string) (*InstallResult, error) {
 
 	result.Version = f.Version
 
+	if err := checkLifecycle(f, i.opts.Force); err != nil {
+		result.Error = err
+		return result, err
+	}
+
+	// The same version is only installed again with --force. HEAD kegs are
+	// named after the commit, which is only known once it has been cloned.
+	kegPath := f.GetCellarPath(i.cfg.HomebrewCellar)
+	kegExists := !i.opts.HeadOnly && !i.opts.Interactive && isNonEmptyDir(kegPath)
+	if kegExists && !i.opts.Force && !i.opts.Reinstall && isCommittedKeg(kegPath) {
+		logger.Info("%s %s is already installed", f.Name, f.Version)
+		result.AlreadyInstalled = true
+		result.Duration = time.Since(start)
+		result.Success = true
+		return result, nil
+	}
+
+	// A missing --cc compiler fails the build before any dependency is
+	// installed for it
+	if !i.shouldUseBottle(f) {
+		if err := i.checkCompiler(f); err != nil {
+			result.Error = err
+			return result, err
+		}
+	}
+
 	// Check dependencies first
 	if !i.opts.IgnoreDependencies {
 		logger.Step("Checking dependencies for %s", f.Name)
@@ -173,29 +320,56 @@ func (i *Installer) InstallFormula(name string) (*InstallResult, error) {
 		return result, nil
 	}
 
+	if i.opts.Reinstall {
+		logger.Step("Reinstalling %s %s", f.Name, f.Version)
+		if err := i.removeInstalledKegs(f.Name); err != nil {
+			result.Error = err
+			return result, errors.NewPermissionError("remove existing keg", filepath.Join(i.cfg.HomebrewCellar, f.Name), err)
+		}
+	} else if kegExists {
+		if isCommittedKeg(kegPath) {
+			logger.Step("Reinstalling %s %s", f.Name, f.Version)
+		} else {
+			logger.Step("Removing incomplete keg %s", kegPath)
+		}
+		if err := os.RemoveAll(kegPath); err != nil {
+			result.Error = err
+			return result, errors.NewPermissionError("remove existing keg", kegPath, err)
+		}
+	}
+
+	// A keg interrupted mid-install would look installed to later runs
+	if !i.opts.HeadOnly && !i.opts.Interactive {
+		unregister := i.removeOnInterrupt(kegPath)
+		defer unregister()
+	}
+
 	// Determine installation method
 	var installErr error
 	if i.shouldUseBottle(f) {
 		logger.Step("Installing from bottle")
 		result.Source = "bottle"
-		installErr = i.installFromBottle(f)
+		installErr = i.pourBottle(f, result)
 
 		// If bottle installation fails, fall back to source
-		if installErr != nil {
-			// Only show warning for unexpected errors, not missing bottles
-			if i.isBottleExpected(f, installErr) {
-				logger.Warn("Bottle installation failed: %v", installErr)
+		if installErr != nil && !i.cfg.NoBottleSourceFallback {
+			if isBottleUnavailable(installErr) {
+				logger.Step("Bottle unavailable, building %s from source", f.Name)
+				logger.Debug("Bottle error: %v", installErr)
 			} else {
-				logger.Debug("No bottle available, building from source: %v", installErr)
+				logger.Warn("Bottle failed, building %s from source: %v", f.Name, installErr)
 			}
-			logger.Step("Falling back to building from source")
+			_ = os.RemoveAll(kegPath)
 			result.Source = "source"
-			installErr = i.installFromSource(f)
+			installErr = i.installBuildDependencies(f)
+			if installErr == nil {
+				installErr = i.installFromSource(f, result)
+			}
 		}
 	} else {
-		logger.Step("Building from source")
+		i.logSourceReason(f)
 		result.Source = "source"
-		installErr = i.installFromSource(f)
+		installErr = i.installFromSource(f, result)
 	}
 
 	if installErr != nil {
@@ -224,16 +398,25 @@ func (i *Installer) InstallFormula(name string) (*InstallResult, error) {
 		return result, installErr
 	}
 
+	// Interactive installs leave building to the user
+	if i.opts.Interactive {
+		result.Duration = time.Since(start)
+		result.Success = true
+		return result, nil
+	}
+
 	// Write install receipt
-	if err := i.writeInstallReceipt(f, result.Source); err != nil {
+	if err := i.writeInstallReceipt(f, result.Source, result.tab, result.verified); err != nil {
 		logger.Warn("Failed to write install receipt: %v", err)
 	}
 
-	// Link formula if needed
-	if !f.KegOnly {
-		if err := i.linkFormula(f); err != nil {
-			logger.Warn("Failed to link formula: %v", err)
-		}
+	linkStart := time.Now()
+	result.LinkError = i.linkKeg(f)
+	result.LinkDuration = time.Since(linkStart)
+
+	// A failed post-install leaves the keg usable, so it can be retried
+	if err := i.runPostInstall(f.Name, f.GetCellarPath(i.cfg.HomebrewCellar), f.PostInstall); err != nil {
+		logger.Warn("%v; run `brew postinstall %s` to try again", err, f.Name)
 	}
 
 	result.Duration = time.Since(start)
@@ -241,6 +424,41 @@ func (i *Installer) InstallFormula(name string) (*InstallResult, error) {
 	return result, nil
 }
 
+// linkKeg symlinks a freshly installed keg into the prefix, unless --no-link
+// asked for it to be left in the cellar. The keg stays installed when linking
+// fails; conflicts with existing files are returned as a *LinkConflictError.
+func (i *Installer) linkKeg(f *formula.Formula) error {
+	// opt links exist for keg-only and unlinked formulae too, so that
+	// dependents can always find them
+	if err := i.repairOptLinks([]string{f.Name}); err != nil {
+		logger.Warn("Failed to link opt directory: %v", err)
+	}
+
+	if i.opts.NoLink {
+		logger.Info("Skipping link of %s; run `brew link %s` to link it", f.Name, f.Name)
+		return nil
+	}
+
+	if f.KegOnly {
+		return nil
+	}
+
+	overwritten, err := i.linkFormula(f)
+	if len(overwritten) > 0 {
+		if recordErr := RecordOverwritten(f.GetInstallReceipt(i.cfg.HomebrewCellar), overwritten); recordErr != nil {
+			logger.Warn("Failed to record overwritten files: %v", recordErr)
+		}
+	}
+
+	var conflict *LinkConflictError
+	if stderrors.As(err, &conflict) {
+		logger.Error("%v", err)
+	} else if err != nil {
+		logger.Warn("Failed to link formula: %v", err)
+	}
+	return err
+}
+
 // InstallCask installs a cask
 func (i *Installer) InstallCask(name string) (*InstallResult, error) {
 	start := time.Now()
@@ -258,16 +476,25 @@ func (i *Installer) InstallCask(name string) (*InstallResult, error) {
 		return result, result.Error
 	}
 
+	// Install formula and cask dependencies first
+	if !i.opts.IgnoreDependencies {
+		if err := i.installDependencyList(caskData.Token, caskDependencies(caskData)); err != nil {
+			result.Error = err
+			return result, err
+		}
+	}
+
 	// Create cask installer
-	caskInstaller := cask.NewCaskInstaller(i.cfg)
+	caskInstaller := i.newCaskInstaller()
 
 	// Set up install options
 	opts := &cask.CaskInstallOptions{
 		Force:        i.opts.Force,
-		RequireSHA:   true,
+		RequireSHA:   i.opts.RequireSHA,
 		Verbose:      i.opts.Verbose,
 		DryRun:       i.opts.DryRun,
-		NoQuarantine: false, // Could be made configurable
+		NoQuarantine: i.opts.NoQuarantine,
+		Languages:    i.opts.CaskLanguages,
 	}
 
 	// Install the cask
@@ -291,7 +518,125 @@ func (i *Installer) InstallCask(name string) (*InstallResult, error) {
 	return result, nil
 }
 
+// FetchCask downloads a cask artifact into the cache without installing it
+func (i *Installer) FetchCask(name string) (string, error) {
+	caskData, err := i.apiClient.GetCask(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch cask '%s': %w", name, err)
+	}
+
+	return i.newCaskInstaller().FetchCask(caskData, i.opts.Force)
+}
+
+// FetchFormula downloads a formula bottle or source archive into the cache without installing it
+func (i *Installer) FetchFormula(name string) (string, error) {
+	f, err := i.resolveFormula(name)
+	if err != nil {
+		return "", err
+	}
+
+	cachePath := i.FormulaCachePath(f)
+	if i.opts.Force {
+		if err := os.Remove(cachePath); err != nil && !os.IsNotExist(err) {
+			return "", errors.NewPermissionError("remove cached download", cachePath, err)
+		}
+	}
+
+	platform := i.platformTag()
+	if !i.opts.BuildFromSource && f.HasBottle(platform) {
+		return i.apiClient.DownloadBottle(f, platform)
+	}
+
+	if f.URL == "" {
+		return "", fmt.Errorf("no source URL available for %s", name)
+	}
+
+	if _, err := os.Stat(cachePath); err == nil && f.SHA256 != "" {
+		if err := i.verifier.VerifySource(cachePath, f.SHA256, 0); err == nil {
+			logger.Debug("Using cached download: %s", cachePath)
+			return cachePath, nil
+		}
+	}
+
+	if err := i.downloadWithMirrors(sourceMirrors(f.URL), cachePath, f.SHA256); err != nil {
+		return "", err
+	}
+
+	return cachePath, nil
+}
+
+// platformTag returns the bottle tag to select, honouring --bottle-tag
+func (i *Installer) platformTag() string {
+	if i.opts.BottleTag != "" {
+		return i.opts.BottleTag
+	}
+	return i.apiClient.GetPlatformTag()
+}
+
+// checkBottleTag refuses to install bottles built for another platform
+// unless --force is given; fetching them for a mirror is always allowed
+func (i *Installer) checkBottleTag() error {
+	host := i.apiClient.GetPlatformTag()
+	if i.opts.BottleTag == "" || i.opts.BottleTag == host {
+		return nil
+	}
+	if !i.opts.Force {
+		return fmt.Errorf("bottle tag %s does not match this platform (%s); use --force to install it anyway", i.opts.BottleTag, host)
+	}
+	logger.Warn("Installing %s bottles on %s: the installed software will probably not run", i.opts.BottleTag, host)
+	return nil
+}
+
+// checkLifecycle refuses to install disabled formulae unless --force is
+// given, and warns about deprecated ones
+func checkLifecycle(f *formula.Formula, force bool) error {
+	switch {
+	case f.Disabled && !force:
+		return fmt.Errorf("%s; use --force to install it anyway", f.DisableMessage())
+	case f.Disabled:
+		logger.Warn("%s; installing anyway because of --force", f.DisableMessage())
+	case f.Deprecated:
+		logger.Warn("%s", f.DeprecationMessage())
+	}
+	return nil
+}
+
+// FormulaCachePath returns where the formula download is stored in the cache
+func (i *Installer) FormulaCachePath(f *formula.Formula) string {
+	platform := i.platformTag()
+	downloadDir := filepath.Join(i.cfg.HomebrewCache, "downloads")
+	if !i.opts.BuildFromSource && f.HasBottle(platform) {
+		return filepath.Join(downloadDir, fmt.Sprintf("%s-%s.%s.bottle.tar.gz", f.Name, f.Version, platform))
+	}
+	return filepath.Join(downloadDir, fmt.Sprintf("%s-%s.tar.gz", f.Name, f.Version))
+}
+
+// CaskCachePath returns where the cask download is stored in the cache
+func (i *Installer) CaskCachePath(name string) (string, error) {
+	caskData, err := i.apiClient.GetCask(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch cask '%s': %w", name, err)
+	}
+	return i.newCaskInstaller().CachePath(caskData), nil
+}
+
+// newCaskInstaller creates a cask installer that shares this installer's downloader
+func (i *Installer) newCaskInstaller() *cask.Installer {
+	caskInstaller := cask.NewCaskInstaller(i.cfg)
+	// Casks verify their own checksum after the download
+	caskInstaller.SetDownloader(func(url, path string) error {
+		return i.downloadFile(url, path, "")
+	})
+	return caskInstaller
+}
+
 func (i *Installer) resolveFormula(name string) (*formula.Formula, error) {
+	// Formula files given by path bypass the API and taps
+	if formula.IsLocalPath(name) {
+		logger.Debug("Loading formula from file %s", name)
+		return formula.LoadFile(name)
+	}
+
 	// First try the API for faster resolution
 	if f, err := i.apiClient.GetFormula(name); err == nil {
 		logger.Debug("Resolved formula %s from API", name)
@@ -341,43 +686,22 @@ func (i *Installer) resolveFormula(name string) (*formula.Formula, error) {
 }
 
 func (i *Installer) installDependencies(f *formula.Formula) error {
-	deps := f.GetDependencies(i.opts.IncludeTest)
-	if len(deps) == 0 {
-		logger.Debug("No dependencies to install")
-		return nil
-	}
-
-	logger.Step("Installing %d dependencies: %s", len(deps), strings.Join(deps, ", "))
-
-	for idx, dep := range deps {
-		logger.Progress("Installing dependency %d/%d: %s", idx+1, len(deps), dep)
-
-		// Check if already installed
-		if installed, err := i.isFormulaInstalled(dep); err != nil {
-			return errors.NewDependencyError(f.Name, dep,
-				fmt.Errorf("failed to check if %s is installed: %w", dep, err))
-		} else if installed {
-			logger.Step("Dependency %s already installed", dep)
-			continue
-		}
-
-		// Recursively install dependency
-		if _, err := i.InstallFormula(dep); err != nil {
-			// Wrap the error with dependency context
-			if brewErr, ok := err.(*errors.BrewError); ok {
-				return errors.NewDependencyError(f.Name, dep, brewErr)
-			}
-			return errors.NewDependencyError(f.Name, dep, err)
-		}
+	return i.installDependencyList(f.Name, i.dependenciesFor(f))
+}
 
-		logger.Success("Dependency %s installed successfully", dep)
+// installBuildDependencies installs the build dependencies skipped when a
+// bottle was expected to be poured
+func (i *Installer) installBuildDependencies(f *formula.Formula) error {
+	if i.opts.IgnoreDependencies {
+		return nil
 	}
-
-	logger.Success("All dependencies installed successfully")
-	return nil
+	return i.installDependencyList(f.Name, buildDependencies(f))
 }
 
 func (i *Installer) shouldUseBottle(f *formula.Formula) bool {
+	if i.opts.Interactive {
+		return false
+	}
 	if i.opts.BuildFromSource && !i.opts.ForceBottle {
 		return false
 	}
@@ -386,32 +710,55 @@ func (i *Installer) shouldUseBottle(f *formula.Formula) bool {
 		return false
 	}
 
-	platform := i.apiClient.GetPlatformTag()
+	platform := i.platformTag()
 	return f.HasBottle(platform)
 }
 
-func (i *Installer) isBottleExpected(f *formula.Formula, err error) bool {
-	// Check if this looks like a formula that should have bottles
-	// vs one that's expected to be source-only
-
-	// If the formula explicitly claims to have bottles, then failure is unexpected
-	platform := i.apiClient.GetPlatformTag()
-	if f.HasBottle(platform) && f.Bottle != nil && f.Bottle.Stable != nil {
-		// Check if it's just a 401/403 auth error (expected for missing bottles)
-		errStr := err.Error()
-		if strings.Contains(errStr, "401") || strings.Contains(errStr, "403") ||
-			strings.Contains(errStr, "not found") || strings.Contains(errStr, "no bottle available") {
-			return false // This is expected for formulae without actual bottles
-		}
-		return true // Other errors are unexpected
+// logSourceReason explains why a formula is built from source. Formulae
+// without bottles by design are built without comment.
+func (i *Installer) logSourceReason(f *formula.Formula) {
+	if f.IsSourceOnly() {
+		logger.Debug("%s has no bottles; building from source", f.Name)
+	} else if platform := i.platformTag(); !f.HasBottle(platform) {
+		logger.Warn("No %s bottle for %s; building from source", platform, f.Name)
 	}
+	logger.Step("Building from source")
+}
 
-	// For formulae without bottle metadata, failure is expected
+// bottleRetries is how many more times a failed bottle is tried before
+// falling back to building from source
+const bottleRetries = 1
+
+// pourBottle installs f from its bottle, retrying failures other than the
+// bottle not being available
+func (i *Installer) pourBottle(f *formula.Formula, result *InstallResult) error {
+	err := i.installFromBottle(f, result)
+	for attempt := 0; err != nil && attempt < bottleRetries && !isBottleUnavailable(err); attempt++ {
+		logger.Warn("Bottle for %s failed, retrying: %v", f.Name, err)
+		err = i.installFromBottle(f, result)
+	}
+	return err
+}
+
+// isBottleUnavailable reports whether a bottle error means there is no
+// bottle to pour, as opposed to one that failed to download or verify
+func isBottleUnavailable(err error) bool {
+	errStr := err.Error()
+	for _, marker := range []string{"status 401", "status 403", "status 404", "HTTP 401", "HTTP 403", "HTTP 404", "not found", "no bottle available"} {
+		if strings.Contains(errStr, marker) {
+			return true
+		}
+	}
 	return false
 }
 
-func (i *Installer) installFromBottle(f *formula.Formula) error {
-	platform := i.apiClient.GetPlatformTag()
+func (i *Installer) installFromBottle(f *formula.Formula, result *InstallResult) error {
+	platform := i.platformTag()
+	downloadStart := time.Now()
+
+	if err := i.checkSHAPolicy(f.Name, f.GetBottleSHA256(platform), false); err != nil {
+		return err
+	}
 
 	// Try to download bottle using API client
 	bottlePath, err := i.apiClient.DownloadBottle(f, platform)
@@ -424,28 +771,26 @@ func (i *Installer) installFromBottle(f *formula.Formula) error {
 
 		// Download bottle manually
 		bottlePath = filepath.Join(i.cfg.HomebrewCache, f.Name+"-"+f.Version+"."+platform+".bottle.tar.gz")
-		if err := i.downloadFile(bottleURL, bottlePath); err != nil {
+		if err := i.downloadFile(bottleURL, bottlePath, f.GetBottleSHA256(platform)); err != nil {
 			return fmt.Errorf("failed to download bottle: %w", err)
 		}
-
-		// Verify SHA256
-		expectedSHA := f.GetBottleSHA256(platform)
-		if expectedSHA != "" {
-			if err := i.verifier.VerifyBottle(bottlePath, expectedSHA, 0); err != nil {
-				return fmt.Errorf("bottle verification failed: %w", err)
-			}
-		}
 	}
 
+	result.DownloadDuration += time.Since(downloadStart)
+	result.verified = f.GetBottleSHA256(platform) != ""
+
 	// Extract bottle
+	extractStart := time.Now()
+	defer func() { result.BuildDuration = time.Since(extractStart) }()
 	cellarPath := f.GetCellarPath(i.cfg.HomebrewCellar)
 	if err := os.MkdirAll(filepath.Dir(cellarPath), 0755); err != nil {
 		return fmt.Errorf("failed to create cellar directory: %w", err)
 	}
 
-	if err := i.extractTarGz(bottlePath, cellarPath); err != nil {
+	if err := i.extractBottleFile(bottlePath, f, cellarPath); err != nil {
 		return fmt.Errorf("failed to extract bottle: %w", err)
 	}
+	result.tab = readBottleTab(cellarPath)
 
 	// Clean up bottle file unless keeping temp files
 	if !i.opts.KeepTmp {
@@ -455,18 +800,41 @@ func (i *Installer) installFromBottle(f *formula.Formula) error {
 	return nil
 }
 
-func (i *Installer) installFromSource(f *formula.Formula) error {
+// checkSHAPolicy decides whether a download without a SHA256 may proceed.
+// HEAD sources have no fixed checksum, so they are exempt but logged as
+// unverified.
+func (i *Installer) checkSHAPolicy(name, expectedSHA256 string, head bool) error {
+	switch {
+	case head:
+		logger.Warn("%s is installed from HEAD; its source cannot be verified", name)
+	case expectedSHA256 != "":
+	case i.opts.RequireSHA:
+		return fmt.Errorf("%s has no SHA256 checksum and --require-sha is set", name)
+	default:
+		logger.Warn("%s has no SHA256 checksum; skipping verification", name)
+	}
+	return nil
+}
+
+func (i *Installer) installFromSource(f *formula.Formula, result *InstallResult) (err error) {
+	if err := i.checkCompiler(f); err != nil {
+		return err
+	}
+
 	// Create temporary build directory
-	buildDir := filepath.Join(i.cfg.HomebrewTemp, f.Name+"-"+f.Version)
-	if err := os.MkdirAll(buildDir, 0755); err != nil {
+	buildDir, err := i.newBuildDir(f.Name + "-" + f.Version)
+	if err != nil {
 		return fmt.Errorf("failed to create build directory: %w", err)
 	}
+	unlock, err := lockBuildDir(buildDir)
+	if err != nil {
+		_ = os.RemoveAll(buildDir)
+		return fmt.Errorf("failed to lock build directory: %w", err)
+	}
+	defer unlock()
 
-	defer func() {
-		if !i.opts.KeepTmp {
-			_ = os.RemoveAll(buildDir)
-		}
-	}()
+	defer i.removeOnInterrupt(buildDir)()
+	defer func() { i.releaseBuildDir(buildDir, err) }()
 
 	// Download source
 	var sourceURL string
@@ -476,33 +844,72 @@ func (i *Installer) installFromSource(f *formula.Formula) error {
 		sourceURL = f.URL
 	}
 
-	logger.Debug("Downloading source from: %s", sourceURL)
-	sourcePath := filepath.Join(buildDir, "source.tar.gz")
-	if err := i.downloadFile(sourceURL, sourcePath); err != nil {
-		return fmt.Errorf("failed to download source: %w", err)
-	}
-	logger.Debug("Downloaded source to: %s", sourcePath)
+	downloadStart := time.Now()
+	sourceExtractDir := filepath.Join(buildDir, "extracted")
 
-	// Verify checksum for stable version
-	if !i.opts.HeadOnly && f.SHA256 != "" {
-		if err := i.verifier.VerifySource(sourcePath, f.SHA256, 0); err != nil {
-			return fmt.Errorf("source verification failed: %w", err)
+	var sourceDir string
+	var buildStart time.Time
+	if i.opts.HeadOnly && f.Head != nil && isGitURL(sourceURL) {
+		sourceDir = filepath.Join(sourceExtractDir, f.Name)
+		commit, err := cloneHead(f, sourceDir)
+		if err != nil {
+			return err
 		}
-	}
+		setHeadVersion(f, commit)
+		result.DownloadDuration += time.Since(downloadStart)
+		buildStart = time.Now()
+	} else {
+		logger.Debug("Downloading source from: %s", sourceURL)
+		sourcePath := filepath.Join(buildDir, "source.tar.gz")
 
-	// Extract source
-	sourceExtractDir := filepath.Join(buildDir, "extracted")
-	logger.Debug("Extracting source to: %s", sourceExtractDir)
-	if err := i.extractTarGz(sourcePath, sourceExtractDir); err != nil {
-		return fmt.Errorf("failed to extract source: %w", err)
-	}
+		// Only the stable version has a checksum to verify
+		expectedSHA := f.SHA256
+		if i.opts.HeadOnly {
+			expectedSHA = ""
+		}
+		if err := i.checkSHAPolicy(f.Name, expectedSHA, i.opts.HeadOnly); err != nil {
+			return err
+		}
+		if err := i.downloadWithMirrors(sourceMirrors(sourceURL), sourcePath, expectedSHA); err != nil {
+			return fmt.Errorf("failed to download source: %w", err)
+		}
+		result.verified = expectedSHA != ""
+		logger.Debug("Downloaded source to: %s", sourcePath)
 
-	// Find the actual source directory (usually contains the project files)
-	sourceDir, err := i.findSourceDirectory(sourceExtractDir)
-	if err != nil {
-		return fmt.Errorf("failed to find source directory: %w", err)
+		result.DownloadDuration += time.Since(downloadStart)
+
+		// Extract source
+		buildStart = time.Now()
+		kind, err := detectArchive(sourcePath, path.Base(sourceURL))
+		if err != nil {
+			return fmt.Errorf("failed to inspect source: %w", err)
+		}
+		if kind == archiveNone {
+			result.Version = f.Version
+			defer func() { result.BuildDuration = time.Since(buildStart) }()
+			return i.installBareBinary(f, sourcePath)
+		}
+		logger.Debug("Extracting source to: %s", sourceExtractDir)
+		if err := i.extractArchive(sourcePath, sourceExtractDir); err != nil {
+			return fmt.Errorf("failed to extract source: %w", err)
+		}
+
+		// Find the actual source directory (usually contains the project files)
+		sourceDir, err = i.findSourceDirectory(sourceExtractDir)
+		if err != nil {
+			return fmt.Errorf("failed to find source directory: %w", err)
+		}
+		logger.Debug("Found source directory: %s", sourceDir)
+
+		if i.opts.HeadOnly {
+			setHeadVersion(f, "")
+		}
+	}
+	result.Version = f.Version
+	defer func() { result.BuildDuration = time.Since(buildStart) }()
+	if i.opts.HeadOnly && !i.opts.Interactive {
+		defer i.removeOnInterrupt(f.GetCellarPath(i.cfg.HomebrewCellar))()
 	}
-	logger.Debug("Found source directory: %s", sourceDir)
 
 	// Apply patches
 	for _, patch := range f.Patches {
@@ -511,8 +918,16 @@ func (i *Installer) installFromSource(f *formula.Formula) error {
 		}
 	}
 
+	// Builds find dependencies through opt paths, so make sure they're current
+	if err := i.repairOptLinks(f.GetDependencies(true)); err != nil {
+		logger.Warn("Failed to repair opt links: %v", err)
+	}
+
 	// Build and install
 	cellarPath := f.GetCellarPath(i.cfg.HomebrewCellar)
+	if i.opts.Interactive {
+		return i.interactiveBuild(f, sourceDir, cellarPath)
+	}
 	logger.Debug("Building in directory: %s", sourceDir)
 	logger.Debug("Installing to: %s", cellarPath)
 	if err := i.buildAndInstall(f, sourceDir, cellarPath); err != nil {
@@ -522,24 +937,46 @@ func (i *Installer) installFromSource(f *formula.Formula) error {
 	return nil
 }
 
-func (i *Installer) downloadFile(url, path string) error {
+func (i *Installer) downloadFile(url, path, expectedSHA256 string) error {
+	_, err := i.downloadFileDigest(url, path, expectedSHA256)
+	return err
+}
+
+// downloadFileDigest downloads like downloadFile and returns the SHA256 of
+// what was written, for callers that have no checksum to verify against
+func (i *Installer) downloadFileDigest(url, path, expectedSHA256 string) (string, error) {
 	filename := filepath.Base(url)
 	logger.Step("Downloading %s", filename)
 
 	// Create directory if it doesn't exist
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return errors.NewPermissionError("create download directory", filepath.Dir(path), err)
+		return "", errors.NewPermissionError("create download directory", filepath.Dir(path), err)
+	}
+
+	// The digest is computed as bytes reach the disk, so verification
+	// never has to read the file back
+	hasher := sha256.New()
+
+	if localPath, ok := strings.CutPrefix(url, "file://"); ok {
+		if err := copyLocalFile(localPath, path, hasher); err != nil {
+			return "", err
+		}
+		return verifyStreamedDigest(path, expectedSHA256, hasher)
 	}
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(i.ctx, "GET", url, http.NoBody)
+	if err != nil {
+		return "", errors.NewNetworkError("download", url, err)
+	}
+	resp, err := utils.DownloadClient().Do(req)
 	if err != nil {
-		return errors.NewNetworkError("download", url, err)
+		return "", errors.NewNetworkError("download", url, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
 		err := fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
-		return errors.NewDownloadError("download", url, err)
+		return "", errors.NewDownloadError("download", url, err)
 	}
 
 	// Get content length for verification
@@ -547,98 +984,98 @@ func (i *Installer) downloadFile(url, path string) error {
 
 	file, err := os.Create(path)
 	if err != nil {
-		return errors.NewPermissionError("create file", path, err)
+		return "", errors.NewPermissionError("create file", path, err)
 	}
 	defer func() { _ = file.Close() }()
+	defer i.removeOnInterrupt(path)()
 
 	// Show download progress if content length is available
 	var reader io.Reader = resp.Body
 	if resp.ContentLength > 0 && !logger.IsQuiet() {
-		reader = &progressReader{
-			reader:   resp.Body,
-			total:    resp.ContentLength,
-			filename: filename,
-		}
+		tracker := downloadProgress().Track(filename, resp.ContentLength)
+		defer tracker.Fail()
+		reader = &progressReader{reader: resp.Body, tracker: tracker}
 	}
 
-	bytesWritten, err := io.Copy(file, reader)
+	bytesWritten, err := io.Copy(io.MultiWriter(file, hasher), reader)
 	if err != nil {
-		return errors.NewDownloadError("save file", url, err)
+		return "", errors.NewDownloadError("save file", url, err)
 	}
 
 	// Verify downloaded size if content length was provided
 	if contentLength > 0 && bytesWritten != contentLength {
+		if i.opts.StrictVerification {
+			_ = file.Close()
+			_ = os.Remove(path)
+			err := fmt.Errorf("downloaded %d bytes, expected %d", bytesWritten, contentLength)
+			return "", errors.NewDownloadError("verify size", url, err)
+		}
 		logger.Warn("Downloaded size (%d bytes) differs from expected size (%d bytes)", bytesWritten, contentLength)
 	}
 
+	digest, err := verifyStreamedDigest(path, expectedSHA256, hasher)
+	if err != nil {
+		return "", err
+	}
+
 	logger.Success("Downloaded %s (%d bytes)", filename, bytesWritten)
-	return nil
+	return digest, nil
 }
 
-// VerifyInstallation verifies the integrity of an installed package
-func (i *Installer) VerifyInstallation(formulaName string) (*verification.VerificationResult, error) {
-	cellarPath := filepath.Join(i.cfg.HomebrewCellar, formulaName)
-	return i.verifier.VerifyInstallation(cellarPath), nil
+// verifyStreamedDigest compares the digest accumulated during a download
+// with the expected one, deleting the file on mismatch. It returns the
+// digest.
+func verifyStreamedDigest(path, expectedSHA256 string, hasher hash.Hash) (string, error) {
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if expectedSHA256 == "" {
+		return actual, nil
+	}
+
+	if !strings.EqualFold(actual, expectedSHA256) {
+		size := int64(-1)
+		if info, err := os.Stat(path); err == nil {
+			size = info.Size()
+		}
+		_ = os.Remove(path)
+		return "", fmt.Errorf("%s: %w", filepath.Base(path), errors.NewChecksumMismatchError("", "", errors.ChecksumMismatch{
+			Expected: expectedSHA256,
+			Actual:   actual,
+			Size:     size,
+		}))
+	}
+
+	logger.Debug("Verified SHA256 of %s", filepath.Base(path))
+	return actual, nil
 }
 
-func (i *Installer) extractTarGz(tarPath, destDir string) error {
-	file, err := os.Open(tarPath)
+// copyLocalFile copies a file:// download source into place, feeding the
+// copied bytes to hasher
+func copyLocalFile(src, dst string, hasher io.Writer) error {
+	in, err := os.Open(src)
 	if err != nil {
-		return err
+		return errors.NewDownloadError("open local file", src, err)
 	}
-	defer func() { _ = file.Close() }()
+	defer func() { _ = in.Close() }()
 
-	gzr, err := gzip.NewReader(file)
+	out, err := os.Create(dst)
 	if err != nil {
-		return err
+		return errors.NewPermissionError("create file", dst, err)
 	}
-	defer func() { _ = gzr.Close() }()
-
-	tr := tar.NewReader(gzr)
+	defer func() { _ = out.Close() }()
 
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-
-		target := filepath.Join(destDir, header.Name)
-
-		switch header.Typeflag {
-		case tar.TypeDir:
-			// Safely convert header.Mode to avoid integer overflow
-			// #nosec G115 - Intentionally masking mode to safe range
-			mode := os.FileMode(header.Mode & 0777) // Mask to only file permission bits
-			if err := os.MkdirAll(target, mode); err != nil {
-				return err
-			}
-		case tar.TypeReg:
-			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
-				return err
-			}
-
-			// Safely convert header.Mode to avoid integer overflow
-			// #nosec G115 - Intentionally masking mode to safe range
-			mode := os.FileMode(header.Mode & 0777) // Mask to only file permission bits
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, mode)
-			if err != nil {
-				return err
-			}
-
-			if _, err := io.Copy(f, tr); err != nil {
-				_ = f.Close()
-				return err
-			}
-			_ = f.Close()
-		}
+	if _, err := io.Copy(io.MultiWriter(out, hasher), in); err != nil {
+		return errors.NewDownloadError("copy local file", src, err)
 	}
 
 	return nil
 }
 
+// VerifyInstallation verifies the integrity of an installed package
+func (i *Installer) VerifyInstallation(formulaName string) (*verification.VerificationResult, error) {
+	cellarPath := filepath.Join(i.cfg.HomebrewCellar, formulaName)
+	return i.verifier.VerifyInstallation(cellarPath), nil
+}
+
 func (i *Installer) findSourceDirectory(extractDir string) (string, error) {
 	// List contents of extract directory
 	files, err := os.ReadDir(extractDir)
@@ -1082,8 +1519,8 @@ func (i *Installer) applyPatch(sourceDir string, patch *formula.Patch) error {
 	if patch.URL != "" {
 		// Download patch from URL
 		logger.Debug("Downloading patch from: %s", patch.URL)
-		patchPath := filepath.Join(i.cfg.HomebrewTemp, "patch-"+filepath.Base(patch.URL))
-		if err := i.downloadFile(patch.URL, patchPath); err != nil {
+		patchPath := i.tempPath("patch-" + filepath.Base(patch.URL))
+		if err := i.downloadFile(patch.URL, patchPath, ""); err != nil {
 			return fmt.Errorf("failed to download patch: %w", err)
 		}
 
@@ -1134,14 +1571,7 @@ func (i *Installer) buildAndInstall(f *formula.Formula, sourceDir, cellarPath st
 	// Simple build process - in practice, this would be much more complex
 	// and would need to handle different build systems (autotools, cmake, etc.)
 
-	// Set environment variables
-	env := os.Environ()
-	env = append(env, "PREFIX="+cellarPath)
-	env = append(env, "HOMEBREW_PREFIX="+i.cfg.HomebrewPrefix)
-
-	if i.opts.CC != "" {
-		env = append(env, "CC="+i.opts.CC)
-	}
+	env := i.buildEnv(cellarPath)
 
 	// Detect build system and build accordingly
 	commands, buildSystem, err := i.detectBuildSystem(sourceDir, cellarPath)
@@ -1155,26 +1585,21 @@ func (i *Installer) buildAndInstall(f *formula.Formula, sourceDir, cellarPath st
 		cmdName := strings.Join(cmdArgs, " ")
 		logger.Step("Running: %s", cmdName)
 
-		// #nosec G204 - cmdArgs come from trusted build system commands
-		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-		cmd.Dir = sourceDir
-		cmd.Env = env
-
 		// Always show live output to match original Homebrew behavior
 		// Capture output for error reporting while streaming live
 		var stdout, stderr strings.Builder
 
 		// Create multi-writers to both capture and display live output
-		cmd.Stdout = io.MultiWriter(&stdout, os.Stdout)
-		cmd.Stderr = io.MultiWriter(&stderr, os.Stderr)
+		var cmdStdout io.Writer = io.MultiWriter(&stdout, os.Stdout)
+		var cmdStderr io.Writer = io.MultiWriter(&stderr, os.Stderr)
 
 		// In quiet mode, only capture without live display
 		if logger.IsQuiet() {
-			cmd.Stdout = &stdout
-			cmd.Stderr = &stderr
+			cmdStdout = &stdout
+			cmdStderr = &stderr
 		}
 
-		if err := cmd.Run(); err != nil {
+		if err := i.runBuildCommand(cmdArgs, sourceDir, env, cmdStdout, cmdStderr); err != nil {
 			// Create detailed build error
 			buildErr := errors.NewBuildError(f.Name, f.Version, err)
 
@@ -1197,24 +1622,122 @@ func (i *Installer) buildAndInstall(f *formula.Formula, sourceDir, cellarPath st
 	return nil
 }
 
-func (i *Installer) linkFormula(f *formula.Formula) error {
+// compilerAliases maps --cc names to the compiler executable they select
+var compilerAliases = map[string]string{
+	"llvm":       "clang",
+	"llvm_clang": "clang",
+}
+
+// normalizeCompiler resolves a --cc alias such as llvm to its executable
+func normalizeCompiler(cc string) string {
+	cc = strings.TrimSpace(cc)
+	if alias, ok := compilerAliases[strings.ToLower(cc)]; ok {
+		return alias
+	}
+	return cc
+}
+
+// checkCompiler fails before anything is downloaded or built when the
+// compiler chosen with --cc is not on the PATH
+func (i *Installer) checkCompiler(f *formula.Formula) error {
+	if i.opts.CC == "" {
+		return nil
+	}
+	if _, err := exec.LookPath(i.opts.CC); err != nil {
+		buildErr := errors.NewBuildError(f.Name, f.Version, fmt.Errorf("compiler %q requested with --cc was not found: %w", i.opts.CC, err))
+		buildErr.Suggestions = []string{
+			fmt.Sprintf("Install %s or make sure it is on your PATH", i.opts.CC),
+			"Pass --cc with an installed compiler, such as clang or gcc",
+		}
+		return buildErr
+	}
+	return nil
+}
+
+// buildEnv returns the environment build commands run in: the allowed part
+// of the user's environment plus the variables brew computes
+func (i *Installer) buildEnv(cellarPath string) []string {
+	env := sanitizeEnv(os.Environ(), i.cfg.EnvFilter)
+	env = append(env, "PREFIX="+cellarPath)
+	env = append(env, "HOMEBREW_PREFIX="+i.cfg.HomebrewPrefix)
+	env = append(env, "HOMEBREW_CELLAR="+i.cfg.HomebrewCellar)
+
+	if i.opts.CC != "" {
+		env = append(env, "CC="+i.opts.CC)
+	}
+	return env
+}
+
+// buildKillGrace is how long a build step may take to exit after SIGTERM
+// before it is killed
+const buildKillGrace = 5 * time.Second
+
+// runBuildCommand runs one build step, killing it and everything it spawned
+// if HOMEBREW_BUILD_TIMEOUT elapses or the command is interrupted
+func (i *Installer) runBuildCommand(cmdArgs []string, dir string, env []string, stdout, stderr io.Writer) error {
+	ctx := i.ctx
+	timeout := time.Duration(i.cfg.BuildTimeout) * time.Second
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// #nosec G204 - cmdArgs come from trusted build system commands
+	cmd := exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// The build runs in its own process group, so the terminal's Ctrl-C
+	// doesn't reach it; the group is stopped explicitly instead
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd, buildKillGrace) }
+	// Don't wait forever on output pipes held open by orphaned children
+	cmd.WaitDelay = 5 * time.Second
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// The interrupt handler exits without waiting for ctx to be noticed;
+	// stop the build before the build directory is removed under it
+	unregister := utils.RegisterCleanup(func() { _ = killProcessGroup(cmd, buildKillGrace) })
+	err := cmd.Wait()
+	unregister()
+
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("%s timed out after %s (HOMEBREW_BUILD_TIMEOUT)", strings.Join(cmdArgs, " "), timeout)
+	case context.Canceled:
+		return fmt.Errorf("%s was interrupted: %w", strings.Join(cmdArgs, " "), ctx.Err())
+	}
+	return err
+}
+
+// linkFormula symlinks the keg's binaries into the prefix, returning the
+// paths it replaced because of --force or --overwrite
+func (i *Installer) linkFormula(f *formula.Formula) ([]string, error) {
 	logger.Debug("Linking formula %s", f.Name)
 
 	cellarPath := f.GetCellarPath(i.cfg.HomebrewCellar)
 	binDir := filepath.Join(cellarPath, "bin")
 
+	var overwritten []string
+
 	// Link binaries
 	if _, err := os.Stat(binDir); err == nil {
 		files, err := os.ReadDir(binDir)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		linkDir := filepath.Join(i.cfg.HomebrewPrefix, "bin")
 		if err := os.MkdirAll(linkDir, 0755); err != nil {
-			return err
+			return nil, err
 		}
 
+		var conflicts []string
 		for _, file := range files {
 			if file.IsDir() {
 				continue
@@ -1223,34 +1746,85 @@ func (i *Installer) linkFormula(f *formula.Formula) error {
 			src := filepath.Join(binDir, file.Name())
 			dst := filepath.Join(linkDir, file.Name())
 
-			// Remove existing link
-			_ = os.Remove(dst)
+			// Only replace links brew owns unless forced
+			switch kind := ClassifyPrefixPath(dst, i.cfg.HomebrewCellar); kind {
+			case PathAbsent:
+			case PathManagedSymlink:
+				if err := os.Remove(dst); err != nil {
+					return overwritten, err
+				}
+			default:
+				if !i.opts.Force && !i.opts.Overwrite {
+					conflicts = append(conflicts, fmt.Sprintf("%s (%s)", dst, kind))
+					continue
+				}
+				logger.Info("Overwriting %s %s", kind, dst)
+				if err := os.RemoveAll(dst); err != nil {
+					return overwritten, err
+				}
+				overwritten = append(overwritten, dst)
+			}
 
 			// Create symlink
 			if err := os.Symlink(src, dst); err != nil {
-				return err
+				return overwritten, err
 			}
 		}
+
+		if len(conflicts) > 0 {
+			return overwritten, &LinkConflictError{Formula: f.Name, Paths: conflicts}
+		}
 	}
 
-	return nil
+	return overwritten, nil
 }
 
-func (i *Installer) writeInstallReceipt(f *formula.Formula, source string) error {
+func (i *Installer) writeInstallReceipt(f *formula.Formula, source string, tab *bottleTab, verified bool) error {
+	return i.writeReceipt(f, source, i.platformTag(), tab, verified)
+}
+
+// writeReceipt records how a keg was installed and for which platform
+func (i *Installer) writeReceipt(f *formula.Formula, source, platform string, tab *bottleTab, verified bool) error {
 	receipt := InstallReceipt{
-		Name:              f.Name,
-		Version:           f.Version,
-		InstalledOn:       time.Now(),
-		InstalledBy:       "brew-go",
-		Source:            source,
-		Dependencies:      f.Dependencies,
-		BuildDependencies: f.BuildDependencies,
-		Platform:          i.apiClient.GetPlatformTag(),
+		Name:               f.Name,
+		Version:            f.Version,
+		InstalledOn:        time.Now(),
+		InstalledBy:        "brew-go",
+		Source:             source,
+		Tap:                f.Tap,
+		SourcePath:         f.Path,
+		Dependencies:       f.GetDependencies(false),
+		BuildDependencies:  f.GetBuildDependencies(),
+		Platform:           platform,
+		Unlinked:           i.opts.NoLink,
+		KegOnly:            f.KegOnly,
+		KegOnlyReason:      f.KegOnlyReason,
+		PostInstall:        f.PostInstall,
+		Service:            f.Service,
+		Verified:           verified,
+		StrictVerification: i.opts.StrictVerification,
+	}
+
+	if receipt.Tap == "" {
+		receipt.Tap = "homebrew/core"
 	}
-
 	if i.opts.CC != "" {
 		receipt.Compiler = i.opts.CC
 	}
+	if source == "bottle" {
+		receipt.Rebuild = f.BottleRebuild()
+		receipt.PouredFromBottle = true
+	}
+	// A bottle's tab knows what it was really built against
+	if tab != nil {
+		if tab.RuntimeDependencies != nil {
+			receipt.Dependencies = tab.dependencyNames()
+			receipt.RuntimeDependencies = tab.RuntimeDependencies
+		}
+		if receipt.Compiler == "" {
+			receipt.Compiler = tab.Compiler
+		}
+	}
 
 	receiptPath := f.GetInstallReceipt(i.cfg.HomebrewCellar)
 	if err := os.MkdirAll(filepath.Dir(receiptPath), 0755); err != nil {
@@ -1265,13 +1839,51 @@ func (i *Installer) writeInstallReceipt(f *formula.Formula, source string) error
 	return os.WriteFile(receiptPath, data, 0644)
 }
 
-func (i *Installer) isFormulaInstalled(name string) (bool, error) {
+// removeOnInterrupt deletes path if brew is interrupted before the returned
+// function is called, unless temporary files are being kept
+func (i *Installer) removeOnInterrupt(path string) func() {
+	return utils.RegisterCleanup(func() {
+		if i.opts.KeepTmp {
+			return
+		}
+		logger.Debug("Removing %s after interrupt", path)
+		_ = os.RemoveAll(path)
+	})
+}
+
+// removeInstalledKegs unlinks and removes every installed version of name,
+// so files dropped from the formula don't survive a reinstall
+func (i *Installer) removeInstalledKegs(name string) error {
 	formulaPath := filepath.Join(i.cfg.HomebrewCellar, name)
-	_, err := os.Stat(formulaPath)
-	if os.IsNotExist(err) {
-		return false, nil
+
+	linkDir := filepath.Join(i.cfg.HomebrewPrefix, "bin")
+	if entries, err := os.ReadDir(linkDir); err == nil {
+		for _, entry := range entries {
+			link := filepath.Join(linkDir, entry.Name())
+			if ClassifyPrefixPath(link, formulaPath) == PathManagedSymlink {
+				logger.Debug("Removing symlink: %s", link)
+				if err := os.Remove(link); err != nil {
+					return err
+				}
+			}
+		}
 	}
-	return err == nil, err
+
+	return os.RemoveAll(formulaPath)
+}
+
+// isCommittedKeg reports whether kegPath holds a finished install. The
+// receipt is written once everything else is in place, so a keg without one
+// was left behind by an interrupted install.
+func isCommittedKeg(kegPath string) bool {
+	_, err := os.Stat(filepath.Join(kegPath, "INSTALL_RECEIPT.json"))
+	return err == nil
+}
+
+// isNonEmptyDir reports whether path is a directory with at least one entry
+func isNonEmptyDir(path string) bool {
+	entries, err := os.ReadDir(path)
+	return err == nil && len(entries) > 0
 }
 
 func getPlatform() string {
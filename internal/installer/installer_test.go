@@ -1,10 +1,19 @@
 package installer
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -14,6 +23,7 @@ import (
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/formula"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
 )
 
 func TestNew(t *testing.T) {
@@ -187,11 +197,12 @@ func TestWriteInstallReceipt(t *testing.T) {
 	testFormula := &formula.Formula{
 		Name:              "test-formula",
 		Version:           "1.0.0",
+		Tap:               "user/repo",
 		Dependencies:      []string{"dep1", "dep2"},
 		BuildDependencies: []string{"build-dep1"},
 	}
 
-	err := installer.writeInstallReceipt(testFormula, "bottle")
+	err := installer.writeInstallReceipt(testFormula, "bottle", nil, true)
 	if err != nil {
 		t.Fatalf("writeInstallReceipt() failed: %v", err)
 	}
@@ -221,38 +232,68 @@ func TestWriteInstallReceipt(t *testing.T) {
 	if !strings.Contains(contentStr, "gcc") {
 		t.Error("Receipt should contain compiler")
 	}
+	if !strings.Contains(contentStr, `"tap": "user/repo"`) {
+		t.Error("Receipt should contain source tap")
+	}
 }
 
-func TestIsFormulaInstalled(t *testing.T) {
+func TestHasCommittedKegInCellar(t *testing.T) {
 	tmpDir := t.TempDir()
-	cfg := &config.Config{
-		HomebrewCellar: tmpDir,
-	}
-	installer := New(cfg, &Options{})
 
 	// Test non-existent formula
-	installed, err := installer.isFormulaInstalled("non-existent")
+	installed, err := HasCommittedKeg(tmpDir, "non-existent")
 	if err != nil {
-		t.Errorf("isFormulaInstalled() error = %v", err)
+		t.Errorf("HasCommittedKeg() error = %v", err)
 	}
 	if installed {
 		t.Error("Non-existent formula should not be installed")
 	}
 
-	// Create a formula directory
-	formulaDir := filepath.Join(tmpDir, "test-formula")
-	err = os.MkdirAll(formulaDir, 0755)
-	if err != nil {
-		t.Fatalf("Failed to create formula directory: %v", err)
+	// A keg without a receipt was left behind by an interrupted install
+	kegDir := filepath.Join(tmpDir, "test-formula", "1.0")
+	if err := os.MkdirAll(filepath.Join(kegDir, "bin"), 0755); err != nil {
+		t.Fatalf("Failed to create keg directory: %v", err)
+	}
+	if installed, _ := HasCommittedKeg(tmpDir, "test-formula"); installed {
+		t.Error("Keg without a receipt should not be installed")
 	}
 
-	// Test existing formula
-	installed, err = installer.isFormulaInstalled("test-formula")
+	if err := os.WriteFile(filepath.Join(kegDir, "INSTALL_RECEIPT.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	installed, err = HasCommittedKeg(tmpDir, "test-formula")
 	if err != nil {
-		t.Errorf("isFormulaInstalled() error = %v", err)
+		t.Errorf("HasCommittedKeg() error = %v", err)
 	}
 	if !installed {
-		t.Error("Existing formula should be installed")
+		t.Error("Keg with a receipt should be installed")
+	}
+}
+
+func TestReadReceipt(t *testing.T) {
+	kegDir := t.TempDir()
+
+	if _, err := ReadReceipt(kegDir); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error for a keg without a receipt, got %v", err)
+	}
+
+	receiptPath := filepath.Join(kegDir, "INSTALL_RECEIPT.json")
+	if err := os.WriteFile(receiptPath, []byte(`{"name": "wget", "tap": "homebrew/core", "dependencies": ["openssl@3"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := ReadReceipt(kegDir)
+	if err != nil {
+		t.Fatalf("ReadReceipt() error = %v", err)
+	}
+	if receipt.Name != "wget" || receipt.Tap != "homebrew/core" || len(receipt.Dependencies) != 1 {
+		t.Errorf("Unexpected receipt %+v", receipt)
+	}
+
+	if err := os.WriteFile(receiptPath, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadReceipt(kegDir); err == nil || os.IsNotExist(err) {
+		t.Errorf("Expected a parse error, got %v", err)
 	}
 }
 
@@ -364,9 +405,9 @@ func TestExtractTarGz(t *testing.T) {
 	nonExistentTar := filepath.Join(tmpDir, "non-existent.tar.gz")
 	destDir := filepath.Join(tmpDir, "dest")
 
-	err := installer.extractTarGz(nonExistentTar, destDir)
+	err := installer.extractArchive(nonExistentTar, destDir)
 	if err == nil {
-		t.Error("extractTarGz() should fail with non-existent file")
+		t.Error("extractArchive() should fail with non-existent file")
 	}
 }
 
@@ -374,10 +415,13 @@ func TestProgressReader(t *testing.T) {
 	content := "Hello, World! This is test content for progress reader."
 	reader := strings.NewReader(content)
 
+	var out bytes.Buffer
+	manager := newProgressManager(&out, false)
+	defer manager.Close()
+
 	progressReader := &progressReader{
-		reader:   reader,
-		total:    int64(len(content)),
-		filename: "test-file.txt",
+		reader:  reader,
+		tracker: manager.Track("test-file.txt", int64(len(content))),
 	}
 
 	// Read in chunks to test progress updates
@@ -397,8 +441,8 @@ func TestProgressReader(t *testing.T) {
 		}
 
 		// Verify progress tracking
-		if progressReader.current != int64(totalRead) {
-			t.Errorf("progressReader.current = %d, want %d", progressReader.current, totalRead)
+		if progressReader.tracker.current != int64(totalRead) {
+			t.Errorf("progressReader.tracker.current = %d, want %d", progressReader.tracker.current, totalRead)
 		}
 	}
 
@@ -407,8 +451,11 @@ func TestProgressReader(t *testing.T) {
 		t.Errorf("Total read = %d, want %d", totalRead, len(content))
 	}
 
-	if progressReader.current != int64(len(content)) {
-		t.Errorf("Final progress = %d, want %d", progressReader.current, len(content))
+	if progressReader.tracker.current != int64(len(content)) {
+		t.Errorf("Final progress = %d, want %d", progressReader.tracker.current, len(content))
+	}
+	if !strings.Contains(out.String(), "Downloaded test-file.txt") {
+		t.Errorf("Expected completed download to be logged, got %q", out.String())
 	}
 }
 
@@ -426,7 +473,7 @@ func TestDownloadFileWithProgress(t *testing.T) {
 	destPath := filepath.Join(tmpDir, "downloaded-file")
 
 	// Test with invalid URL
-	err := installer.downloadFile("invalid://url", destPath)
+	err := installer.downloadFile("invalid://url", destPath, "")
 	if err == nil {
 		t.Error("downloadFile() should fail with invalid URL")
 	}
@@ -437,6 +484,101 @@ func TestDownloadFileWithProgress(t *testing.T) {
 	}
 }
 
+func TestDownloadFileStreamsChecksum(t *testing.T) {
+	logger.Init(false, false, true)
+
+	content := bytes.Repeat([]byte("bottle data "), 64*1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256(content)
+	expected := hex.EncodeToString(sum[:])
+
+	installer := New(&config.Config{}, &Options{})
+	tmpDir := t.TempDir()
+
+	path := filepath.Join(tmpDir, "good.tar.gz")
+	if err := installer.downloadFile(server.URL+"/good.tar.gz", path, expected); err != nil {
+		t.Fatalf("downloadFile() error = %v", err)
+	}
+	onDisk, err := utils.ComputeSHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onDisk != expected {
+		t.Errorf("Digest of saved file = %s, streamed digest accepted %s", onDisk, expected)
+	}
+
+	path = filepath.Join(tmpDir, "bad.tar.gz")
+	err = installer.downloadFile(server.URL+"/bad.tar.gz", path, strings.Repeat("0", 64))
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Expected checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected file to be deleted after checksum mismatch")
+	}
+}
+
+func TestDownloadFileStopsWhenContextCancelled(t *testing.T) {
+	logger.Init(false, false, true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	installer := New(&config.Config{}, &Options{})
+	installer.SetContext(ctx)
+
+	err := installer.downloadFile(server.URL+"/file.tar.gz", filepath.Join(t.TempDir(), "file.tar.gz"), "")
+	if err == nil || !strings.Contains(err.Error(), "context canceled") {
+		t.Fatalf("downloadFile() with a cancelled context: error = %v, want context canceled", err)
+	}
+}
+
+// truncatingTransport answers every request with fewer bytes than its
+// Content-Length announces
+type truncatingTransport struct{}
+
+func (truncatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		ContentLength: 100,
+		Body:          io.NopCloser(strings.NewReader("data")),
+		Request:       req,
+	}, nil
+}
+
+func TestDownloadFileSizeMismatch(t *testing.T) {
+	logger.Init(false, false, true)
+
+	oldTransport := utils.Transport
+	defer func() { utils.Transport = oldTransport }()
+	utils.Transport = truncatingTransport{}
+
+	for _, strict := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "file.tar.gz")
+		installer := New(&config.Config{StrictVerification: strict}, &Options{})
+
+		err := installer.downloadFile("https://example.com/file.tar.gz", path, "")
+		if strict {
+			if err == nil || !strings.Contains(err.Error(), "expected 100") {
+				t.Errorf("strict downloadFile() error = %v, want a size mismatch", err)
+			}
+			if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+				t.Error("Expected the truncated download to be removed")
+			}
+		} else if err != nil {
+			t.Errorf("downloadFile() error = %v, want only a warning", err)
+		}
+	}
+}
+
 func TestFindSourceDirectory(t *testing.T) {
 	cfg := &config.Config{}
 	installer := New(cfg, &Options{})
@@ -960,3 +1102,555 @@ func TestVerificationOptions(t *testing.T) {
 		t.Error("Non-strict installer should have verifier")
 	}
 }
+
+func TestInstallFormulaFromLocalFile(t *testing.T) {
+	logger.Init(false, false, true)
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not available")
+	}
+
+	cfg := newLocalFormulaConfig(t)
+
+	// Source tarball with a trivial Makefile, next to the formula file
+	tarball := writeSourceTarball(t, filepath.Join(localFormulaDir(cfg), "hello-1.0.tar.gz"), map[string]string{
+		"hello-1.0/Makefile": helloMakefile,
+		"hello-1.0/hello":    helloScript,
+	})
+	sum := sha256.Sum256(tarball)
+
+	formulaYAML := "name: hello\nversion: 1.0.0\nurl: hello-1.0.tar.gz\nsha256: " + hex.EncodeToString(sum[:]) + "\n" +
+		"service:\n  run: [\"$HOMEBREW_PREFIX/opt/hello/bin/hello\"]\n  keep_alive: true\n"
+	formulaPath := writeLocalFormula(t, cfg, "hello", formulaYAML, nil)
+
+	inst := New(cfg, &Options{BuildFromSource: true})
+	result, err := inst.InstallFormula(formulaPath)
+	if err != nil {
+		t.Fatalf("InstallFormula() error = %v", err)
+	}
+	if result.Source != "source" {
+		t.Errorf("result.Source = %v, want source", result.Source)
+	}
+	if result.DownloadDuration <= 0 || result.BuildDuration <= 0 || result.LinkDuration <= 0 {
+		t.Errorf("Expected phase durations to be recorded, got download=%v build=%v link=%v",
+			result.DownloadDuration, result.BuildDuration, result.LinkDuration)
+	}
+	if phases := result.DownloadDuration + result.BuildDuration + result.LinkDuration; phases > result.Duration {
+		t.Errorf("Phase durations %v exceed total %v", phases, result.Duration)
+	}
+
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "hello", "1.0.0", "bin", "hello")); err != nil {
+		t.Errorf("Expected binary in cellar: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.HomebrewCellar, "hello", "1.0.0", "INSTALL_RECEIPT.json"))
+	if err != nil {
+		t.Fatalf("Expected install receipt: %v", err)
+	}
+	var receipt InstallReceipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		t.Fatal(err)
+	}
+	if receipt.SourcePath != formulaPath {
+		t.Errorf("receipt.SourcePath = %q, want %q", receipt.SourcePath, formulaPath)
+	}
+	if receipt.Service == nil || len(receipt.Service.Run) != 1 || !receipt.Service.KeepAlive {
+		t.Errorf("receipt.Service = %+v, want the formula's service", receipt.Service)
+	}
+}
+
+func TestRequireSHA(t *testing.T) {
+	logger.Init(false, false, true)
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not available")
+	}
+
+	cfg := newLocalFormulaConfig(t)
+
+	tarballPath := filepath.Join(localFormulaDir(cfg), "hello-1.0.tar.gz")
+	writeSourceTarball(t, tarballPath, map[string]string{
+		"hello-1.0/Makefile": helloMakefile,
+		"hello-1.0/hello":    helloScript,
+	})
+
+	// A stable formula without a checksum is refused before downloading
+	stable := &formula.Formula{Name: "hello", Version: "1.0", URL: "file://" + tarballPath}
+	inst := New(cfg, &Options{BuildFromSource: true, RequireSHA: true})
+	err := inst.installFromSource(stable, &InstallResult{})
+	if err == nil || !strings.Contains(err.Error(), "--require-sha") {
+		t.Fatalf("installFromSource() error = %v, want --require-sha refusal", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "hello", "1.0")); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be installed for the refused formula")
+	}
+
+	// HEAD installs have no fixed checksum and are exempt
+	head := &formula.Formula{Name: "hello", Version: "HEAD", Head: &formula.Head{URL: "file://" + tarballPath}}
+	inst = New(cfg, &Options{BuildFromSource: true, HeadOnly: true, RequireSHA: true})
+	if err := inst.installFromSource(head, &InstallResult{}); err != nil {
+		t.Fatalf("installFromSource() for HEAD error = %v", err)
+	}
+	if !formula.IsHeadVersion(head.Version) || head.Version == "HEAD" {
+		t.Errorf("Expected a dated HEAD version, got %q", head.Version)
+	}
+	if _, err := os.Stat(filepath.Join(head.GetCellarPath(cfg.HomebrewCellar), "bin", "hello")); err != nil {
+		t.Errorf("Expected HEAD build in cellar: %v", err)
+	}
+
+	// Without --require-sha a missing checksum only warns
+	inst = New(cfg, &Options{})
+	if err := inst.checkSHAPolicy("hello", "", false); err != nil {
+		t.Errorf("checkSHAPolicy() without --require-sha error = %v", err)
+	}
+
+	// HOMEBREW_REQUIRE_SHA applies to every installer, not just install's
+	requireCfg := *cfg
+	requireCfg.RequireSHA = true
+	if err := New(&requireCfg, &Options{}).checkSHAPolicy("hello", "", false); err == nil {
+		t.Error("checkSHAPolicy() with HOMEBREW_REQUIRE_SHA should refuse a missing checksum")
+	}
+}
+
+func TestReinstallReplacesKegWithoutForcing(t *testing.T) {
+	logger.Init(false, false, true)
+
+	cfg := newLocalFormulaConfig(t)
+	formulaYAML := "name: hello\nversion: 1.0.0\nbinary: hello\nbottle: unneeded\nurl: hello-1.0.sh\nsha256: " + helloScriptSHA256() + "\n"
+	formulaPath := writeLocalFormula(t, cfg, "hello", formulaYAML, map[string]string{"hello-1.0.sh": helloScript})
+	if _, err := New(cfg, &Options{}).InstallFormula(formulaPath); err != nil {
+		t.Fatalf("InstallFormula() error = %v", err)
+	}
+
+	// A file left in the old keg, an older keg and a file brew doesn't own
+	keg := filepath.Join(cfg.HomebrewCellar, "hello", "1.0.0")
+	stale := filepath.Join(keg, "stale.txt")
+	if err := os.WriteFile(stale, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldKeg := filepath.Join(cfg.HomebrewCellar, "hello", "0.9.0")
+	if err := os.MkdirAll(filepath.Join(oldKeg, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(oldKeg, "bin", "hello-old"), []byte(helloScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	oldLink := filepath.Join(cfg.HomebrewPrefix, "bin", "hello-old")
+	if err := os.Symlink(filepath.Join(oldKeg, "bin", "hello-old"), oldLink); err != nil {
+		t.Fatal(err)
+	}
+	foreign := filepath.Join(cfg.HomebrewPrefix, "bin", "hello")
+	if err := os.Remove(foreign); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(foreign, []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := New(cfg, &Options{Reinstall: true}).InstallFormula(formulaPath)
+	if err != nil {
+		t.Fatalf("reinstall error = %v", err)
+	}
+	if result.AlreadyInstalled {
+		t.Error("Reinstall should not report the formula as already installed")
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("Reinstall should remove the existing keg first")
+	}
+	if _, err := os.Stat(oldKeg); !os.IsNotExist(err) {
+		t.Error("Reinstall should remove older kegs")
+	}
+	if _, err := os.Lstat(oldLink); !os.IsNotExist(err) {
+		t.Error("Reinstall should unlink the removed kegs")
+	}
+	if _, err := os.Stat(filepath.Join(keg, "bin", "hello")); err != nil {
+		t.Errorf("Expected reinstalled binary: %v", err)
+	}
+	if data, err := os.ReadFile(foreign); err != nil || string(data) != "mine" {
+		t.Errorf("Reinstall should not overwrite files brew doesn't manage, got %q, %v", data, err)
+	}
+	var conflict *LinkConflictError
+	if !errors.As(result.LinkError, &conflict) {
+		t.Errorf("Expected a link conflict, got %v", result.LinkError)
+	}
+
+	// Disabled formulae still need --force
+	disabledYAML := formulaYAML + "disabled: true\ndisable_reason: does_not_build\n"
+	writeLocalFormula(t, cfg, "hello", disabledYAML, nil)
+	if _, err := New(cfg, &Options{Reinstall: true}).InstallFormula(formulaPath); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("reinstall of a disabled formula: error = %v, want disabled", err)
+	}
+	if _, err := os.Stat(keg); err != nil {
+		t.Errorf("A refused reinstall should keep the existing keg: %v", err)
+	}
+}
+
+func TestInstallFormulaAlreadyInstalled(t *testing.T) {
+	logger.Init(false, false, true)
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not available")
+	}
+
+	tests := []struct {
+		name             string
+		force            bool
+		alreadyInstalled bool
+	}{
+		{name: "skips existing keg without force", alreadyInstalled: true},
+		{name: "reinstalls existing keg with force", force: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newLocalFormulaConfig(t)
+
+			tarball := writeSourceTarball(t, filepath.Join(localFormulaDir(cfg), "hello-1.0.tar.gz"), map[string]string{
+				"hello-1.0/Makefile": helloMakefile,
+				"hello-1.0/hello":    helloScript,
+			})
+			sum := sha256.Sum256(tarball)
+
+			formulaYAML := "name: hello\nversion: 1.0.0\nurl: hello-1.0.tar.gz\nsha256: " + hex.EncodeToString(sum[:]) + "\n"
+			formulaPath := writeLocalFormula(t, cfg, "hello", formulaYAML, nil)
+
+			// An existing keg of the same version with a leftover file
+			keg := filepath.Join(cfg.HomebrewCellar, "hello", "1.0.0")
+			stale := filepath.Join(keg, "stale.txt")
+			if err := os.MkdirAll(keg, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(stale, []byte("old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(keg, "INSTALL_RECEIPT.json"), []byte("{}"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			inst := New(cfg, &Options{BuildFromSource: true, Force: tt.force})
+			result, err := inst.InstallFormula(formulaPath)
+			if err != nil {
+				t.Fatalf("InstallFormula() error = %v", err)
+			}
+			if result.AlreadyInstalled != tt.alreadyInstalled {
+				t.Errorf("result.AlreadyInstalled = %v, want %v", result.AlreadyInstalled, tt.alreadyInstalled)
+			}
+
+			_, staleErr := os.Stat(stale)
+			_, binErr := os.Stat(filepath.Join(keg, "bin", "hello"))
+			if tt.force {
+				if !os.IsNotExist(staleErr) {
+					t.Error("Reinstall should remove the existing keg first")
+				}
+				if binErr != nil {
+					t.Errorf("Expected reinstalled binary: %v", binErr)
+				}
+			} else {
+				if staleErr != nil {
+					t.Error("Existing keg should be left untouched")
+				}
+				if binErr == nil {
+					t.Error("Formula should not be installed again without --force")
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveOnInterrupt(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tests := []struct {
+		name     string
+		keepTmp  bool
+		finished bool
+		removed  bool
+	}{
+		{name: "interrupted keg is removed", removed: true},
+		{name: "keep-tmp leaves keg in place", keepTmp: true},
+		{name: "finished install is kept", finished: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keg := filepath.Join(t.TempDir(), "Cellar", "hello", "1.0.0")
+			if err := os.MkdirAll(filepath.Join(keg, "bin"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			inst := New(&config.Config{}, &Options{KeepTmp: tt.keepTmp})
+			unregister := inst.removeOnInterrupt(keg)
+			if tt.finished {
+				unregister()
+			}
+			utils.RunCleanups()
+			unregister()
+
+			_, err := os.Stat(keg)
+			if removed := os.IsNotExist(err); removed != tt.removed {
+				t.Errorf("keg removed = %v, want %v", removed, tt.removed)
+			}
+		})
+	}
+}
+
+func TestRunBuildCommandTimeout(t *testing.T) {
+	logger.Init(false, false, true)
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	inst := New(&config.Config{BuildTimeout: 1}, &Options{})
+
+	// The background sleep keeps stdout open, so only killing the whole
+	// process group lets the command return promptly
+	var output bytes.Buffer
+	start := time.Now()
+	err := inst.runBuildCommand([]string{"sh", "-c", "echo configuring; sleep 30 & sleep 30"}, t.TempDir(), os.Environ(), &output, &output)
+	elapsed := time.Since(start)
+
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("runBuildCommand() error = %v, want timeout", err)
+	}
+	if elapsed > 4*time.Second {
+		t.Errorf("runBuildCommand() took %v, want it killed at the timeout", elapsed)
+	}
+	if !strings.Contains(output.String(), "configuring") {
+		t.Errorf("Expected output before the timeout to be captured, got %q", output.String())
+	}
+}
+
+func TestRunBuildCommandInterrupted(t *testing.T) {
+	logger.Init(false, false, true)
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inst := New(&config.Config{}, &Options{})
+	inst.SetContext(ctx)
+
+	// Interrupt the way the signal handler does once the build is running
+	dir := t.TempDir()
+	started := filepath.Join(dir, "started")
+	go func() {
+		for {
+			if _, err := os.Stat(started); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		utils.RunCleanups()
+	}()
+
+	var output bytes.Buffer
+	start := time.Now()
+	err := inst.runBuildCommand([]string{"sh", "-c", "sleep 30 & touch started; wait"}, dir, os.Environ(), &output, &output)
+	elapsed := time.Since(start)
+
+	if err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Fatalf("runBuildCommand() error = %v, want interrupted", err)
+	}
+	if elapsed > 4*time.Second {
+		t.Errorf("runBuildCommand() took %v, want the process group stopped on interrupt", elapsed)
+	}
+}
+
+// writeSourceTarball writes a gzipped tarball of files to path and returns its bytes
+func writeSourceTarball(t *testing.T, path string, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newLocalFormulaConfig returns a config rooted in a fresh temporary
+// directory. The API answers every lookup with 404, so only formula files
+// written with writeLocalFormula resolve.
+func newLocalFormulaConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	tempDir := t.TempDir()
+	return &config.Config{
+		HomebrewPrefix: filepath.Join(tempDir, "prefix"),
+		HomebrewCellar: filepath.Join(tempDir, "prefix", "Cellar"),
+		HomebrewCache:  filepath.Join(tempDir, "cache"),
+		HomebrewTemp:   filepath.Join(tempDir, "tmp"),
+	}
+}
+
+// localFormulaDir is where writeLocalFormula puts formula files; relative
+// urls in them resolve against it
+func localFormulaDir(cfg *config.Config) string {
+	return filepath.Join(filepath.Dir(cfg.HomebrewPrefix), "formulae")
+}
+
+// writeLocalFormula writes name.yaml and the given source files into
+// localFormulaDir and returns the formula's path
+func writeLocalFormula(t *testing.T, cfg *config.Config, name, formulaYAML string, files map[string]string) string {
+	t.Helper()
+
+	dir := localFormulaDir(cfg)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	formulaPath := filepath.Join(dir, name+".yaml")
+	if err := os.WriteFile(formulaPath, []byte(formulaYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return formulaPath
+}
+
+// helloScript is a bare binary source for formulae declaring `binary: hello`
+const helloScript = "#!/bin/sh\necho hello\n"
+
+// helloMakefile builds and installs a hello script shipped alongside it
+const helloMakefile = "all:\n\t@true\n\ninstall:\n\tmkdir -p $(PREFIX)/bin\n\tcp hello $(PREFIX)/bin/hello\n"
+
+// helloScriptSHA256 is the checksum of helloScript
+func helloScriptSHA256() string {
+	sum := sha256.Sum256([]byte(helloScript))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestInstallReceiptVerified(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tests := []struct {
+		name         string
+		source       string
+		headOnly     bool
+		strict       bool
+		wantVerified bool
+	}{
+		{"checksummed", "url: hello-1.0.sh\nsha256: " + helloScriptSHA256() + "\n", false, true, true},
+		{"HEAD", "head:\n  url: hello-1.0.sh\n", true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newLocalFormulaConfig(t)
+			formulaPath := writeLocalFormula(t, cfg, "hello", "name: hello\nversion: 1.0.0\nbinary: hello\nbottle: unneeded\n"+tt.source,
+				map[string]string{"hello-1.0.sh": helloScript})
+
+			if _, err := New(cfg, &Options{HeadOnly: tt.headOnly, StrictVerification: tt.strict}).InstallFormula(formulaPath); err != nil {
+				t.Fatalf("InstallFormula() error = %v", err)
+			}
+
+			receipts, _ := filepath.Glob(filepath.Join(cfg.HomebrewCellar, "hello", "*", "INSTALL_RECEIPT.json"))
+			if len(receipts) != 1 {
+				t.Fatalf("Expected one install receipt, got %v", receipts)
+			}
+			data, err := os.ReadFile(receipts[0])
+			if err != nil {
+				t.Fatalf("Failed to read receipt: %v", err)
+			}
+			var receipt InstallReceipt
+			if err := json.Unmarshal(data, &receipt); err != nil {
+				t.Fatal(err)
+			}
+			if receipt.Verified != tt.wantVerified {
+				t.Errorf("receipt.Verified = %v, want %v", receipt.Verified, tt.wantVerified)
+			}
+			if receipt.StrictVerification != tt.strict {
+				t.Errorf("receipt.StrictVerification = %v, want %v", receipt.StrictVerification, tt.strict)
+			}
+			if !strings.Contains(string(data), `"verified"`) {
+				t.Errorf("Receipt should always record verified, got %s", data)
+			}
+		})
+	}
+}
+
+func TestInstallDeprecatedAndDisabledFormula(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tests := []struct {
+		name        string
+		lifecycle   string
+		force       bool
+		wantErr     string
+		wantWarning string
+	}{
+		{
+			name:        "deprecated warns but installs",
+			lifecycle:   "deprecated: true\ndeprecation_date: \"2024-01-01\"\ndeprecation_reason: unmaintained\n",
+			wantWarning: "hello has been deprecated because it is not maintained upstream (since 2024-01-01)",
+		},
+		{
+			name:      "disabled refuses without force",
+			lifecycle: "disabled: true\ndisable_date: \"2024-06-01\"\ndisable_reason: does_not_build\n",
+			wantErr:   "hello has been disabled because it does not build (since 2024-06-01); use --force",
+		},
+		{
+			name:        "disabled installs with force",
+			lifecycle:   "disabled: true\ndisable_reason: does_not_build\n",
+			force:       true,
+			wantWarning: "hello has been disabled because it does not build",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newLocalFormulaConfig(t)
+			formulaYAML := "name: hello\nversion: 1.0.0\nbinary: hello\nbottle: unneeded\nurl: hello-1.0.sh\nsha256: " + helloScriptSHA256() + "\n" + tt.lifecycle
+			formulaPath := writeLocalFormula(t, cfg, "hello", formulaYAML, map[string]string{"hello-1.0.sh": helloScript})
+
+			var err error
+			warnings := captureWarnings(t, func() {
+				_, err = New(cfg, &Options{Force: tt.force}).InstallFormula(formulaPath)
+			})
+
+			installed := isNonEmptyDir(filepath.Join(cfg.HomebrewCellar, "hello", "1.0.0"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("InstallFormula() error = %v, want %q", err, tt.wantErr)
+				}
+				if installed {
+					t.Error("Disabled formula should not be installed without --force")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("InstallFormula() error = %v", err)
+			}
+			if !installed {
+				t.Error("Expected the formula to be installed")
+			}
+			if !strings.Contains(warnings, tt.wantWarning) {
+				t.Errorf("Expected warning %q, got:\n%s", tt.wantWarning, warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package installer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// interactiveOut receives the build commands an interactive install would
+// have run; tests replace it
+var interactiveOut io.Writer = os.Stdout
+
+// interactiveShell opens a shell in dir; tests replace it
+var interactiveShell = func(dir string, env []string) error {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	// #nosec G204 - the shell is the user's own
+	cmd := exec.Command(shell)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// interactiveBuild prints the commands a source build would run and opens
+// a shell in the unpacked sources instead of running them
+func (i *Installer) interactiveBuild(f *formula.Formula, sourceDir, cellarPath string) error {
+	if i.opts.Git {
+		if err := initSourceRepo(sourceDir); err != nil {
+			return err
+		}
+	}
+
+	commands, buildSystem, err := i.detectBuildSystem(sourceDir, cellarPath)
+	if err != nil {
+		logger.Warn("Could not detect how to build %s: %v", f.Name, err)
+	}
+
+	_, _ = fmt.Fprintf(interactiveOut, "==> Entering interactive mode\n")
+	_, _ = fmt.Fprintf(interactiveOut, "Type `exit` to return; nothing will be installed.\n")
+	_, _ = fmt.Fprintf(interactiveOut, "Install to this prefix: %s\n", cellarPath)
+	if len(commands) > 0 {
+		_, _ = fmt.Fprintf(interactiveOut, "Build commands (%s):\n", buildSystem)
+		for _, cmdArgs := range commands {
+			_, _ = fmt.Fprintf(interactiveOut, "  %s\n", strings.Join(cmdArgs, " "))
+		}
+	}
+
+	if err := interactiveShell(sourceDir, i.buildEnv(cellarPath)); err != nil {
+		logger.Debug("Interactive shell exited: %v", err)
+	}
+	return nil
+}
+
+// initSourceRepo commits the unpacked sources to a new git repository so
+// changes made while debugging can be turned into patches
+func initSourceRepo(dir string) error {
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open git worktree: %w", err)
+	}
+	if err := worktree.AddGlob("."); err != nil {
+		return fmt.Errorf("failed to add sources to git: %w", err)
+	}
+
+	_, err = worktree.Commit("Initial commit", &git.CommitOptions{
+		AllowEmptyCommits: true,
+		Author:            &object.Signature{Name: "Homebrew", Email: "homebrew@localhost", When: time.Now()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit sources: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package installer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestInteractiveInstall(t *testing.T) {
+	logger.Init(false, false, true)
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not available")
+	}
+
+	for _, withGit := range []bool{false, true} {
+		name := "plain"
+		if withGit {
+			name = "git"
+		}
+		t.Run(name, func(t *testing.T) {
+			cfg := newLocalFormulaConfig(t)
+
+			tarball := writeSourceTarball(t, filepath.Join(localFormulaDir(cfg), "hello-1.0.tar.gz"), map[string]string{
+				"hello-1.0/Makefile": helloMakefile,
+				"hello-1.0/hello":    helloScript,
+			})
+			sum := sha256.Sum256(tarball)
+			formulaYAML := "name: hello\nversion: 1.0.0\nurl: hello-1.0.tar.gz\nsha256: " + hex.EncodeToString(sum[:]) + "\n"
+			formulaPath := writeLocalFormula(t, cfg, "hello", formulaYAML, nil)
+
+			var out bytes.Buffer
+			oldOut, oldShell := interactiveOut, interactiveShell
+			defer func() { interactiveOut, interactiveShell = oldOut, oldShell }()
+			interactiveOut = &out
+
+			var shellDir string
+			var hasGit bool
+			interactiveShell = func(dir string, env []string) error {
+				shellDir = dir
+				if _, err := os.Stat(filepath.Join(dir, "Makefile")); err != nil {
+					t.Errorf("Expected extracted sources in %s: %v", dir, err)
+				}
+				if repo, err := git.PlainOpen(dir); err == nil {
+					_, headErr := repo.Head()
+					hasGit = headErr == nil
+				}
+				return nil
+			}
+
+			inst := New(cfg, &Options{Interactive: true, Git: withGit})
+			result, err := inst.InstallFormula(formulaPath)
+			if err != nil {
+				t.Fatalf("InstallFormula() error = %v", err)
+			}
+			if !result.Success || result.Source != "source" {
+				t.Errorf("Unexpected result %+v", result)
+			}
+
+			if shellDir == "" {
+				t.Fatal("Expected a shell to be opened in the sources")
+			}
+			if hasGit != withGit {
+				t.Errorf("git repository present = %v, want %v", hasGit, withGit)
+			}
+			if !strings.Contains(out.String(), "make install") {
+				t.Errorf("Expected the build commands to be printed, got %q", out.String())
+			}
+
+			if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "hello")); !os.IsNotExist(err) {
+				t.Error("Expected nothing to be installed into the cellar")
+			}
+			if _, err := os.Stat(filepath.Join(cfg.HomebrewPrefix, "opt", "hello")); !os.IsNotExist(err) {
+				t.Error("Expected nothing to be linked")
+			}
+		})
+	}
+}
+
+func TestInteractiveInstallBuildsDependencies(t *testing.T) {
+	logger.Init(false, false, true)
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not available")
+	}
+
+	cfg := newLocalFormulaConfig(t)
+	depTarball := writeSourceTarball(t, filepath.Join(localFormulaDir(cfg), "dep-1.0.tar.gz"), map[string]string{
+		"dep-1.0/Makefile": helloMakefile,
+		"dep-1.0/hello":    helloScript,
+	})
+	depSum := sha256.Sum256(depTarball)
+	depPath := writeLocalFormula(t, cfg, "dep", "name: dep\nversion: 1.0.0\nbottle: unneeded\nurl: dep-1.0.tar.gz\nsha256: "+hex.EncodeToString(depSum[:])+"\n", nil)
+	tarball := writeSourceTarball(t, filepath.Join(localFormulaDir(cfg), "app-1.0.tar.gz"), map[string]string{
+		"app-1.0/Makefile": "all:\n\t@true\n",
+	})
+	sum := sha256.Sum256(tarball)
+	formulaPath := writeLocalFormula(t, cfg, "app", "name: app\nversion: 1.0.0\nurl: app-1.0.tar.gz\nsha256: "+hex.EncodeToString(sum[:])+"\ndependencies:\n  - "+depPath+"\n", nil)
+
+	oldOut, oldShell := interactiveOut, interactiveShell
+	defer func() { interactiveOut, interactiveShell = oldOut, oldShell }()
+	interactiveOut = &bytes.Buffer{}
+	var shells []string
+	interactiveShell = func(dir string, env []string) error {
+		shells = append(shells, dir)
+		return nil
+	}
+
+	if _, err := New(cfg, &Options{Interactive: true, Git: true}).InstallFormula(formulaPath); err != nil {
+		t.Fatalf("InstallFormula() error = %v", err)
+	}
+
+	if len(shells) != 1 || !strings.Contains(shells[0], "app") {
+		t.Errorf("Expected one shell, in the requested formula's sources, got %v", shells)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "dep", "1.0.0", "bin", "hello")); err != nil {
+		t.Errorf("Expected the dependency to be installed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCellar, "app")); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be installed for the requested formula")
+	}
+}
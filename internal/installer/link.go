@@ -0,0 +1,277 @@
+package installer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pilshchikov/homebrew-go/internal/errors"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// PathKind classifies an existing path in the Homebrew prefix
+type PathKind int
+
+const (
+	// PathAbsent means nothing exists at the path
+	PathAbsent PathKind = iota
+	// PathManagedSymlink is a symlink pointing into the Cellar
+	PathManagedSymlink
+	// PathForeignSymlink is a symlink pointing outside the Cellar
+	PathForeignSymlink
+	// PathRegularFile is a real file or directory not created by brew
+	PathRegularFile
+)
+
+// String returns a human-readable name for the path kind
+func (k PathKind) String() string {
+	switch k {
+	case PathAbsent:
+		return "absent"
+	case PathManagedSymlink:
+		return "managed symlink"
+	case PathForeignSymlink:
+		return "foreign symlink"
+	case PathRegularFile:
+		return "regular file"
+	}
+	return "unknown"
+}
+
+// LinkConflictError reports prefix files that kept a keg from being linked
+type LinkConflictError struct {
+	Formula string
+	Paths   []string
+}
+
+// Error implements the error interface
+func (e *LinkConflictError) Error() string {
+	return fmt.Sprintf("could not link %s because these files already exist and are not managed by Homebrew: %s; "+
+		"reinstall with `brew install --overwrite %s` or run `brew link --overwrite %s` to replace them",
+		e.Formula, strings.Join(e.Paths, ", "), e.Formula, e.Formula)
+}
+
+// ClassifyPrefixPath reports whether path is absent, a symlink into cellar,
+// a symlink elsewhere, or a real file
+func ClassifyPrefixPath(path, cellar string) PathKind {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return PathAbsent
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		return PathRegularFile
+	}
+
+	target, err := os.Readlink(path)
+	if err != nil {
+		return PathForeignSymlink
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+
+	rel, err := filepath.Rel(filepath.Clean(cellar), filepath.Clean(target))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return PathForeignSymlink
+	}
+	return PathManagedSymlink
+}
+
+// repairOptLinks points opt/<name> at the newest installed version of each
+// formula, replacing missing, dangling or stale links
+func (i *Installer) repairOptLinks(names []string) error {
+	optDir := filepath.Join(i.cfg.HomebrewPrefix, "opt")
+
+	for _, name := range names {
+		// Dependencies may be tap-qualified; the cellar only uses short names
+		name = name[strings.LastIndex(name, "/")+1:]
+
+		newest, err := newestInstalledVersion(filepath.Join(i.cfg.HomebrewCellar, name))
+		if err != nil || newest == "" {
+			// Not installed; nothing to point at
+			continue
+		}
+
+		optLink := filepath.Join(optDir, name)
+		target := filepath.Join(i.cfg.HomebrewCellar, name, newest)
+
+		switch ClassifyPrefixPath(optLink, i.cfg.HomebrewCellar) {
+		case PathAbsent:
+		case PathManagedSymlink:
+			if current, err := os.Readlink(optLink); err == nil && current == target {
+				continue
+			}
+		default:
+			logger.Warn("Not repairing %s: it is not managed by Homebrew", optLink)
+			continue
+		}
+
+		if err := os.MkdirAll(optDir, 0755); err != nil {
+			return errors.NewPermissionError("create opt directory", optDir, err)
+		}
+		if err := os.Remove(optLink); err != nil && !os.IsNotExist(err) {
+			return errors.NewPermissionError("remove opt link", optLink, err)
+		}
+		if err := os.Symlink(target, optLink); err != nil {
+			return errors.NewPermissionError("create opt link", optLink, err)
+		}
+		logger.Debug("Linked %s -> %s", optLink, target)
+	}
+
+	return nil
+}
+
+// CompleteLink finishes linking a formula that `brew link` has symlinked:
+// it points opt/<name> at the newest keg and clears the receipt's unlinked
+// state left by --no-link
+func (i *Installer) CompleteLink(name string) error {
+	if err := i.repairOptLinks([]string{name}); err != nil {
+		return err
+	}
+
+	formulaPath := filepath.Join(i.cfg.HomebrewCellar, name)
+	newest, err := newestInstalledVersion(formulaPath)
+	if err != nil || newest == "" {
+		return err
+	}
+	return markLinked(filepath.Join(formulaPath, newest, "INSTALL_RECEIPT.json"))
+}
+
+// markLinked drops the unlinked flag from a receipt, leaving other fields
+// untouched
+func markLinked(receiptPath string) error {
+	return editReceipt(receiptPath, func(receipt map[string]json.RawMessage) (bool, error) {
+		if _, ok := receipt["unlinked"]; !ok {
+			return false, nil
+		}
+		delete(receipt, "unlinked")
+		return true, nil
+	})
+}
+
+// RecordOverwritten adds paths replaced while linking to a receipt so that
+// they can be accounted for when the keg is unlinked
+func RecordOverwritten(receiptPath string, paths []string) error {
+	return editReceipt(receiptPath, func(receipt map[string]json.RawMessage) (bool, error) {
+		var recorded []string
+		if raw, ok := receipt["overwritten"]; ok {
+			if err := json.Unmarshal(raw, &recorded); err != nil {
+				return false, err
+			}
+		}
+		for _, path := range paths {
+			if !slices.Contains(recorded, path) {
+				recorded = append(recorded, path)
+			}
+		}
+
+		raw, err := json.Marshal(recorded)
+		if err != nil {
+			return false, err
+		}
+		receipt["overwritten"] = raw
+		return true, nil
+	})
+}
+
+// TakeOverwritten returns the prefix paths that linking any keg of a formula
+// replaced, clearing them from the receipts once they have been accounted for
+func TakeOverwritten(cellar, name string) ([]string, error) {
+	formulaPath := filepath.Join(cellar, name)
+	versions, err := os.ReadDir(formulaPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var overwritten []string
+	for _, version := range versions {
+		if !version.IsDir() {
+			continue
+		}
+		err := editReceipt(filepath.Join(formulaPath, version.Name(), "INSTALL_RECEIPT.json"), func(receipt map[string]json.RawMessage) (bool, error) {
+			raw, ok := receipt["overwritten"]
+			if !ok {
+				return false, nil
+			}
+			var recorded []string
+			if err := json.Unmarshal(raw, &recorded); err != nil {
+				return false, err
+			}
+			for _, path := range recorded {
+				if !slices.Contains(overwritten, path) {
+					overwritten = append(overwritten, path)
+				}
+			}
+			delete(receipt, "overwritten")
+			return true, nil
+		})
+		if err != nil {
+			return overwritten, err
+		}
+	}
+
+	return overwritten, nil
+}
+
+// editReceipt rewrites a receipt's raw fields with edit, leaving fields it
+// doesn't touch as they were. Missing receipts are left alone.
+func editReceipt(receiptPath string, edit func(receipt map[string]json.RawMessage) (bool, error)) error {
+	data, err := os.ReadFile(receiptPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var receipt map[string]json.RawMessage
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", receiptPath, err)
+	}
+	changed, err := edit(receipt)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", receiptPath, err)
+	}
+	if !changed {
+		return nil
+	}
+
+	data, err = json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(receiptPath, data, 0644)
+}
+
+// newestInstalledVersion returns the highest version directory of a formula
+// in the cellar
+func newestInstalledVersion(formulaPath string) (string, error) {
+	entries, err := os.ReadDir(formulaPath)
+	if err != nil {
+		return "", err
+	}
+
+	var newest *formula.Formula
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		candidate := &formula.Formula{Version: entry.Name()}
+		if newest == nil || candidate.IsNewer(newest) {
+			newest = candidate
+		}
+	}
+
+	if newest == nil {
+		return "", nil
+	}
+	return newest.Version, nil
+}
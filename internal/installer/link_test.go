@@ -0,0 +1,252 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestClassifyPrefixPath(t *testing.T) {
+	tempDir := t.TempDir()
+	cellar := filepath.Join(tempDir, "Cellar")
+	binDir := filepath.Join(tempDir, "bin")
+	for _, dir := range []string{filepath.Join(cellar, "wget", "1.21", "bin"), binDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	managed := filepath.Join(binDir, "wget")
+	if err := os.Symlink(filepath.Join(cellar, "wget", "1.21", "bin", "wget"), managed); err != nil {
+		t.Fatal(err)
+	}
+	relative := filepath.Join(binDir, "wget-rel")
+	if err := os.Symlink(filepath.Join("..", "Cellar", "wget", "1.21", "bin", "wget"), relative); err != nil {
+		t.Fatal(err)
+	}
+	foreign := filepath.Join(binDir, "python")
+	if err := os.Symlink("/usr/bin/python3", foreign); err != nil {
+		t.Fatal(err)
+	}
+	regular := filepath.Join(binDir, "my-script")
+	if err := os.WriteFile(regular, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		expected PathKind
+	}{
+		{managed, PathManagedSymlink},
+		{relative, PathManagedSymlink},
+		{foreign, PathForeignSymlink},
+		{regular, PathRegularFile},
+		{binDir, PathRegularFile},
+		{filepath.Join(binDir, "missing"), PathAbsent},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			if kind := ClassifyPrefixPath(tt.path, cellar); kind != tt.expected {
+				t.Errorf("ClassifyPrefixPath(%s) = %v, want %v", tt.path, kind, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLinkFormulaRefusesForeignFiles(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tests := []struct {
+		name       string
+		force      bool
+		wantErr    bool
+		wantLinked bool
+		existing   func(t *testing.T, dst, cellar string)
+	}{
+		{
+			name: "regular file is kept without force",
+			existing: func(t *testing.T, dst, cellar string) {
+				if err := os.WriteFile(dst, []byte("user binary"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name:  "regular file is replaced with force",
+			force: true,
+			existing: func(t *testing.T, dst, cellar string) {
+				if err := os.WriteFile(dst, []byte("user binary"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantLinked: true,
+		},
+		{
+			name: "managed symlink is replaced",
+			existing: func(t *testing.T, dst, cellar string) {
+				if err := os.Symlink(filepath.Join(cellar, "hello", "0.9.0", "bin", "hello"), dst); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantLinked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			cfg := &config.Config{
+				HomebrewPrefix: tempDir,
+				HomebrewCellar: filepath.Join(tempDir, "Cellar"),
+			}
+			f := &formula.Formula{Name: "hello", Version: "1.0.0"}
+
+			src := filepath.Join(f.GetCellarPath(cfg.HomebrewCellar), "bin", "hello")
+			if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(src, []byte("#!/bin/sh\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			dst := filepath.Join(tempDir, "bin", "hello")
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				t.Fatal(err)
+			}
+			tt.existing(t, dst, cfg.HomebrewCellar)
+
+			inst := New(cfg, &Options{Force: tt.force})
+			_, err := inst.linkFormula(f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("linkFormula() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			target, _ := os.Readlink(dst)
+			if linked := target == src; linked != tt.wantLinked {
+				t.Errorf("linked = %v, want %v (target %q)", linked, tt.wantLinked, target)
+			}
+			if !tt.wantLinked {
+				if data, err := os.ReadFile(dst); err != nil || string(data) != "user binary" {
+					t.Error("User file should be left untouched")
+				}
+			}
+		})
+	}
+}
+
+func TestRepairOptLinks(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewPrefix: tempDir,
+		HomebrewCellar: filepath.Join(tempDir, "Cellar"),
+	}
+	for _, version := range []string{"3.9.0", "3.10.0"} {
+		if err := os.MkdirAll(filepath.Join(cfg.HomebrewCellar, "openssl", version), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// opt link left pointing at a version that was deleted by hand
+	optLink := filepath.Join(tempDir, "opt", "openssl")
+	if err := os.MkdirAll(filepath.Dir(optLink), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(cfg.HomebrewCellar, "openssl", "1.1.1"), optLink); err != nil {
+		t.Fatal(err)
+	}
+
+	inst := New(cfg, &Options{})
+	if err := inst.repairOptLinks([]string{"homebrew/core/openssl", "not-installed"}); err != nil {
+		t.Fatalf("repairOptLinks() error = %v", err)
+	}
+
+	target, err := os.Readlink(optLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(cfg.HomebrewCellar, "openssl", "3.10.0"); target != want {
+		t.Errorf("opt link points to %s, want %s", target, want)
+	}
+	if _, err := os.Lstat(filepath.Join(tempDir, "opt", "not-installed")); !os.IsNotExist(err) {
+		t.Error("No opt link should be created for formulae that aren't installed")
+	}
+}
+
+func TestInstallNoLink(t *testing.T) {
+	logger.Init(false, false, true)
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not available")
+	}
+
+	cfg := newLocalFormulaConfig(t)
+	depPath := writeLocalFormula(t, cfg, "dep", "name: dep\nversion: 1.0.0\nbinary: dep\nbottle: unneeded\nurl: hello-1.0.sh\nsha256: "+helloScriptSHA256()+"\n",
+		map[string]string{"hello-1.0.sh": helloScript})
+	tarball := writeSourceTarball(t, filepath.Join(localFormulaDir(cfg), "hello-1.0.tar.gz"), map[string]string{
+		"hello-1.0/Makefile": helloMakefile,
+		"hello-1.0/hello":    helloScript,
+	})
+	sum := sha256.Sum256(tarball)
+	formulaPath := writeLocalFormula(t, cfg, "hello", "name: hello\nversion: 1.0.0\nurl: hello-1.0.tar.gz\nsha256: "+hex.EncodeToString(sum[:])+"\ndependencies:\n  - "+depPath+"\n", nil)
+
+	inst := New(cfg, &Options{NoLink: true})
+	if _, err := inst.InstallFormula(formulaPath); err != nil {
+		t.Fatalf("InstallFormula() error = %v", err)
+	}
+
+	kegPath := filepath.Join(cfg.HomebrewCellar, "hello", "1.0.0")
+	if _, err := os.Stat(filepath.Join(kegPath, "bin", "hello")); err != nil {
+		t.Errorf("Expected the keg to be installed: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(cfg.HomebrewPrefix, "bin", "hello")); !os.IsNotExist(err) {
+		t.Error("Expected no bin/hello symlink with --no-link")
+	}
+
+	// Dependents find the keg through opt/, and --no-link only applies to
+	// the formula that was asked for
+	if target, err := os.Readlink(filepath.Join(cfg.HomebrewPrefix, "opt", "hello")); err != nil || target != kegPath {
+		t.Errorf("Expected opt link to %s with --no-link, got %q (%v)", kegPath, target, err)
+	}
+	if _, err := os.Lstat(filepath.Join(cfg.HomebrewPrefix, "bin", "dep")); err != nil {
+		t.Errorf("Expected the dependency to be linked: %v", err)
+	}
+
+	receiptPath := filepath.Join(kegPath, "INSTALL_RECEIPT.json")
+	readReceipt := func() InstallReceipt {
+		t.Helper()
+		data, err := os.ReadFile(receiptPath)
+		if err != nil {
+			t.Fatalf("Expected an install receipt: %v", err)
+		}
+		var receipt InstallReceipt
+		if err := json.Unmarshal(data, &receipt); err != nil {
+			t.Fatal(err)
+		}
+		return receipt
+	}
+	if receipt := readReceipt(); !receipt.Unlinked || receipt.Name != "hello" {
+		t.Errorf("Expected the receipt to record the keg as unlinked, got %+v", receipt)
+	}
+
+	// brew link completes the install
+	if err := New(cfg, &Options{}).CompleteLink("hello"); err != nil {
+		t.Fatalf("CompleteLink() error = %v", err)
+	}
+	if receipt := readReceipt(); receipt.Unlinked || receipt.Name != "hello" {
+		t.Errorf("Expected linking to clear the unlinked state, got %+v", receipt)
+	}
+	if target, err := os.Readlink(filepath.Join(cfg.HomebrewPrefix, "opt", "hello")); err != nil || target != kegPath {
+		t.Errorf("Expected opt link to %s, got %q (%v)", kegPath, target, err)
+	}
+}
@@ -0,0 +1,178 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/errors"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
+)
+
+// bottleFileRegexp matches bottle file names such as
+// wget-1.21.3.arm64_sequoia.bottle.tar.gz or
+// wget-1.21.3.arm64_sequoia.bottle.1.tar.gz
+var bottleFileRegexp = regexp.MustCompile(`^(.+)-([^-]+)\.([a-z0-9_]+)\.bottle(?:\.(\d+))?\.tar\.gz$`)
+
+// LocalBottle is what a bottle's file name says about it
+type LocalBottle struct {
+	Path     string
+	Name     string
+	Version  string
+	Platform string
+	Rebuild  int
+}
+
+// IsBottlePath reports whether arg names a bottle file rather than a formula
+func IsBottlePath(arg string) bool {
+	if !bottleFileRegexp.MatchString(filepath.Base(arg)) {
+		return false
+	}
+	info, err := os.Stat(arg)
+	return err == nil && !info.IsDir()
+}
+
+// ParseBottlePath reads the formula name, version and platform tag from a
+// bottle's file name
+func ParseBottlePath(path string) (*LocalBottle, error) {
+	m := bottleFileRegexp.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return nil, fmt.Errorf("%s is not named like a bottle (NAME-VERSION.PLATFORM.bottle.tar.gz)", filepath.Base(path))
+	}
+	rebuild, _ := strconv.Atoi(m[4])
+	return &LocalBottle{Path: path, Name: m[1], Version: m[2], Platform: m[3], Rebuild: rebuild}, nil
+}
+
+// InstallBottleFile pours a bottle from a local file, for installs without
+// network access. The bottle is verified only if a .sha256 file sits next
+// to it.
+func (i *Installer) InstallBottleFile(path string) (*InstallResult, error) {
+	start := time.Now()
+
+	bottle, err := ParseBottlePath(path)
+	if err != nil {
+		return nil, err
+	}
+	result := &InstallResult{Name: bottle.Name, Version: bottle.Version, Source: "bottle"}
+
+	if host := i.platformTag(); bottle.Platform != host && bottle.Platform != "all" {
+		if !i.opts.Force {
+			result.Error = fmt.Errorf("bottle %s is for %s, not %s; use --force to install it anyway", filepath.Base(path), bottle.Platform, host)
+			return result, result.Error
+		}
+		logger.Warn("Installing a %s bottle on %s", bottle.Platform, host)
+	}
+	if err := i.checkPrefixWritable(); err != nil {
+		result.Error = err
+		return result, err
+	}
+	verified, err := verifyLocalBottle(path)
+	if err != nil {
+		result.Error = err
+		return result, err
+	}
+
+	f := &formula.Formula{
+		Name:    bottle.Name,
+		Version: bottle.Version,
+		Bottle:  &formula.Bottle{Stable: &formula.BottleSpec{Rebuild: bottle.Rebuild}},
+	}
+	kegPath := f.GetCellarPath(i.cfg.HomebrewCellar)
+	if isNonEmptyDir(kegPath) {
+		if !i.opts.Force && isCommittedKeg(kegPath) {
+			logger.Info("%s %s is already installed", f.Name, f.Version)
+			result.AlreadyInstalled = true
+			result.Success = true
+			return result, nil
+		}
+		if err := os.RemoveAll(kegPath); err != nil {
+			result.Error = errors.NewPermissionError("remove existing keg", kegPath, err)
+			return result, result.Error
+		}
+	}
+
+	logger.Step("Pouring %s", filepath.Base(path))
+	extractStart := time.Now()
+	if err := i.extractBottleFile(path, f, kegPath); err != nil {
+		result.Error = fmt.Errorf("failed to extract bottle: %w", err)
+		return result, result.Error
+	}
+	result.BuildDuration = time.Since(extractStart)
+
+	if err := i.writeReceipt(f, "bottle", bottle.Platform, readBottleTab(kegPath), verified); err != nil {
+		logger.Warn("Failed to write install receipt: %v", err)
+	}
+
+	linkStart := time.Now()
+	result.LinkError = i.linkKeg(f)
+	result.LinkDuration = time.Since(linkStart)
+
+	logger.Warn("%s was installed without its dependencies; install them separately if it needs any", f.Name)
+	result.Duration = time.Since(start)
+	result.Success = true
+	return result, nil
+}
+
+// verifyLocalBottle checks a bottle against path.sha256 when one exists,
+// reporting whether it was verified
+func verifyLocalBottle(path string) (bool, error) {
+	data, err := os.ReadFile(path + ".sha256")
+	if os.IsNotExist(err) {
+		logger.Warn("%s has no .sha256 file; skipping verification", filepath.Base(path))
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read checksum: %w", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return false, fmt.Errorf("%s.sha256 is empty", filepath.Base(path))
+	}
+	actual, err := utils.ComputeSHA256(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to checksum bottle: %w", err)
+	}
+	if !strings.EqualFold(actual, fields[0]) {
+		size := int64(-1)
+		if info, err := os.Stat(path); err == nil {
+			size = info.Size()
+		}
+		bottle, _ := ParseBottlePath(path)
+		return false, errors.NewChecksumMismatchError(bottle.Name, bottle.Version, errors.ChecksumMismatch{
+			Expected: fields[0],
+			Actual:   actual,
+			Size:     size,
+		})
+	}
+	return true, nil
+}
+
+// extractBottleFile unpacks a bottle into kegPath. Bottles built by
+// Homebrew nest the keg under NAME/VERSION; others hold the keg contents
+// directly.
+func (i *Installer) extractBottleFile(path string, f *formula.Formula, kegPath string) error {
+	staging := filepath.Join(filepath.Dir(kegPath), "."+TempPrefix+"pour-"+f.Version)
+	_ = os.RemoveAll(staging)
+	defer func() { _ = os.RemoveAll(staging) }()
+	defer i.removeOnInterrupt(kegPath)()
+
+	if err := i.extractArchive(path, staging); err != nil {
+		return err
+	}
+
+	src := staging
+	if nested := filepath.Join(staging, f.Name, f.Version); isNonEmptyDir(nested) {
+		src = nested
+	}
+	if err := os.Rename(src, kegPath); err != nil {
+		return errors.NewPermissionError("move bottle into cellar", kegPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestParseBottlePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    LocalBottle
+		wantErr bool
+	}{
+		{path: "./wget-1.21.3.arm64_sequoia.bottle.tar.gz", want: LocalBottle{Name: "wget", Version: "1.21.3", Platform: "arm64_sequoia"}},
+		{path: "/tmp/python-tk-3.12.1_1.x86_64_linux.bottle.2.tar.gz", want: LocalBottle{Name: "python-tk", Version: "3.12.1_1", Platform: "x86_64_linux", Rebuild: 2}},
+		{path: "wget-1.21.3.tar.gz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := ParseBottlePath(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseBottlePath() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBottlePath() error = %v", err)
+			}
+			if got.Name != tt.want.Name || got.Version != tt.want.Version || got.Platform != tt.want.Platform || got.Rebuild != tt.want.Rebuild {
+				t.Errorf("ParseBottlePath() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallBottleFile(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewPrefix: filepath.Join(tempDir, "prefix"),
+		HomebrewCellar: filepath.Join(tempDir, "prefix", "Cellar"),
+		HomebrewCache:  filepath.Join(tempDir, "cache"),
+	}
+	inst := New(cfg, &Options{})
+
+	bottlePath := filepath.Join(tempDir, "wget-1.21.3."+inst.platformTag()+".bottle.tar.gz")
+	data := writeSourceTarball(t, bottlePath, map[string]string{
+		"wget/1.21.3/bin/wget":         "#!/bin/sh\necho wget\n",
+		"wget/1.21.3/share/man/wget.1": "manual\n",
+	})
+	if !IsBottlePath(bottlePath) {
+		t.Fatalf("Expected %s to be recognised as a bottle", bottlePath)
+	}
+
+	// A mismatching .sha256 stops the install
+	if err := os.WriteFile(bottlePath+".sha256", []byte("deadbeef  wget.bottle.tar.gz\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inst.InstallBottleFile(bottlePath); err == nil {
+		t.Fatal("Expected a checksum mismatch to fail the install")
+	}
+	kegPath := filepath.Join(cfg.HomebrewCellar, "wget", "1.21.3")
+	if _, err := os.Stat(kegPath); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be installed for a corrupt bottle")
+	}
+
+	sum := sha256.Sum256(data)
+	if err := os.WriteFile(bottlePath+".sha256", []byte(hex.EncodeToString(sum[:])+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := inst.InstallBottleFile(bottlePath)
+	if err != nil {
+		t.Fatalf("InstallBottleFile() error = %v", err)
+	}
+	if result.Name != "wget" || result.Version != "1.21.3" || result.Source != "bottle" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	for _, file := range []string{"bin/wget", "share/man/wget.1"} {
+		if _, err := os.Stat(filepath.Join(kegPath, file)); err != nil {
+			t.Errorf("Expected %s in the keg: %v", file, err)
+		}
+	}
+	if entries, _ := os.ReadDir(filepath.Join(cfg.HomebrewCellar, "wget")); len(entries) != 1 {
+		t.Errorf("Expected only the keg under Cellar/wget, got %v", entries)
+	}
+
+	receiptData, err := os.ReadFile(filepath.Join(kegPath, "INSTALL_RECEIPT.json"))
+	if err != nil {
+		t.Fatalf("Expected an install receipt: %v", err)
+	}
+	var receipt InstallReceipt
+	if err := json.Unmarshal(receiptData, &receipt); err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Name != "wget" || receipt.Version != "1.21.3" || receipt.Source != "bottle" || receipt.Platform != inst.platformTag() {
+		t.Errorf("Unexpected receipt %+v", receipt)
+	}
+
+	if _, err := os.Lstat(filepath.Join(cfg.HomebrewPrefix, "bin", "wget")); err != nil {
+		t.Errorf("Expected wget to be linked: %v", err)
+	}
+}
+
+func TestInstallBottleFileForeignPlatform(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewPrefix: filepath.Join(tempDir, "prefix"),
+		HomebrewCellar: filepath.Join(tempDir, "prefix", "Cellar"),
+	}
+	bottlePath := filepath.Join(tempDir, "wget-1.21.3.sparc_solaris.bottle.tar.gz")
+	writeSourceTarball(t, bottlePath, map[string]string{"wget/1.21.3/bin/wget": "#!/bin/sh\n"})
+
+	if _, err := New(cfg, &Options{}).InstallBottleFile(bottlePath); err == nil {
+		t.Error("Expected a bottle for another platform to require --force")
+	}
+	if _, err := New(cfg, &Options{Force: true}).InstallBottleFile(bottlePath); err != nil {
+		t.Errorf("InstallBottleFile() with --force error = %v", err)
+	}
+}
+
+func TestInstallBottleFileLinkConflict(t *testing.T) {
+	logger.Init(false, false, true)
+
+	for _, overwrite := range []bool{false, true} {
+		t.Run(fmt.Sprintf("overwrite=%v", overwrite), func(t *testing.T) {
+			tempDir := t.TempDir()
+			cfg := &config.Config{
+				HomebrewPrefix: filepath.Join(tempDir, "prefix"),
+				HomebrewCellar: filepath.Join(tempDir, "prefix", "Cellar"),
+			}
+			inst := New(cfg, &Options{Overwrite: overwrite})
+
+			bottlePath := filepath.Join(tempDir, "wget-1.21.3."+inst.platformTag()+".bottle.tar.gz")
+			writeSourceTarball(t, bottlePath, map[string]string{"wget/1.21.3/bin/wget": "#!/bin/sh\n"})
+
+			// A link to a wget installed outside Homebrew
+			foreign := filepath.Join(tempDir, "opt", "wget")
+			if err := os.MkdirAll(filepath.Dir(foreign), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(foreign, []byte("#!/bin/sh\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			linkPath := filepath.Join(cfg.HomebrewPrefix, "bin", "wget")
+			if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(foreign, linkPath); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := inst.InstallBottleFile(bottlePath)
+			if err != nil {
+				t.Fatalf("InstallBottleFile() error = %v", err)
+			}
+
+			kegBinary := filepath.Join(cfg.HomebrewCellar, "wget", "1.21.3", "bin", "wget")
+			target, _ := os.Readlink(linkPath)
+			receiptData, err := os.ReadFile(filepath.Join(cfg.HomebrewCellar, "wget", "1.21.3", "INSTALL_RECEIPT.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var receipt InstallReceipt
+			if err := json.Unmarshal(receiptData, &receipt); err != nil {
+				t.Fatal(err)
+			}
+
+			if !overwrite {
+				var conflict *LinkConflictError
+				if !errors.As(result.LinkError, &conflict) {
+					t.Fatalf("Expected a link conflict, got %v", result.LinkError)
+				}
+				if len(conflict.Paths) != 1 || !strings.Contains(conflict.Paths[0], linkPath) {
+					t.Errorf("Unexpected conflicting paths %v", conflict.Paths)
+				}
+				if !strings.Contains(conflict.Error(), "--overwrite") {
+					t.Errorf("Expected the conflict to suggest --overwrite: %v", conflict)
+				}
+				if target != foreign {
+					t.Errorf("Existing link should be kept, points at %q", target)
+				}
+				return
+			}
+
+			if result.LinkError != nil {
+				t.Fatalf("Expected --overwrite to link, got %v", result.LinkError)
+			}
+			if target != kegBinary {
+				t.Errorf("Expected %s to point into the keg, points at %q", linkPath, target)
+			}
+			if len(receipt.Overwritten) != 1 || receipt.Overwritten[0] != linkPath {
+				t.Errorf("Expected the receipt to record %s, got %v", linkPath, receipt.Overwritten)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package installer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// GitHub hosts used to derive codeload mirrors, replaced in tests
+var (
+	githubHost   = "https://github.com"
+	codeloadHost = "https://codeload.github.com"
+)
+
+// sourceMirrors returns the URLs to try for a source download, primary first
+func sourceMirrors(url string) []string {
+	urls := []string{url}
+	if mirror, ok := codeloadURL(url); ok {
+		urls = append(urls, mirror)
+	}
+	return urls
+}
+
+// codeloadURL maps a GitHub repository archive URL such as
+// github.com/<owner>/<repo>/archive/<ref>.tar.gz to the codeload.github.com
+// host that serves it. Release assets are uploaded files rather than
+// generated archives, so codeload has no copy of them.
+func codeloadURL(url string) (string, bool) {
+	path, ok := strings.CutPrefix(url, githubHost+"/")
+	if !ok {
+		return "", false
+	}
+
+	owner, rest, _ := strings.Cut(path, "/")
+	repo, rest, _ := strings.Cut(rest, "/")
+	ref, ok := strings.CutPrefix(rest, "archive/")
+	if owner == "" || repo == "" || !ok {
+		return "", false
+	}
+
+	for ext, format := range map[string]string{".tar.gz": "tar.gz", ".zip": "zip"} {
+		if ref, ok := strings.CutSuffix(ref, ext); ok && ref != "" {
+			return fmt.Sprintf("%s/%s/%s/%s/%s", codeloadHost, owner, repo, format, ref), true
+		}
+	}
+	return "", false
+}
+
+// downloadWithMirrors downloads from the first URL that succeeds
+func (i *Installer) downloadWithMirrors(urls []string, path, expectedSHA256 string) error {
+	var lastErr error
+	for idx, url := range urls {
+		if idx > 0 {
+			logger.Warn("Download failed, trying mirror %s", url)
+		}
+		if lastErr = i.downloadFile(url, path, expectedSHA256); lastErr == nil {
+			return nil
+		}
+		logger.Debug("Download of %s failed: %v", url, lastErr)
+	}
+	return lastErr
+}
@@ -0,0 +1,75 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestCodeloadURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		want   string
+		wantOK bool
+	}{
+		{"https://github.com/owner/repo/archive/v1.2.3.tar.gz", "https://codeload.github.com/owner/repo/tar.gz/v1.2.3", true},
+		{"https://github.com/owner/repo/archive/refs/tags/v1.2.3.tar.gz", "https://codeload.github.com/owner/repo/tar.gz/refs/tags/v1.2.3", true},
+		{"https://github.com/owner/repo/archive/main.zip", "https://codeload.github.com/owner/repo/zip/main", true},
+		{"https://github.com/owner/repo/releases/download/v1.0/repo-1.0.tar.gz", "", false},
+		{"https://example.com/owner/repo/archive/v1.tar.gz", "", false},
+		{"https://github.com/owner/repo/archive/.tar.gz", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := codeloadURL(tt.url)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("codeloadURL(%q) = %q, %v; want %q, %v", tt.url, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestDownloadFallsBackToCodeload(t *testing.T) {
+	logger.Init(false, false, true)
+
+	content := []byte("source archive")
+	var codeloadHits int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/owner/repo/archive/v1.0.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	})
+	mux.HandleFunc("/codeload/owner/repo/tar.gz/v1.0", func(w http.ResponseWriter, r *http.Request) {
+		codeloadHits++
+		_, _ = w.Write(content)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	origGitHub, origCodeload := githubHost, codeloadHost
+	githubHost, codeloadHost = server.URL, server.URL+"/codeload"
+	defer func() { githubHost, codeloadHost = origGitHub, origCodeload }()
+
+	sum := sha256.Sum256(content)
+	path := filepath.Join(t.TempDir(), "source.tar.gz")
+	inst := New(&config.Config{}, &Options{})
+
+	urls := sourceMirrors(server.URL + "/owner/repo/archive/v1.0.tar.gz")
+	if len(urls) != 2 {
+		t.Fatalf("sourceMirrors() = %v, want codeload fallback", urls)
+	}
+	if err := inst.downloadWithMirrors(urls, path, hex.EncodeToString(sum[:])); err != nil {
+		t.Fatalf("downloadWithMirrors() error = %v", err)
+	}
+	if codeloadHits != 1 {
+		t.Errorf("Expected one codeload request, got %d", codeloadHits)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != string(content) {
+		t.Errorf("Downloaded %q, %v; want %q", data, err, content)
+	}
+}
@@ -0,0 +1,186 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+)
+
+// Install methods reported in a plan
+const (
+	PlanBottle    = "bottle"
+	PlanSource    = "source"
+	PlanCask      = "cask"
+	PlanInstalled = "installed"
+)
+
+// PlanStep is one package an install would process
+type PlanStep struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version,omitempty"`
+	Method      string   `json:"method"`
+	URL         string   `json:"url,omitempty"`
+	Size        int64    `json:"size,omitempty"`
+	Dependency  bool     `json:"dependency"`
+	Keg         string   `json:"keg,omitempty"`
+	LinkTargets []string `json:"link_targets,omitempty"`
+}
+
+// Plan lists what installing some formulae would do, dependencies first
+type Plan struct {
+	Steps []PlanStep `json:"steps"`
+}
+
+// Plan resolves the formulae and their dependencies into install order
+// without downloading or installing anything. Sizes come from the
+// Content-Length of each download and are omitted when unknown.
+func (i *Installer) Plan(names []string) (*Plan, error) {
+	if err := i.checkBottleTag(); err != nil {
+		return nil, err
+	}
+
+	plan, err := i.resolvePlan(names)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sizes are looked up together so a slow server doesn't hold up the rest
+	var wg sync.WaitGroup
+	for idx := range plan.Steps {
+		step := &plan.Steps[idx]
+		if step.URL == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			step.Size = i.downloadSize(step.URL)
+		}()
+	}
+	wg.Wait()
+	return plan, nil
+}
+
+// MissingDependencies returns the dependencies installing the formulae would
+// pull in because they aren't installed yet, in install order
+func (i *Installer) MissingDependencies(names []string) ([]string, error) {
+	plan, err := i.resolvePlan(names)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, step := range plan.Steps {
+		if step.Dependency && step.Method != PlanInstalled {
+			missing = append(missing, step.Name)
+		}
+	}
+	return missing, nil
+}
+
+// resolvePlan orders the formulae after their dependencies
+func (i *Installer) resolvePlan(names []string) (*Plan, error) {
+	plan := &Plan{Steps: []PlanStep{}}
+	visited := make(map[string]bool)
+	for _, name := range names {
+		if err := i.planFormula(plan, name, false, visited, nil); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+func (i *Installer) planFormula(plan *Plan, name string, dependency bool, visited map[string]bool, stack []string) error {
+	for _, parent := range stack {
+		if parent == name {
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(stack, " -> "), name)
+		}
+	}
+	if visited[name] {
+		return nil
+	}
+
+	f, err := i.resolveFormula(name)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+
+	if dependency {
+		if installed, err := HasCommittedKeg(i.cfg.HomebrewCellar, f.Name); err == nil && installed {
+			visited[name] = true
+			plan.Steps = append(plan.Steps, PlanStep{Name: f.Name, Version: f.Version, Method: PlanInstalled, Dependency: true})
+			return nil
+		}
+	}
+
+	if !i.opts.IgnoreDependencies {
+		for _, dep := range i.dependenciesFor(f) {
+			if dep.Cask {
+				if !visited["cask:"+dep.Name] {
+					visited["cask:"+dep.Name] = true
+					plan.Steps = append(plan.Steps, PlanStep{Name: dep.Name, Method: PlanCask, Dependency: true})
+				}
+				continue
+			}
+			if err := i.planFormula(plan, dep.Name, true, visited, append(stack, name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	visited[name] = true
+	if !dependency && i.opts.OnlyDependencies {
+		return nil
+	}
+	plan.Steps = append(plan.Steps, i.planStep(f, dependency))
+	return nil
+}
+
+// planStep describes how a formula would be installed and linked
+func (i *Installer) planStep(f *formula.Formula, dependency bool) PlanStep {
+	step := PlanStep{
+		Name:       f.Name,
+		Version:    f.Version,
+		Dependency: dependency,
+		Keg:        f.GetCellarPath(i.cfg.HomebrewCellar),
+	}
+
+	if i.shouldUseBottle(f) {
+		step.Method = PlanBottle
+		step.URL = f.GetBottleURL(i.platformTag())
+	} else {
+		step.Method = PlanSource
+		step.URL = f.URL
+		if i.opts.HeadOnly && f.Head != nil {
+			step.URL = f.Head.URL
+		}
+	}
+
+	step.LinkTargets = []string{filepath.Join(i.cfg.HomebrewPrefix, "opt", f.Name)}
+	if !f.KegOnly {
+		step.LinkTargets = append(step.LinkTargets, filepath.Join(i.cfg.HomebrewPrefix, "bin"))
+	}
+	return step
+}
+
+// planSizeTimeout bounds how long a plan waits for a download's size
+const planSizeTimeout = 5 * time.Second
+
+// downloadSize returns how large a download is, or 0 when it isn't known
+func (i *Installer) downloadSize(url string) int64 {
+	if localPath, ok := strings.CutPrefix(url, "file://"); ok {
+		if info, err := os.Stat(localPath); err == nil {
+			return info.Size()
+		}
+		return 0
+	}
+	if isGitURL(url) {
+		return 0
+	}
+	return i.apiClient.DownloadSize(url, planSizeTimeout)
+}
@@ -0,0 +1,72 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// postInstallStep runs one post-install shell command in dir; tests
+// replace it
+var postInstallStep = func(step, dir string, env []string) error {
+	// #nosec G204 - steps come from the formula definition
+	cmd := exec.Command("/bin/sh", "-c", step)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// runPostInstall runs a formula's post-install steps in its keg with the
+// build environment
+func (i *Installer) runPostInstall(name, kegPath string, steps []string) error {
+	if len(steps) == 0 {
+		return nil
+	}
+
+	logger.Progress("Running post-install for %s", name)
+	env := i.buildEnv(kegPath)
+	for _, step := range steps {
+		logger.Step("Running: %s", step)
+		if err := postInstallStep(step, kegPath, env); err != nil {
+			return fmt.Errorf("post-install step %q for %s failed: %w", step, name, err)
+		}
+	}
+	return nil
+}
+
+// PostInstall re-runs the post-install steps of an installed formula in its
+// newest keg. The steps recorded in the receipt are used, falling back to
+// those the formula declares.
+func (i *Installer) PostInstall(name string) error {
+	formulaPath := filepath.Join(i.cfg.HomebrewCellar, name)
+	newest, err := newestInstalledVersion(formulaPath)
+	if err != nil || newest == "" {
+		return fmt.Errorf("formula %s is not installed", name)
+	}
+	kegPath := filepath.Join(formulaPath, newest)
+
+	var steps []string
+	if receipt, err := ReadReceipt(kegPath); err == nil {
+		steps = receipt.PostInstall
+	} else if !os.IsNotExist(err) {
+		logger.Warn("Failed to read install receipt of %s: %v", name, err)
+	}
+
+	if len(steps) == 0 {
+		if f, err := i.resolveFormula(name); err == nil {
+			steps = f.PostInstall
+		} else {
+			logger.Debug("Failed to resolve formula %s: %v", name, err)
+		}
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("formula %s has no post-install steps", name)
+	}
+
+	return i.runPostInstall(name, kegPath, steps)
+}
@@ -0,0 +1,70 @@
+package installer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestPostInstall(t *testing.T) {
+	logger.Init(false, false, true)
+
+	// Only the steps recorded in receipts can be found
+	cfg := newLocalFormulaConfig(t)
+
+	type run struct {
+		step, dir string
+		env       []string
+	}
+	var runs []run
+	original := postInstallStep
+	postInstallStep = func(step, dir string, env []string) error {
+		runs = append(runs, run{step, dir, env})
+		return nil
+	}
+	defer func() { postInstallStep = original }()
+
+	inst := New(cfg, &Options{})
+	if err := inst.PostInstall("hello"); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Fatalf("PostInstall() error = %v, want not installed", err)
+	}
+
+	writeReceipt := func(name string, receipt InstallReceipt) string {
+		kegPath := filepath.Join(cfg.HomebrewCellar, name, "1.0")
+		if err := os.MkdirAll(kegPath, 0755); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(receipt)
+		if err := os.WriteFile(filepath.Join(kegPath, "INSTALL_RECEIPT.json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return kegPath
+	}
+
+	writeReceipt("plain", InstallReceipt{Name: "plain", Version: "1.0"})
+	if err := inst.PostInstall("plain"); err == nil || !strings.Contains(err.Error(), "no post-install") {
+		t.Fatalf("PostInstall() error = %v, want no post-install steps", err)
+	}
+
+	kegPath := writeReceipt("hello", InstallReceipt{Name: "hello", Version: "1.0", PostInstall: []string{"mkdir -p var/hello", "touch var/hello/ready"}})
+	if err := inst.PostInstall("hello"); err != nil {
+		t.Fatalf("PostInstall() error = %v", err)
+	}
+
+	if len(runs) != 2 || runs[0].step != "mkdir -p var/hello" || runs[1].step != "touch var/hello/ready" {
+		t.Fatalf("Ran %+v, want both recorded steps in order", runs)
+	}
+	for _, r := range runs {
+		if r.dir != kegPath {
+			t.Errorf("Step %q ran in %s, want the keg %s", r.step, r.dir, kegPath)
+		}
+		if !slices.Contains(r.env, "PREFIX="+kegPath) || !slices.Contains(r.env, "HOMEBREW_PREFIX="+cfg.HomebrewPrefix) {
+			t.Errorf("Step %q environment lacks PREFIX and HOMEBREW_PREFIX", r.step)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package installer
+
+import (
+	stderrors "errors"
+	"fmt"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/errors"
+)
+
+// checkPrefixWritable makes sure the prefix and cellar can be written before
+// anything is downloaded, so a misowned prefix fails with advice instead of
+// a raw error half way through extracting
+func (i *Installer) checkPrefixWritable() error {
+	err := i.cfg.CheckPrefixWritable()
+	var notWritable *config.NotWritableError
+	if !stderrors.As(err, &notWritable) {
+		return err
+	}
+
+	brewErr := errors.NewPermissionError("write to prefix", notWritable.Path, err)
+	brewErr.Suggestions = []string{
+		fmt.Sprintf("Take ownership of the prefix: %s", notWritable.Fix()),
+		"Running brew with sudo is not recommended, as it leaves root-owned files behind",
+	}
+	return brewErr
+}
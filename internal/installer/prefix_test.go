@@ -0,0 +1,74 @@
+package installer
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/errors"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestInstallFormulaReadOnlyPrefix(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	logger.Init(false, false, true)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	t.Setenv("HOMEBREW_API_DOMAIN", server.URL)
+
+	prefix := filepath.Join(t.TempDir(), "prefix")
+	if err := os.Mkdir(prefix, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(prefix, 0755) })
+
+	cfg := &config.Config{
+		HomebrewPrefix: prefix,
+		HomebrewCellar: filepath.Join(prefix, "Cellar"),
+		HomebrewCache:  t.TempDir(),
+	}
+	inst := New(cfg, &Options{})
+
+	_, err := inst.InstallFormula("wget")
+	var brewErr *errors.BrewError
+	if !stderrors.As(err, &brewErr) || brewErr.Type != errors.PermissionError {
+		t.Fatalf("Expected a permission error, got %v", err)
+	}
+	if len(brewErr.Suggestions) == 0 {
+		t.Error("Expected suggestions for fixing the prefix ownership")
+	}
+	if requests != 0 {
+		t.Errorf("Nothing should be fetched before the prefix check, got %d requests", requests)
+	}
+}
+
+func TestCheckPrefixWritable(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewPrefix: tempDir,
+		HomebrewCellar: filepath.Join(tempDir, "Cellar", "not-yet-created"),
+	}
+	inst := New(cfg, &Options{})
+
+	if err := inst.checkPrefixWritable(); err != nil {
+		t.Errorf("checkPrefixWritable() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "Cellar")); !os.IsNotExist(err) {
+		t.Error("The check should not create the cellar")
+	}
+	entries, _ := os.ReadDir(tempDir)
+	if len(entries) != 0 {
+		t.Errorf("The write probe should be cleaned up, found %d entries", len(entries))
+	}
+}
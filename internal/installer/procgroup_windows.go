@@ -0,0 +1,19 @@
+//go:build windows
+
+package installer
+
+import (
+	"os/exec"
+	"time"
+)
+
+// setProcessGroup is a no-op; Windows has no POSIX process groups
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills cmd itself; there is no way to ask it to stop first
+func killProcessGroup(cmd *exec.Cmd, grace time.Duration) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,182 @@
+package installer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/utils"
+)
+
+// progressInterval limits how often a download reports its progress
+const progressInterval = 100 * time.Millisecond
+
+// progressManager renders the progress of concurrent downloads. All writes
+// to the terminal go through a single goroutine: on a TTY every active
+// download owns one line that is redrawn in place, elsewhere each download
+// logs a single line once it completes.
+type progressManager struct {
+	out     io.Writer
+	tty     bool
+	updates chan progressUpdate
+	closed  chan struct{}
+
+	// Only touched by the render goroutine
+	lines []*progressTracker
+	drawn int
+}
+
+// progressUpdate is a tracker's state as sent to the render goroutine
+type progressUpdate struct {
+	tracker *progressTracker
+	current int64
+	done    bool
+	failed  bool
+	ack     chan struct{}
+}
+
+// progressTracker reports the progress of one download to its manager
+type progressTracker struct {
+	manager    *progressManager
+	name       string
+	total      int64
+	current    int64
+	lastUpdate time.Time
+	finished   bool
+
+	// Last state rendered, owned by the render goroutine
+	shown       int64
+	shownDone   bool
+	shownFailed bool
+}
+
+// downloadProgress is the manager shared by all downloads in this process
+var downloadProgress = sync.OnceValue(func() *progressManager {
+	return newProgressManager(os.Stdout, utils.IsTerminal(os.Stdout))
+})
+
+// newProgressManager starts a manager rendering to out
+func newProgressManager(out io.Writer, tty bool) *progressManager {
+	m := &progressManager{
+		out:     out,
+		tty:     tty,
+		updates: make(chan progressUpdate),
+		closed:  make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+// Track registers a download of total bytes
+func (m *progressManager) Track(name string, total int64) *progressTracker {
+	return &progressTracker{manager: m, name: name, total: total}
+}
+
+// Close stops the manager once pending updates have been rendered
+func (m *progressManager) Close() {
+	close(m.updates)
+	<-m.closed
+}
+
+func (m *progressManager) run() {
+	defer close(m.closed)
+	for update := range m.updates {
+		m.apply(update)
+		if update.ack != nil {
+			close(update.ack)
+		}
+	}
+}
+
+// apply records an update and redraws the display
+func (m *progressManager) apply(update progressUpdate) {
+	t := update.tracker
+	if !m.isTracked(t) {
+		m.lines = append(m.lines, t)
+	}
+	t.shown = update.current
+	t.shownDone = update.done
+	t.shownFailed = update.failed
+
+	if !m.tty {
+		if update.done {
+			_, _ = fmt.Fprintln(m.out, t.line())
+		}
+		return
+	}
+
+	if m.drawn > 0 {
+		_, _ = fmt.Fprintf(m.out, "\033[%dA", m.drawn)
+	}
+	for _, line := range m.lines {
+		_, _ = fmt.Fprintf(m.out, "\r\033[K%s\n", line.line())
+	}
+	m.drawn = len(m.lines)
+
+	// Once everything has finished, later output starts below the display
+	for _, line := range m.lines {
+		if !line.shownDone {
+			return
+		}
+	}
+	m.lines = nil
+	m.drawn = 0
+}
+
+func (m *progressManager) isTracked(t *progressTracker) bool {
+	for _, line := range m.lines {
+		if line == t {
+			return true
+		}
+	}
+	return false
+}
+
+// line formats the last rendered state of a download
+func (t *progressTracker) line() string {
+	totalMB := float64(t.total) / 1024 / 1024
+	if t.shownFailed {
+		return fmt.Sprintf("    Failed to download %s", t.name)
+	}
+	if t.shownDone {
+		return fmt.Sprintf("    Downloaded %s (%.1f MB) - 100%%", t.name, totalMB)
+	}
+	currentMB := float64(t.shown) / 1024 / 1024
+	percent := float64(t.shown) / float64(t.total) * 100
+	return fmt.Sprintf("    Downloading %s (%.1f/%.1f MB) - %.1f%%", t.name, currentMB, totalMB, percent)
+}
+
+// Add records n more bytes, reporting at most every progressInterval
+func (t *progressTracker) Add(n int64) {
+	if t.finished {
+		return
+	}
+	t.current += n
+	if now := time.Now(); now.Sub(t.lastUpdate) > progressInterval {
+		t.lastUpdate = now
+		t.manager.updates <- progressUpdate{tracker: t, current: t.current}
+	}
+}
+
+// Done marks the download finished and waits until that has been rendered.
+// Calling it more than once has no effect.
+func (t *progressTracker) Done() {
+	t.finish(false)
+}
+
+// Fail marks the download as failed unless it already finished
+func (t *progressTracker) Fail() {
+	t.finish(true)
+}
+
+func (t *progressTracker) finish(failed bool) {
+	if t.finished {
+		return
+	}
+	t.finished = true
+	ack := make(chan struct{})
+	t.manager.updates <- progressUpdate{tracker: t, current: t.current, done: true, failed: failed, ack: ack}
+	<-ack
+}
@@ -0,0 +1,108 @@
+package installer
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// renderTerminal replays output containing the cursor movements the
+// progress manager uses and returns the resulting screen lines
+func renderTerminal(t *testing.T, output string) []string {
+	t.Helper()
+
+	escape := regexp.MustCompile(`^\033\[(\d*)([AK])`)
+	screen := []string{""}
+	row := 0
+	for i := 0; i < len(output); {
+		if m := escape.FindStringSubmatch(output[i:]); m != nil {
+			switch m[2] {
+			case "A":
+				n, _ := strconv.Atoi(m[1])
+				row -= n
+				if row < 0 {
+					t.Fatalf("Cursor moved above the first line in %q", output)
+				}
+			case "K":
+				screen[row] = ""
+			}
+			i += len(m[0])
+			continue
+		}
+
+		switch c := output[i]; c {
+		case '\r':
+		case '\n':
+			row++
+			if row == len(screen) {
+				screen = append(screen, "")
+			}
+		default:
+			screen[row] += string(c)
+		}
+		i++
+	}
+	return screen[:len(screen)-1]
+}
+
+func TestProgressManagerConcurrentDownloads(t *testing.T) {
+	var out bytes.Buffer
+	manager := newProgressManager(&out, true)
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"alpha.tar.gz", "beta.tar.gz"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracker := manager.Track(name, 4*1024*1024)
+			for i := 0; i < 4; i++ {
+				tracker.lastUpdate = tracker.lastUpdate.Add(-progressInterval)
+				tracker.Add(1024 * 1024)
+			}
+			tracker.Done()
+		}()
+	}
+	wg.Wait()
+	manager.Close()
+
+	screen := renderTerminal(t, out.String())
+	for _, line := range screen {
+		if strings.Contains(line, "alpha") == strings.Contains(line, "beta") {
+			t.Errorf("Expected each line to show one download, got %q", line)
+		}
+	}
+
+	sort.Strings(screen)
+	want := []string{
+		"    Downloaded alpha.tar.gz (4.0 MB) - 100%",
+		"    Downloaded beta.tar.gz (4.0 MB) - 100%",
+	}
+	if strings.Join(screen, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Final screen = %q, want %q", screen, want)
+	}
+}
+
+func TestProgressManagerPlainOutput(t *testing.T) {
+	var out bytes.Buffer
+	manager := newProgressManager(&out, false)
+
+	tracker := manager.Track("gamma.tar.gz", 2*1024*1024)
+	tracker.Add(1024 * 1024)
+	tracker.Add(1024 * 1024)
+	tracker.Done()
+	tracker.Done()
+
+	failed := manager.Track("delta.tar.gz", 1024)
+	failed.Add(10)
+	failed.Fail()
+	manager.Close()
+
+	want := "    Downloaded gamma.tar.gz (2.0 MB) - 100%\n    Failed to download delta.tar.gz\n"
+	if out.String() != want {
+		t.Errorf("Plain output = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,111 @@
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// TempPrefix starts the name of everything staged in HOMEBREW_TEMP, which
+// may be shared with other programs
+const TempPrefix = "brew-go-"
+
+// StaleTempAge is how old a staging directory must be before it is assumed
+// to be left over from an interrupted or failed build
+const StaleTempAge = 24 * time.Hour
+
+const (
+	// buildLockFile is locked inside a build directory while its build runs
+	buildLockFile = ".brew-go-lock"
+
+	// keepMarkerFile marks a build directory kept with --keep-tmp, which
+	// sweeps must never remove
+	keepMarkerFile = ".brew-go-keep"
+)
+
+// tempPath returns a staging path in HOMEBREW_TEMP
+func (i *Installer) tempPath(name string) string {
+	return filepath.Join(i.cfg.HomebrewTemp, TempPrefix+name)
+}
+
+// newBuildDir creates a staging directory in HOMEBREW_TEMP for one build.
+// Each build gets its own, so a retry never starts from a failed build's
+// leftovers and concurrent builds of the same formula don't collide.
+func (i *Installer) newBuildDir(name string) (string, error) {
+	if err := os.MkdirAll(i.cfg.HomebrewTemp, 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(i.cfg.HomebrewTemp, TempPrefix+name+"-")
+}
+
+// releaseBuildDir removes a build directory once its build is done. Failed
+// builds keep theirs for debugging, as does --keep-tmp, and say where it is.
+func (i *Installer) releaseBuildDir(path string, buildErr error) {
+	switch {
+	case buildErr != nil:
+		logger.Warn("Build files kept for debugging in %s", path)
+	case i.opts.KeepTmp:
+		if err := os.WriteFile(filepath.Join(path, keepMarkerFile), nil, 0644); err != nil {
+			logger.Debug("Failed to mark %s as kept: %v", path, err)
+		}
+		logger.Info("Temporary files kept in %s", path)
+	default:
+		_ = os.RemoveAll(path)
+	}
+}
+
+// StaleTempPaths returns the staging directories and files in tempDir that
+// are older than maxAge, except those kept with --keep-tmp and those a
+// running build has locked
+func StaleTempPaths(tempDir string, maxAge time.Duration) ([]string, error) {
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	var stale []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), TempPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		path := filepath.Join(tempDir, entry.Name())
+		if entry.IsDir() && (isKeptBuildDir(path) || buildDirInUse(path)) {
+			continue
+		}
+		stale = append(stale, path)
+	}
+	return stale, nil
+}
+
+// isKeptBuildDir reports whether dir was kept with --keep-tmp
+func isKeptBuildDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, keepMarkerFile))
+	return err == nil
+}
+
+// SweepTemp removes staging paths in tempDir left behind by builds that
+// failed or were killed more than StaleTempAge ago
+func SweepTemp(tempDir string) {
+	stale, err := StaleTempPaths(tempDir, StaleTempAge)
+	if err != nil {
+		logger.Debug("Failed to scan %s for stale build directories: %v", tempDir, err)
+		return
+	}
+	for _, path := range stale {
+		logger.Debug("Removing stale build directory %s", path)
+		if err := os.RemoveAll(path); err != nil {
+			logger.Debug("Failed to remove %s: %v", path, err)
+		}
+	}
+}
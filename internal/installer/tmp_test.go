@@ -0,0 +1,193 @@
+package installer
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+func TestSweepTemp(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	old := time.Now().Add(-2 * StaleTempAge)
+
+	aged := filepath.Join(tempDir, TempPrefix+"wget-1.21")
+	fresh := filepath.Join(tempDir, TempPrefix+"curl-8.0")
+	foreign := filepath.Join(tempDir, "someone-elses-dir")
+	for _, dir := range []string{aged, fresh, foreign} {
+		if err := os.MkdirAll(filepath.Join(dir, "extracted"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "source.tar.gz"), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range []string{aged, foreign} {
+		if err := os.Chtimes(dir, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SweepTemp(tempDir)
+
+	if _, err := os.Stat(aged); !os.IsNotExist(err) {
+		t.Error("Expected the aged build directory to be swept")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("Expected a build directory in use to be kept: %v", err)
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Errorf("Expected directories brew-go did not create to be kept: %v", err)
+	}
+
+	// A missing temp directory is not an error
+	SweepTemp(filepath.Join(tempDir, "missing"))
+}
+
+func TestSweepTempSkipsLockedAndKeptDirs(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	old := time.Now().Add(-2 * StaleTempAge)
+
+	// A long build started over a day ago by another brew process
+	building := filepath.Join(tempDir, TempPrefix+"llvm-17-123")
+	kept := filepath.Join(tempDir, TempPrefix+"wget-1.21-456")
+	for _, dir := range []string{building, kept} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unlock, err := lockBuildDir(building)
+	if err != nil {
+		t.Fatalf("lockBuildDir() error = %v", err)
+	}
+	inst := New(&config.Config{HomebrewTemp: tempDir}, &Options{KeepTmp: true})
+	inst.releaseBuildDir(kept, nil)
+	for _, dir := range []string{building, kept} {
+		if err := os.Chtimes(dir, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SweepTemp(tempDir)
+
+	if _, err := os.Stat(building); err != nil {
+		t.Errorf("Expected a build directory in use to be kept: %v", err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("Expected a --keep-tmp directory to be kept: %v", err)
+	}
+
+	// Once the build finishes or dies, its directory can be swept
+	unlock()
+	SweepTemp(tempDir)
+	if _, err := os.Stat(building); !os.IsNotExist(err) {
+		t.Error("Expected an unlocked aged build directory to be swept")
+	}
+}
+
+func TestNewBuildDirIsUniquePerBuild(t *testing.T) {
+	inst := New(&config.Config{HomebrewTemp: filepath.Join(t.TempDir(), "tmp")}, &Options{})
+
+	// A failed build's directory is kept; the retry must not build in it
+	first, err := inst.newBuildDir("hello-1.0.0")
+	if err != nil {
+		t.Fatalf("newBuildDir() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(first, "leftover"), []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := inst.newBuildDir("hello-1.0.0")
+	if err != nil {
+		t.Fatalf("newBuildDir() error = %v", err)
+	}
+	if first == second {
+		t.Fatalf("newBuildDir() returned %s twice", first)
+	}
+	if !strings.HasPrefix(filepath.Base(second), TempPrefix+"hello-1.0.0-") {
+		t.Errorf("newBuildDir() = %s, want it named after the build", second)
+	}
+	if entries, _ := os.ReadDir(second); len(entries) != 0 {
+		t.Errorf("Expected a fresh build directory, found %d entries", len(entries))
+	}
+}
+
+// captureOutput runs fn and returns what the logger printed to stdout and stderr
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStdout, oldStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	logger.Init(false, false, false)
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+
+	fn()
+
+	os.Stdout, os.Stderr = oldStdout, oldStderr
+	_ = w.Close()
+	logger.Init(false, false, true)
+	return string(<-done)
+}
+
+func TestBuildDirReportedWhenKept(t *testing.T) {
+	logger.Init(false, false, true)
+
+	goodSHA := helloScriptSHA256()
+
+	tests := []struct {
+		name     string
+		keepTmp  bool
+		sha      string
+		wantKept bool
+		wantErr  bool
+	}{
+		{"removed after success", false, goodSHA, false, false},
+		{"kept with keep-tmp", true, goodSHA, true, false},
+		{"kept after failure", false, strings.Repeat("0", 64), true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newLocalFormulaConfig(t)
+			formulaPath := writeLocalFormula(t, cfg, "hello", "name: hello\nversion: 1.0.0\nbinary: hello\nbottle: unneeded\nurl: hello-1.0.sh\nsha256: "+tt.sha+"\n",
+				map[string]string{"hello-1.0.sh": helloScript})
+
+			var err error
+			output := captureOutput(t, func() {
+				_, err = New(cfg, &Options{KeepTmp: tt.keepTmp}).InstallFormula(formulaPath)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InstallFormula() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			kept, err := filepath.Glob(filepath.Join(cfg.HomebrewTemp, TempPrefix+"hello-1.0.0-*"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(kept) > 0) != tt.wantKept {
+				t.Fatalf("Build directories kept = %v, want kept %v", kept, tt.wantKept)
+			}
+			if tt.wantKept && !strings.Contains(output, kept[0]) {
+				t.Errorf("Expected kept build directory %s to be printed; output:\n%s", kept[0], output)
+			}
+		})
+	}
+}
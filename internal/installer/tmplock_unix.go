@@ -0,0 +1,39 @@
+//go:build !windows
+
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lockBuildDir holds a lock on dir until the returned function is called,
+// so that sweeps by other brew processes leave it alone. The kernel drops
+// the lock if the process dies, which makes the directory sweepable again.
+func lockBuildDir(dir string) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(dir, buildLockFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return func() { _ = f.Close() }, nil
+}
+
+// buildDirInUse reports whether a running build holds dir's lock
+func buildDirInUse(dir string) bool {
+	f, err := os.Open(filepath.Join(dir, buildLockFile))
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		return err == syscall.EWOULDBLOCK
+	}
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return false
+}
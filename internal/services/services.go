@@ -0,0 +1,528 @@
+package services
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/api"
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+	"github.com/pilshchikov/homebrew-go/internal/installer"
+)
+
+// Controller starts and stops services through the platform service manager
+type Controller interface {
+	Start(name string) error
+	Run(name string) error
+	Stop(name string) error
+	IsRunning(name string) bool
+}
+
+// Status represents the state of a managed service
+type Status string
+
+const (
+	StatusStarted Status = "started"
+	StatusStopped Status = "stopped"
+	StatusError   Status = "error"
+	StatusNone    Status = "none"
+)
+
+// Service describes a formula service and its current state
+type Service struct {
+	Name     string `json:"name"`
+	Status   Status `json:"status"`
+	User     string `json:"user,omitempty"`
+	File     string `json:"file,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+}
+
+// Manager queries the platform service manager for formula services
+type Manager struct {
+	cfg *config.Config
+
+	// resolve looks up formulae whose kegs don't record their service
+	resolve func(name string) (*formula.Formula, error)
+}
+
+// NewManager creates a new service manager
+func NewManager(cfg *config.Config) *Manager {
+	return &Manager{
+		cfg: cfg,
+		resolve: func(name string) (*formula.Formula, error) {
+			return api.NewClient(cfg).GetFormula(name)
+		},
+	}
+}
+
+// Label returns the service label used for a formula
+func Label(name string) string {
+	return "homebrew.mxcl." + name
+}
+
+// ServiceFileName returns the platform service file name for a formula
+func ServiceFileName(name string) string {
+	if runtime.GOOS == "darwin" {
+		return Label(name) + ".plist"
+	}
+	return "homebrew." + name + ".service"
+}
+
+// ServiceFilePath returns where the service file is registered for the user
+func (m *Manager) ServiceFilePath(name string) string {
+	home, _ := os.UserHomeDir()
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, "Library", "LaunchAgents", ServiceFileName(name))
+	}
+	return filepath.Join(home, ".config", "systemd", "user", ServiceFileName(name))
+}
+
+// RunFilePath returns where the service file of a service started with
+// `brew services run` is written, outside the directories loaded at login
+func (m *Manager) RunFilePath(name string) string {
+	return filepath.Join(m.cfg.HomebrewPrefix, "var", "homebrew", "services", ServiceFileName(name))
+}
+
+// List returns all installed formulae that provide a service. Only services
+// recorded in install receipts or already registered are listed, so that
+// listing never has to resolve every installed formula.
+func (m *Manager) List() ([]*Service, error) {
+	entries, err := os.ReadDir(m.cfg.HomebrewCellar)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cellar: %w", err)
+	}
+
+	var services []*Service
+	for _, entry := range entries {
+		if !entry.IsDir() || !m.hasServiceDefinition(entry.Name()) {
+			continue
+		}
+		services = append(services, m.Get(entry.Name()))
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	return services, nil
+}
+
+// Get returns the current state of a single formula service
+func (m *Manager) Get(name string) *Service {
+	svc := &Service{Name: name, Status: StatusNone}
+
+	file := m.ServiceFilePath(name)
+	if _, err := os.Stat(file); err != nil {
+		return svc
+	}
+
+	svc.File = file
+	svc.Status, svc.ExitCode = m.queryStatus(name)
+	if svc.Status == StatusStarted || svc.Status == StatusError {
+		if u, err := user.Current(); err == nil {
+			svc.User = u.Username
+		}
+	}
+
+	return svc
+}
+
+// hasServiceDefinition reports whether the newest keg records a service or
+// one is already registered
+func (m *Manager) hasServiceDefinition(name string) bool {
+	if kegService(m.newestKeg(name)) != nil {
+		return true
+	}
+	_, err := os.Stat(m.ServiceFilePath(name))
+	return err == nil
+}
+
+func (m *Manager) queryStatus(name string) (Status, int) {
+	if runtime.GOOS == "darwin" {
+		output, err := exec.Command("launchctl", "print", m.domain()+"/"+Label(name)).Output()
+		if err != nil {
+			// Service file exists but is not loaded
+			return StatusStopped, 0
+		}
+		return ParseLaunchctlPrint(string(output))
+	}
+
+	// is-active exits non-zero for inactive units, so only the output matters
+	output, _ := exec.Command("systemctl", "--user", "is-active", "homebrew."+name).Output()
+	return ParseSystemctlIsActive(string(output)), 0
+}
+
+// Start registers a service to launch at login and starts it
+func (m *Manager) Start(name string) error {
+	target := m.ServiceFilePath(name)
+	if err := m.writeServiceFile(name, target); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "darwin" {
+		return runServiceCommand("launchctl", "bootstrap", m.domain(), target)
+	}
+
+	if err := runServiceCommand("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	return runServiceCommand("systemctl", "--user", "enable", "--now", "homebrew."+name)
+}
+
+// Run starts a service once without registering it to launch at login
+func (m *Manager) Run(name string) error {
+	source := m.RunFilePath(name)
+	if err := m.writeServiceFile(name, source); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "darwin" {
+		return runServiceCommand("launchctl", "bootstrap", m.domain(), source)
+	}
+
+	if err := runServiceCommand("systemctl", "--user", "link", source); err != nil {
+		return err
+	}
+	return runServiceCommand("systemctl", "--user", "start", "homebrew."+name)
+}
+
+// Stop stops a service and unregisters it from launching at login
+func (m *Manager) Stop(name string) error {
+	if runtime.GOOS == "darwin" {
+		if err := runServiceCommand("launchctl", "bootout", m.domain()+"/"+Label(name)); err != nil {
+			return err
+		}
+	} else if err := runServiceCommand("systemctl", "--user", "disable", "--now", "homebrew."+name); err != nil {
+		return err
+	}
+
+	if err := os.Remove(m.ServiceFilePath(name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove service file: %w", err)
+	}
+	return nil
+}
+
+// IsRunning reports whether the service is currently running
+func (m *Manager) IsRunning(name string) bool {
+	status, _ := m.queryStatus(name)
+	return status == StatusStarted
+}
+
+// Restart stops a service, waits for it to exit, then starts it again
+func Restart(c Controller, name string, timeout time.Duration) error {
+	if c.IsRunning(name) {
+		if err := c.Stop(name); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", name, err)
+		}
+
+		deadline := time.Now().Add(timeout)
+		for c.IsRunning(name) {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("timed out waiting for %s to stop", name)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	if err := c.Start(name); err != nil {
+		return fmt.Errorf("failed to start %s: %w", name, err)
+	}
+	return nil
+}
+
+// definition returns the service a formula defines: the one recorded in the
+// receipt of its newest keg, or else the formula's own
+func (m *Manager) definition(name string) (*formula.Service, error) {
+	keg := m.newestKeg(name)
+	if keg == "" {
+		return nil, fmt.Errorf("formula %s is not installed", name)
+	}
+	if svc := kegService(keg); svc != nil {
+		return svc, nil
+	}
+
+	f, err := m.resolve(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve formula %s: %w", name, err)
+	}
+	if f.Service == nil || len(f.Service.Run) == 0 {
+		return nil, fmt.Errorf("formula %s does not define a service", name)
+	}
+	return f.Service, nil
+}
+
+// writeServiceFile generates the platform service file of a formula at path
+func (m *Manager) writeServiceFile(name, path string) error {
+	svc, err := m.definition(name)
+	if err != nil {
+		return err
+	}
+
+	var data string
+	if runtime.GOOS == "darwin" {
+		data = launchdPlist(name, m.expand(svc))
+	} else {
+		data = systemdUnit(name, m.expand(svc))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create service directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		return fmt.Errorf("failed to install service file: %w", err)
+	}
+	return nil
+}
+
+// newestKeg returns the path of the highest installed version of a formula
+func (m *Manager) newestKeg(name string) string {
+	formulaPath := filepath.Join(m.cfg.HomebrewCellar, name)
+	entries, err := os.ReadDir(formulaPath)
+	if err != nil {
+		return ""
+	}
+
+	var newest *formula.Formula
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		candidate := &formula.Formula{Version: entry.Name()}
+		if newest == nil || candidate.IsNewer(newest) {
+			newest = candidate
+		}
+	}
+
+	if newest == nil {
+		return ""
+	}
+	return filepath.Join(formulaPath, newest.Version)
+}
+
+// kegService reads the service recorded in a keg's install receipt
+func kegService(keg string) *formula.Service {
+	if keg == "" {
+		return nil
+	}
+	receipt, err := installer.ReadReceipt(keg)
+	if err != nil || receipt.Service == nil || len(receipt.Service.Run) == 0 {
+		return nil
+	}
+	return receipt.Service
+}
+
+// expand returns a copy of svc with $HOMEBREW_PREFIX replaced by the prefix
+func (m *Manager) expand(svc *formula.Service) *formula.Service {
+	replace := func(value string) string {
+		return strings.ReplaceAll(value, "$HOMEBREW_PREFIX", m.cfg.HomebrewPrefix)
+	}
+
+	expanded := *svc
+	expanded.Run = make([]string, len(svc.Run))
+	for i, arg := range svc.Run {
+		expanded.Run[i] = replace(arg)
+	}
+	expanded.WorkingDir = replace(svc.WorkingDir)
+	expanded.LogPath = replace(svc.LogPath)
+	expanded.ErrorLogPath = replace(svc.ErrorLogPath)
+	if svc.Environment != nil {
+		expanded.Environment = make(map[string]string, len(svc.Environment))
+		for key, value := range svc.Environment {
+			expanded.Environment[key] = replace(value)
+		}
+	}
+	return &expanded
+}
+
+// launchdPlist renders a service as a launchd property list
+func launchdPlist(name string, svc *formula.Service) string {
+	var b strings.Builder
+	str := func(value string) {
+		b.WriteString("\t<string>")
+		_ = xml.EscapeText(&b, []byte(value))
+		b.WriteString("</string>\n")
+	}
+	key := func(k string) {
+		fmt.Fprintf(&b, "\t<key>%s</key>\n", k)
+	}
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	key("Label")
+	str(Label(name))
+	key("ProgramArguments")
+	b.WriteString("\t<array>\n")
+	for _, arg := range svc.Run {
+		b.WriteString("\t")
+		str(arg)
+	}
+	b.WriteString("\t</array>\n")
+	if svc.RunType == "" || svc.RunType == "immediate" {
+		key("RunAtLoad")
+		b.WriteString("\t<true/>\n")
+	}
+	if svc.KeepAlive {
+		key("KeepAlive")
+		b.WriteString("\t<true/>\n")
+	}
+	if svc.WorkingDir != "" {
+		key("WorkingDirectory")
+		str(svc.WorkingDir)
+	}
+	if svc.LogPath != "" {
+		key("StandardOutPath")
+		str(svc.LogPath)
+	}
+	if svc.ErrorLogPath != "" {
+		key("StandardErrorPath")
+		str(svc.ErrorLogPath)
+	}
+	if len(svc.Environment) > 0 {
+		key("EnvironmentVariables")
+		b.WriteString("\t<dict>\n")
+		for _, k := range sortedKeys(svc.Environment) {
+			b.WriteString("\t")
+			key(k)
+			b.WriteString("\t")
+			str(svc.Environment[k])
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	b.WriteString("</dict>\n</plist>\n")
+
+	return b.String()
+}
+
+// systemdUnit renders a service as a systemd user unit
+func systemdUnit(name string, svc *formula.Service) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[Unit]\nDescription=Homebrew generated unit for %s\n\n", name)
+	b.WriteString("[Install]\nWantedBy=default.target\n\n")
+	b.WriteString("[Service]\nType=simple\n")
+
+	args := make([]string, len(svc.Run))
+	for i, arg := range svc.Run {
+		args[i] = systemdQuote(arg)
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(args, " "))
+	if svc.KeepAlive {
+		b.WriteString("Restart=always\n")
+	}
+	if svc.WorkingDir != "" {
+		fmt.Fprintf(&b, "WorkingDirectory=%s\n", svc.WorkingDir)
+	}
+	if svc.LogPath != "" {
+		fmt.Fprintf(&b, "StandardOutput=append:%s\n", svc.LogPath)
+	}
+	if svc.ErrorLogPath != "" {
+		fmt.Fprintf(&b, "StandardError=append:%s\n", svc.ErrorLogPath)
+	}
+	for _, k := range sortedKeys(svc.Environment) {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(k+"="+svc.Environment[k]))
+	}
+
+	return b.String()
+}
+
+// systemdQuote quotes a unit file word that contains spaces or quotes and
+// escapes % so it isn't read as a specifier
+func systemdQuote(value string) string {
+	value = strings.ReplaceAll(value, "%", "%%")
+	if value != "" && !strings.ContainsAny(value, " \t\"'\\") {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+// sortedKeys returns the keys of m in order, so generated files are stable
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *Manager) domain() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+func runServiceCommand(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ParseLaunchctlPrint extracts the service status from `launchctl print` output
+func ParseLaunchctlPrint(output string) (Status, int) {
+	var (
+		state    string
+		hasPID   bool
+		exitCode int
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "state":
+			state = value
+		case "pid":
+			hasPID = true
+		case "last exit code":
+			// Values look like "1", "78: EX_CONFIG" or "(never exited)"
+			raw, _, _ := strings.Cut(value, ":")
+			if code, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil {
+				exitCode = code
+			}
+		}
+	}
+
+	if state == "running" || hasPID {
+		return StatusStarted, 0
+	}
+	if exitCode != 0 {
+		return StatusError, exitCode
+	}
+	return StatusStopped, 0
+}
+
+// ParseSystemctlIsActive maps `systemctl is-active` output to a service status
+func ParseSystemctlIsActive(output string) Status {
+	switch strings.TrimSpace(output) {
+	case "active", "activating", "reloading":
+		return StatusStarted
+	case "failed":
+		return StatusError
+	default:
+		return StatusStopped
+	}
+}
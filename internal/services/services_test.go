@@ -0,0 +1,353 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/config"
+	"github.com/pilshchikov/homebrew-go/internal/formula"
+)
+
+func TestParseLaunchctlPrint(t *testing.T) {
+	tests := []struct {
+		name         string
+		output       string
+		expected     Status
+		expectedCode int
+	}{
+		{
+			name: "running service",
+			output: `gui/501/homebrew.mxcl.postgresql = {
+	active count = 1
+	path = /Users/me/Library/LaunchAgents/homebrew.mxcl.postgresql.plist
+	state = running
+	program = /opt/homebrew/opt/postgresql/bin/postgres
+	pid = 4321
+	last exit code = (never exited)
+}`,
+			expected: StatusStarted,
+		},
+		{
+			name: "loaded but not running",
+			output: `gui/501/homebrew.mxcl.redis = {
+	active count = 0
+	state = not running
+	last exit code = 0
+}`,
+			expected: StatusStopped,
+		},
+		{
+			name: "crashed service",
+			output: `gui/501/homebrew.mxcl.nginx = {
+	state = not running
+	last exit code = 78: EX_CONFIG
+}`,
+			expected:     StatusError,
+			expectedCode: 78,
+		},
+		{
+			name: "failed service",
+			output: `gui/501/homebrew.mxcl.nginx = {
+	state = not running
+	last exit code = 1
+}`,
+			expected:     StatusError,
+			expectedCode: 1,
+		},
+		{
+			name:     "empty output",
+			output:   "",
+			expected: StatusStopped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := ParseLaunchctlPrint(tt.output)
+			if status != tt.expected {
+				t.Errorf("ParseLaunchctlPrint() status = %v, want %v", status, tt.expected)
+			}
+			if code != tt.expectedCode {
+				t.Errorf("ParseLaunchctlPrint() exit code = %v, want %v", code, tt.expectedCode)
+			}
+		})
+	}
+}
+
+func TestParseSystemctlIsActive(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected Status
+	}{
+		{"active\n", StatusStarted},
+		{"activating\n", StatusStarted},
+		{"inactive\n", StatusStopped},
+		{"failed\n", StatusError},
+		{"unknown\n", StatusStopped},
+		{"", StatusStopped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			if status := ParseSystemctlIsActive(tt.output); status != tt.expected {
+				t.Errorf("ParseSystemctlIsActive(%q) = %v, want %v", tt.output, status, tt.expected)
+			}
+		})
+	}
+}
+
+// writeReceipt writes an install receipt recording svc into a keg
+func writeReceipt(t *testing.T, keg string, svc *formula.Service) {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]interface{}{"name": filepath.Base(filepath.Dir(keg)), "service": svc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(keg, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(keg, "INSTALL_RECEIPT.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListServices(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+
+	cfg := &config.Config{HomebrewCellar: filepath.Join(tmpDir, "Cellar")}
+	manager := NewManager(cfg)
+
+	// Formula whose receipt records a service definition
+	writeReceipt(t, filepath.Join(cfg.HomebrewCellar, "redis", "7.2.0"), &formula.Service{Run: []string{"redis-server"}})
+
+	// Formula without a service definition
+	writeReceipt(t, filepath.Join(cfg.HomebrewCellar, "wget", "1.21"), nil)
+
+	list, err := manager.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	if len(list) != 1 {
+		t.Fatalf("List() returned %d services, want 1", len(list))
+	}
+	if list[0].Name != "redis" {
+		t.Errorf("List()[0].Name = %v, want redis", list[0].Name)
+	}
+	if list[0].Status != StatusNone {
+		t.Errorf("List()[0].Status = %v, want %v", list[0].Status, StatusNone)
+	}
+}
+
+func TestServiceDefinition(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{HomebrewCellar: filepath.Join(tmpDir, "Cellar")}
+
+	recorded := &formula.Service{Run: []string{"recorded"}}
+	resolved := &formula.Service{Run: []string{"resolved"}}
+	var resolves []string
+	manager := NewManager(cfg)
+	manager.resolve = func(name string) (*formula.Formula, error) {
+		resolves = append(resolves, name)
+		if name == "wget" {
+			return &formula.Formula{Name: name}, nil
+		}
+		return &formula.Formula{Name: name, Service: resolved}, nil
+	}
+
+	// The newest keg's receipt wins over older kegs and the formula
+	writeReceipt(t, filepath.Join(cfg.HomebrewCellar, "redis", "7.9.0"), nil)
+	writeReceipt(t, filepath.Join(cfg.HomebrewCellar, "redis", "7.10.0"), recorded)
+	writeReceipt(t, filepath.Join(cfg.HomebrewCellar, "postgresql", "16.0"), nil)
+	writeReceipt(t, filepath.Join(cfg.HomebrewCellar, "wget", "1.21"), nil)
+
+	if svc, err := manager.definition("redis"); err != nil || svc.Run[0] != "recorded" {
+		t.Errorf("definition(redis) = %v, %v, want the recorded service", svc, err)
+	}
+	if len(resolves) != 0 {
+		t.Errorf("Expected a recorded service not to resolve the formula, resolved %v", resolves)
+	}
+
+	// Kegs installed before receipts recorded services use the formula's
+	if svc, err := manager.definition("postgresql"); err != nil || svc.Run[0] != "resolved" {
+		t.Errorf("definition(postgresql) = %v, %v, want the formula's service", svc, err)
+	}
+
+	if _, err := manager.definition("wget"); err == nil || !strings.Contains(err.Error(), "does not define a service") {
+		t.Errorf("definition(wget) error = %v, want no service", err)
+	}
+	if _, err := manager.definition("missing"); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("definition(missing) error = %v, want not installed", err)
+	}
+}
+
+func TestWriteServiceFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewPrefix: filepath.Join(tmpDir, "prefix"),
+		HomebrewCellar: filepath.Join(tmpDir, "prefix", "Cellar"),
+	}
+	manager := NewManager(cfg)
+	writeReceipt(t, filepath.Join(cfg.HomebrewCellar, "redis", "7.2.0"), &formula.Service{
+		Run:       []string{"$HOMEBREW_PREFIX/opt/redis/bin/redis-server", "$HOMEBREW_PREFIX/etc/redis.conf"},
+		KeepAlive: true,
+	})
+
+	path := manager.RunFilePath("redis")
+	if err := manager.writeServiceFile("redis", path); err != nil {
+		t.Fatalf("writeServiceFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := filepath.Join(cfg.HomebrewPrefix, "opt", "redis", "bin", "redis-server")
+	if !strings.Contains(string(data), server) {
+		t.Errorf("Expected the generated service to run %s, got:\n%s", server, data)
+	}
+	if strings.Contains(string(data), "$HOMEBREW_PREFIX") {
+		t.Errorf("Expected $HOMEBREW_PREFIX to be expanded, got:\n%s", data)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	plist := launchdPlist("redis", &formula.Service{
+		Run:          []string{"/opt/homebrew/opt/redis/bin/redis-server", "/opt/homebrew/etc/redis.conf"},
+		KeepAlive:    true,
+		WorkingDir:   "/opt/homebrew/var",
+		LogPath:      "/opt/homebrew/var/log/redis.log",
+		ErrorLogPath: "/opt/homebrew/var/log/redis.log",
+		Environment:  map[string]string{"LANG": "en_US.UTF-8", "REDIS_MODE": "a&b"},
+	})
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>homebrew.mxcl.redis</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>/opt/homebrew/opt/redis/bin/redis-server</string>
+		<string>/opt/homebrew/etc/redis.conf</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>WorkingDirectory</key>
+	<string>/opt/homebrew/var</string>
+	<key>StandardOutPath</key>
+	<string>/opt/homebrew/var/log/redis.log</string>
+	<key>StandardErrorPath</key>
+	<string>/opt/homebrew/var/log/redis.log</string>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>LANG</key>
+		<string>en_US.UTF-8</string>
+		<key>REDIS_MODE</key>
+		<string>a&amp;b</string>
+	</dict>
+</dict>
+</plist>
+`
+	if plist != want {
+		t.Errorf("launchdPlist() =\n%s\nwant:\n%s", plist, want)
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	unit := systemdUnit("redis", &formula.Service{
+		Run:         []string{"/home/linuxbrew/.linuxbrew/opt/redis/bin/redis-server", "--save 60 1"},
+		KeepAlive:   true,
+		LogPath:     "/home/linuxbrew/.linuxbrew/var/log/redis.log",
+		Environment: map[string]string{"PROMPT": "100%"},
+	})
+
+	want := `[Unit]
+Description=Homebrew generated unit for redis
+
+[Install]
+WantedBy=default.target
+
+[Service]
+Type=simple
+ExecStart=/home/linuxbrew/.linuxbrew/opt/redis/bin/redis-server "--save 60 1"
+Restart=always
+StandardOutput=append:/home/linuxbrew/.linuxbrew/var/log/redis.log
+Environment=PROMPT=100%%
+`
+	if unit != want {
+		t.Errorf("systemdUnit() =\n%s\nwant:\n%s", unit, want)
+	}
+}
+
+// slowStopController keeps reporting running for a few polls after Stop
+type slowStopController struct {
+	events       []string
+	pollsToStop  int
+	stopped      bool
+	startedEarly bool
+}
+
+func (c *slowStopController) Start(name string) error {
+	if !c.stopped || c.pollsToStop > 0 {
+		c.startedEarly = true
+	}
+	c.events = append(c.events, "start")
+	return nil
+}
+
+func (c *slowStopController) Run(name string) error { return nil }
+
+func (c *slowStopController) Stop(name string) error {
+	c.stopped = true
+	c.events = append(c.events, "stop")
+	return nil
+}
+
+func (c *slowStopController) IsRunning(name string) bool {
+	if !c.stopped {
+		return true
+	}
+	if c.pollsToStop > 0 {
+		c.pollsToStop--
+		return true
+	}
+	return false
+}
+
+func TestRestartWaitsForStop(t *testing.T) {
+	c := &slowStopController{pollsToStop: 2}
+
+	if err := Restart(c, "redis", time.Second); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+
+	if c.startedEarly {
+		t.Error("Restart() started the service before it finished stopping")
+	}
+	if len(c.events) != 2 || c.events[0] != "stop" || c.events[1] != "start" {
+		t.Errorf("Restart() events = %v, want [stop start]", c.events)
+	}
+}
+
+func TestRestartStopTimeout(t *testing.T) {
+	c := &slowStopController{pollsToStop: 1000}
+
+	if err := Restart(c, "redis", 150*time.Millisecond); err == nil {
+		t.Error("Restart() expected timeout error")
+	}
+	for _, event := range c.events {
+		if event == "start" {
+			t.Error("Restart() started the service after stop timed out")
+		}
+	}
+}
@@ -1,19 +1,29 @@
 package tap
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"math"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/formula"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
+	"github.com/pilshchikov/homebrew-go/internal/utils"
 )
 
 // Tap represents a Homebrew tap (third-party repository)
@@ -35,21 +45,140 @@ type Manager struct {
 	cfg *config.Config
 }
 
-// ProgressWriter implements io.Writer for git progress reporting
+// cloneRetryDelay is the initial backoff between clone attempts
+var cloneRetryDelay = 2 * time.Second
+
+// githubAPIURL is the GitHub REST API endpoint used to probe remotes before
+// cloning, replaced in tests
+var githubAPIURL = "https://api.github.com"
+
+// ErrNotTap is returned when a repository was cloned but holds neither a
+// Formula nor a Casks directory
+var ErrNotTap = errors.New("not a valid tap")
+
+// gitProgressInterval limits how often a git operation reports its progress
+const gitProgressInterval = 100 * time.Millisecond
+
+// gitProgressPattern matches sideband progress such as
+// "Receiving objects:  45% (450/1000), 1.20 MiB | 2.00 MiB/s"
+var gitProgressPattern = regexp.MustCompile(`^([A-Za-z ]+):\s+\d+% \((\d+)/(\d+)\)`)
+
+// ProgressWriter implements io.Writer for git progress reporting. Object
+// counts sent by the remote are redrawn in place on a single terminal line,
+// or printed once per completed phase when out isn't a terminal; other
+// messages only show up in debug output.
 type ProgressWriter struct {
 	prefix string
+
+	// out receives progress lines; tty redraws them in place
+	out io.Writer
+	tty bool
+
+	phase      string
+	percent    int
+	lastUpdate time.Time
+	drawn      bool
+}
+
+// newProgressWriter returns a writer reporting git progress on stdout
+func newProgressWriter(prefix string) *ProgressWriter {
+	if logger.IsQuiet() {
+		return &ProgressWriter{prefix: prefix, out: io.Discard}
+	}
+	return &ProgressWriter{prefix: prefix, out: os.Stdout, tty: utils.IsTerminal(os.Stdout)}
+}
+
+// gitProgress is one parsed sideband progress line
+type gitProgress struct {
+	Phase   string
+	Current int
+	Total   int
+}
+
+// Percent returns how much of the phase has completed
+func (p gitProgress) Percent() int {
+	if p.Total <= 0 {
+		return 100
+	}
+	return p.Current * 100 / p.Total
+}
+
+// parseGitProgress extracts the object counts from a git progress line
+func parseGitProgress(line string) (gitProgress, bool) {
+	match := gitProgressPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return gitProgress{}, false
+	}
+	current, err := strconv.Atoi(match[2])
+	if err != nil {
+		return gitProgress{}, false
+	}
+	total, err := strconv.Atoi(match[3])
+	if err != nil {
+		return gitProgress{}, false
+	}
+	return gitProgress{Phase: strings.TrimSpace(match[1]), Current: current, Total: total}, true
 }
 
 // Write implements io.Writer interface for progress reporting
 func (pw *ProgressWriter) Write(p []byte) (n int, err error) {
-	message := string(p)
-	// Clean up git progress messages and log them
-	if strings.TrimSpace(message) != "" {
-		logger.Debug("%s: %s", pw.prefix, strings.TrimSpace(message))
+	// Git redraws progress in place with carriage returns, so one write can
+	// hold several updates
+	for _, line := range strings.FieldsFunc(string(p), func(r rune) bool {
+		return r == '\r' || r == '\n'
+	}) {
+		pw.writeLine(strings.TrimSpace(line))
 	}
 	return len(p), nil
 }
 
+func (pw *ProgressWriter) writeLine(line string) {
+	if line == "" {
+		return
+	}
+
+	progress, ok := parseGitProgress(line)
+	if !ok {
+		logger.Debug("%s: %s", pw.prefix, line)
+		return
+	}
+
+	percent := progress.Percent()
+	now := time.Now()
+	changed := progress.Phase != pw.phase || percent != pw.percent
+	if !changed {
+		return
+	}
+	if progress.Phase == pw.phase && percent < 100 && now.Sub(pw.lastUpdate) < gitProgressInterval {
+		return
+	}
+
+	pw.phase = progress.Phase
+	pw.percent = percent
+	pw.lastUpdate = now
+
+	text := fmt.Sprintf("%s: %s %d%% (%d/%d)", pw.prefix, progress.Phase, percent, progress.Current, progress.Total)
+	if pw.out == nil {
+		return
+	}
+	if pw.tty {
+		_, _ = fmt.Fprintf(pw.out, "\r\033[K%s", text)
+		pw.drawn = true
+	} else if percent == 100 {
+		_, _ = fmt.Fprintln(pw.out, text)
+	}
+}
+
+// Finish ends the redrawn progress line so later output starts on its own
+func (pw *ProgressWriter) Finish() {
+	if pw.drawn {
+		_, _ = fmt.Fprintln(pw.out)
+		pw.drawn = false
+	}
+	pw.phase = ""
+	pw.percent = 0
+}
+
 // NewManager creates a new tap manager
 func NewManager(cfg *config.Config) *Manager {
 	return &Manager{cfg: cfg}
@@ -60,6 +189,9 @@ func (m *Manager) ListTaps() ([]*Tap, error) {
 	tapsDir := filepath.Join(m.cfg.HomebrewRepository, "Library", "Taps")
 
 	var taps []*Tap
+	if _, err := os.Stat(tapsDir); os.IsNotExist(err) {
+		return taps, nil
+	}
 
 	err := filepath.WalkDir(tapsDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -135,7 +267,11 @@ func (m *Manager) AddTap(name, remote string, options *TapOptions) error {
 	// Determine remote URL
 	if remote == "" {
 		remote = m.getDefaultRemote(name)
+		if options.SSH || m.cfg.GitUseSSH {
+			remote = toSSHRemote(remote)
+		}
 	}
+	remote = m.rewriteRemote(remote)
 
 	tapPath := m.getTapPath(name)
 
@@ -144,24 +280,21 @@ func (m *Manager) AddTap(name, remote string, options *TapOptions) error {
 		return fmt.Errorf("failed to create tap directory: %w", err)
 	}
 
-	// Clone the repository
-	logger.Step("Cloning %s", remote)
-	progressWriter := &ProgressWriter{prefix: fmt.Sprintf("Clone %s", name)}
-	cloneOptions := &git.CloneOptions{
-		URL:      remote,
-		Progress: progressWriter,
-	}
-
-	if options.Shallow {
-		cloneOptions.Depth = 1
+	// Catch missing GitHub repositories before spending a clone on them
+	if err := m.probeRemote(remote); err != nil {
+		if len(options.Mirrors) == 0 {
+			return fmt.Errorf("failed to clone tap: %w", err)
+		}
+		logger.Warn("%v, trying mirrors", err)
 	}
 
-	if options.Branch != "" {
-		cloneOptions.ReferenceName = plumbing.ReferenceName("refs/heads/" + options.Branch)
-		cloneOptions.SingleBranch = true
+	// Clone the repository, falling back to mirrors in order
+	remotes := []string{remote}
+	for _, mirror := range options.Mirrors {
+		remotes = append(remotes, m.rewriteRemote(mirror))
 	}
 
-	repo, err := git.PlainClone(tapPath, false, cloneOptions)
+	repo, err := m.cloneWithRetry(name, tapPath, remotes, options)
 	if err != nil {
 		return fmt.Errorf("failed to clone tap: %w", err)
 	}
@@ -188,6 +321,132 @@ func (m *Manager) AddTap(name, remote string, options *TapOptions) error {
 	return nil
 }
 
+// cloneWithRetry clones the first reachable remote, retrying transient failures with backoff
+func (m *Manager) cloneWithRetry(name, tapPath string, remotes []string, options *TapOptions) (*git.Repository, error) {
+	attempts := m.cfg.GitCloneRetries
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for _, remote := range remotes {
+		delay := cloneRetryDelay
+		for attempt := 1; attempt <= attempts; attempt++ {
+			logger.Step("Cloning %s", remote)
+			progressWriter := newProgressWriter(fmt.Sprintf("Clone %s", name))
+			if attempt > 1 {
+				progressWriter.prefix = fmt.Sprintf("Clone %s (attempt %d/%d)", name, attempt, attempts)
+			}
+
+			cloneOptions := &git.CloneOptions{
+				URL:      remote,
+				Progress: progressWriter,
+			}
+
+			if options.Shallow {
+				cloneOptions.Depth = 1
+			}
+
+			if options.Branch != "" {
+				cloneOptions.ReferenceName = plumbing.ReferenceName("refs/heads/" + options.Branch)
+				cloneOptions.SingleBranch = true
+			}
+
+			repo, err := git.PlainClone(tapPath, false, cloneOptions)
+			progressWriter.Finish()
+			if err == nil {
+				return repo, nil
+			}
+			lastErr = err
+
+			// Remove any partial clone before the next attempt
+			_ = os.RemoveAll(tapPath)
+
+			if !isTransientCloneError(err) {
+				logger.Debug("Clone of %s failed permanently: %v", remote, err)
+				break
+			}
+
+			if attempt < attempts {
+				logger.Warn("Clone of %s failed (attempt %d/%d), retrying in %s: %v", remote, attempt, attempts, delay, err)
+				time.Sleep(delay)
+				delay *= 2
+			}
+		}
+
+		if len(remotes) > 1 {
+			logger.Warn("Failed to clone %s: %v", remote, lastErr)
+		}
+	}
+
+	return nil, lastErr
+}
+
+// probeRemote checks that a GitHub https remote exists with a lightweight
+// API request. Remotes elsewhere and inconclusive probes are let through so
+// the clone itself reports the problem.
+func (m *Manager) probeRemote(remote string) error {
+	owner, repo, ok := githubRemoteRepo(remote)
+	if !ok {
+		return nil
+	}
+
+	if !strings.HasPrefix(repo, "homebrew-") {
+		logger.Warn("%s/%s does not follow the homebrew-<name> tap naming convention", owner, repo)
+	}
+
+	req, err := http.NewRequest("HEAD", fmt.Sprintf("%s/repos/%s/%s", githubAPIURL, owner, repo), http.NoBody)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second, Transport: utils.Transport, CheckRedirect: utils.CheckRedirect}
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.Debug("Could not probe %s: %v", remote, err)
+		return nil
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s/%s on GitHub", transport.ErrRepositoryNotFound, owner, repo)
+	}
+	return nil
+}
+
+// githubRemoteRepo extracts owner and repository from an https GitHub remote.
+// SSH remotes are skipped since they may reach private repositories the
+// anonymous API cannot see.
+func githubRemoteRepo(remote string) (string, string, bool) {
+	path, ok := strings.CutPrefix(remote, "https://github.com/")
+	if !ok {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), true
+}
+
+// isTransientCloneError reports whether retrying the same remote may succeed
+func isTransientCloneError(err error) bool {
+	switch {
+	case errors.Is(err, transport.ErrRepositoryNotFound),
+		errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, transport.ErrInvalidAuthMethod),
+		errors.Is(err, transport.ErrEmptyRemoteRepository):
+		return false
+	}
+	return true
+}
+
 // RemoveTap removes (uninstalls) a tap
 func (m *Manager) RemoveTap(name string, options *TapOptions) error {
 	if options == nil {
@@ -219,11 +478,28 @@ func (m *Manager) RemoveTap(name string, options *TapOptions) error {
 		}
 	}
 
+	// The tap's formula list is needed to tell which downloads it produced
+	cached := m.tapDownloads(tap)
+
 	// Remove the tap directory
 	if err := os.RemoveAll(tap.Path); err != nil {
 		return fmt.Errorf("failed to remove tap directory: %w", err)
 	}
 
+	// Downloads of kegs built from the tap are of no use once it's gone
+	for _, path := range cached {
+		if err := os.Remove(path); err != nil {
+			logger.Warn("Failed to remove cached download %s: %v", path, err)
+		}
+	}
+
+	// Data derived from the tap would otherwise be served if it is re-added
+	if cachePath := m.cachePathForTap(tap.Name); cachePath != "" {
+		if err := os.RemoveAll(cachePath); err != nil {
+			logger.Warn("Failed to remove cached data for %s: %v", name, err)
+		}
+	}
+
 	logger.Success("Untapped %s", name)
 	return nil
 }
@@ -254,11 +530,25 @@ func (m *Manager) UpdateTap(name string) error {
 	}
 
 	// Pull updates
-	progressWriter := &ProgressWriter{prefix: fmt.Sprintf("Update %s", name)}
+	progressWriter := newProgressWriter(fmt.Sprintf("Update %s", name))
 	err = workTree.Pull(&git.PullOptions{
 		RemoteName: "origin",
 		Progress:   progressWriter,
 	})
+	progressWriter.Finish()
+
+	// Shallow clones can fail to pull once history diverges; fetch the full history and retry
+	if err != nil && err != git.NoErrAlreadyUpToDate && m.IsShallow(name) {
+		logger.Info("Tap %s is a shallow clone, fetching full history", name)
+		if unshallowErr := m.Unshallow(name); unshallowErr != nil {
+			return fmt.Errorf("failed to update tap: %w (%v)", err, unshallowErr)
+		}
+		err = workTree.Pull(&git.PullOptions{
+			RemoteName: "origin",
+			Progress:   progressWriter,
+		})
+		progressWriter.Finish()
+	}
 
 	if err == git.NoErrAlreadyUpToDate {
 		logger.Info("Tap %s is already up to date", name)
@@ -271,12 +561,121 @@ func (m *Manager) UpdateTap(name string) error {
 	return nil
 }
 
+// SetTapRemote points an installed tap's origin at a new remote, so later
+// updates pull from there
+func (m *Manager) SetTapRemote(name, remote string) error {
+	if err := m.validateTapName(name); err != nil {
+		return fmt.Errorf("invalid tap name: %w", err)
+	}
+	if err := validateRemoteURL(remote); err != nil {
+		return fmt.Errorf("invalid remote: %w", err)
+	}
+	remote = m.rewriteRemote(remote)
+
+	tap, err := m.GetTap(name)
+	if err != nil || !tap.Installed {
+		return fmt.Errorf("tap %s is not installed", name)
+	}
+
+	repo, err := git.PlainOpen(tap.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open tap repository: %w", err)
+	}
+
+	repoConfig, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read tap repository config: %w", err)
+	}
+
+	origin, ok := repoConfig.Remotes["origin"]
+	if !ok {
+		return fmt.Errorf("tap %s has no origin remote", name)
+	}
+	previous := strings.Join(origin.URLs, ", ")
+	origin.URLs = []string{remote}
+
+	if err := repo.SetConfig(repoConfig); err != nil {
+		return fmt.Errorf("failed to update tap remote: %w", err)
+	}
+
+	logger.Success("Changed %s remote from %s to %s", name, previous, remote)
+	return nil
+}
+
+// validateRemoteURL rejects remotes git could not clone from
+func validateRemoteURL(remote string) error {
+	if remote == "" {
+		return fmt.Errorf("remote cannot be empty")
+	}
+	if strings.ContainsAny(remote, " \t\n") {
+		return fmt.Errorf("remote cannot contain whitespace")
+	}
+	if _, err := transport.NewEndpoint(remote); err != nil {
+		return err
+	}
+	return nil
+}
+
+// IsShallow reports whether the tap was cloned with truncated history
+func (m *Manager) IsShallow(name string) bool {
+	repo, err := git.PlainOpen(m.getTapPath(name))
+	if err != nil {
+		return false
+	}
+
+	shallows, err := repo.Storer.Shallow()
+	return err == nil && len(shallows) > 0
+}
+
+// Unshallow fetches the full history of a shallow tap
+func (m *Manager) Unshallow(name string) error {
+	tapPath := m.getTapPath(name)
+	repo, err := git.PlainOpen(tapPath)
+	if err != nil {
+		return fmt.Errorf("failed to open tap repository: %w", err)
+	}
+
+	if !m.IsShallow(name) {
+		logger.Debug("Tap %s already has full history", name)
+		return nil
+	}
+
+	logger.Step("Fetching full history for %s", name)
+
+	// Equivalent of `git fetch --unshallow`, which deepens by the maximum depth
+	progressWriter := newProgressWriter(fmt.Sprintf("Unshallow %s", name))
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		Depth:      math.MaxInt32,
+		Progress:   progressWriter,
+	})
+	progressWriter.Finish()
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to unshallow tap: %w", err)
+	}
+
+	// go-git only ever adds shallow boundaries, so drop them once history is complete
+	if err := os.Remove(filepath.Join(tapPath, ".git", "shallow")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to update shallow state: %w", err)
+	}
+
+	logger.Success("Fetched full history for %s", name)
+	return nil
+}
+
 // TapOptions contains options for tap operations
 type TapOptions struct {
 	Force   bool
 	Quiet   bool
 	Shallow bool
 	Branch  string
+	SSH     bool
+	Mirrors []string
+}
+
+// TapPath returns where a tap is or would be cloned
+func (m *Manager) TapPath(name string) string {
+	return m.getTapPath(name)
 }
 
 func (m *Manager) getTapPath(name string) string {
@@ -290,6 +689,83 @@ func (m *Manager) getTapPath(name string) string {
 		parts[0], "homebrew-"+parts[1])
 }
 
+// cachePathForTap returns the directory holding cached API data and generated
+// formulae for a tap, or "" when no cache is configured
+func (m *Manager) cachePathForTap(name string) string {
+	if m.cfg.HomebrewCache == "" {
+		return ""
+	}
+
+	parts := strings.Split(strings.ToLower(name), "/")
+	if len(parts) != 2 {
+		parts = []string{"homebrew", strings.ToLower(name)}
+	}
+
+	return filepath.Join(m.cfg.HomebrewCache, "taps", parts[0], parts[1])
+}
+
+// tapDownloads returns the cached downloads of kegs whose install receipts
+// record the tap. A formula of the same name may come from homebrew/core or
+// another tap, so downloads without such a receipt are left alone, as are
+// cask downloads, whose receipts don't record a tap.
+func (m *Manager) tapDownloads(t *Tap) []string {
+	if m.cfg.HomebrewCache == "" {
+		return nil
+	}
+
+	downloadDir := filepath.Join(m.cfg.HomebrewCache, "downloads")
+	entries, err := os.ReadDir(downloadDir)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, name := range listTapEntries(filepath.Join(t.Path, "Formula")) {
+		for version, tap := range m.receiptTaps(name) {
+			if !strings.EqualFold(tap, t.Name) {
+				continue
+			}
+			for _, entry := range entries {
+				if !entry.IsDir() && isKegDownload(entry.Name(), name, version) {
+					paths = append(paths, filepath.Join(downloadDir, entry.Name()))
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// listTapEntries returns the names of the formula files in dir
+func listTapEntries(dir string) []string {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && (strings.HasSuffix(file.Name(), ".rb") || strings.HasSuffix(file.Name(), ".yaml")) {
+			names = append(names, strings.TrimSuffix(file.Name(), filepath.Ext(file.Name())))
+		}
+	}
+	return names
+}
+
+// isKegDownload reports whether file is the source archive or a bottle of
+// name at version, named NAME-VERSION.tar.gz or
+// NAME-VERSION.PLATFORM.bottle.tar.gz like the installer writes them
+func isKegDownload(file, name, version string) bool {
+	rest, ok := strings.CutPrefix(file, name+"-"+version+".")
+	if !ok {
+		return false
+	}
+	if rest == "tar.gz" {
+		return true
+	}
+	platform, ok := strings.CutSuffix(rest, ".bottle.tar.gz")
+	return ok && platform != "" && !strings.Contains(platform, ".")
+}
+
 func (m *Manager) validateTapName(name string) error {
 	// Basic validation for tap names
 	if name == "" {
@@ -311,6 +787,33 @@ func (m *Manager) getDefaultRemote(name string) string {
 	return fmt.Sprintf("https://github.com/homebrew/homebrew-%s.git", name)
 }
 
+// rewriteRemote applies the longest matching HOMEBREW_GIT_REMOTE_REWRITE prefix rule
+func (m *Manager) rewriteRemote(remote string) string {
+	var match string
+	for from := range m.cfg.GitRemoteRewrite {
+		if strings.HasPrefix(remote, from) && len(from) > len(match) {
+			match = from
+		}
+	}
+	if match == "" {
+		return remote
+	}
+	return m.cfg.GitRemoteRewrite[match] + strings.TrimPrefix(remote, match)
+}
+
+// toSSHRemote converts an https remote URL to its SSH form
+func toSSHRemote(remote string) string {
+	rest, ok := strings.CutPrefix(remote, "https://")
+	if !ok {
+		return remote
+	}
+	host, path, ok := strings.Cut(rest, "/")
+	if !ok {
+		return remote
+	}
+	return fmt.Sprintf("git@%s:%s", host, path)
+}
+
 func (m *Manager) isTapDirectory(path string) bool {
 	// Check if directory contains Formula or Casks subdirectories
 	formulaDir := filepath.Join(path, "Formula")
@@ -432,7 +935,7 @@ func (m *Manager) verifyTap(tapPath string) error {
 	}
 
 	if !hasFormulae && !hasCasks {
-		return fmt.Errorf("tap does not contain Formula or Casks directories")
+		return fmt.Errorf("repository was cloned but is %w: no Formula or Casks directory", ErrNotTap)
 	}
 
 	return nil
@@ -463,7 +966,18 @@ func (m *Manager) getInstalledFormulaeFromTap(tap *Tap) ([]string, error) {
 
 // isFormulaFromTap checks if an installed formula is from a specific tap
 func (m *Manager) isFormulaFromTap(formulaName, tapName string) bool {
-	// Use simple heuristic: check if the formula exists in the tap's Formula directory
+	// The install receipt records the tap a keg was built from
+	if taps := m.receiptTaps(formulaName); len(taps) > 0 {
+		for _, tap := range taps {
+			if strings.EqualFold(tap, tapName) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Kegs installed before receipts recorded the tap fall back to checking
+	// whether the tap still provides the formula
 	tapPath := m.getTapPath(tapName)
 	formulaInTap := filepath.Join(tapPath, "Formula", formulaName+".rb")
 	yamlInTap := filepath.Join(tapPath, "Formula", formulaName+".yaml")
@@ -475,12 +989,42 @@ func (m *Manager) isFormulaFromTap(formulaName, tapName string) bool {
 		return true
 	}
 
-	// TODO: For more accuracy, parse install receipt to check tap information
-	// receiptPath := filepath.Join(m.cfg.HomebrewCellar, formulaName, "INSTALL_RECEIPT.json")
-
 	return false
 }
 
+// receiptTaps returns the tap recorded in the install receipt of each
+// installed version of a formula, keyed by version
+func (m *Manager) receiptTaps(formulaName string) map[string]string {
+	formulaPath := filepath.Join(m.cfg.HomebrewCellar, formulaName)
+	versions, err := os.ReadDir(formulaPath)
+	if err != nil {
+		return nil
+	}
+
+	taps := make(map[string]string)
+	for _, version := range versions {
+		if !version.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(formulaPath, version.Name(), "INSTALL_RECEIPT.json"))
+		if err != nil {
+			continue
+		}
+		// installer imports tap, so installer.ReadReceipt isn't available here
+		var receipt struct {
+			Tap string `json:"tap"`
+		}
+		if err := json.Unmarshal(data, &receipt); err != nil {
+			logger.Debug("Ignoring unreadable receipt for %s %s: %v", formulaName, version.Name(), err)
+			continue
+		}
+		if receipt.Tap != "" {
+			taps[version.Name()] = receipt.Tap
+		}
+	}
+	return taps
+}
+
 // GetFormula returns a formula from this tap
 func (t *Tap) GetFormula(name string) (*formula.Formula, error) {
 	formulaPath := filepath.Join(t.Path, "Formula", name+".rb")
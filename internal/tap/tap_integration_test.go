@@ -1,13 +1,20 @@
 package tap
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"strings"
 
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/pilshchikov/homebrew-go/internal/config"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 )
@@ -326,6 +333,77 @@ func TestProgressWriter(t *testing.T) {
 	}
 }
 
+func TestParseGitProgress(t *testing.T) {
+	tests := []struct {
+		line        string
+		wantOK      bool
+		wantPhase   string
+		wantPercent int
+	}{
+		{"Counting objects:   1% (12/1200)", true, "Counting objects", 1},
+		{"Compressing objects:  50% (300/600)", true, "Compressing objects", 50},
+		{"Receiving objects:  45% (450/1000), 1.20 MiB | 2.00 MiB/s", true, "Receiving objects", 45},
+		{"Resolving deltas: 100% (512/512), done.", true, "Resolving deltas", 100},
+		{"Enumerating objects: 1200, done.", false, "", 0},
+		{"Total 1200 (delta 512), reused 0 (delta 0), pack-reused 1200", false, "", 0},
+		{"", false, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			progress, ok := parseGitProgress(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("parseGitProgress(%q) ok = %v, want %v", tt.line, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if progress.Phase != tt.wantPhase {
+				t.Errorf("Phase = %q, want %q", progress.Phase, tt.wantPhase)
+			}
+			if progress.Percent() != tt.wantPercent {
+				t.Errorf("Percent() = %d, want %d", progress.Percent(), tt.wantPercent)
+			}
+		})
+	}
+}
+
+func TestProgressWriterPercentages(t *testing.T) {
+	// One write holding several carriage-return separated updates, as sent
+	// by the remote; intermediate percentages are throttled away
+	send := func(writer *ProgressWriter) {
+		_, _ = writer.Write([]byte("Enumerating objects: 4, done.\n" +
+			"Counting objects:  25% (1/4)\rCounting objects:  50% (2/4)\rCounting objects: 100% (4/4)\rCounting objects: 100% (4/4), done.\n" +
+			"Receiving objects:  50% (2/4)\r"))
+		_, _ = writer.Write([]byte("Receiving objects: 100% (4/4), done.\n"))
+		writer.Finish()
+	}
+
+	t.Run("terminal", func(t *testing.T) {
+		var out bytes.Buffer
+		send(&ProgressWriter{prefix: "Clone test", out: &out, tty: true})
+
+		want := "\r\033[KClone test: Counting objects 25% (1/4)" +
+			"\r\033[KClone test: Counting objects 100% (4/4)" +
+			"\r\033[KClone test: Receiving objects 50% (2/4)" +
+			"\r\033[KClone test: Receiving objects 100% (4/4)\n"
+		if out.String() != want {
+			t.Errorf("output = %q, want %q", out.String(), want)
+		}
+	})
+
+	t.Run("not a terminal", func(t *testing.T) {
+		var out bytes.Buffer
+		send(&ProgressWriter{prefix: "Clone test", out: &out})
+
+		want := "Clone test: Counting objects 100% (4/4)\n" +
+			"Clone test: Receiving objects 100% (4/4)\n"
+		if out.String() != want {
+			t.Errorf("output = %q, want %q", out.String(), want)
+		}
+	})
+}
+
 func TestAddTap(t *testing.T) {
 	logger.Init(false, false, true)
 
@@ -428,6 +506,74 @@ func TestRemoveTap(t *testing.T) {
 	}
 }
 
+func TestRemoveTapPurgesCache(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewRepository: tempDir,
+		HomebrewCellar:     filepath.Join(tempDir, "Cellar"),
+		HomebrewCache:      filepath.Join(tempDir, "Cache"),
+	}
+	manager := NewManager(cfg)
+
+	tapPath := filepath.Join(tempDir, "Library", "Taps", "test", "homebrew-example")
+	for _, file := range []string{"Formula/mytool.rb", "Formula/wget.rb", "Formula/curl.rb", "Casks/mycask.rb"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tapPath, file)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tapPath, file), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// mytool 1.0 was built from the tap; wget is installed from core and
+	// curl isn't installed, so their downloads aren't known to be the tap's
+	for keg, tap := range map[string]string{"mytool/1.0": "test/example", "wget/1.21": "homebrew/core"} {
+		kegPath := filepath.Join(cfg.HomebrewCellar, keg)
+		if err := os.MkdirAll(kegPath, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(kegPath, "INSTALL_RECEIPT.json"), []byte(`{"tap": "`+tap+`"}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cached := map[string]bool{
+		"downloads/mytool-1.0.tar.gz":                     false,
+		"downloads/mytool-1.0.arm64_sonoma.bottle.tar.gz": false,
+		"downloads/mytool-1.0.1.tar.gz":                   true,
+		"downloads/mytool-extra-1.0.tar.gz":               true,
+		"downloads/wget-1.21.tar.gz":                      true,
+		"downloads/curl-8.0.tar.gz":                       true,
+		"cask/mycask-3.1.dmg":                             true,
+		"taps/test/example/formula.json":                  false,
+	}
+	for file := range cached {
+		path := filepath.Join(cfg.HomebrewCache, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := manager.RemoveTap("test/example", &TapOptions{Force: true}); err != nil {
+		t.Fatalf("RemoveTap() error = %v", err)
+	}
+
+	for file, kept := range cached {
+		_, err := os.Stat(filepath.Join(cfg.HomebrewCache, file))
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s: exists = %v, want %v", file, exists, kept)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.HomebrewCache, "taps", "test", "example")); !os.IsNotExist(err) {
+		t.Error("Expected the tap cache directory to be removed")
+	}
+}
+
 func TestUpdateTap(t *testing.T) {
 	logger.Init(false, false, true)
 
@@ -561,6 +707,51 @@ func TestIsFormulaFromTap(t *testing.T) {
 	}
 }
 
+func TestIsFormulaFromTapUsesReceipt(t *testing.T) {
+	logger.Init(false, false, true)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		HomebrewRepository: tempDir,
+		HomebrewCellar:     filepath.Join(tempDir, "Cellar"),
+	}
+	manager := NewManager(cfg)
+
+	// The tap provides "shared" too, but the receipt says it came from core
+	formulaDir := filepath.Join(tempDir, "Library", "Taps", "test", "homebrew-example", "Formula")
+	if err := os.MkdirAll(formulaDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(formulaDir, "shared.rb"), []byte("# shared"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	receipts := map[string]string{
+		"removed/1.0": `{"name": "removed", "tap": "test/example"}`,
+		"shared/2.0":  `{"name": "shared", "tap": "homebrew/core"}`,
+	}
+	for keg, receipt := range receipts {
+		kegPath := filepath.Join(cfg.HomebrewCellar, keg)
+		if err := os.MkdirAll(kegPath, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(kegPath, "INSTALL_RECEIPT.json"), []byte(receipt), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No formula file exists for "removed", only the receipt links it to the tap
+	if !manager.isFormulaFromTap("removed", "test/example") {
+		t.Error("Expected removed to be from test/example according to its receipt")
+	}
+	if manager.isFormulaFromTap("shared", "test/example") {
+		t.Error("Expected shared to be attributed to homebrew/core according to its receipt")
+	}
+	if !manager.isFormulaFromTap("shared", "homebrew/core") {
+		t.Error("Expected shared to be from homebrew/core")
+	}
+}
+
 func TestTapGetFormula(t *testing.T) {
 	logger.Init(false, false, true)
 
@@ -687,3 +878,285 @@ func TestTapListFormulae(t *testing.T) {
 		t.Error("Expected error for non-existent formula directory")
 	}
 }
+
+// createSourceTap creates a local git repository with the given number of commits
+func createSourceTap(t *testing.T, commits int) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+
+	workTree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+
+	_ = os.MkdirAll(filepath.Join(dir, "Formula"), 0755)
+	for i := 0; i < commits; i++ {
+		formulaFile := filepath.Join("Formula", fmt.Sprintf("formula%d.rb", i))
+		_ = os.WriteFile(filepath.Join(dir, formulaFile), []byte("# test formula"), 0644)
+		if _, err := workTree.Add(formulaFile); err != nil {
+			t.Fatalf("Failed to stage file: %v", err)
+		}
+		_, err := workTree.Commit(fmt.Sprintf("commit %d", i), &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+	}
+
+	return dir
+}
+
+func TestUnshallowTap(t *testing.T) {
+	logger.Init(false, false, true)
+
+	source := createSourceTap(t, 3)
+	cfg := &config.Config{
+		HomebrewRepository: t.TempDir(),
+	}
+	manager := NewManager(cfg)
+
+	if err := manager.AddTap("test/shallow", "file://"+source, &TapOptions{Shallow: true}); err != nil {
+		t.Fatalf("AddTap() error = %v", err)
+	}
+
+	if !manager.IsShallow("test/shallow") {
+		t.Fatal("Expected shallow clone after --shallow tap")
+	}
+
+	if err := manager.Unshallow("test/shallow"); err != nil {
+		t.Fatalf("Unshallow() error = %v", err)
+	}
+
+	if manager.IsShallow("test/shallow") {
+		t.Error("Expected full clone after Unshallow()")
+	}
+
+	repo, err := git.PlainOpen(manager.getTapPath("test/shallow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iter, err := repo.Log(&git.LogOptions{})
+	if err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+	count := 0
+	_ = iter.ForEach(func(*object.Commit) error {
+		count++
+		return nil
+	})
+	if count != 3 {
+		t.Errorf("Expected 3 commits after unshallow, got %d", count)
+	}
+}
+
+func TestUpdateShallowTapReportsUnshallowError(t *testing.T) {
+	logger.Init(false, false, true)
+
+	source := createSourceTap(t, 2)
+	cfg := &config.Config{
+		HomebrewRepository: t.TempDir(),
+	}
+	manager := NewManager(cfg)
+
+	if err := manager.AddTap("test/shallow", "file://"+source, &TapOptions{Shallow: true}); err != nil {
+		t.Fatalf("AddTap() error = %v", err)
+	}
+
+	// With the remote gone both the pull and the history fetch fail
+	if err := os.RemoveAll(source); err != nil {
+		t.Fatal(err)
+	}
+
+	err := manager.UpdateTap("test/shallow")
+	if err == nil {
+		t.Fatal("Expected UpdateTap() to fail without a remote")
+	}
+	if !strings.Contains(err.Error(), "failed to unshallow tap") {
+		t.Errorf("UpdateTap() error = %v, want the unshallow failure included", err)
+	}
+}
+
+func TestSetTapRemote(t *testing.T) {
+	logger.Init(false, false, true)
+
+	fork := createSourceTap(t, 1)
+	upstream := createSourceTap(t, 1)
+	cfg := &config.Config{
+		HomebrewRepository: t.TempDir(),
+	}
+	manager := NewManager(cfg)
+
+	if err := manager.AddTap("test/remote", "file://"+fork, nil); err != nil {
+		t.Fatalf("AddTap() error = %v", err)
+	}
+	tapPath := manager.getTapPath("test/remote")
+
+	if err := manager.SetTapRemote("test/remote", "file://"+upstream); err != nil {
+		t.Fatalf("SetTapRemote() error = %v", err)
+	}
+	if got := manager.getRemoteURL(tapPath); got != "file://"+upstream {
+		t.Errorf("getRemoteURL() = %v, want %v", got, "file://"+upstream)
+	}
+
+	for _, remote := range []string{"", "https://example.com/has space.git"} {
+		if err := manager.SetTapRemote("test/remote", remote); err == nil {
+			t.Errorf("SetTapRemote(%q) expected error", remote)
+		}
+	}
+	if got := manager.getRemoteURL(tapPath); got != "file://"+upstream {
+		t.Errorf("Invalid remotes should leave origin unchanged, got %v", got)
+	}
+
+	if err := manager.SetTapRemote("test/missing", "file://"+upstream); err == nil {
+		t.Error("SetTapRemote() on a tap that is not installed expected error")
+	}
+}
+
+func TestAddTapMirrorFallback(t *testing.T) {
+	logger.Init(false, false, true)
+
+	origDelay := cloneRetryDelay
+	cloneRetryDelay = time.Millisecond
+	defer func() { cloneRetryDelay = origDelay }()
+
+	source := createSourceTap(t, 1)
+	cfg := &config.Config{
+		HomebrewRepository: t.TempDir(),
+		GitCloneRetries:    2,
+	}
+	manager := NewManager(cfg)
+
+	missing := "file://" + filepath.Join(t.TempDir(), "missing-remote")
+	options := &TapOptions{Mirrors: []string{"file://" + source}}
+
+	if err := manager.AddTap("test/mirror", missing, options); err != nil {
+		t.Fatalf("AddTap() with mirror error = %v", err)
+	}
+
+	tap, err := manager.GetTap("test/mirror")
+	if err != nil || !tap.Installed {
+		t.Fatalf("Expected tap to be installed from mirror, got %v, %v", tap, err)
+	}
+	if tap.Remote != "file://"+source {
+		t.Errorf("Tap remote = %v, want mirror %v", tap.Remote, "file://"+source)
+	}
+}
+
+func TestAddTapAllRemotesFail(t *testing.T) {
+	logger.Init(false, false, true)
+
+	origDelay := cloneRetryDelay
+	cloneRetryDelay = time.Millisecond
+	defer func() { cloneRetryDelay = origDelay }()
+
+	cfg := &config.Config{
+		HomebrewRepository: t.TempDir(),
+		GitCloneRetries:    2,
+	}
+	manager := NewManager(cfg)
+
+	missing := "file://" + filepath.Join(t.TempDir(), "missing-remote")
+	err := manager.AddTap("test/broken", missing, &TapOptions{Mirrors: []string{missing + "-mirror"}})
+	if err == nil || !strings.Contains(err.Error(), "failed to clone") {
+		t.Fatalf("Expected clone error, got %v", err)
+	}
+
+	// No partial clone is left behind
+	if _, err := os.Stat(manager.getTapPath("test/broken")); !os.IsNotExist(err) {
+		t.Error("Expected partial clone directory to be removed")
+	}
+}
+
+func TestAddTapNotATap(t *testing.T) {
+	logger.Init(false, false, true)
+
+	source := t.TempDir()
+	repo, err := git.PlainInit(source, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+	workTree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	_ = os.WriteFile(filepath.Join(source, "README.md"), []byte("not a tap"), 0644)
+	if _, err := workTree.Add("README.md"); err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+	_, err = workTree.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	manager := NewManager(&config.Config{HomebrewRepository: t.TempDir()})
+
+	err = manager.AddTap("test/plain", "file://"+source, nil)
+	if !errors.Is(err, ErrNotTap) {
+		t.Fatalf("AddTap() error = %v, want %v", err, ErrNotTap)
+	}
+	if strings.Contains(err.Error(), "failed to clone") {
+		t.Errorf("Clone succeeded, error should not report a clone failure: %v", err)
+	}
+
+	if _, err := os.Stat(manager.getTapPath("test/plain")); !os.IsNotExist(err) {
+		t.Error("Expected cloned directory to be removed")
+	}
+}
+
+func TestAddTapProbeMissingGitHubRepo(t *testing.T) {
+	logger.Init(false, false, true)
+
+	var probed string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probed = r.Method + " " + r.URL.Path
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	origURL := githubAPIURL
+	githubAPIURL = server.URL
+	defer func() { githubAPIURL = origURL }()
+
+	manager := NewManager(&config.Config{HomebrewRepository: t.TempDir()})
+
+	err := manager.AddTap("test/missing", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to clone") {
+		t.Fatalf("Expected clone error for missing repository, got %v", err)
+	}
+	if probed != "HEAD /repos/test/homebrew-missing" {
+		t.Errorf("Probe request = %q", probed)
+	}
+	if _, err := os.Stat(manager.getTapPath("test/missing")); !os.IsNotExist(err) {
+		t.Error("Expected no tap directory after failed probe")
+	}
+}
+
+func TestGithubRemoteRepo(t *testing.T) {
+	tests := []struct {
+		remote    string
+		wantOwner string
+		wantRepo  string
+		wantOK    bool
+	}{
+		{"https://github.com/user/homebrew-tools.git", "user", "homebrew-tools", true},
+		{"https://github.com/user/homebrew-tools", "user", "homebrew-tools", true},
+		{"git@github.com:user/homebrew-tools.git", "", "", false},
+		{"https://gitlab.com/user/homebrew-tools.git", "", "", false},
+		{"file:///tmp/tap", "", "", false},
+	}
+
+	for _, tt := range tests {
+		owner, repo, ok := githubRemoteRepo(tt.remote)
+		if owner != tt.wantOwner || repo != tt.wantRepo || ok != tt.wantOK {
+			t.Errorf("githubRemoteRepo(%q) = %q, %q, %v", tt.remote, owner, repo, ok)
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package tap
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/pilshchikov/homebrew-go/internal/config"
 )
 
@@ -75,6 +78,75 @@ func TestGetDefaultRemote(t *testing.T) {
 	}
 }
 
+func TestToSSHRemote(t *testing.T) {
+	tests := []struct {
+		name     string
+		remote   string
+		expected string
+	}{
+		{
+			name:     "github https",
+			remote:   "https://github.com/user/homebrew-repo.git",
+			expected: "git@github.com:user/homebrew-repo.git",
+		},
+		{
+			name:     "already ssh",
+			remote:   "git@github.com:user/homebrew-repo.git",
+			expected: "git@github.com:user/homebrew-repo.git",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := toSSHRemote(tt.remote)
+			if result != tt.expected {
+				t.Errorf("toSSHRemote() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRewriteRemote(t *testing.T) {
+	cfg := &config.Config{
+		GitRemoteRewrite: map[string]string{
+			"https://github.com/":      "https://git.internal.example.com/mirror/",
+			"https://github.com/user/": "https://git.internal.example.com/user/",
+		},
+	}
+	manager := NewManager(cfg)
+
+	tests := []struct {
+		name     string
+		remote   string
+		expected string
+	}{
+		{
+			name:     "prefix rewrite to internal host",
+			remote:   "https://github.com/homebrew/homebrew-core.git",
+			expected: "https://git.internal.example.com/mirror/homebrew/homebrew-core.git",
+		},
+		{
+			name:     "longest prefix wins",
+			remote:   "https://github.com/user/homebrew-repo.git",
+			expected: "https://git.internal.example.com/user/homebrew-repo.git",
+		},
+		{
+			name:     "no matching rule",
+			remote:   "https://gitlab.com/user/homebrew-repo.git",
+			expected: "https://gitlab.com/user/homebrew-repo.git",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := manager.rewriteRemote(tt.remote)
+			if result != tt.expected {
+				t.Errorf("rewriteRemote() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestGetTapPath(t *testing.T) {
 	tmpDir := t.TempDir()
 	cfg := &config.Config{
@@ -434,3 +506,23 @@ func TestTapListFormulaeOriginal(t *testing.T) {
 		}
 	}
 }
+
+func TestIsTransientCloneError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"network error", errors.New("connection reset by peer"), true},
+		{"not found", transport.ErrRepositoryNotFound, false},
+		{"wrapped auth error", fmt.Errorf("clone: %w", transport.ErrAuthenticationRequired), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := isTransientCloneError(tt.err); result != tt.expected {
+				t.Errorf("isTransientCloneError() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
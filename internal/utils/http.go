@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pilshchikov/homebrew-go/internal/logger"
+)
+
+// maxRedirects matches the limit net/http applies by default
+const maxRedirects = 10
+
+// sensitiveHeaders carry credentials that must not reach another host
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// loggedHeaders are the request headers written to the debug log; the values
+// of sensitive ones are redacted
+var loggedHeaders = []string{"Accept", "Range", "User-Agent", "X-Request-ID", "Authorization", "Proxy-Authorization", "Cookie"}
+
+// Transport is shared by API requests and downloads so that connections to
+// the same host are kept alive and reused; tests may replace it
+var Transport http.RoundTripper = NewTransport()
+
+// NewTransport returns an HTTP transport tuned for many small requests to a
+// handful of hosts, as when fetching a formula and its dependencies
+func NewTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// DownloadClient returns a client over the shared transport. Downloads have
+// no overall timeout since large archives can legitimately take minutes.
+func DownloadClient() *http.Client {
+	return &http.Client{Transport: Transport, CheckRedirect: CheckRedirect}
+}
+
+// CheckRedirect follows redirects but drops credentials when one leaves the
+// original host, so a token for GitHub is never sent on to a mirror or CDN.
+// Same-host redirects, such as GHCR blob redirects, keep them.
+func CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+
+	original := via[0].URL
+	if req.URL.Host != original.Host || (original.Scheme == "https" && req.URL.Scheme != "https") {
+		for _, header := range sensitiveHeaders {
+			req.Header.Del(header)
+		}
+	}
+	return nil
+}
+
+// LoggingTransport logs each request and its response through Logf. Values
+// of credential headers are never logged.
+type LoggingTransport struct {
+	Next http.RoundTripper
+	Logf func(format string, args ...interface{})
+}
+
+// NewLoggingTransport wraps next so requests are logged at debug level
+func NewLoggingTransport(next http.RoundTripper) http.RoundTripper {
+	if t, ok := next.(*LoggingTransport); ok {
+		return t
+	}
+	return &LoggingTransport{Next: next, Logf: logger.Debug}
+}
+
+// RoundTrip logs the request, passes it on and logs the outcome
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := req.URL.Redacted()
+	t.Logf("HTTP %s %s%s", req.Method, url, formatLoggedHeaders(req.Header))
+
+	start := time.Now()
+	resp, err := t.Next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		t.Logf("HTTP %s %s failed after %s: %v", req.Method, url, elapsed, err)
+		return resp, err
+	}
+
+	t.Logf("HTTP %s %s: %s, content-length %d, %s", req.Method, url, resp.Status, resp.ContentLength, elapsed)
+	return resp, nil
+}
+
+// formatLoggedHeaders returns the headers worth logging, with credentials
+// replaced by a placeholder
+func formatLoggedHeaders(header http.Header) string {
+	var parts []string
+	for _, name := range loggedHeaders {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		if slices.Contains(sensitiveHeaders, name) {
+			value = "[REDACTED]"
+		}
+		parts = append(parts, name+": "+value)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckRedirectStripsCredentials(t *testing.T) {
+	var seen map[string]string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		seen[r.URL.Path] = r.Header.Get("Authorization")
+	}
+
+	mirror := httptest.NewServer(http.HandlerFunc(record))
+	defer mirror.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cross-host":
+			http.Redirect(w, r, mirror.URL+"/mirrored", http.StatusFound)
+		case "/same-host":
+			http.Redirect(w, r, "/blob", http.StatusTemporaryRedirect)
+		default:
+			record(w, r)
+		}
+	}))
+	defer origin.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		landing  string
+		expected string
+	}{
+		{"cross-host redirect drops auth", "/cross-host", "/mirrored", ""},
+		{"same-host redirect keeps auth", "/same-host", "/blob", "Bearer secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = make(map[string]string)
+			req, err := http.NewRequest("GET", origin.URL+tt.path, http.NoBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer secret")
+
+			resp, err := DownloadClient().Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			_ = resp.Body.Close()
+
+			auth, ok := seen[tt.landing]
+			if !ok {
+				t.Fatalf("redirect did not reach %s", tt.landing)
+			}
+			if auth != tt.expected {
+				t.Errorf("Authorization at %s = %q, want %q", tt.landing, auth, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckRedirectLimit(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.com/next", http.NoBody)
+	via := make([]*http.Request, maxRedirects)
+	for i := range via {
+		via[i] = req
+	}
+	if err := CheckRedirect(req, via); err == nil {
+		t.Error("Expected an error after too many redirects")
+	}
+}
+
+func TestLoggingTransportRedactsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var lines []string
+	transport := &LoggingTransport{
+		Next: http.DefaultTransport,
+		Logf: func(format string, args ...interface{}) {
+			lines = append(lines, fmt.Sprintf(format, args...))
+		},
+	}
+
+	req, err := http.NewRequest("GET", server.URL+"/formula.json", http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("User-Agent", "Homebrew-Go/test")
+
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected a request and a response line, got %q", lines)
+	}
+	output := strings.Join(lines, "\n")
+	for _, want := range []string{"GET " + server.URL + "/formula.json", "User-Agent: Homebrew-Go/test", "Authorization: [REDACTED]", "200 OK", "content-length 2"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected log to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "secret-token") {
+		t.Errorf("Token leaked into the log:\n%s", output)
+	}
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// InterruptExitCode is the exit status used after an interrupt, as in shells
+const InterruptExitCode = 130
+
+var (
+	cleanupMu     sync.Mutex
+	cleanupNextID int
+	cleanups      = make(map[int]func())
+)
+
+// RegisterCleanup adds fn to the work done when the process is interrupted.
+// The returned function unregisters it once the guarded operation finishes.
+func RegisterCleanup(fn func()) func() {
+	cleanupMu.Lock()
+	defer cleanupMu.Unlock()
+
+	id := cleanupNextID
+	cleanupNextID++
+	cleanups[id] = fn
+
+	return func() {
+		cleanupMu.Lock()
+		defer cleanupMu.Unlock()
+		delete(cleanups, id)
+	}
+}
+
+// RunCleanups runs every registered cleanup, newest first, and clears them
+func RunCleanups() {
+	cleanupMu.Lock()
+	ids := make([]int, 0, len(cleanups))
+	for id := range cleanups {
+		ids = append(ids, id)
+	}
+	// Later registrations run first, like deferred calls
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	fns := make([]func(), 0, len(ids))
+	for _, id := range ids {
+		fns = append(fns, cleanups[id])
+		delete(cleanups, id)
+	}
+	cleanupMu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// HandleInterrupts cancels the running command on SIGINT or SIGTERM, runs
+// the registered cleanups and exits with InterruptExitCode. The returned
+// function stops listening for signals.
+func HandleInterrupts(cancel func()) func() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go handleInterrupts(sigCh, done, cancel, os.Exit)
+
+	return func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
+
+func handleInterrupts(sigCh <-chan os.Signal, done <-chan struct{}, cancel func(), exit func(int)) {
+	select {
+	case <-sigCh:
+		cancel()
+		RunCleanups()
+		exit(InterruptExitCode)
+	case <-done:
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunCleanups(t *testing.T) {
+	var order []int
+	RegisterCleanup(func() { order = append(order, 1) })
+	unregister := RegisterCleanup(func() { order = append(order, 2) })
+	RegisterCleanup(func() { order = append(order, 3) })
+	unregister()
+
+	RunCleanups()
+	if !reflect.DeepEqual(order, []int{3, 1}) {
+		t.Errorf("RunCleanups() ran %v, want [3 1]", order)
+	}
+
+	// Cleanups only run once
+	RunCleanups()
+	if len(order) != 2 {
+		t.Errorf("RunCleanups() ran cleanups again: %v", order)
+	}
+}
+
+func TestHandleInterruptsRemovesStaging(t *testing.T) {
+	staging := filepath.Join(t.TempDir(), "Cellar", "wget", "1.21")
+	if err := os.MkdirAll(filepath.Join(staging, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	unregister := RegisterCleanup(func() { _ = os.RemoveAll(staging) })
+	defer unregister()
+
+	sigCh := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	cancelled := false
+	exitCode := make(chan int, 1)
+
+	go handleInterrupts(sigCh, done, func() { cancelled = true }, func(code int) { exitCode <- code })
+	sigCh <- os.Interrupt
+
+	select {
+	case code := <-exitCode:
+		if code != InterruptExitCode {
+			t.Errorf("exit code = %d, want %d", code, InterruptExitCode)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("interrupt was not handled")
+	}
+
+	if !cancelled {
+		t.Error("Interrupt should cancel the running command")
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Error("Staging directory should be removed after interrupt")
+	}
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if IsTerminal(f) {
+		t.Error("IsTerminal() = true for a regular file")
+	}
+
+	_ = f.Close()
+	if IsTerminal(f) {
+		t.Error("IsTerminal() = true for a closed file")
+	}
+}
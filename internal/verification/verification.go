@@ -134,7 +134,8 @@ func (v *Verifier) verifyChecksum(filePath string, checksum Checksum) (bool, err
 	}
 	defer func() { _ = file.Close() }()
 
-	if _, err := io.Copy(hasher, file); err != nil {
+	size, err := io.Copy(hasher, file)
+	if err != nil {
 		return false, fmt.Errorf("failed to compute %s checksum: %w", checksum.Type, err)
 	}
 
@@ -152,7 +153,12 @@ func (v *Verifier) verifyChecksum(filePath string, checksum Checksum) (bool, err
 			formula = parts[0]
 			version = parts[1]
 		}
-		return false, errors.NewChecksumError(formula, version, expectedChecksum, actualChecksum)
+		return false, errors.NewChecksumMismatchError(formula, version, errors.ChecksumMismatch{
+			Path:     filePath,
+			Expected: expectedChecksum,
+			Actual:   actualChecksum,
+			Size:     size,
+		})
 	}
 
 	logger.Debug("%s checksum verified successfully", checksum.Type)
@@ -259,6 +265,15 @@ func (result *VerificationResult) GetSummary() string {
 	return fmt.Sprintf("✗ Verification failed: %s", strings.Join(issues, ", "))
 }
 
+// failure explains a failed verification of what, keeping the first
+// underlying error (such as a checksum mismatch) in the chain
+func (result *VerificationResult) failure(what string) error {
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%s verification failed: %w", what, result.Errors[0])
+	}
+	return fmt.Errorf("%s verification failed: %s", what, result.GetSummary())
+}
+
 // LogResults logs the verification results with appropriate log levels
 func (result *VerificationResult) LogResults() {
 	if result.IsVerificationSuccessful() {
@@ -300,7 +315,7 @@ func (pv *PackageVerifier) VerifyBottle(bottlePath, expectedSHA256 string, expec
 	result.LogResults()
 
 	if !result.IsVerificationSuccessful() {
-		return fmt.Errorf("bottle verification failed: %s", result.GetSummary())
+		return result.failure("bottle")
 	}
 
 	return nil
@@ -320,7 +335,7 @@ func (pv *PackageVerifier) VerifySource(sourcePath, expectedSHA256 string, expec
 	result.LogResults()
 
 	if !result.IsVerificationSuccessful() {
-		return fmt.Errorf("source verification failed: %s", result.GetSummary())
+		return result.failure("source")
 	}
 
 	return nil
@@ -3,12 +3,14 @@ package verification
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	stderrors "errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/pilshchikov/homebrew-go/internal/errors"
 	"github.com/pilshchikov/homebrew-go/internal/logger"
 )
 
@@ -423,6 +425,45 @@ func TestPackageVerifier(t *testing.T) {
 	}
 }
 
+func TestPackageVerifierChecksumDiagnostics(t *testing.T) {
+	logger.Init(false, false, true)
+
+	testFile := filepath.Join(t.TempDir(), "hello-1.0.tar.gz")
+	testContent := "corrupted download"
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(testContent))
+	actual := hex.EncodeToString(sum[:])
+	expected := strings.Repeat("ab", 32)
+
+	pv := NewPackageVerifier(false)
+	for name, verify := range map[string]func(string, string, int64) error{
+		"bottle": pv.VerifyBottle,
+		"source": pv.VerifySource,
+	} {
+		err := verify(testFile, expected, 0)
+		if err == nil {
+			t.Fatalf("%s verification should fail with a wrong checksum", name)
+		}
+
+		msg := err.Error()
+		for _, want := range []string{expected, actual, fmt.Sprintf("%d bytes", len(testContent)), "brew cleanup"} {
+			if !strings.Contains(msg, want) {
+				t.Errorf("%s verification error %q does not mention %q", name, msg, want)
+			}
+		}
+
+		var brewErr *errors.BrewError
+		if !stderrors.As(err, &brewErr) || brewErr.Checksum == nil {
+			t.Fatalf("%s verification error should wrap a BrewError with checksum details, got %v", name, err)
+		}
+		if brewErr.Checksum.Path != testFile || brewErr.Checksum.Size != int64(len(testContent)) {
+			t.Errorf("Checksum details = %+v, want path %s and size %d", brewErr.Checksum, testFile, len(testContent))
+		}
+	}
+}
+
 func TestVerifyInstallation(t *testing.T) {
 	// Initialize logger for tests
 	logger.Init(false, false, true) // quiet mode